@@ -45,6 +45,20 @@ func main() {
 		err = db.AutoMigrate(
 			&entity.User{},
 			&entity.AppVersion{},
+			&entity.Role{},
+			&entity.RolePermission{},
+			&entity.UserRole{},
+			&entity.Upload{},
+			&entity.Notification{},
+			&entity.NotificationPreference{},
+			&entity.NotificationChannelSetting{},
+			&entity.ScheduledNotification{},
+			&entity.SceneLink{},
+			&entity.Order{},
+			&entity.Payment{},
+			&entity.PaymentEvent{},
+			&entity.Refund{},
+			&entity.ReconciliationDiscrepancy{},
 		)
 		if err != nil {
 			log.Fatalf("Migration failed: %v", err)
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/wire"
+)
+
+// runCommand 执行子命令
+func runCommand(app *wire.App, name string, args []string) error {
+	switch name {
+	case "reconcile":
+		return runReconcile(app, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runReconcile 核对指定日期的支付渠道对账单并输出结果，默认核对前一天
+// 用法: server [-config path] reconcile [-date YYYY-MM-DD]
+func runReconcile(app *wire.App, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	date := fs.String("date", "", "Bill date to reconcile (YYYY-MM-DD), defaults to yesterday")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var result *dto.ReconciliationResultDTO
+	var err error
+	if *date == "" {
+		result, err = app.Reconciliation.ReconcileYesterday(ctx)
+	} else {
+		result, err = app.Reconciliation.Reconcile(ctx, *date)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
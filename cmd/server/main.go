@@ -26,7 +26,7 @@ import (
 )
 
 func main() {
-	// 解析命令行参数
+	// 解析命令行参数，参数之后可跟子命令，如 reconcile -date 2024-01-02
 	configPath := flag.String("config", "", "Configuration file path (e.g., config/config.yaml)")
 	flag.Parse()
 
@@ -50,6 +50,14 @@ func main() {
 		logger.Fatal("Failed to init app", zap.Error(err))
 	}
 
+	// 执行子命令后退出，不启动服务
+	if flag.NArg() > 0 {
+		if err := runCommand(app, flag.Arg(0), flag.Args()[1:]); err != nil {
+			logger.Fatal("Command failed", zap.String("command", flag.Arg(0)), zap.Error(err))
+		}
+		return
+	}
+
 	// 启动HTTP服务器
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
@@ -69,6 +77,9 @@ func main() {
 		}
 	}()
 
+	// 启动后台任务
+	app.Scheduler.Start(context.Background())
+
 	// 优雅关闭
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -83,6 +94,9 @@ func main() {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	// 停止后台任务
+	app.Scheduler.Stop()
+
 	logger.Info("Server exited")
 }
 
@@ -2,13 +2,20 @@ package dto
 
 // AppVersionDTO 应用版本 DTO
 type AppVersionDTO struct {
-	Version      string `json:"version"`
-	Name         string `json:"name"`
-	Description  string `json:"description,omitempty"`
-	MinVersion   string `json:"minVersion,omitempty"`
-	ForceUpdate  bool   `json:"forceUpdate"`
-	ReleaseNotes string `json:"releaseNotes,omitempty"`
-	BuildTime    int64  `json:"buildTime"` // 毫秒时间戳
+	Version           string `json:"version"`
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	MinVersion        string `json:"minVersion,omitempty"`
+	ForceUpdate       bool   `json:"forceUpdate"`
+	ReleaseNotes      string `json:"releaseNotes,omitempty"`
+	IsActive          bool   `json:"isActive"`
+	Channel           string `json:"channel"`
+	Platform          string `json:"platform,omitempty"`
+	RolloutPercentage int    `json:"rolloutPercentage"`
+	RolloutStatus     string `json:"rolloutStatus"`
+	BuildTime         int64  `json:"buildTime"` // 毫秒时间戳
+	CreatedAt         int64  `json:"createdAt"` // 毫秒时间戳
+	UpdatedAt         int64  `json:"updatedAt"` // 毫秒时间戳
 }
 
 // CreateAppVersionRequest 创建版本请求
@@ -17,12 +24,62 @@ type CreateAppVersionRequest struct {
 	Name         string `json:"name" binding:"required"`
 	Description  string `json:"description"`
 	MinVersion   string `json:"minVersion"`
-	IsActive     bool   `json:"isActive"`
+	IsActive     bool   `json:"isActive"` // 创建即全量发布
 	ForceUpdate  bool   `json:"forceUpdate"`
 	ReleaseNotes string `json:"releaseNotes"`
+	Channel      string `json:"channel" binding:"omitempty,oneof=stable beta internal"` // 默认 stable
+	Platform     string `json:"platform" binding:"omitempty,max=20"`                    // 为空表示全平台
 }
 
 // SetActiveVersionRequest 设置活跃版本请求
 type SetActiveVersionRequest struct {
 	Version string `json:"version" binding:"required"`
 }
+
+// UpdateAppVersionRequest 更新版本请求（版本号不可修改）
+type UpdateAppVersionRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	Description  string  `json:"description"`
+	MinVersion   string  `json:"minVersion"`
+	ForceUpdate  bool    `json:"forceUpdate"`
+	ReleaseNotes string  `json:"releaseNotes"`
+	Channel      string  `json:"channel" binding:"omitempty,oneof=stable beta internal"` // 为空表示不修改
+	Platform     *string `json:"platform" binding:"omitempty,max=20"`                    // 为空表示不修改，空字符串表示全平台
+}
+
+// RolloutRequest 灰度放量请求
+type RolloutRequest struct {
+	Percentage int `json:"percentage" binding:"required,min=1,max=100"`
+}
+
+// ListAppVersionsRequest 版本列表查询请求
+type ListAppVersionsRequest struct {
+	Page     int `form:"page"`
+	PageSize int `form:"pageSize"`
+}
+
+// UpdateCheckRequest 客户端检查更新请求
+type UpdateCheckRequest struct {
+	CurrentVersion string `form:"currentVersion" binding:"required"`
+	Platform       string `form:"platform" binding:"omitempty,max=20"`
+	Channel        string `form:"channel" binding:"omitempty,oneof=stable beta internal"` // 默认 stable，internal 需具备相应权限
+}
+
+// ReleaseNoteDTO 版本更新说明
+type ReleaseNoteDTO struct {
+	Version      string `json:"version"`
+	Name         string `json:"name"`
+	ForceUpdate  bool   `json:"forceUpdate"`
+	ReleaseNotes string `json:"releaseNotes,omitempty"`
+	BuildTime    int64  `json:"buildTime"` // 毫秒时间戳
+}
+
+// UpdateCheckResponse 客户端检查更新响应
+type UpdateCheckResponse struct {
+	HasUpdate      bool           `json:"hasUpdate"`
+	Mandatory      bool           `json:"mandatory"` // 是否必须更新后才能继续使用
+	CurrentVersion string         `json:"currentVersion"`
+	LatestVersion  *AppVersionDTO `json:"latestVersion,omitempty"`
+	// SkippedVersions 当前版本之后(不含)到最新版本(含)之间的全部版本说明，按版本号倒序
+	SkippedVersions []*ReleaseNoteDTO `json:"skippedVersions"`
+}
@@ -9,24 +9,34 @@ type WechatLoginRequest struct {
 
 // LoginResponse 登录响应 (去家庭化架构)
 type LoginResponse struct {
-	Token     string      `json:"token"`
-	UserInfo  UserInfoDTO `json:"userInfo"`
-	IsNewUser bool        `json:"isNewUser"` // 是否为新用户
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refreshToken"`
+	ExpiresIn    int         `json:"expiresIn"` // 访问令牌有效期(秒)
+	UserInfo     UserInfoDTO `json:"userInfo"`
+	IsNewUser    bool        `json:"isNewUser"` // 是否为新用户
 }
 
 // UserInfoDTO 用户信息DTO
 type UserInfoDTO struct {
-	OpenID        string `json:"openid"`
-	NickName      string `json:"nickName"`
-	AvatarURL     string `json:"avatarUrl"`
-	CreateTime    int64  `json:"createTime"`
-	LastLoginTime int64  `json:"lastLoginTime"`
+	OpenID        string   `json:"openid"`
+	NickName      string   `json:"nickName"`
+	AvatarURL     string   `json:"avatarUrl"`
+	Roles         []string `json:"roles,omitempty"`
+	CreateTime    int64    `json:"createTime"`
+	LastLoginTime int64    `json:"lastLoginTime"`
+}
+
+// RefreshTokenRequest 刷新Token请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
 }
 
 // RefreshTokenResponse 刷新Token响应
 type RefreshTokenResponse struct {
-	Token     string `json:"token"`
-	ExpiresIn int    `json:"expiresIn"`
+	Token            string `json:"token"`
+	RefreshToken     string `json:"refreshToken"`
+	ExpiresIn        int    `json:"expiresIn"`        // 访问令牌有效期(秒)
+	RefreshExpiresIn int    `json:"refreshExpiresIn"` // 刷新令牌有效期(秒)
 }
 
 // UpdateUserInfoRequest 更新用户信息请求
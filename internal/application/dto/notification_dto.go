@@ -0,0 +1,90 @@
+package dto
+
+// NotificationDTO 通知投递记录 DTO
+type NotificationDTO struct {
+	ID            string `json:"id"`
+	UserID        string `json:"userId,omitempty"` // 仅推送渠道有值
+	Channel       string `json:"channel"`
+	Recipient     string `json:"recipient"`
+	TemplateID    string `json:"templateId,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Content       string `json:"content,omitempty"`
+	Status        string `json:"status"` // pending/sending/sent/failed
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"nextAttemptAt,omitempty"` // 毫秒时间戳
+	LastError     string `json:"lastError,omitempty"`
+	SentAt        int64  `json:"sentAt,omitempty"` // 毫秒时间戳
+	CreatedAt     int64  `json:"createdAt"`        // 毫秒时间戳
+	UpdatedAt     int64  `json:"updatedAt"`        // 毫秒时间戳
+}
+
+// ListNotificationsRequest 通知投递记录查询请求
+type ListNotificationsRequest struct {
+	Channel   string `form:"channel" binding:"omitempty,oneof=sms email push wechat"`
+	Recipient string `form:"recipient"`
+	Status    string `form:"status" binding:"omitempty,oneof=pending sending sent failed"`
+	Page      int    `form:"page"`
+	PageSize  int    `form:"pageSize"`
+}
+
+// NotificationChannelSettingDTO 按通知类型与渠道的接收设置
+type NotificationChannelSettingDTO struct {
+	Type    string `json:"type" binding:"required,max=32"`
+	Channel string `json:"channel" binding:"required,oneof=sms email push wechat"`
+	Enabled bool   `json:"enabled"`
+}
+
+// NotificationPreferencesDTO 用户通知偏好
+type NotificationPreferencesDTO struct {
+	Timezone        string                           `json:"timezone"`                  // IANA 时区，为空表示使用系统默认时区
+	QuietHoursStart string                           `json:"quietHoursStart,omitempty"` // 免打扰开始时间 HH:MM
+	QuietHoursEnd   string                           `json:"quietHoursEnd,omitempty"`   // 免打扰结束时间 HH:MM，早于开始时间表示跨越午夜
+	Channels        []*NotificationChannelSettingDTO `json:"channels"`                  // 未列出的类型与渠道默认接收
+}
+
+// UpdateNotificationPreferencesRequest 更新通知偏好请求，整体替换
+type UpdateNotificationPreferencesRequest struct {
+	Timezone        string                           `json:"timezone" binding:"max=64"`
+	QuietHoursStart string                           `json:"quietHoursStart" binding:"required_with=QuietHoursEnd"`
+	QuietHoursEnd   string                           `json:"quietHoursEnd" binding:"required_with=QuietHoursStart"`
+	Channels        []*NotificationChannelSettingDTO `json:"channels" binding:"max=100,dive"`
+}
+
+// CreateScheduledNotificationRequest 创建定时通知请求
+// 单次通知指定 sendAt；重复通知指定 recurrence，可同时指定 sendAt 作为首次发送的最早时间
+type CreateScheduledNotificationRequest struct {
+	Type       string                 `json:"type" binding:"required,max=32"`
+	Channel    string                 `json:"channel" binding:"required,oneof=push wechat"`
+	Locale     string                 `json:"locale" binding:"max=16"`
+	Variables  map[string]interface{} `json:"variables"`                        // 模板变量，通知类型注册了模板时必须完整
+	Title      string                 `json:"title" binding:"max=255"`          // 未使用模板时的标题
+	Content    string                 `json:"content" binding:"max=1000"`       // 未使用模板时的内容
+	SendAt     int64                  `json:"sendAt" binding:"omitempty,min=0"` // 毫秒时间戳
+	Recurrence string                 `json:"recurrence" binding:"max=64"`      // 5 段 cron 表达式，如 "0 9 * * *"
+	Timezone   string                 `json:"timezone" binding:"max=64"`        // 计算重复规则的 IANA 时区，为空时使用用户偏好或系统默认时区
+}
+
+// ScheduledNotificationDTO 定时通知 DTO
+type ScheduledNotificationDTO struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Channel    string                 `json:"channel"`
+	Locale     string                 `json:"locale,omitempty"`
+	Variables  map[string]interface{} `json:"variables,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Content    string                 `json:"content,omitempty"`
+	Recurrence string                 `json:"recurrence,omitempty"`
+	Timezone   string                 `json:"timezone"`
+	Status     string                 `json:"status"`              // active/completed/cancelled
+	NextRunAt  int64                  `json:"nextRunAt,omitempty"` // 毫秒时间戳，仅等待发送时有值
+	LastRunAt  int64                  `json:"lastRunAt,omitempty"` // 毫秒时间戳
+	RunCount   int                    `json:"runCount"`
+	CreatedAt  int64                  `json:"createdAt"` // 毫秒时间戳
+}
+
+// ListScheduledNotificationsRequest 定时通知列表查询请求
+type ListScheduledNotificationsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=active completed cancelled"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"pageSize"`
+}
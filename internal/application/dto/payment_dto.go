@@ -0,0 +1,63 @@
+package dto
+
+// PaymentDTO 支付单 DTO，金额单位为分
+type PaymentDTO struct {
+	ID               string `json:"id"`
+	PaymentNo        string `json:"paymentNo"`
+	OrderID          string `json:"orderId"`
+	UserID           string `json:"userId"`
+	Method           string `json:"method"`
+	Amount           int64  `json:"amount"`
+	Currency         string `json:"currency"`
+	Status           string `json:"status"` // pending/paid/failed/partially_refunded/refunded/cancelled
+	TransactionID    string `json:"transactionId,omitempty"`
+	RefundedAmount   int64  `json:"refundedAmount"`   // 累计已退款金额
+	RefundingAmount  int64  `json:"refundingAmount"`  // 退款处理中的金额
+	RefundableAmount int64  `json:"refundableAmount"` // 剩余可退款金额
+	ExpireAt         int64  `json:"expireAt"`         // 毫秒时间戳
+	PaidAt           int64  `json:"paidAt,omitempty"` // 毫秒时间戳
+	CreatedAt        int64  `json:"createdAt"`        // 毫秒时间戳
+	UpdatedAt        int64  `json:"updatedAt"`        // 毫秒时间戳
+}
+
+// RefundDTO 退款单 DTO，金额单位为分
+type RefundDTO struct {
+	ID              string `json:"id"`
+	RefundNo        string `json:"refundNo"`
+	PaymentID       string `json:"paymentId"`
+	IdempotencyKey  string `json:"idempotencyKey"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	Reason          string `json:"reason,omitempty"`
+	Status          string `json:"status"` // pending/processing/succeeded/failed
+	ChannelRefundID string `json:"channelRefundId,omitempty"`
+	FailReason      string `json:"failReason,omitempty"`
+	Attempts        int    `json:"attempts"`
+	SucceededAt     int64  `json:"succeededAt,omitempty"` // 毫秒时间戳
+	CreatedAt       int64  `json:"createdAt"`             // 毫秒时间戳
+	UpdatedAt       int64  `json:"updatedAt"`             // 毫秒时间戳
+}
+
+// PaymentEventDTO 支付事件 DTO
+type PaymentEventDTO struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	FromStatus string `json:"fromStatus,omitempty"`
+	ToStatus   string `json:"toStatus,omitempty"`
+	Source     string `json:"source"`
+	Detail     string `json:"detail,omitempty"`
+	CreatedAt  int64  `json:"createdAt"` // 毫秒时间戳
+}
+
+// PaymentDetailDTO 支付单详情，含退款单与支付事件
+type PaymentDetailDTO struct {
+	Payment *PaymentDTO        `json:"payment"`
+	Refunds []*RefundDTO       `json:"refunds"`
+	Events  []*PaymentEventDTO `json:"events"`
+}
+
+// CreateRefundRequest 申请退款请求，幂等键通过 Idempotency-Key 请求头传递
+type CreateRefundRequest struct {
+	Amount int64  `json:"amount" binding:"required,min=1"` // 退款金额(分)
+	Reason string `json:"reason" binding:"max=80"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+// ReconciliationDiscrepancyDTO 对账差异 DTO，金额单位为分
+type ReconciliationDiscrepancyDTO struct {
+	ID            string `json:"id"`
+	BillDate      string `json:"billDate"`   // YYYY-MM-DD
+	Type          string `json:"type"`       // missing_locally/missing_remotely/amount_mismatch/paid_after_close
+	RecordType    string `json:"recordType"` // trade/refund
+	PaymentNo     string `json:"paymentNo"`
+	RefundNo      string `json:"refundNo,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+	LocalAmount   int64  `json:"localAmount"`
+	RemoteAmount  int64  `json:"remoteAmount"`
+	Detail        string `json:"detail,omitempty"`
+	Status        string `json:"status"` // open/resolved
+	Note          string `json:"note,omitempty"`
+	ResolvedBy    string `json:"resolvedBy,omitempty"`
+	ResolvedAt    int64  `json:"resolvedAt,omitempty"` // 毫秒时间戳
+	CreatedAt     int64  `json:"createdAt"`            // 毫秒时间戳
+}
+
+// ListDiscrepanciesRequest 对账差异查询请求
+type ListDiscrepanciesRequest struct {
+	BillDate string `form:"billDate" binding:"omitempty,datetime=2006-01-02"`
+	Type     string `form:"type" binding:"omitempty,oneof=missing_locally missing_remotely amount_mismatch paid_after_close"`
+	Status   string `form:"status" binding:"omitempty,oneof=open resolved"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"pageSize"`
+}
+
+// ResolveDiscrepancyRequest 处理对账差异请求
+type ResolveDiscrepancyRequest struct {
+	Note string `json:"note" binding:"required,max=255"` // 处理说明，如已补单、已线下退款
+}
+
+// RunReconciliationRequest 执行对账请求
+type RunReconciliationRequest struct {
+	BillDate string `json:"billDate" binding:"required,datetime=2006-01-02"`
+}
+
+// ReconciliationResultDTO 对账结果
+type ReconciliationResultDTO struct {
+	BillDate      string                          `json:"billDate"`
+	TradeCount    int                             `json:"tradeCount"`  // 对账单交易记录数
+	RefundCount   int                             `json:"refundCount"` // 对账单退款记录数
+	Matched       int                             `json:"matched"`     // 双方一致的记录数
+	Discrepancies []*ReconciliationDiscrepancyDTO `json:"discrepancies"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "encoding/json"
+
+// CreateSceneLinkRequest 登记场景短链请求
+type CreateSceneLinkRequest struct {
+	Payload    json.RawMessage `json:"payload" binding:"required"`                                 // 业务数据，任意 JSON
+	Page       string          `json:"page" binding:"max=128"`                                     // 扫码进入的小程序页面
+	MaxUses    int             `json:"maxUses" binding:"min=0,max=100000"`                         // 最大使用次数，0 表示不限制
+	ExpiresIn  int             `json:"expiresIn" binding:"min=0"`                                  // 有效期(秒)，0 表示使用默认有效期
+	QRCode     bool            `json:"qrcode"`                                                     // 是否同时生成小程序码，需要提供 page
+	EnvVersion string          `json:"envVersion" binding:"omitempty,oneof=release trial develop"` // 小程序码打开的小程序版本
+}
+
+// SceneLinkDTO 场景短链
+type SceneLinkDTO struct {
+	Code      string          `json:"code"`
+	Scene     string          `json:"scene"` // 小程序码 scene 参数，如 c=AB23CD
+	Page      string          `json:"page,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatorID string          `json:"creatorId"`
+	MaxUses   int             `json:"maxUses"`             // 0 表示不限制
+	UseCount  int             `json:"useCount"`            // 已使用次数(含本次解析)
+	ExpiresAt int64           `json:"expiresAt,omitempty"` // 毫秒时间戳
+	CreatedAt int64           `json:"createdAt"`           // 毫秒时间戳
+	QRCodeURL string          `json:"qrcodeUrl,omitempty"` // 小程序码地址，登记时要求生成才有值
+}
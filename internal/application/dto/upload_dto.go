@@ -0,0 +1,47 @@
+package dto
+
+// PresignUploadRequest 申请预签名直传地址请求
+type PresignUploadRequest struct {
+	Type        string `json:"type" binding:"required"`        // 上传类型，如 user_avatar
+	RelatedID   string `json:"relatedId"`                      // 关联业务ID
+	Filename    string `json:"filename" binding:"required"`    // 原始文件名，扩展名需与内容类型一致
+	ContentType string `json:"contentType" binding:"required"` // 内容类型，上传时 Content-Type 必须与此一致
+	Size        int64  `json:"size" binding:"required,min=1"`  // 文件字节数，上传内容必须与此一致
+}
+
+// PresignUploadResponse 预签名直传地址
+type PresignUploadResponse struct {
+	ID        string            `json:"id"`        // 直传会话ID，上传完成后回调完成接口时使用
+	Method    string            `json:"method"`    // 上传请求方法
+	URL       string            `json:"url"`       // 上传地址
+	Headers   map[string]string `json:"headers"`   // 上传时必须携带的请求头
+	ExpiresAt int64             `json:"expiresAt"` // 地址过期时间(毫秒时间戳)
+}
+
+// ListUploadsRequest 上传文件列表查询请求
+type ListUploadsRequest struct {
+	Type     string `form:"type"` // 上传类型，为空表示全部
+	Page     int    `form:"page"`
+	PageSize int    `form:"pageSize"`
+}
+
+// UploadDTO 上传文件 DTO
+type UploadDTO struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	RelatedID   string `json:"relatedId,omitempty"`
+	Filename    string `json:"filename"` // 原始文件名
+	URL         string `json:"url"`
+	Path        string `json:"path"` // 存储对象键
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"contentType"`
+	Orphaned    bool   `json:"orphaned"`  // 是否已不再被引用，孤儿文件会被定期清理
+	CreatedAt   int64  `json:"createdAt"` // 毫秒时间戳
+}
+
+// UploadUsageDTO 存储用量
+type UploadUsageDTO struct {
+	Used  int64 `json:"used"`  // 已用字节数
+	Quota int64 `json:"quota"` // 配额字节数，0 表示不限制
+}
@@ -0,0 +1,42 @@
+package dto
+
+// RecordSubscriptionsRequest 记录订阅消息授权结果请求
+// Results 为 wx.requestSubscribeMessage 成功回调中的模板授权结果: templateID -> accept/reject/ban/filter
+type RecordSubscriptionsRequest struct {
+	Results map[string]string `json:"results" binding:"required,min=1,max=20"`
+}
+
+// SubscribeQuotaDTO 订阅消息剩余额度
+type SubscribeQuotaDTO struct {
+	TemplateType string `json:"templateType"` // 模板类型，对应 wechat.subscribe_templates 的键
+	TemplateID   string `json:"templateId"`
+	Remaining    int    `json:"remaining"` // 剩余可发送次数
+}
+
+// QRCodeColor 小程序码线条颜色(RGB 十进制)
+type QRCodeColor struct {
+	R int `json:"r" binding:"min=0,max=255"`
+	G int `json:"g" binding:"min=0,max=255"`
+	B int `json:"b" binding:"min=0,max=255"`
+}
+
+// GenerateQRCodeRequest 生成小程序码请求
+type GenerateQRCodeRequest struct {
+	Type       string       `json:"type" binding:"omitempty,oneof=unlimited wxacode qrcode"` // 默认 unlimited；wxacode、qrcode 有数量上限
+	Scene      string       `json:"scene" binding:"max=128"`                                 // 场景参数 "key1=val1&key2=val2"，unlimited 最多 32 个字符，其余拼接在 page 之后
+	Page       string       `json:"page" binding:"max=128"`                                  // 小程序页面路径，如 pages/baby/join/join
+	Width      int          `json:"width" binding:"omitempty,min=280,max=1280"`              // 宽度(像素)，默认 430
+	AutoColor  bool         `json:"autoColor"`                                               // 自动配置线条颜色
+	LineColor  *QRCodeColor `json:"lineColor"`                                               // 线条颜色，autoColor 为 false 时生效
+	IsHyaline  bool         `json:"isHyaline"`                                               // 透明底色
+	EnvVersion string       `json:"envVersion" binding:"omitempty,oneof=release trial develop"`
+}
+
+// QRCodeDTO 小程序码
+type QRCodeDTO struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"`
+	Scene  string `json:"scene,omitempty"` // 规范化后的 scene
+	Path   string `json:"path,omitempty"`  // 扫码进入的页面
+	Cached bool   `json:"cached"`          // 是否为已生成过的小程序码
+}
@@ -2,13 +2,23 @@ package service
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	"github.com/wxlbd/polaris/internal/application/dto"
 	"github.com/wxlbd/polaris/internal/domain/entity"
 	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
 	"github.com/wxlbd/polaris/pkg/errors"
 )
 
+const (
+	defaultPageSize = 20  // 默认分页大小
+	maxPageSize     = 100 // 最大分页大小
+
+	maxVersionLength = 20 // 版本号最大长度，与 app_versions.version 列宽一致
+)
+
 // AppVersionService 应用版本服务
 type AppVersionService struct {
 	appVersionRepo repository.AppVersionRepository
@@ -28,15 +38,7 @@ func (s *AppVersionService) GetCurrentVersion(ctx context.Context) (*dto.AppVers
 		return nil, err
 	}
 
-	return &dto.AppVersionDTO{
-		Version:      appVersion.Version,
-		Name:         appVersion.Name,
-		Description:  appVersion.Description,
-		MinVersion:   appVersion.MinVersion,
-		ForceUpdate:  appVersion.ForceUpdate,
-		ReleaseNotes: appVersion.ReleaseNotes,
-		BuildTime:    appVersion.BuildTime.UnixMilli(),
-	}, nil
+	return toAppVersionDTO(appVersion), nil
 }
 
 // GetVersionByNumber 根据版本号获取版本信息
@@ -46,57 +48,426 @@ func (s *AppVersionService) GetVersionByNumber(ctx context.Context, version stri
 		return nil, err
 	}
 
-	return &dto.AppVersionDTO{
-		Version:      appVersion.Version,
-		Name:         appVersion.Name,
-		Description:  appVersion.Description,
-		MinVersion:   appVersion.MinVersion,
-		ForceUpdate:  appVersion.ForceUpdate,
-		ReleaseNotes: appVersion.ReleaseNotes,
-		BuildTime:    appVersion.BuildTime.UnixMilli(),
-	}, nil
+	return toAppVersionDTO(appVersion), nil
+}
+
+// ListVersions 分页查询版本列表，req 中的分页参数会被规范化
+func (s *AppVersionService) ListVersions(ctx context.Context, req *dto.ListAppVersionsRequest) ([]*dto.AppVersionDTO, int64, error) {
+	req.Page, req.PageSize = normalizePage(req.Page, req.PageSize)
+	page, pageSize := req.Page, req.PageSize
+
+	total, err := s.appVersionRepo.Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	appVersions, err := s.appVersionRepo.List(ctx, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]*dto.AppVersionDTO, 0, len(appVersions))
+	for _, appVersion := range appVersions {
+		records = append(records, toAppVersionDTO(appVersion))
+	}
+
+	return records, total, nil
 }
 
 // CreateVersion 创建新版本
 func (s *AppVersionService) CreateVersion(ctx context.Context, req *dto.CreateAppVersionRequest) (*dto.AppVersionDTO, error) {
-	// 验证版本号格式
-	if req.Version == "" {
-		return nil, errors.New(errors.ParamError, "version is required")
+	// 验证版本号格式，统一按规范化后的形式存储
+	version, err := parseVersion("version", req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMinVersion(version, req.MinVersion); err != nil {
+		return nil, err
+	}
+
+	// 版本号唯一，仅构建元数据不同的版本优先级相同，无法区分新旧，视为重复
+	if err := s.ensureVersionUnique(ctx, version); err != nil {
+		return nil, err
 	}
 
 	appVersion := &entity.AppVersion{
-		Version:      req.Version,
+		Version:      version.String(),
 		Name:         req.Name,
 		Description:  req.Description,
-		MinVersion:   req.MinVersion,
-		IsActive:     req.IsActive,
+		MinVersion:   normalizeVersion(req.MinVersion),
 		ForceUpdate:  req.ForceUpdate,
 		ReleaseNotes: req.ReleaseNotes,
+		Channel:      req.Channel,
+		Platform:     normalizePlatform(req.Platform),
+		// 新版本默认为草稿，需通过灰度或全量发布后才会推送给客户端
+		RolloutStatus: entity.RolloutStatusDraft,
+	}
+	if appVersion.Channel == "" {
+		appVersion.Channel = entity.ChannelStable
 	}
 
 	if err := s.appVersionRepo.Create(ctx, appVersion); err != nil {
 		return nil, err
 	}
 
-	return &dto.AppVersionDTO{
-		Version:      appVersion.Version,
-		Name:         appVersion.Name,
-		Description:  appVersion.Description,
-		MinVersion:   appVersion.MinVersion,
-		ForceUpdate:  appVersion.ForceUpdate,
-		ReleaseNotes: appVersion.ReleaseNotes,
-		BuildTime:    appVersion.BuildTime.UnixMilli(),
-	}, nil
+	// 创建即全量发布时，需同时将同一发布线的其他版本设为非活跃
+	if req.IsActive {
+		if err := s.appVersionRepo.SetActive(ctx, appVersion.Version); err != nil {
+			return nil, err
+		}
+		return s.GetVersionByNumber(ctx, appVersion.Version)
+	}
+
+	return toAppVersionDTO(appVersion), nil
+}
+
+// UpdateVersion 更新版本信息
+func (s *AppVersionService) UpdateVersion(ctx context.Context, version string, req *dto.UpdateAppVersionRequest) (*dto.AppVersionDTO, error) {
+	appVersion, err := s.appVersionRepo.FindByVersion(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := parseVersion("version", appVersion.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMinVersion(current, req.MinVersion); err != nil {
+		return nil, err
+	}
+
+	// 全量版本切换发布线会使原发布线失去活跃版本，需先激活其他版本
+	if appVersion.IsActive && (req.Channel != "" && req.Channel != appVersion.Channel ||
+		req.Platform != nil && normalizePlatform(*req.Platform) != appVersion.Platform) {
+		return nil, errors.New(errors.Conflict, "cannot change channel or platform of the active app version")
+	}
+
+	appVersion.Name = req.Name
+	appVersion.Description = req.Description
+	appVersion.MinVersion = normalizeVersion(req.MinVersion)
+	appVersion.ForceUpdate = req.ForceUpdate
+	appVersion.ReleaseNotes = req.ReleaseNotes
+	if req.Channel != "" {
+		appVersion.Channel = req.Channel
+	}
+	if req.Platform != nil {
+		appVersion.Platform = normalizePlatform(*req.Platform)
+	}
+
+	if err := s.appVersionRepo.Update(ctx, appVersion); err != nil {
+		return nil, err
+	}
+
+	return toAppVersionDTO(appVersion), nil
 }
 
-// SetActiveVersion 设置活跃版本
+// SetActiveVersion 全量发布指定版本
+// 版本成为所在渠道、平台的活跃版本，灰度比例置为 100%
 func (s *AppVersionService) SetActiveVersion(ctx context.Context, version string) error {
 	// 验证版本是否存在
-	_, err := s.appVersionRepo.FindByVersion(ctx, version)
+	appVersion, err := s.appVersionRepo.FindByVersion(ctx, version)
 	if err != nil {
 		return err
 	}
 
+	if appVersion.RolloutStatus == entity.RolloutStatusRolledBack {
+		return errors.New(errors.Conflict, "app version has been rolled back")
+	}
+
 	// 设置为活跃版本
 	return s.appVersionRepo.SetActive(ctx, version)
 }
+
+// StartRollout 开始或调整灰度放量，也用于恢复已暂停的灰度
+// 放量至 100% 即为全量发布；全量版本重新按比例放量时不再是全量版本，放量至 100% 后恢复
+func (s *AppVersionService) StartRollout(ctx context.Context, version string, percentage int) (*dto.AppVersionDTO, error) {
+	appVersion, err := s.appVersionRepo.FindByVersion(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if appVersion.RolloutStatus == entity.RolloutStatusRolledBack {
+		return nil, errors.New(errors.Conflict, "app version has been rolled back")
+	}
+
+	if percentage >= 100 {
+		if err := s.appVersionRepo.SetActive(ctx, version); err != nil {
+			return nil, err
+		}
+		return s.GetVersionByNumber(ctx, version)
+	}
+
+	appVersion.RolloutStatus = entity.RolloutStatusActive
+	appVersion.RolloutPercentage = percentage
+	appVersion.IsActive = false
+	if err := s.appVersionRepo.Update(ctx, appVersion); err != nil {
+		return nil, err
+	}
+
+	return toAppVersionDTO(appVersion), nil
+}
+
+// PauseRollout 暂停灰度，暂停期间不再向任何用户推送该版本，全量版本暂停后不再是全量版本
+func (s *AppVersionService) PauseRollout(ctx context.Context, version string) (*dto.AppVersionDTO, error) {
+	appVersion, err := s.appVersionRepo.FindByVersion(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if appVersion.RolloutStatus != entity.RolloutStatusActive {
+		return nil, errors.New(errors.Conflict, "app version is not being rolled out")
+	}
+
+	appVersion.RolloutStatus = entity.RolloutStatusPaused
+	appVersion.IsActive = false
+	if err := s.appVersionRepo.Update(ctx, appVersion); err != nil {
+		return nil, err
+	}
+
+	return toAppVersionDTO(appVersion), nil
+}
+
+// RollbackRollout 回滚版本
+// 回滚后该版本不再推送，客户端检查更新时回落到其他已发布版本；版本记录保留
+func (s *AppVersionService) RollbackRollout(ctx context.Context, version string) (*dto.AppVersionDTO, error) {
+	appVersion, err := s.appVersionRepo.FindByVersion(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if !appVersion.IsReleased() {
+		return nil, errors.New(errors.Conflict, "app version is not released")
+	}
+
+	appVersion.RolloutStatus = entity.RolloutStatusRolledBack
+	appVersion.IsActive = false
+	if err := s.appVersionRepo.Update(ctx, appVersion); err != nil {
+		return nil, err
+	}
+
+	return toAppVersionDTO(appVersion), nil
+}
+
+// DeleteVersion 删除版本
+// 当前活跃版本与灰度中的版本不允许删除，需先激活其他版本或回滚
+func (s *AppVersionService) DeleteVersion(ctx context.Context, version string) error {
+	appVersion, err := s.appVersionRepo.FindByVersion(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	if appVersion.IsActive {
+		return errors.New(errors.Conflict, "cannot delete the active app version")
+	}
+	if appVersion.RolloutStatus == entity.RolloutStatusActive {
+		return errors.New(errors.Conflict, "cannot delete an app version that is being rolled out")
+	}
+
+	return s.appVersionRepo.Delete(ctx, version)
+}
+
+// CheckUpdate 客户端检查更新
+// 最新版本为用户可见渠道、匹配平台且用户处于放量范围内的最高版本；userKey 为用户标识，
+// 用于灰度分桶，匿名用户只能获取全量版本。当前版本低于最新版本的 MinVersion，
+// 或跳过的版本(含最新版本)中存在强制更新版本时，本次更新为强制更新
+func (s *AppVersionService) CheckUpdate(ctx context.Context, req *dto.UpdateCheckRequest, userKey string, allowInternal bool) (*dto.UpdateCheckResponse, error) {
+	current, err := parseVersion("currentVersion", req.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := req.Channel
+	if channel == "" {
+		channel = entity.ChannelStable
+	}
+	if channel == entity.ChannelInternal && !allowInternal {
+		return nil, errors.ErrPermissionDenied
+	}
+	platform := normalizePlatform(req.Platform)
+
+	resp := &dto.UpdateCheckResponse{
+		CurrentVersion:  current.String(),
+		SkippedVersions: []*dto.ReleaseNoteDTO{},
+	}
+
+	appVersions, err := s.appVersionRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 筛选当前用户所在发布线上已发布的版本
+	type release struct {
+		version    valueobject.SemVer
+		appVersion *entity.AppVersion
+	}
+	visible := make(map[string]bool)
+	for _, ch := range entity.VisibleChannels(channel) {
+		visible[ch] = true
+	}
+	var releases []release
+	var latest *release
+	for _, appVersion := range appVersions {
+		if !appVersion.IsReleased() || !visible[appVersion.Channel] || !appVersion.MatchesPlatform(platform) {
+			continue
+		}
+		v, err := valueobject.ParseSemVer(appVersion.Version)
+		if err != nil {
+			// 历史遗留的非语义化版本号无法比较，忽略
+			continue
+		}
+		item := release{version: v, appVersion: appVersion}
+		releases = append(releases, item)
+		if appVersion.InRollout(userKey) && (latest == nil || v.GreaterThan(latest.version)) {
+			latest = &item
+		}
+	}
+
+	// 尚未发布任何版本或已是最新版本，视为无更新
+	if latest == nil || !latest.version.GreaterThan(current) {
+		return resp, nil
+	}
+	latestVersion := latest.version
+
+	resp.HasUpdate = true
+	resp.LatestVersion = toAppVersionDTO(latest.appVersion)
+
+	if latest.appVersion.MinVersion != "" {
+		minVersion, err := valueobject.ParseSemVer(latest.appVersion.MinVersion)
+		if err != nil {
+			return nil, errors.Wrap(errors.InternalError, "min version is not a valid semantic version", err)
+		}
+		resp.Mandatory = current.LessThan(minVersion)
+	}
+
+	// 收集 (current, latest] 区间内的版本；非最新的预发布版本不单独面向用户，跳过
+	var skippedVersions []release
+	for _, item := range releases {
+		v := item.version
+		if !v.GreaterThan(current) || v.GreaterThan(latestVersion) {
+			continue
+		}
+		if v.IsPreRelease() && !v.Equals(latestVersion) {
+			continue
+		}
+		skippedVersions = append(skippedVersions, item)
+	}
+
+	sort.Slice(skippedVersions, func(i, j int) bool {
+		return skippedVersions[i].version.GreaterThan(skippedVersions[j].version)
+	})
+
+	for _, item := range skippedVersions {
+		if item.appVersion.ForceUpdate {
+			resp.Mandatory = true
+		}
+		resp.SkippedVersions = append(resp.SkippedVersions, &dto.ReleaseNoteDTO{
+			Version:      item.appVersion.Version,
+			Name:         item.appVersion.Name,
+			ForceUpdate:  item.appVersion.ForceUpdate,
+			ReleaseNotes: item.appVersion.ReleaseNotes,
+			BuildTime:    item.appVersion.BuildTime.UnixMilli(),
+		})
+	}
+
+	return resp, nil
+}
+
+// ensureVersionUnique 校验不存在与 version 优先级相同(忽略构建元数据)的版本
+func (s *AppVersionService) ensureVersionUnique(ctx context.Context, version valueobject.SemVer) error {
+	appVersions, err := s.appVersionRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, appVersion := range appVersions {
+		// 历史遗留的非语义化版本号无法解析，只按原文比较
+		existing, err := valueobject.ParseSemVer(appVersion.Version)
+		if err == nil && existing.Equals(version) || appVersion.Version == version.String() {
+			return errors.New(errors.Conflict, "app version already exists: "+appVersion.Version)
+		}
+	}
+	return nil
+}
+
+// toAppVersionDTO 实体转换为 DTO
+func toAppVersionDTO(appVersion *entity.AppVersion) *dto.AppVersionDTO {
+	return &dto.AppVersionDTO{
+		Version:           appVersion.Version,
+		Name:              appVersion.Name,
+		Description:       appVersion.Description,
+		MinVersion:        appVersion.MinVersion,
+		ForceUpdate:       appVersion.ForceUpdate,
+		ReleaseNotes:      appVersion.ReleaseNotes,
+		IsActive:          appVersion.IsActive,
+		Channel:           appVersion.Channel,
+		Platform:          appVersion.Platform,
+		RolloutPercentage: appVersion.RolloutPercentage,
+		RolloutStatus:     appVersion.RolloutStatus,
+		BuildTime:         appVersion.BuildTime.UnixMilli(),
+		CreatedAt:         appVersion.CreatedAt.UnixMilli(),
+		UpdatedAt:         appVersion.UpdatedAt.UnixMilli(),
+	}
+}
+
+// parseVersion 解析并校验语义化版本号
+func parseVersion(field, version string) (valueobject.SemVer, error) {
+	v, err := valueobject.ParseSemVer(version)
+	if err != nil {
+		return valueobject.SemVer{}, errors.New(errors.ParamError, field+" is not a valid semantic version: "+err.Error())
+	}
+	if len(v.String()) > maxVersionLength {
+		return valueobject.SemVer{}, errors.New(errors.ParamError, field+" is too long")
+	}
+	return v, nil
+}
+
+// validateMinVersion 校验最小支持版本：可为空，否则必须为合法版本号且不高于当前版本
+func validateMinVersion(version valueobject.SemVer, minVersion string) error {
+	if minVersion == "" {
+		return nil
+	}
+	minVer, err := parseVersion("minVersion", minVersion)
+	if err != nil {
+		return err
+	}
+	if minVer.GreaterThan(version) {
+		return errors.New(errors.ParamError, "minVersion must not be greater than version")
+	}
+	return nil
+}
+
+// normalizeVersion 返回规范化后的版本号，调用前需已通过校验
+func normalizeVersion(version string) string {
+	if version == "" {
+		return ""
+	}
+	v, err := valueobject.ParseSemVer(version)
+	if err != nil {
+		return version
+	}
+	return v.String()
+}
+
+// normalizePlatform 规范化平台标识
+func normalizePlatform(platform string) string {
+	return strings.ToLower(strings.TrimSpace(platform))
+}
+
+// normalizePage 规范化分页参数
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// isNotFound 判断是否为资源不存在错误
+func isNotFound(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.NotFound
+}
@@ -2,38 +2,65 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
 
 	"github.com/wxlbd/polaris/internal/application/dto"
 	"github.com/wxlbd/polaris/internal/domain/entity"
 	"github.com/wxlbd/polaris/internal/domain/repository"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/logger"
+	"github.com/wxlbd/polaris/internal/infrastructure/token"
 	"github.com/wxlbd/polaris/internal/infrastructure/wechat"
 	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/utils"
 )
 
 // AuthService 认证服务 (去家庭化架构)
 type AuthService struct {
-	userRepo     repository.UserRepository
-	cfg          *config.Config
-	wechatClient *wechat.Client
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	tokenRepo     repository.TokenRepository
+	keyManager    *token.KeyManager
+	cfg           *config.Config
+	wechatClient  *wechat.Client
+	uploadService *UploadService
 }
 
 // NewAuthService 创建认证服务
 func NewAuthService(
 	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	tokenRepo repository.TokenRepository,
+	keyManager *token.KeyManager,
 	cfg *config.Config,
 	wechatClient *wechat.Client,
+	uploadService *UploadService,
 ) *AuthService {
 	return &AuthService{
-		userRepo:     userRepo,
-		cfg:          cfg,
-		wechatClient: wechatClient,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		tokenRepo:     tokenRepo,
+		keyManager:    keyManager,
+		cfg:           cfg,
+		wechatClient:  wechatClient,
+		uploadService: uploadService,
 	}
 }
 
+// tokenPair 访问令牌/刷新令牌对
+type tokenPair struct {
+	accessToken      string
+	refreshToken     string
+	expiresIn        int      // 访问令牌有效期(秒)
+	refreshExpiresIn int      // 刷新令牌有效期(秒)
+	roles            []string // 写入访问令牌的角色
+}
+
 // WechatLogin 微信小程序登录 (去家庭化架构)
 func (s *AuthService) WechatLogin(ctx context.Context, req *dto.WechatLoginRequest) (*dto.LoginResponse, error) {
 	// 使用 SDK 调用微信API获取openid
@@ -83,42 +110,106 @@ func (s *AuthService) WechatLogin(ctx context.Context, req *dto.WechatLoginReque
 		}
 	}
 
-	// 生成Token
-	token, err := s.generateToken(user.OpenID)
+	// 每次登录开启一个新的会话(令牌族)
+	sessionID, err := utils.GenerateToken(16)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "生成会话ID失败", err)
+	}
+
+	pair, err := s.issueTokenPair(ctx, user, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &dto.LoginResponse{
-		Token: token,
+		Token:        pair.accessToken,
+		RefreshToken: pair.refreshToken,
+		ExpiresIn:    pair.expiresIn,
 		UserInfo: dto.UserInfoDTO{
 			OpenID:    user.OpenID,
 			NickName:  user.NickName,
 			AvatarURL: user.AvatarURL,
+			Roles:     pair.roles,
 		},
 		IsNewUser: isNewUser, // 前端根据此字段判断是否需要引导创建宝宝
 	}, nil
 }
 
-// RefreshToken 刷新Token
-func (s *AuthService) RefreshToken(ctx context.Context, openID string) (*dto.RefreshTokenResponse, error) {
+// RefreshToken 使用刷新令牌换取新的令牌对
+// 刷新令牌一次性使用，每次刷新都会轮换；若检测到已使用过的刷新令牌被再次提交，
+// 说明令牌可能已泄露，整个会话(令牌族)将被吊销
+func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.RefreshTokenResponse, error) {
+	tokenHash := hashRefreshToken(req.RefreshToken)
+
+	stored, err := s.tokenRepo.FindRefreshToken(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.IsExpired(now) {
+		return nil, errors.ErrTokenExpired
+	}
+
+	revoked, err := s.tokenRepo.IsSessionRevoked(ctx, stored.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.ErrTokenRevoked
+	}
+
+	// 原子地占用该刷新令牌，失败说明令牌被重复使用
+	first, err := s.tokenRepo.MarkRefreshTokenUsed(ctx, tokenHash, now)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		logger.Warn("Refresh token reuse detected, revoking session",
+			zap.String("openid", stored.OpenID),
+			zap.String("sid", stored.SessionID),
+		)
+		if err := s.tokenRepo.RevokeSession(ctx, stored.SessionID, s.sessionRevocationTTL()); err != nil {
+			return nil, err
+		}
+		return nil, errors.ErrTokenRevoked
+	}
+
 	// 验证用户存在
-	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	user, err := s.userRepo.FindByOpenID(ctx, stored.OpenID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 生成新Token
-	token, err := s.generateToken(user.OpenID)
+	pair, err := s.issueTokenPair(ctx, user, stored.SessionID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &dto.RefreshTokenResponse{
-		Token: token,
+		Token:            pair.accessToken,
+		RefreshToken:     pair.refreshToken,
+		ExpiresIn:        pair.expiresIn,
+		RefreshExpiresIn: pair.refreshExpiresIn,
 	}, nil
 }
 
+// Logout 退出登录
+// 吊销当前访问令牌以及其所属会话下的全部刷新令牌
+func (s *AuthService) Logout(ctx context.Context, jti, sessionID string, expiresAt time.Time) error {
+	if jti != "" {
+		if err := s.tokenRepo.RevokeAccessToken(ctx, jti, time.Until(expiresAt)); err != nil {
+			return err
+		}
+	}
+	if sessionID != "" {
+		if err := s.tokenRepo.RevokeSession(ctx, sessionID, s.sessionRevocationTTL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetUserInfo 获取用户信息
 func (s *AuthService) GetUserInfo(ctx context.Context, openID string) (*dto.UserInfoDTO, error) {
 	user, err := s.userRepo.FindByOpenID(ctx, openID)
@@ -144,6 +235,7 @@ func (s *AuthService) UpdateUserInfo(ctx context.Context, openID string, req *dt
 	}
 
 	// 更新用户信息
+	oldAvatarURL := user.AvatarURL
 	user.NickName = req.NickName
 	user.AvatarURL = req.AvatarURL
 
@@ -152,6 +244,16 @@ func (s *AuthService) UpdateUserInfo(ctx context.Context, openID string, req *dt
 		return nil, err
 	}
 
+	// 更换头像后旧头像文件不再被引用，由后台任务延迟清理；登记失败不影响资料更新
+	if oldAvatarURL != user.AvatarURL {
+		if err := s.uploadService.ReplaceReference(ctx, openID, UploadTypeUserAvatar, oldAvatarURL, user.AvatarURL); err != nil {
+			logger.Warn("Failed to track avatar reference",
+				zap.String("openid", openID),
+				zap.Error(err),
+			)
+		}
+	}
+
 	// 返回更新后的用户信息
 	return &dto.UserInfoDTO{
 		OpenID:        user.OpenID,
@@ -162,20 +264,102 @@ func (s *AuthService) UpdateUserInfo(ctx context.Context, openID string, req *dt
 	}, nil
 }
 
-// generateToken 生成JWT Token
-func (s *AuthService) generateToken(openID string) (string, error) {
+// issueTokenPair 签发访问令牌，并生成一个新的刷新令牌存入服务端
+// 角色在每次签发时从数据库读取，角色变更最迟在下一次刷新后生效
+func (s *AuthService) issueTokenPair(ctx context.Context, user *entity.User, sessionID string) (*tokenPair, error) {
+	roles, err := s.roleRepo.FindRoleCodesByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles = entity.EffectiveRoles(roles)
+
+	accessToken, err := s.generateToken(user.OpenID, sessionID, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "生成刷新令牌失败", err)
+	}
+
 	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		Subject:   openID,
-		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour * time.Duration(s.cfg.JWT.ExpireHours))),
-		IssuedAt:  jwt.NewNumericDate(now),
+	refreshTTL := s.refreshTTL()
+	if err := s.tokenRepo.SaveRefreshToken(ctx, &entity.RefreshToken{
+		TokenHash: hashRefreshToken(refreshToken),
+		SessionID: sessionID,
+		OpenID:    user.OpenID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(refreshTTL),
+	}); err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.cfg.JWT.Secret))
+	return &tokenPair{
+		accessToken:      accessToken,
+		refreshToken:     refreshToken,
+		expiresIn:        int(s.accessTTL().Seconds()),
+		refreshExpiresIn: int(refreshTTL.Seconds()),
+		roles:            roles,
+	}, nil
+}
+
+// generateToken 生成JWT访问令牌
+func (s *AuthService) generateToken(openID, sessionID string, roles []string) (string, error) {
+	jti, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", errors.Wrap(errors.InternalError, "生成Token失败", err)
+	}
+
+	now := time.Now()
+	claims := token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   openID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		SessionID: sessionID,
+		Roles:     roles,
+	}
+
+	tokenString, err := s.keyManager.Sign(&claims)
 	if err != nil {
 		return "", errors.Wrap(errors.InternalError, "生成Token失败", err)
 	}
 
 	return tokenString, nil
 }
+
+// JWKS 获取用于验证访问令牌的公钥集合
+func (s *AuthService) JWKS() token.JWKSet {
+	return s.keyManager.JWKS()
+}
+
+// accessTTL 访问令牌有效期
+func (s *AuthService) accessTTL() time.Duration {
+	return time.Hour * time.Duration(s.cfg.JWT.ExpireHours)
+}
+
+// refreshTTL 刷新令牌有效期
+func (s *AuthService) refreshTTL() time.Duration {
+	if s.cfg.JWT.RefreshExpireHours <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Hour * time.Duration(s.cfg.JWT.RefreshExpireHours)
+}
+
+// sessionRevocationTTL 会话吊销记录的保留时间
+// 需覆盖该会话下任意令牌的剩余有效期
+func (s *AuthService) sessionRevocationTTL() time.Duration {
+	if s.accessTTL() > s.refreshTTL() {
+		return s.accessTTL()
+	}
+	return s.refreshTTL()
+}
+
+// hashRefreshToken 计算刷新令牌哈希，服务端不保存明文
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/imageutil"
+)
+
+// DirectUploadService 预签名直传服务
+// 客户端申请短时有效的签名地址后直接上传到存储，文件不再经过应用服务器转发；
+// 直传复用断点续传会话：上传内容作为会话的唯一分片，完成回调时统一交由 UploadService 校验、清洗与登记
+type DirectUploadService struct {
+	cfg                    *config.Config
+	uploadService          *UploadService
+	resumableUploadService *ResumableUploadService
+	sessionRepo            repository.UploadSessionRepository
+	fileStorage            repository.FileStorage
+}
+
+// NewDirectUploadService 创建预签名直传服务
+func NewDirectUploadService(
+	cfg *config.Config,
+	uploadService *UploadService,
+	resumableUploadService *ResumableUploadService,
+	sessionRepo repository.UploadSessionRepository,
+	fileStorage repository.FileStorage,
+) *DirectUploadService {
+	return &DirectUploadService{
+		cfg:                    cfg,
+		uploadService:          uploadService,
+		resumableUploadService: resumableUploadService,
+		sessionRepo:            sessionRepo,
+		fileStorage:            fileStorage,
+	}
+}
+
+// Presign 创建直传会话并签发上传地址
+// 签名约束对象键、内容类型与字节数，客户端只能按申请时声明的内容上传
+func (s *DirectUploadService) Presign(ctx context.Context, openID string, req *dto.PresignUploadRequest) (*dto.PresignUploadResponse, error) {
+	presigner, ok := s.fileStorage.(repository.UploadPresigner)
+	if !ok {
+		return nil, errors.New(errors.InternalError, "Storage backend does not support direct upload")
+	}
+
+	contentType := strings.ToLower(req.ContentType)
+	if contentType == "image/jpg" {
+		contentType = imageutil.MIMEJPEG
+	}
+	if !s.uploadService.isAllowedType(contentType) {
+		return nil, errors.New(errors.ParamError, "Content type "+req.ContentType+" is not allowed")
+	}
+	if ext := normalizeExt(filepath.Ext(req.Filename)); imageutil.ExtensionOf(contentType) != ext {
+		return nil, errors.New(errors.ParamError, "Content type does not match file extension "+ext)
+	}
+
+	session, err := s.resumableUploadService.createSession(ctx, openID, req.Size, UploadType(req.Type), req.RelatedID, req.Filename, true)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := time.Duration(s.cfg.Upload.Direct.ExpireMinutes) * time.Minute
+	presigned, err := presigner.PresignPut(ctx, session.DirectKey, contentType, req.Size, expires)
+	if err != nil {
+		_ = s.sessionRepo.Delete(ctx, session.ID)
+		return nil, err
+	}
+
+	return &dto.PresignUploadResponse{
+		ID:        session.ID,
+		Method:    presigned.Method,
+		URL:       presigned.URL,
+		Headers:   presigned.Headers,
+		ExpiresAt: presigned.ExpiresAt.UnixMilli(),
+	}, nil
+}
+
+// Complete 客户端上传完成后回调，登记直传的文件
+func (s *DirectUploadService) Complete(ctx context.Context, openID, id string) (*UploadResult, error) {
+	session, err := s.resumableUploadService.GetSession(ctx, openID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsDirect() {
+		return nil, errors.New(errors.NotFound, "Upload session not found")
+	}
+
+	if !session.IsComplete() {
+		info, err := s.fileStorage.Stat(ctx, session.DirectKey)
+		if isNotFound(err) {
+			return nil, errors.New(errors.Conflict, "File has not been uploaded yet")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.Size != session.Length {
+			return nil, errors.New(errors.ParamError, fmt.Sprintf("Uploaded file is %d bytes, expected %d", info.Size, session.Length))
+		}
+
+		// Register the uploaded object as the only part; a concurrent callback may have done it already
+		if _, err := s.sessionRepo.AppendPart(ctx, id, 0, session.Length, session.DirectKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.resumableUploadService.Complete(ctx, openID, id)
+}
+
+// PutSigned 接收预签名直传的上传内容
+// 仅用于由应用自身接收直传数据的存储后端(本地存储)，对象存储由客户端直接上传
+func (s *DirectUploadService) PutSigned(ctx context.Context, key string, query url.Values, contentType string, r io.Reader) error {
+	verifier, ok := s.fileStorage.(repository.PresignedUploadVerifier)
+	if !ok {
+		return errors.New(errors.NotFound, "Direct upload endpoint not available")
+	}
+
+	size, err := verifier.VerifyPut(key, query, contentType)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, size+1))
+	if err != nil {
+		return errors.Wrap(errors.ParamError, "Failed to read upload content", err)
+	}
+	if int64(len(data)) != size {
+		return errors.New(errors.ParamError, fmt.Sprintf("Content length does not match signed size %d", size))
+	}
+
+	return s.fileStorage.Put(ctx, key, bytes.NewReader(data), size, contentType)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// NotificationService 通知服务
+// 发送经由领域服务写入发件箱，由后台任务异步投递；投递状态可按通知查询
+type NotificationService struct {
+	notificationDomainService *domainservice.NotificationDomainService
+	notificationRepo          repository.NotificationRepository
+	preferenceRepo            repository.NotificationPreferenceRepository
+	userRepo                  repository.UserRepository
+}
+
+// NewNotificationService 创建通知服务
+func NewNotificationService(
+	notificationDomainService *domainservice.NotificationDomainService,
+	notificationRepo repository.NotificationRepository,
+	preferenceRepo repository.NotificationPreferenceRepository,
+	userRepo repository.UserRepository,
+) *NotificationService {
+	return &NotificationService{
+		notificationDomainService: notificationDomainService,
+		notificationRepo:          notificationRepo,
+		preferenceRepo:            preferenceRepo,
+		userRepo:                  userRepo,
+	}
+}
+
+// Send 发送通知(写入发件箱)
+func (s *NotificationService) Send(ctx context.Context, req domainservice.NotificationRequest) error {
+	return s.notificationDomainService.SendNotification(ctx, req)
+}
+
+// GetNotification 查询通知投递状态
+func (s *NotificationService) GetNotification(ctx context.Context, id int64) (*dto.NotificationDTO, error) {
+	notification, err := s.notificationRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toNotificationDTO(notification), nil
+}
+
+// ListNotifications 分页查询通知投递记录
+func (s *NotificationService) ListNotifications(ctx context.Context, req *dto.ListNotificationsRequest) ([]*dto.NotificationDTO, int64, error) {
+	req.Page, req.PageSize = normalizePage(req.Page, req.PageSize)
+	page, pageSize := req.Page, req.PageSize
+
+	filter := repository.NotificationFilter{
+		Channel:   req.Channel,
+		Recipient: req.Recipient,
+		Status:    req.Status,
+	}
+
+	total, err := s.notificationRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notifications, err := s.notificationRepo.List(ctx, filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]*dto.NotificationDTO, 0, len(notifications))
+	for _, notification := range notifications {
+		records = append(records, toNotificationDTO(notification))
+	}
+
+	return records, total, nil
+}
+
+// GetPreferences 查询用户的通知偏好，未设置时返回默认值
+func (s *NotificationService) GetPreferences(ctx context.Context, openID string) (*dto.NotificationPreferencesDTO, error) {
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return nil, err
+	}
+
+	preference, err := s.preferenceRepo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		if !isNotFound(err) {
+			return nil, err
+		}
+		preference = &entity.NotificationPreference{UserID: user.ID}
+	}
+
+	return toNotificationPreferencesDTO(preference), nil
+}
+
+// UpdatePreferences 更新用户的通知偏好
+func (s *NotificationService) UpdatePreferences(ctx context.Context, openID string, req *dto.UpdateNotificationPreferencesRequest) (*dto.NotificationPreferencesDTO, error) {
+	if err := validatePreferences(req); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	preference := &entity.NotificationPreference{
+		UserID:          user.ID,
+		Timezone:        req.Timezone,
+		QuietHoursStart: req.QuietHoursStart,
+		QuietHoursEnd:   req.QuietHoursEnd,
+		Channels:        make([]entity.NotificationChannelSetting, 0, len(req.Channels)),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	for _, setting := range req.Channels {
+		preference.Channels = append(preference.Channels, entity.NotificationChannelSetting{
+			Type:    setting.Type,
+			Channel: setting.Channel,
+			Enabled: setting.Enabled,
+		})
+	}
+
+	if err := s.preferenceRepo.Save(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	return toNotificationPreferencesDTO(preference), nil
+}
+
+// validatePreferences 校验通知偏好：时区有效、时刻格式正确、同一类型与渠道不重复
+func validatePreferences(req *dto.UpdateNotificationPreferencesRequest) error {
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return errors.New(errors.ParamError, fmt.Sprintf("无效的时区: %s", req.Timezone))
+		}
+	}
+	for _, clock := range []string{req.QuietHoursStart, req.QuietHoursEnd} {
+		if clock == "" {
+			continue
+		}
+		if _, err := entity.ParseClock(clock); err != nil {
+			return errors.New(errors.ParamError, fmt.Sprintf("免打扰时间格式应为 HH:MM: %s", clock))
+		}
+	}
+
+	seen := make(map[string]bool, len(req.Channels))
+	for _, setting := range req.Channels {
+		key := setting.Type + "/" + setting.Channel
+		if seen[key] {
+			return errors.New(errors.ParamError, fmt.Sprintf("重复的渠道设置: %s", key))
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// toNotificationPreferencesDTO 转换为通知偏好 DTO
+func toNotificationPreferencesDTO(preference *entity.NotificationPreference) *dto.NotificationPreferencesDTO {
+	result := &dto.NotificationPreferencesDTO{
+		Timezone:        preference.Timezone,
+		QuietHoursStart: preference.QuietHoursStart,
+		QuietHoursEnd:   preference.QuietHoursEnd,
+		Channels:        make([]*dto.NotificationChannelSettingDTO, 0, len(preference.Channels)),
+	}
+	for _, setting := range preference.Channels {
+		result.Channels = append(result.Channels, &dto.NotificationChannelSettingDTO{
+			Type:    setting.Type,
+			Channel: setting.Channel,
+			Enabled: setting.Enabled,
+		})
+	}
+	return result
+}
+
+// toNotificationDTO 转换为通知投递记录 DTO
+func toNotificationDTO(n *entity.Notification) *dto.NotificationDTO {
+	record := &dto.NotificationDTO{
+		ID:         strconv.FormatInt(n.ID, 10),
+		Channel:    n.Channel,
+		Recipient:  n.Recipient,
+		TemplateID: n.TemplateID,
+		Title:      n.Title,
+		Content:    n.Content,
+		Status:     n.Status,
+		Attempts:   n.Attempts,
+		LastError:  n.LastError,
+		SentAt:     n.SentAt,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+	}
+	if n.UserID != 0 {
+		record.UserID = strconv.FormatInt(n.UserID, 10)
+	}
+	// 已结束投递的通知不再有下次投递时间
+	if !n.IsFinished() {
+		record.NextAttemptAt = n.NextAttemptAt
+	}
+	return record
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/payment"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// PaymentService 支付服务
+type PaymentService struct {
+	cfg                  config.PaymentConfig
+	paymentDomainService *domainservice.PaymentDomainService
+	wechatPayGateway     *payment.WechatPayGateway
+	logger               *zap.Logger
+}
+
+// NewPaymentService 创建支付服务
+func NewPaymentService(
+	cfg *config.Config,
+	paymentDomainService *domainservice.PaymentDomainService,
+	wechatPayGateway *payment.WechatPayGateway,
+	logger *zap.Logger,
+) *PaymentService {
+	return &PaymentService{
+		cfg:                  cfg.Payment,
+		paymentDomainService: paymentDomainService,
+		wechatPayGateway:     wechatPayGateway,
+		logger:               logger,
+	}
+}
+
+// HandleWechatNotify 处理微信支付回调通知
+// 验签、解密或更新支付单失败返回错误，由调用方应答失败，微信支付会按策略重新通知
+func (s *PaymentService) HandleWechatNotify(ctx context.Context, header http.Header, body []byte) error {
+	notification, err := s.wechatPayGateway.ParseNotification(ctx, header, body)
+	if err != nil {
+		s.logger.Warn("Rejected wechatpay notification", zap.Error(err))
+		return err
+	}
+
+	fields := []zap.Field{
+		zap.String("id", notification.ID),
+		zap.String("eventType", notification.EventType),
+		zap.String("paymentNo", notification.PaymentNo),
+		zap.String("transactionId", notification.TransactionID),
+		zap.String("refundNo", notification.RefundNo),
+		zap.Int64("amount", notification.Amount.Amount()),
+	}
+
+	if err := s.paymentDomainService.HandleNotification(ctx, notification); err != nil {
+		if errors.CodeOf(err) == errors.NotFound {
+			// 支付单不存在时重试也无法处理，应答成功避免微信支付反复通知
+			s.logger.Error("Wechatpay notification for unknown payment", append(fields, zap.Error(err))...)
+			return nil
+		}
+		s.logger.Warn("Failed to handle wechatpay notification", append(fields, zap.Error(err))...)
+		return err
+	}
+
+	s.logger.Info("Handled wechatpay notification", fields...)
+	return nil
+}
+
+// CancelExpired 取消全部已过期仍待支付的支付单，返回取消的条数
+func (s *PaymentService) CancelExpired(ctx context.Context) (int, error) {
+	batchSize := max(s.cfg.BatchSize, 1)
+
+	cancelled := 0
+	for ctx.Err() == nil {
+		count, err := s.paymentDomainService.CancelExpired(ctx, time.Now(), batchSize)
+		cancelled += count
+		if err != nil || count < batchSize {
+			return cancelled, err
+		}
+	}
+	return cancelled, nil
+}
+
+// ReconcileRefunds 对账一批已到对账时间的未完成退款单，返回已完成的条数
+// 仍在处理中的退款单按指数退避安排下次对账，每次任务只处理一批
+func (s *PaymentService) ReconcileRefunds(ctx context.Context) (int, error) {
+	return s.paymentDomainService.ReconcileRefunds(ctx, time.Now(), max(s.cfg.BatchSize, 1))
+}
+
+// GetPaymentDetail 查询支付单详情，含退款单与支付事件
+func (s *PaymentService) GetPaymentDetail(ctx context.Context, paymentNo string) (*dto.PaymentDetailDTO, error) {
+	payment, err := s.paymentDomainService.GetPayment(ctx, paymentNo)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.paymentDomainService.ListRefunds(ctx, paymentNo)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.paymentDomainService.ListPaymentEvents(ctx, paymentNo)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &dto.PaymentDetailDTO{
+		Payment: toPaymentDTO(payment),
+		Refunds: make([]*dto.RefundDTO, 0, len(refunds)),
+		Events:  make([]*dto.PaymentEventDTO, 0, len(events)),
+	}
+	for _, refund := range refunds {
+		detail.Refunds = append(detail.Refunds, toRefundDTO(refund))
+	}
+	for _, event := range events {
+		detail.Events = append(detail.Events, toPaymentEventDTO(event))
+	}
+	return detail, nil
+}
+
+// Refund 申请退款，同一支付单内相同幂等键的重复请求返回同一退款单
+// 退款异步处理，返回的退款单可能仍为处理中，结果以退款通知或对账为准
+func (s *PaymentService) Refund(ctx context.Context, paymentNo, idempotencyKey string, req *dto.CreateRefundRequest) (*dto.RefundDTO, error) {
+	payment, err := s.paymentDomainService.GetPayment(ctx, paymentNo)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := valueobject.NewMoney(req.Amount, valueobject.Currency(payment.Currency))
+	if err != nil {
+		return nil, errors.Wrap(errors.ParamError, "无效的退款金额", err)
+	}
+
+	refund, err := s.paymentDomainService.ProcessRefund(ctx, paymentNo, amount, req.Reason, idempotencyKey)
+	if err != nil {
+		s.logger.Warn("Failed to refund payment",
+			zap.String("paymentNo", paymentNo),
+			zap.Int64("amount", req.Amount),
+			zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Refund requested",
+		zap.String("paymentNo", paymentNo),
+		zap.String("refundNo", refund.RefundNo),
+		zap.Int64("amount", refund.Amount),
+		zap.String("status", string(refund.Status)))
+	return toRefundDTO(refund), nil
+}
+
+// toPaymentDTO 转换为支付单 DTO
+func toPaymentDTO(p *entity.Payment) *dto.PaymentDTO {
+	return &dto.PaymentDTO{
+		ID:               strconv.FormatInt(p.ID, 10),
+		PaymentNo:        p.PaymentNo,
+		OrderID:          strconv.FormatInt(p.OrderID, 10),
+		UserID:           strconv.FormatInt(p.UserID, 10),
+		Method:           p.Method,
+		Amount:           p.Amount,
+		Currency:         p.Currency,
+		Status:           string(p.Status),
+		TransactionID:    p.TransactionID,
+		RefundedAmount:   p.RefundedAmount,
+		RefundingAmount:  p.RefundingAmount,
+		RefundableAmount: p.RefundableMoney().Amount(),
+		ExpireAt:         p.ExpireAt,
+		PaidAt:           p.PaidAt,
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+// toRefundDTO 转换为退款单 DTO
+func toRefundDTO(r *entity.Refund) *dto.RefundDTO {
+	return &dto.RefundDTO{
+		ID:              strconv.FormatInt(r.ID, 10),
+		RefundNo:        r.RefundNo,
+		PaymentID:       strconv.FormatInt(r.PaymentID, 10),
+		IdempotencyKey:  r.IdempotencyKey,
+		Amount:          r.Amount,
+		Currency:        r.Currency,
+		Reason:          r.Reason,
+		Status:          string(r.Status),
+		ChannelRefundID: r.ChannelRefundID,
+		FailReason:      r.FailReason,
+		Attempts:        r.Attempts,
+		SucceededAt:     r.SucceededAt,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+	}
+}
+
+// toPaymentEventDTO 转换为支付事件 DTO
+func toPaymentEventDTO(e *entity.PaymentEvent) *dto.PaymentEventDTO {
+	return &dto.PaymentEventDTO{
+		ID:         strconv.FormatInt(e.ID, 10),
+		Type:       e.Type,
+		FromStatus: string(e.FromStatus),
+		ToStatus:   string(e.ToStatus),
+		Source:     e.Source,
+		Detail:     e.Detail,
+		CreatedAt:  e.CreatedAt,
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+)
+
+// permissionCacheTTL 角色权限缓存时间
+const permissionCacheTTL = time.Minute
+
+// cachedPermissions 缓存的角色权限
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+// PermissionService 权限服务
+// 将令牌中的角色解析为权限集合，按角色做短时进程内缓存，避免每个请求都查询数据库
+type PermissionService struct {
+	roleRepo repository.RoleRepository
+
+	mu    sync.RWMutex
+	cache map[string]cachedPermissions
+}
+
+// NewPermissionService 创建权限服务
+func NewPermissionService(roleRepo repository.RoleRepository) *PermissionService {
+	return &PermissionService{
+		roleRepo: roleRepo,
+		cache:    make(map[string]cachedPermissions),
+	}
+}
+
+// ResolvePermissions 解析角色集合拥有的全部权限
+func (s *PermissionService) ResolvePermissions(ctx context.Context, roles []string) ([]string, error) {
+	now := time.Now()
+	seen := make(map[string]struct{})
+	permissions := make([]string, 0)
+
+	for _, role := range entity.EffectiveRoles(roles) {
+		rolePermissions, err := s.rolePermissions(ctx, role, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range rolePermissions {
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			permissions = append(permissions, p)
+		}
+	}
+
+	return permissions, nil
+}
+
+// HasPermission 判断角色集合是否拥有指定权限
+func (s *PermissionService) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	permissions, err := s.ResolvePermissions(ctx, roles)
+	if err != nil {
+		return false, err
+	}
+	return entity.HasPermission(permissions, permission), nil
+}
+
+// rolePermissions 获取单个角色的权限(带缓存)
+func (s *PermissionService) rolePermissions(ctx context.Context, role string, now time.Time) ([]string, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[role]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.permissions, nil
+	}
+
+	permissions, err := s.roleRepo.FindPermissionsByRoleCodes(ctx, []string{role})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[role] = cachedPermissions{
+		permissions: permissions,
+		expiresAt:   now.Add(permissionCacheTTL),
+	}
+	s.mu.Unlock()
+
+	return permissions, nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/snowflake"
+)
+
+const (
+	// statementLockTTL 核对一个账单日期时持有锁的时长，核对进程异常退出后到期可重新核对
+	statementLockTTL = 30 * time.Minute
+	// statementDoneTTL 每日核对完成后保留完成标记的时长，覆盖到次日核对时间之后
+	statementDoneTTL = 48 * time.Hour
+)
+
+// ReconciliationService 对账服务
+type ReconciliationService struct {
+	reconciliationDomainService *domainservice.ReconciliationDomainService
+	reconciliationRepo          repository.ReconciliationRepository
+	lockRepo                    repository.LockRepository
+	statementTime               time.Duration // 每日核对时刻相对当天零点(北京时间)的偏移
+	logger                      *zap.Logger
+}
+
+// NewReconciliationService 创建对账服务，每日核对时刻配置格式错误时返回错误
+func NewReconciliationService(
+	cfg *config.Config,
+	reconciliationDomainService *domainservice.ReconciliationDomainService,
+	reconciliationRepo repository.ReconciliationRepository,
+	lockRepo repository.LockRepository,
+	logger *zap.Logger,
+) (*ReconciliationService, error) {
+	statementTime, err := time.Parse("15:04", cfg.Payment.StatementTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment.statement_time %q: %w", cfg.Payment.StatementTime, err)
+	}
+
+	return &ReconciliationService{
+		reconciliationDomainService: reconciliationDomainService,
+		reconciliationRepo:          reconciliationRepo,
+		lockRepo:                    lockRepo,
+		statementTime:               time.Duration(statementTime.Hour())*time.Hour + time.Duration(statementTime.Minute())*time.Minute,
+		logger:                      logger,
+	}, nil
+}
+
+// Reconcile 核对指定日期(YYYY-MM-DD)的渠道对账单，渠道账单在次日生成，只能核对今天之前的日期
+// 同一日期同时只能有一次核对，正在核对时返回 Conflict
+func (s *ReconciliationService) Reconcile(ctx context.Context, billDate string) (*dto.ReconciliationResultDTO, error) {
+	date, err := time.ParseInLocation("2006-01-02", billDate, domainservice.BillLocation)
+	if err != nil {
+		return nil, errors.Wrap(errors.ParamError, "账单日期格式应为 YYYY-MM-DD", err)
+	}
+	if !date.Before(today()) {
+		return nil, errors.New(errors.ParamError, "只能核对今天之前的账单")
+	}
+
+	// 替换差异时先删后写，同一日期并发核对会互相覆盖
+	lockName, owner := "payment_statement:run:"+billDate, strconv.FormatInt(snowflake.Generate(), 10)
+	acquired, err := s.lockRepo.Acquire(ctx, lockName, owner, statementLockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, errors.New(errors.Conflict, "该日期的账单正在核对")
+	}
+	defer func() {
+		if err := s.lockRepo.Release(context.Background(), lockName, owner); err != nil {
+			s.logger.Warn("Failed to release statement lock", zap.String("billDate", billDate), zap.Error(err))
+		}
+	}()
+
+	result, err := s.reconciliationDomainService.Reconcile(ctx, date)
+	if err != nil {
+		s.logger.Warn("Failed to reconcile payment statement", zap.String("billDate", billDate), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Reconciled payment statement",
+		zap.String("billDate", result.BillDate),
+		zap.Int("trades", result.TradeCount),
+		zap.Int("refunds", result.RefundCount),
+		zap.Int("matched", result.Matched),
+		zap.Int("discrepancies", len(result.Discrepancies)))
+
+	resultDTO := &dto.ReconciliationResultDTO{
+		BillDate:      result.BillDate,
+		TradeCount:    result.TradeCount,
+		RefundCount:   result.RefundCount,
+		Matched:       result.Matched,
+		Discrepancies: make([]*dto.ReconciliationDiscrepancyDTO, 0, len(result.Discrepancies)),
+	}
+	for _, discrepancy := range result.Discrepancies {
+		resultDTO.Discrepancies = append(resultDTO.Discrepancies, toDiscrepancyDTO(discrepancy))
+	}
+	return resultDTO, nil
+}
+
+// ReconcileYesterday 核对前一天的渠道对账单
+func (s *ReconciliationService) ReconcileYesterday(ctx context.Context) (*dto.ReconciliationResultDTO, error) {
+	return s.Reconcile(ctx, today().AddDate(0, 0, -1).Format("2006-01-02"))
+}
+
+// ReconcileDaily 每天到达核对时刻后核对一次前一天的渠道对账单，未到核对时刻或当天已核对时返回 nil
+// 多实例部署时以分布式锁保证只有一个实例核对；核对成功后锁保留为完成标记，失败时释放，下次检查时重试
+func (s *ReconciliationService) ReconcileDaily(ctx context.Context) (*dto.ReconciliationResultDTO, error) {
+	start := today()
+	if time.Since(start) < s.statementTime {
+		return nil, nil
+	}
+
+	billDate := start.AddDate(0, 0, -1).Format("2006-01-02")
+	lockName, owner := "payment_statement:daily:"+billDate, strconv.FormatInt(snowflake.Generate(), 10)
+	acquired, err := s.lockRepo.Acquire(ctx, lockName, owner, statementLockTTL)
+	if err != nil || !acquired {
+		return nil, err
+	}
+
+	result, err := s.Reconcile(ctx, billDate)
+	if err != nil {
+		if releaseErr := s.lockRepo.Release(context.Background(), lockName, owner); releaseErr != nil {
+			s.logger.Warn("Failed to release statement lock", zap.String("billDate", billDate), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	if extended, err := s.lockRepo.Extend(ctx, lockName, owner, statementDoneTTL); err != nil || !extended {
+		s.logger.Warn("Failed to mark statement reconciled", zap.String("billDate", billDate), zap.Bool("extended", extended), zap.Error(err))
+	}
+	return result, nil
+}
+
+// ListDiscrepancies 分页查询对账差异
+func (s *ReconciliationService) ListDiscrepancies(ctx context.Context, req *dto.ListDiscrepanciesRequest) ([]*dto.ReconciliationDiscrepancyDTO, int64, error) {
+	req.Page, req.PageSize = normalizePage(req.Page, req.PageSize)
+	page, pageSize := req.Page, req.PageSize
+
+	filter := repository.DiscrepancyFilter{
+		BillDate: req.BillDate,
+		Type:     req.Type,
+		Status:   req.Status,
+	}
+
+	total, err := s.reconciliationRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	discrepancies, err := s.reconciliationRepo.List(ctx, filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]*dto.ReconciliationDiscrepancyDTO, 0, len(discrepancies))
+	for _, discrepancy := range discrepancies {
+		records = append(records, toDiscrepancyDTO(discrepancy))
+	}
+
+	return records, total, nil
+}
+
+// ResolveDiscrepancy 将对账差异标记为已处理，重新核对该日期时不再重复记录
+func (s *ReconciliationService) ResolveDiscrepancy(ctx context.Context, id int64, openID string, req *dto.ResolveDiscrepancyRequest) (*dto.ReconciliationDiscrepancyDTO, error) {
+	discrepancy, err := s.reconciliationRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	discrepancy.Note = req.Note
+	discrepancy.ResolvedBy = openID
+	discrepancy.ResolvedAt = now
+	discrepancy.UpdatedAt = now
+	if err := s.reconciliationRepo.Resolve(ctx, discrepancy); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Resolved reconciliation discrepancy",
+		zap.Int64("id", discrepancy.ID),
+		zap.String("billDate", discrepancy.BillDate),
+		zap.String("type", discrepancy.Type),
+		zap.String("resolvedBy", openID))
+	return toDiscrepancyDTO(discrepancy), nil
+}
+
+// today 账单时区的今天零点
+func today() time.Time {
+	now := time.Now().In(domainservice.BillLocation)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, domainservice.BillLocation)
+}
+
+// toDiscrepancyDTO 转换为对账差异 DTO
+func toDiscrepancyDTO(d *entity.ReconciliationDiscrepancy) *dto.ReconciliationDiscrepancyDTO {
+	return &dto.ReconciliationDiscrepancyDTO{
+		ID:            strconv.FormatInt(d.ID, 10),
+		BillDate:      d.BillDate,
+		Type:          d.Type,
+		RecordType:    d.RecordType,
+		PaymentNo:     d.PaymentNo,
+		RefundNo:      d.RefundNo,
+		TransactionID: d.TransactionID,
+		LocalAmount:   d.LocalAmount,
+		RemoteAmount:  d.RemoteAmount,
+		Detail:        d.Detail,
+		Status:        d.Status,
+		Note:          d.Note,
+		ResolvedBy:    d.ResolvedBy,
+		ResolvedAt:    d.ResolvedAt,
+		CreatedAt:     d.CreatedAt,
+	}
+}
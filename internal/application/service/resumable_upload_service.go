@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/utils"
+)
+
+const (
+	// resumablePartDir 断点续传分片的存储目录，分片不对外提供访问
+	resumablePartDir = "resumable"
+	// resumableSweepBatch 每批清理的过期会话数
+	resumableSweepBatch = 100
+)
+
+// ResumableUploadService 断点续传上传服务
+// 客户端先创建会话，再按偏移量分片上传，每个分片作为独立对象保存在文件存储中；
+// 最后一个分片接收后自动合并，交由 UploadService 完成校验、清洗与存储
+type ResumableUploadService struct {
+	cfg           *config.Config
+	uploadService *UploadService
+	sessionRepo   repository.UploadSessionRepository
+	fileStorage   repository.FileStorage
+	logger        *zap.Logger
+}
+
+// NewResumableUploadService 创建断点续传上传服务
+func NewResumableUploadService(
+	cfg *config.Config,
+	uploadService *UploadService,
+	sessionRepo repository.UploadSessionRepository,
+	fileStorage repository.FileStorage,
+	logger *zap.Logger,
+) *ResumableUploadService {
+	return &ResumableUploadService{
+		cfg:           cfg,
+		uploadService: uploadService,
+		sessionRepo:   sessionRepo,
+		fileStorage:   fileStorage,
+		logger:        logger,
+	}
+}
+
+// MaxSize 允许上传的最大文件字节数
+func (s *ResumableUploadService) MaxSize() int64 {
+	return s.uploadService.MaxSize()
+}
+
+// CreateSession 创建上传会话
+// 在接收任何数据之前校验文件名、大小与上传类型
+func (s *ResumableUploadService) CreateSession(ctx context.Context, openID string, length int64, uploadType UploadType, relatedID, filename string) (*entity.UploadSession, error) {
+	return s.createSession(ctx, openID, length, uploadType, relatedID, filename, false)
+}
+
+// createSession 创建上传会话，直传会话分配供客户端直接上传的暂存对象键
+func (s *ResumableUploadService) createSession(ctx context.Context, openID string, length int64, uploadType UploadType, relatedID, filename string, direct bool) (*entity.UploadSession, error) {
+	if length <= 0 {
+		return nil, errors.New(errors.ParamError, "Upload-Length must be greater than 0")
+	}
+	if err := s.uploadService.ValidateUpload(filename, length, uploadType); err != nil {
+		return nil, err
+	}
+	// 提前拒绝超出配额的上传，合并后写入上传记录时再次校验
+	if err := s.uploadService.checkQuota(ctx, openID, length); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateToken(16)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "Failed to generate upload id", err)
+	}
+
+	now := time.Now()
+	session := &entity.UploadSession{
+		ID:        id,
+		OpenID:    openID,
+		Type:      string(uploadType),
+		RelatedID: relatedID,
+		Filename:  originalFilename(filename),
+		Length:    length,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(s.cfg.Upload.Resumable.ExpireHours) * time.Hour),
+	}
+	if direct {
+		session.DirectKey = path.Join(resumablePartDir, id, "direct")
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetSession 获取当前用户的上传会话，不属于该用户或已过期的会话视为不存在
+func (s *ResumableUploadService) GetSession(ctx context.Context, openID, id string) (*entity.UploadSession, error) {
+	session, err := s.sessionRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.OpenID != openID || session.IsExpired(time.Now()) {
+		return nil, errors.New(errors.NotFound, "Upload session not found")
+	}
+	return session, nil
+}
+
+// WriteChunk 从 offset 处写入一个分片
+// 连接中途断开时保留已收到的部分，客户端可从新的偏移量继续上传；
+// 接收到全部数据后立即合并完成上传，合并失败(非内容错误)时可调用 Complete 重试
+func (s *ResumableUploadService) WriteChunk(ctx context.Context, openID, id string, offset int64, r io.Reader) (*entity.UploadSession, error) {
+	session, err := s.GetSession(ctx, openID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsDirect() {
+		return nil, errors.New(errors.Conflict, "Upload session only accepts direct upload")
+	}
+	if offset != session.Offset {
+		return nil, errors.New(errors.Conflict, fmt.Sprintf("Upload-Offset mismatch, expected %d", session.Offset))
+	}
+
+	remaining := session.Length - session.Offset
+	limit := remaining
+	if maxChunk := s.cfg.Upload.Resumable.MaxChunkSize; maxChunk > 0 && maxChunk < limit {
+		limit = maxChunk
+	}
+
+	data, readErr := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(data)) > limit {
+		if limit == remaining {
+			return nil, errors.New(errors.ParamError, "Chunk exceeds Upload-Length")
+		}
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("Chunk exceeds %d bytes", limit))
+	}
+	if readErr != nil {
+		s.logger.Warn("Resumable upload chunk interrupted",
+			zap.String("id", id),
+			zap.Int64("offset", offset),
+			zap.Int("received", len(data)),
+			zap.Error(readErr),
+		)
+	}
+	if len(data) == 0 {
+		return session, nil
+	}
+
+	partKey, err := s.partKey(id, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fileStorage.Put(ctx, partKey, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+		return nil, err
+	}
+
+	// 并发请求可能已推进偏移量，此时丢弃本分片
+	ok, err := s.sessionRepo.AppendPart(ctx, id, offset, int64(len(data)), partKey)
+	if err != nil || !ok {
+		s.deletePart(ctx, partKey)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New(errors.Conflict, "Upload-Offset mismatch, upload was modified concurrently")
+	}
+
+	session.Offset += int64(len(data))
+	session.Parts = append(session.Parts, partKey)
+	if session.IsComplete() {
+		if _, err := s.finish(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// Complete 合并已接收的全部分片并完成上传
+// 上传已完成(接收最后一个分片时已自动合并)的会话直接返回原结果
+func (s *ResumableUploadService) Complete(ctx context.Context, openID, id string) (*UploadResult, error) {
+	session, err := s.GetSession(ctx, openID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsFinished() {
+		return finishedResult(session)
+	}
+	if !session.IsComplete() {
+		return nil, errors.New(errors.Conflict, fmt.Sprintf("Upload incomplete, received %d of %d bytes", session.Offset, session.Length))
+	}
+	return s.finish(ctx, session)
+}
+
+// finish 合并分片并登记上传，上传结果保存在会话中，重复完成时直接返回
+func (s *ResumableUploadService) finish(ctx context.Context, session *entity.UploadSession) (*UploadResult, error) {
+	openID, id := session.OpenID, session.ID
+	ok, err := s.sessionRepo.ClaimCompletion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New(errors.Conflict, "Upload is already being completed")
+	}
+
+	data, err := s.readParts(ctx, session)
+	var result *UploadResult
+	if err == nil {
+		result, err = s.uploadService.UploadData(ctx, openID, session.Filename, data, UploadType(session.Type), session.RelatedID)
+	}
+	if err != nil {
+		if isParamError(err) {
+			// 内容错误重试也不会成功
+			_ = s.discard(ctx, session)
+		} else if releaseErr := s.sessionRepo.ReleaseCompletion(ctx, id); releaseErr != nil {
+			s.logger.Error("Failed to release resumable upload", zap.String("id", id), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "Failed to encode upload result", err)
+	}
+	if err := s.sessionRepo.Finish(ctx, id, encoded); err != nil {
+		s.logger.Error("Failed to finish resumable upload", zap.String("id", id), zap.Error(err))
+		if err := s.discard(ctx, session); err != nil {
+			s.logger.Error("Failed to clean up resumable upload", zap.String("id", id), zap.Error(err))
+		}
+		return result, nil
+	}
+	for _, partKey := range session.Parts {
+		s.deletePart(ctx, partKey)
+	}
+	session.Parts = nil
+	session.Result = encoded
+	return result, nil
+}
+
+// finishedResult 解析已完成会话中保存的上传结果
+func finishedResult(session *entity.UploadSession) (*UploadResult, error) {
+	var result UploadResult
+	if err := json.Unmarshal(session.Result, &result); err != nil {
+		return nil, errors.Wrap(errors.InternalError, "Failed to decode upload result", err)
+	}
+	return &result, nil
+}
+
+// Abort 终止上传并删除已接收的分片
+func (s *ResumableUploadService) Abort(ctx context.Context, openID, id string) error {
+	session, err := s.GetSession(ctx, openID, id)
+	if err != nil {
+		return err
+	}
+	return s.discard(ctx, session)
+}
+
+// SweepExpired 清理过期未完成的上传会话及其分片，返回清理的会话数
+func (s *ResumableUploadService) SweepExpired(ctx context.Context) (int, error) {
+	swept := 0
+	for {
+		ids, err := s.sessionRepo.FindExpired(ctx, time.Now(), resumableSweepBatch)
+		if err != nil {
+			return swept, err
+		}
+
+		for _, id := range ids {
+			session, err := s.sessionRepo.FindByID(ctx, id)
+			if isNotFound(err) {
+				// 会话数据已在 Redis 中过期，只剩索引项
+				session = &entity.UploadSession{ID: id}
+			} else if err != nil {
+				return swept, err
+			}
+			if err := s.discard(ctx, session); err != nil {
+				return swept, err
+			}
+			swept++
+		}
+
+		if len(ids) < resumableSweepBatch {
+			return swept, nil
+		}
+	}
+}
+
+// isResumablePart 是否为断点续传分片的存储对象键
+func isResumablePart(key string) bool {
+	cleaned := path.Clean(key)
+	return cleaned == resumablePartDir || strings.HasPrefix(cleaned, resumablePartDir+"/")
+}
+
+// partKey 分片的存储对象键，随机后缀避免同一偏移量的并发写入互相覆盖
+func (s *ResumableUploadService) partKey(id string, offset int64) (string, error) {
+	suffix, err := utils.GenerateToken(4)
+	if err != nil {
+		return "", errors.Wrap(errors.InternalError, "Failed to generate part key", err)
+	}
+	return path.Join(resumablePartDir, id, fmt.Sprintf("%020d-%s", offset, suffix)), nil
+}
+
+// readParts 按偏移量顺序拼接已接收的全部分片
+func (s *ResumableUploadService) readParts(ctx context.Context, session *entity.UploadSession) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, session.Length))
+	for _, partKey := range session.Parts {
+		rc, _, err := s.fileStorage.Get(ctx, partKey)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(buf, rc)
+		rc.Close()
+		if err != nil {
+			return nil, errors.Wrap(errors.InternalError, "Failed to read upload part", err)
+		}
+	}
+	if int64(buf.Len()) != session.Length {
+		return nil, errors.New(errors.InternalError, fmt.Sprintf("Upload parts total %d bytes, expected %d", buf.Len(), session.Length))
+	}
+	return buf.Bytes(), nil
+}
+
+// discard 删除全部分片与会话
+func (s *ResumableUploadService) discard(ctx context.Context, session *entity.UploadSession) error {
+	for _, partKey := range session.Parts {
+		s.deletePart(ctx, partKey)
+	}
+	if session.IsDirect() && !session.IsFinished() && !slices.Contains(session.Parts, session.DirectKey) {
+		s.deletePart(ctx, session.DirectKey)
+	}
+	return s.sessionRepo.Delete(ctx, session.ID)
+}
+
+// deletePart 尽力删除分片，残留的分片只占用空间
+func (s *ResumableUploadService) deletePart(ctx context.Context, partKey string) {
+	if err := s.fileStorage.Delete(ctx, partKey); err != nil {
+		s.logger.Warn("Failed to delete resumable upload part", zap.String("key", partKey), zap.Error(err))
+	}
+}
+
+// isParamError 判断是否为参数错误
+func isParamError(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.ParamError
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/logger"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/utils"
+)
+
+const (
+	// sceneLinkParam 场景短链在 scene 中的参数名
+	sceneLinkParam = "c"
+	// sceneLinkCodeLength 短码长度，16 位共 80 位熵，无法通过枚举猜中；c=<短码> 不超过 scene 的 32 个字符
+	sceneLinkCodeLength = 16
+	// sceneLinkCreateAttempts 短码冲突时的最大重试次数
+	sceneLinkCreateAttempts = 5
+	// sceneLinkSweepBatch 每批清理的过期短链数
+	sceneLinkSweepBatch = 500
+)
+
+// SceneLinkService 小程序码场景短链服务
+// 业务数据以短码登记在服务端，小程序码 scene 中只放 c=<短码>，扫码后凭短码换取数据，
+// 不受 scene 32 个字符的限制；短链可限制有效期与使用次数
+type SceneLinkService struct {
+	cfg           config.SceneLinkConfig
+	sceneLinkRepo repository.SceneLinkRepository
+	userRepo      repository.UserRepository
+	wechatService *WechatService
+}
+
+// NewSceneLinkService 创建小程序码场景短链服务
+func NewSceneLinkService(
+	cfg *config.Config,
+	sceneLinkRepo repository.SceneLinkRepository,
+	userRepo repository.UserRepository,
+	wechatService *WechatService,
+) *SceneLinkService {
+	return &SceneLinkService{
+		cfg:           cfg.Wechat.SceneLink,
+		sceneLinkRepo: sceneLinkRepo,
+		userRepo:      userRepo,
+		wechatService: wechatService,
+	}
+}
+
+// Create 登记场景短链，可同时生成 scene 为 c=<短码> 的小程序码
+// 小程序码依赖登记得到的短码，生成失败时删除已登记的短链，避免留下无人持有的短链
+func (s *SceneLinkService) Create(ctx context.Context, openID string, req *dto.CreateSceneLinkRequest) (*dto.SceneLinkDTO, error) {
+	if !json.Valid(req.Payload) {
+		return nil, errors.New(errors.ParamError, "payload 不是有效的 JSON")
+	}
+	if s.cfg.MaxPayloadSize > 0 && len(req.Payload) > s.cfg.MaxPayloadSize {
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("payload 不能超过 %d 字节", s.cfg.MaxPayloadSize))
+	}
+	if req.QRCode && req.Page == "" {
+		return nil, errors.New(errors.ParamError, "生成小程序码需要提供 page")
+	}
+
+	ttl := time.Duration(req.ExpiresIn) * time.Second
+	if ttl == 0 {
+		ttl = time.Duration(s.cfg.DefaultTTL) * time.Hour
+	}
+	if maxTTL := time.Duration(s.cfg.MaxTTL) * time.Hour; maxTTL > 0 && ttl > maxTTL {
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("有效期不能超过 %s", maxTTL))
+	}
+
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	link := &entity.SceneLink{
+		CreatorID: user.ID,
+		Page:      req.Page,
+		Payload:   string(req.Payload),
+		MaxUses:   req.MaxUses,
+		CreatedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+	}
+	if ttl > 0 {
+		link.ExpiresAt = now.Add(ttl).UnixMilli()
+	}
+
+	if err := s.register(ctx, link); err != nil {
+		return nil, err
+	}
+
+	result := toSceneLinkDTO(link)
+	if req.QRCode {
+		qrcode, err := s.wechatService.GenerateQRCode(ctx, &dto.GenerateQRCodeRequest{
+			Scene:      result.Scene,
+			Page:       req.Page,
+			EnvVersion: req.EnvVersion,
+		})
+		if err != nil {
+			// 请求已取消时同样需要删除
+			if deleteErr := s.sceneLinkRepo.Delete(context.WithoutCancel(ctx), link.Code); deleteErr != nil {
+				logger.Warn("Failed to delete scene link after QR code generation failed",
+					zap.String("code", link.Code),
+					zap.Error(deleteErr),
+				)
+			}
+			return nil, err
+		}
+		result.QRCodeURL = qrcode.URL
+	}
+
+	return result, nil
+}
+
+// Resolve 解析场景短链并记录一次使用
+// code 可以是短码本身，也可以是完整的 scene(如 c=AB23CD)
+func (s *SceneLinkService) Resolve(ctx context.Context, code string) (*dto.SceneLinkDTO, error) {
+	if value, ok := utils.ParseScene(code)[sceneLinkParam]; ok {
+		code = value
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	link, err := s.sceneLinkRepo.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	consumed, err := s.sceneLinkRepo.Consume(ctx, code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		if link.IsExpired(now) {
+			return nil, errors.New(errors.InvalidInvitation, "链接已过期")
+		}
+		// 查询后被并发请求用完
+		return nil, errors.New(errors.InvalidInvitation, "链接使用次数已达上限")
+	}
+
+	link.UseCount++
+	return toSceneLinkDTO(link), nil
+}
+
+// SweepExpired 删除过期超过保留期的短链，返回删除条数
+func (s *SceneLinkService) SweepExpired(ctx context.Context) (int, error) {
+	before := time.Now().Add(-time.Duration(s.cfg.Retention) * time.Hour).UnixMilli()
+
+	swept := 0
+	for ctx.Err() == nil {
+		deleted, err := s.sceneLinkRepo.DeleteExpired(ctx, before, sceneLinkSweepBatch)
+		swept += int(deleted)
+		if err != nil || deleted < sceneLinkSweepBatch {
+			return swept, err
+		}
+	}
+	return swept, nil
+}
+
+// register 生成短码并登记，短码冲突时重新生成
+func (s *SceneLinkService) register(ctx context.Context, link *entity.SceneLink) error {
+	for range sceneLinkCreateAttempts {
+		code, err := utils.GenerateCode(sceneLinkCodeLength)
+		if err != nil {
+			return errors.Wrap(errors.InternalError, "生成短码失败", err)
+		}
+		link.Code = code
+
+		created, err := s.sceneLinkRepo.Create(ctx, link)
+		if err != nil {
+			return err
+		}
+		if created {
+			return nil
+		}
+	}
+	return errors.New(errors.Conflict, "生成短码失败，请重试")
+}
+
+// toSceneLinkDTO 转换为场景短链 DTO
+func toSceneLinkDTO(link *entity.SceneLink) *dto.SceneLinkDTO {
+	return &dto.SceneLinkDTO{
+		Code:      link.Code,
+		Scene:     utils.BuildScene(map[string]string{sceneLinkParam: link.Code}),
+		Page:      link.Page,
+		Payload:   json.RawMessage(link.Payload),
+		CreatorID: strconv.FormatInt(link.CreatorID, 10),
+		MaxUses:   link.MaxUses,
+		UseCount:  link.UseCount,
+		ExpiresAt: link.ExpiresAt,
+		CreatedAt: link.CreatedAt,
+	}
+}
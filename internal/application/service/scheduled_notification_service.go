@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/snowflake"
+)
+
+// minRecurrenceInterval 重复通知的最小间隔，避免 @every 等规则过于频繁
+const minRecurrenceInterval = time.Minute
+
+// ScheduledNotificationService 定时通知服务
+// 定时通知落库后由后台任务在到期时领取，经通知领域服务写入发件箱；进程重启不影响已创建的定时通知
+type ScheduledNotificationService struct {
+	cfg                       config.ScheduledNotificationConfig
+	defaultTimezone           string
+	notificationDomainService *domainservice.NotificationDomainService
+	scheduledRepo             repository.ScheduledNotificationRepository
+	preferenceRepo            repository.NotificationPreferenceRepository
+	userRepo                  repository.UserRepository
+	logger                    *zap.Logger
+}
+
+// NewScheduledNotificationService 创建定时通知服务
+func NewScheduledNotificationService(
+	cfg *config.Config,
+	notificationDomainService *domainservice.NotificationDomainService,
+	scheduledRepo repository.ScheduledNotificationRepository,
+	preferenceRepo repository.NotificationPreferenceRepository,
+	userRepo repository.UserRepository,
+	logger *zap.Logger,
+) *ScheduledNotificationService {
+	defaultTimezone := cfg.Notification.DefaultTimezone
+	if defaultTimezone == "" {
+		defaultTimezone = time.Local.String()
+	}
+	return &ScheduledNotificationService{
+		cfg:                       cfg.Notification.Scheduled,
+		defaultTimezone:           defaultTimezone,
+		notificationDomainService: notificationDomainService,
+		scheduledRepo:             scheduledRepo,
+		preferenceRepo:            preferenceRepo,
+		userRepo:                  userRepo,
+		logger:                    logger,
+	}
+}
+
+// Create 为当前用户创建定时通知
+// 立即校验渠道已启用与模板变量，避免到期后才发现无法发送
+func (s *ScheduledNotificationService) Create(ctx context.Context, openID string, req *dto.CreateScheduledNotificationRequest) (*dto.ScheduledNotificationDTO, error) {
+	if !s.notificationDomainService.SupportsChannel(domainservice.NotificationChannel(req.Channel)) {
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("通知渠道 %s 未启用", req.Channel))
+	}
+
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return nil, err
+	}
+
+	templated, err := s.notificationDomainService.ValidateTemplate(domainservice.NotificationRequest{
+		Type:      req.Type,
+		Channel:   domainservice.NotificationChannel(req.Channel),
+		Locale:    req.Locale,
+		Variables: req.Variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !templated {
+		// 微信订阅消息只能按模板发送
+		if req.Channel == string(domainservice.ChannelWechat) || req.Content == "" {
+			return nil, errors.New(errors.ParamError, fmt.Sprintf("通知类型 %s 未注册 %s 渠道的模板", req.Type, req.Channel))
+		}
+	}
+
+	if s.cfg.MaxPerUser > 0 {
+		active, err := s.scheduledRepo.CountByUserID(ctx, user.ID, entity.ScheduledNotificationStatusActive)
+		if err != nil {
+			return nil, err
+		}
+		if active >= int64(s.cfg.MaxPerUser) {
+			return nil, errors.New(errors.Conflict, fmt.Sprintf("等待发送的定时通知已达上限 %d 条", s.cfg.MaxPerUser))
+		}
+	}
+
+	now := time.Now()
+	notification := &entity.ScheduledNotification{
+		ID:         snowflake.Generate(),
+		UserID:     user.ID,
+		Type:       req.Type,
+		Channel:    req.Channel,
+		Locale:     req.Locale,
+		Title:      req.Title,
+		Content:    req.Content,
+		Recurrence: req.Recurrence,
+		Timezone:   req.Timezone,
+		Status:     entity.ScheduledNotificationStatusActive,
+		CreatedAt:  now.UnixMilli(),
+		UpdatedAt:  now.UnixMilli(),
+	}
+	if notification.Timezone == "" {
+		notification.Timezone = s.userTimezone(ctx, user.ID)
+	}
+	if len(req.Variables) > 0 {
+		variables, err := json.Marshal(req.Variables)
+		if err != nil {
+			return nil, errors.Wrap(errors.ParamError, "无效的模板变量", err)
+		}
+		notification.Variables = string(variables)
+	}
+
+	if err := s.schedule(notification, req.SendAt, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduledRepo.Create(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	return toScheduledNotificationDTO(notification), nil
+}
+
+// List 分页查询当前用户的定时通知
+func (s *ScheduledNotificationService) List(ctx context.Context, openID string, req *dto.ListScheduledNotificationsRequest) ([]*dto.ScheduledNotificationDTO, int64, error) {
+	req.Page, req.PageSize = normalizePage(req.Page, req.PageSize)
+	page, pageSize := req.Page, req.PageSize
+
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.scheduledRepo.CountByUserID(ctx, user.ID, req.Status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notifications, err := s.scheduledRepo.ListByUserID(ctx, user.ID, req.Status, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]*dto.ScheduledNotificationDTO, 0, len(notifications))
+	for _, notification := range notifications {
+		records = append(records, toScheduledNotificationDTO(notification))
+	}
+
+	return records, total, nil
+}
+
+// Cancel 取消当前用户的定时通知
+func (s *ScheduledNotificationService) Cancel(ctx context.Context, openID string, id int64) error {
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return err
+	}
+
+	notification, err := s.scheduledRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if notification.UserID != user.ID {
+		return errors.New(errors.PermissionDenied, "No permission to cancel this scheduled notification")
+	}
+
+	cancelled, err := s.scheduledRepo.Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errors.New(errors.Conflict, "定时通知已发送或已取消")
+	}
+	return nil
+}
+
+// DispatchDue 发送全部到期的定时通知，返回写入发件箱的条数
+// 领取时已推进下次发送时间，发送失败不会重试，由发件箱负责后续投递重试
+func (s *ScheduledNotificationService) DispatchDue(ctx context.Context) (int, error) {
+	batchSize := max(s.cfg.BatchSize, 1)
+
+	sent := 0
+	for ctx.Err() == nil {
+		notifications, err := s.scheduledRepo.ClaimDue(ctx, time.Now(), batchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, notification := range notifications {
+			if s.send(ctx, notification) {
+				sent++
+			}
+		}
+
+		if len(notifications) < batchSize {
+			break
+		}
+	}
+
+	return sent, nil
+}
+
+// send 发送一条已领取的定时通知，返回是否写入发件箱
+func (s *ScheduledNotificationService) send(ctx context.Context, notification *entity.ScheduledNotification) bool {
+	err := s.buildAndSend(ctx, notification)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, domainservice.ErrNotificationSuppressed):
+		s.logger.Debug("Scheduled notification suppressed",
+			zap.Int64("id", notification.ID),
+			zap.Int64("userId", notification.UserID),
+			zap.String("type", notification.Type),
+		)
+	default:
+		s.logger.Warn("Failed to send scheduled notification",
+			zap.Int64("id", notification.ID),
+			zap.Int64("userId", notification.UserID),
+			zap.String("type", notification.Type),
+			zap.Error(err),
+		)
+	}
+	return false
+}
+
+// buildAndSend 按定时通知构造通知请求并发送
+func (s *ScheduledNotificationService) buildAndSend(ctx context.Context, notification *entity.ScheduledNotification) error {
+	user, err := s.userRepo.FindByID(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	req := domainservice.NotificationRequest{
+		UserID:  notification.UserID,
+		Type:    notification.Type,
+		Channel: domainservice.NotificationChannel(notification.Channel),
+		OpenID:  user.OpenID,
+		Locale:  notification.Locale,
+		Title:   notification.Title,
+		Content: notification.Content,
+	}
+	if notification.Variables != "" {
+		if err := json.Unmarshal([]byte(notification.Variables), &req.Variables); err != nil {
+			return errors.Wrap(errors.InternalError, "invalid scheduled notification variables", err)
+		}
+	}
+
+	return s.notificationDomainService.SendNotification(ctx, req)
+}
+
+// schedule 校验发送时间与重复规则，计算首次发送时间
+func (s *ScheduledNotificationService) schedule(notification *entity.ScheduledNotification, sendAt int64, now time.Time) error {
+	if _, err := time.LoadLocation(notification.Timezone); err != nil {
+		return errors.New(errors.ParamError, fmt.Sprintf("无效的时区: %s", notification.Timezone))
+	}
+
+	if !notification.IsRecurring() {
+		if sendAt == 0 {
+			return errors.New(errors.ParamError, "需要提供发送时间或重复规则")
+		}
+		if sendAt <= now.UnixMilli() {
+			return errors.New(errors.ParamError, "发送时间必须晚于当前时间")
+		}
+		notification.NextRunAt = sendAt
+		return nil
+	}
+
+	// 首次发送时间为 sendAt(未指定时为当前时间)起的第一个匹配时刻
+	start := now
+	if sendAt > now.UnixMilli() {
+		start = time.UnixMilli(sendAt - 1)
+	}
+	first, err := notification.NextAfter(start)
+	if err != nil {
+		return errors.New(errors.ParamError, fmt.Sprintf("无效的重复规则: %s", notification.Recurrence))
+	}
+	second, err := notification.NextAfter(first)
+	if err == nil && second.Sub(first) < minRecurrenceInterval {
+		return errors.New(errors.ParamError, fmt.Sprintf("重复间隔不能小于 %s", minRecurrenceInterval))
+	}
+
+	notification.NextRunAt = first.UnixMilli()
+	return nil
+}
+
+// userTimezone 用户偏好中的时区，未设置时使用系统默认时区
+func (s *ScheduledNotificationService) userTimezone(ctx context.Context, userID int64) string {
+	preference, err := s.preferenceRepo.FindByUserID(ctx, userID)
+	if err == nil && preference.Timezone != "" {
+		return preference.Timezone
+	}
+	return s.defaultTimezone
+}
+
+// toScheduledNotificationDTO 转换为定时通知 DTO
+func toScheduledNotificationDTO(n *entity.ScheduledNotification) *dto.ScheduledNotificationDTO {
+	record := &dto.ScheduledNotificationDTO{
+		ID:         strconv.FormatInt(n.ID, 10),
+		Type:       n.Type,
+		Channel:    n.Channel,
+		Locale:     n.Locale,
+		Title:      n.Title,
+		Content:    n.Content,
+		Recurrence: n.Recurrence,
+		Timezone:   n.Timezone,
+		Status:     n.Status,
+		LastRunAt:  n.LastRunAt,
+		RunCount:   n.RunCount,
+		CreatedAt:  n.CreatedAt,
+	}
+	if n.Variables != "" {
+		_ = json.Unmarshal([]byte(n.Variables), &record.Variables)
+	}
+	// 已结束的定时通知不再有下次发送时间
+	if n.Status == entity.ScheduledNotificationStatusActive {
+		record.NextRunAt = n.NextRunAt
+	}
+	return record
+}
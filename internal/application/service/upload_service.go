@@ -1,21 +1,36 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"mime/multipart"
-	"os"
+	"net/url"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
 	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/imageutil"
+	"github.com/wxlbd/polaris/pkg/snowflake"
 )
 
 // UploadService 文件上传服务
 type UploadService struct {
-	cfg *config.Config
+	cfg         *config.Config
+	fileStorage repository.FileStorage
+	uploadRepo  repository.UploadRepository
+	userRepo    repository.UserRepository
+	lockRepo    repository.LockRepository
 }
 
 // UploadType 上传类型
@@ -26,27 +41,86 @@ const (
 	UploadTypeBabyAvatar UploadType = "baby_avatar"
 )
 
+const (
+	// orphanSweepBatch 每批清理的孤儿文件数
+	orphanSweepBatch = 100
+	// storageKeyLockTTL 存储对象锁的有效期
+	storageKeyLockTTL = time.Minute
+	// storageKeyLockWait 等待存储对象锁的最长时间
+	storageKeyLockWait = 10 * time.Second
+	// storageKeyLockRetry 存储对象锁被占用时的重试间隔
+	storageKeyLockRetry = 50 * time.Millisecond
+)
+
 // UploadResult 上传结果
 type UploadResult struct {
-	URL      string `json:"url"`      // 完整访问URL
-	Path     string `json:"path"`     // 相对路径
-	Filename string `json:"filename"` // 文件名
-	Size     int64  `json:"size"`     // 文件大小
+	ID       int64           `json:"id,string"`          // 上传记录ID
+	URL      string          `json:"url"`                // 完整访问URL
+	Path     string          `json:"path"`               // 存储对象键
+	Filename string          `json:"filename"`           // 文件名
+	Size     int64           `json:"size"`               // 文件大小
+	SHA256   string          `json:"sha256"`             // 内容哈希
+	Variants []VariantResult `json:"variants,omitempty"` // 缩略图，仅头像类上传生成
+}
+
+// VariantResult 缩略图
+type VariantResult struct {
+	Size   int    `json:"size"`   // 长边像素
+	Format string `json:"format"` // 编码格式
+	URL    string `json:"url"`
+	Path   string `json:"path"`
+}
+
+// encodedVariant 已编码待保存的缩略图
+type encodedVariant struct {
+	VariantResult
+	data []byte
 }
 
 // NewUploadService 创建上传服务
-func NewUploadService(cfg *config.Config) *UploadService {
+func NewUploadService(cfg *config.Config, fileStorage repository.FileStorage, uploadRepo repository.UploadRepository, userRepo repository.UserRepository, lockRepo repository.LockRepository) *UploadService {
 	return &UploadService{
-		cfg: cfg,
+		cfg:         cfg,
+		fileStorage: fileStorage,
+		uploadRepo:  uploadRepo,
+		userRepo:    userRepo,
+		lockRepo:    lockRepo,
 	}
 }
 
 // UploadFile 上传文件
-func (s *UploadService) UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, uploadType UploadType, relatedID string) (*UploadResult, error) {
+// 文件以清洗后内容的 SHA-256 命名，相同内容只存储一份，每次上传各自记录一条上传记录
+func (s *UploadService) UploadFile(ctx context.Context, openID string, fileHeader *multipart.FileHeader, uploadType UploadType, relatedID string) (*UploadResult, error) {
 	if fileHeader == nil {
 		return nil, errors.New(errors.ParamError, "File cannot be empty")
 	}
+	if err := s.ValidateUpload(fileHeader.Filename, fileHeader.Size, uploadType); err != nil {
+		return nil, err
+	}
 
+	// Read file content
+	data, err := s.readFile(fileHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saveUpload(ctx, openID, fileHeader.Filename, data, uploadType, relatedID)
+}
+
+// UploadData 上传已接收完整的文件内容，如断点续传合并后的文件
+func (s *UploadService) UploadData(ctx context.Context, openID, filename string, data []byte, uploadType UploadType, relatedID string) (*UploadResult, error) {
+	if len(data) == 0 {
+		return nil, errors.New(errors.ParamError, "File cannot be empty")
+	}
+	if err := s.ValidateUpload(filename, int64(len(data)), uploadType); err != nil {
+		return nil, err
+	}
+
+	return s.saveUpload(ctx, openID, filename, data, uploadType, relatedID)
+}
+
+// ValidateUpload 校验文件名扩展名、文件大小与上传类型，在接收文件内容之前即可调用
+func (s *UploadService) ValidateUpload(filename string, size int64, uploadType UploadType) error {
 	// MIME type to extension mapping
 	mimeToExt := map[string]string{
 		"image/jpeg": ".jpg",
@@ -57,7 +131,7 @@ func (s *UploadService) UploadFile(ctx context.Context, fileHeader *multipart.Fi
 
 	// Validate file type
 	allowedTypes := s.cfg.Upload.AllowedTypes
-	fileExt := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	fileExt := normalizeExt(filepath.Ext(filename))
 	isAllowed := false
 	allowedExts := []string{}
 
@@ -77,99 +151,539 @@ func (s *UploadService) UploadFile(ctx context.Context, fileHeader *multipart.Fi
 	}
 
 	if !isAllowed {
-		return nil, errors.New(errors.ParamError, "Unsupported file type. Allowed types: "+strings.Join(allowedExts, ", "))
+		return errors.New(errors.ParamError, "Unsupported file type. Allowed types: "+strings.Join(allowedExts, ", "))
 	}
 
 	// Validate file size
-	if fileHeader.Size > s.cfg.Upload.MaxSize {
-		return nil, errors.New(errors.ParamError, "File size exceeds limit")
+	if size > s.cfg.Upload.MaxSize {
+		return errors.New(errors.ParamError, "File size exceeds limit")
+	}
+
+	// Validate upload type
+	if !s.isValidType(uploadType) {
+		return errors.New(errors.ParamError, "Unsupported upload type")
+	}
+
+	return nil
+}
+
+// MaxSize 允许上传的最大文件字节数
+func (s *UploadService) MaxSize() int64 {
+	return s.cfg.Upload.MaxSize
+}
+
+// saveUpload Sanitize, store and record a validated upload
+func (s *UploadService) saveUpload(ctx context.Context, openID, filename string, data []byte, uploadType UploadType, relatedID string) (*UploadResult, error) {
+	fileExt := normalizeExt(filepath.Ext(filename))
+
+	// Validate actual content type, the extension alone can be forged
+	contentType := imageutil.DetectContentType(data)
+	if !s.isAllowedType(contentType) {
+		return nil, errors.New(errors.ParamError, "File content type "+contentType+" is not allowed")
+	}
+	if imageutil.ExtensionOf(contentType) != fileExt {
+		return nil, errors.New(errors.ParamError, "File content does not match its extension "+fileExt)
+	}
+
+	// Decode and re-encode the image to verify it and strip metadata (EXIF, GPS location, etc.)
+	img, err := imageutil.Sanitize(data, imageutil.Options{
+		MaxWidth:  s.cfg.Upload.MaxWidth,
+		MaxHeight: s.cfg.Upload.MaxHeight,
+		MaxFrames: s.cfg.Upload.MaxFrames,
+		MaxPixels: s.cfg.Upload.MaxPixels,
+	})
+	if err != nil {
+		return nil, s.imageError(err)
+	}
+
+	// Name the file by content hash, so identical files share one object and names never collide
+	sum := sha256.Sum256(img.Data)
+	hash := hex.EncodeToString(sum[:])
+	key := ContentKey("images", hash, imageutil.ExtensionOf(img.ContentType))
+
+	var result *UploadResult
+	// 按实际存储的大小校验配额，校验与写入上传记录在用户的配额锁内完成，避免并发上传各自通过校验后合计超出配额
+	err = s.withQuotaLock(ctx, openID, func() error {
+		if err := s.checkQuota(ctx, openID, int64(len(img.Data))); err != nil {
+			return err
+		}
+
+		// 写入上传记录前持有存储对象锁，避免并发删除最后一个引用时删掉共享的存储对象
+		return s.withStorageKeyLock(ctx, key, func() error {
+			var err error
+			result, err = s.storeUpload(ctx, openID, filename, key, hash, img, uploadType, relatedID)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// storeUpload 以 key 保存清洗后的图片及其缩略图并写入上传记录
+func (s *UploadService) storeUpload(ctx context.Context, openID, filename, key, hash string, img *imageutil.Image, uploadType UploadType, relatedID string) (*UploadResult, error) {
+	// Encode missing variants before saving anything, so a failure leaves no partial upload behind
+	var variants []encodedVariant
+	if s.hasVariants(uploadType) {
+		var err error
+		if variants, err = s.encodeVariants(ctx, key, img); err != nil {
+			return nil, err
+		}
 	}
 
-	// Get subdirectory
-	subDir := s.getSubDir(uploadType)
-	if subDir == "" {
-		return nil, errors.New(errors.ParamError, "Unsupported upload type")
+	// Save file, skipped when the same content is already stored
+	if err := PutIfAbsent(ctx, s.fileStorage, key, img.Data, img.ContentType); err != nil {
+		return nil, err
 	}
 
-	// Generate filename
-	filename := s.generateFilename(fileHeader.Filename, uploadType, relatedID)
+	result := &UploadResult{
+		URL:      s.fileStorage.URL(key),
+		Path:     key,
+		Filename: path.Base(key),
+		Size:     int64(len(img.Data)),
+		SHA256:   hash,
+	}
 
-	// Create save path
-	storagePath := s.cfg.Upload.StoragePath
-	dirPath := filepath.Join(storagePath, "images", subDir)
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return nil, errors.Wrap(errors.InternalError, "Failed to create directory", err)
+	// Save variants
+	for _, variant := range variants {
+		if variant.data != nil {
+			contentType := imageutil.FormatContentType(variant.Format)
+			if err := s.fileStorage.Put(ctx, variant.Path, bytes.NewReader(variant.data), int64(len(variant.data)), contentType); err != nil {
+				return nil, err
+			}
+		}
+		result.Variants = append(result.Variants, variant.VariantResult)
 	}
 
-	// Save file
-	filePath := filepath.Join(dirPath, filename)
-	if err := s.saveFile(fileHeader, filePath); err != nil {
+	// Record the upload
+	upload := &entity.Upload{
+		ID:          snowflake.Generate(),
+		OpenID:      openID,
+		Type:        string(uploadType),
+		RelatedID:   relatedID,
+		StorageKey:  key,
+		Filename:    originalFilename(filename),
+		Size:        result.Size,
+		SHA256:      hash,
+		ContentType: img.ContentType,
+	}
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
 		return nil, err
 	}
+	result.ID = upload.ID
+
+	return result, nil
+}
+
+// OpenFile 打开已上传的文件
+// size 大于 0 时返回对应规格的缩略图，客户端支持 WebP 时优先返回 WebP；
+// 缩略图不存在(如功能上线前上传的文件)时回落到原图
+func (s *UploadService) OpenFile(ctx context.Context, key string, size int, acceptWebP bool) (io.ReadCloser, *repository.FileInfo, error) {
+	// Parts of unfinished resumable uploads are not public
+	if isResumablePart(key) {
+		return nil, nil, errors.New(errors.NotFound, "file not found")
+	}
 
-	// Generate access URL
-	relPath := filepath.Join("uploads", "images", subDir, filename)
-	url := s.cfg.Server.BaseURL + "/" + filepath.ToSlash(relPath)
+	if size > 0 {
+		candidates, ok := s.variantsOfSize(size, acceptWebP)
+		if !ok {
+			return nil, nil, errors.New(errors.ParamError, fmt.Sprintf("Unsupported size %d", size))
+		}
 
-	return &UploadResult{
-		URL:      url,
-		Path:     "/" + relPath,
-		Filename: filename,
-		Size:     fileHeader.Size,
-	}, nil
+		for _, variant := range candidates {
+			rc, info, err := s.fileStorage.Get(ctx, VariantKey(key, variant.Size, variant.Format))
+			if err == nil {
+				return rc, info, nil
+			}
+			if !isNotFound(err) {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return s.fileStorage.Get(ctx, key)
 }
 
-// getSubDir Get subdirectory by upload type
-func (s *UploadService) getSubDir(uploadType UploadType) string {
-	switch uploadType {
-	case UploadTypeUserAvatar:
-		return "users"
-	case UploadTypeBabyAvatar:
-		return "babies"
-	default:
-		return ""
+// ListUploads 分页查询当前用户的上传文件
+func (s *UploadService) ListUploads(ctx context.Context, openID string, req *dto.ListUploadsRequest) ([]*dto.UploadDTO, int64, error) {
+	req.Page, req.PageSize = normalizePage(req.Page, req.PageSize)
+	page, pageSize := req.Page, req.PageSize
+
+	total, err := s.uploadRepo.CountByOpenID(ctx, openID, req.Type)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	uploads, err := s.uploadRepo.ListByOpenID(ctx, openID, req.Type, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]*dto.UploadDTO, 0, len(uploads))
+	for _, upload := range uploads {
+		records = append(records, s.toUploadDTO(upload))
 	}
+
+	return records, total, nil
 }
 
-// generateFilename Generate unique filename
-func (s *UploadService) generateFilename(originalFilename string, uploadType UploadType, relatedID string) string {
-	// Get file extension
-	ext := filepath.Ext(originalFilename)
-	if ext == "" {
-		ext = ".jpg" // Default extension
+// GetUsage 查询当前用户的存储用量
+func (s *UploadService) GetUsage(ctx context.Context, openID string) (*dto.UploadUsageDTO, error) {
+	used, err := s.uploadRepo.SumSizeByOpenID(ctx, openID)
+	if err != nil {
+		return nil, err
 	}
+	return &dto.UploadUsageDTO{Used: used, Quota: s.cfg.Upload.Quota}, nil
+}
 
-	// Build prefix
-	prefix := string(uploadType)
-	if relatedID != "" {
-		prefix = prefix + "_" + relatedID
+// DeleteUpload 删除当前用户的上传文件
+// 存储对象按内容共享，仅当没有其他上传记录引用时才删除对象及其缩略图；
+// 正在用作头像的文件不能删除，需先更换头像，旧头像由孤儿清理任务删除
+func (s *UploadService) DeleteUpload(ctx context.Context, openID string, id int64) error {
+	upload, err := s.uploadRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if upload.OpenID != openID {
+		return errors.New(errors.PermissionDenied, "No permission to delete this upload")
 	}
 
-	// Add timestamp
-	timestamp := time.Now().Format("20060102_150405")
+	user, err := s.userRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return err
+	}
+	if key, ok := s.storageKeyOf(user.AvatarURL); ok && key == upload.StorageKey {
+		return errors.New(errors.Conflict, "Upload is in use as the current avatar")
+	}
+
+	return s.removeUpload(ctx, upload)
+}
+
+// ReplaceReference 业务数据由引用 oldURL 改为引用 newURL 时调用
+// 旧文件标记为孤儿并在保留期后清理，新文件若此前被标记为孤儿则恢复为在用；不属于本存储的地址(如微信头像)忽略
+func (s *UploadService) ReplaceReference(ctx context.Context, openID string, uploadType UploadType, oldURL, newURL string) error {
+	oldKey, oldOK := s.storageKeyOf(oldURL)
+	newKey, newOK := s.storageKeyOf(newURL)
 
-	// Generate unique filename: {prefix}_{timestamp}{ext}
-	return fmt.Sprintf("%s_%s%s", prefix, timestamp, ext)
+	if newOK {
+		if err := s.uploadRepo.SetOrphanedAt(ctx, openID, string(uploadType), newKey, 0); err != nil {
+			return err
+		}
+	}
+	if oldOK && (!newOK || oldKey != newKey) {
+		if err := s.uploadRepo.SetOrphanedAt(ctx, openID, string(uploadType), oldKey, time.Now().UnixMilli()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// saveFile Save file to disk
-func (s *UploadService) saveFile(fileHeader *multipart.FileHeader, filePath string) error {
+// SweepOrphans 删除超过保留期的孤儿文件，返回删除的上传记录数
+func (s *UploadService) SweepOrphans(ctx context.Context) (int, error) {
+	retention := time.Duration(s.cfg.Upload.Orphan.RetentionHours) * time.Hour
+	before := time.Now().Add(-retention).UnixMilli()
+
+	swept := 0
+	for {
+		uploads, err := s.uploadRepo.FindOrphaned(ctx, before, orphanSweepBatch)
+		if err != nil {
+			return swept, err
+		}
+
+		for _, upload := range uploads {
+			if err := s.removeUpload(ctx, upload); err != nil && !isNotFound(err) {
+				return swept, err
+			}
+			swept++
+		}
+
+		if len(uploads) < orphanSweepBatch {
+			return swept, nil
+		}
+	}
+}
+
+// ContentKey 返回按内容哈希命名的存储对象键，如 images/ab/abcd...ef.jpg
+// 以哈希前两位分目录，避免单个目录下文件过多
+func ContentKey(dir, hash, ext string) string {
+	return path.Join(dir, hash[:2], hash+ext)
+}
+
+// PutIfAbsent 对象不存在时才写入
+// 用于按内容哈希命名的对象：键相同即内容相同，已存在时无需重复上传
+func PutIfAbsent(ctx context.Context, fileStorage repository.FileStorage, key string, data []byte, contentType string) error {
+	if _, err := fileStorage.Stat(ctx, key); err == nil {
+		return nil
+	} else if !isNotFound(err) {
+		return err
+	}
+	return fileStorage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// VariantKey 返回缩略图的存储对象键，如 images/ab/abcd.jpg -> images/ab/abcd_64.webp
+func VariantKey(key string, size int, format string) string {
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(key, path.Ext(key)), size, imageutil.FormatExtension(format))
+}
+
+// hasVariants Whether the upload type needs variants
+func (s *UploadService) hasVariants(uploadType UploadType) bool {
+	return uploadType == UploadTypeUserAvatar || uploadType == UploadTypeBabyAvatar
+}
+
+// encodeVariants Resize and encode all configured variants.
+// Variants already stored for the same content are not encoded again and have nil data.
+func (s *UploadService) encodeVariants(ctx context.Context, key string, img *imageutil.Image) ([]encodedVariant, error) {
+	variants := make([]encodedVariant, 0, len(s.cfg.Upload.Variants))
+	for _, cfg := range s.cfg.Upload.Variants {
+		if cfg.Size <= 0 || imageutil.FormatExtension(cfg.Format) == "" {
+			return nil, errors.New(errors.InternalError, fmt.Sprintf("Invalid image variant config: %d %s", cfg.Size, cfg.Format))
+		}
+
+		variantKey := VariantKey(key, cfg.Size, cfg.Format)
+		variant := encodedVariant{
+			VariantResult: VariantResult{
+				Size:   cfg.Size,
+				Format: cfg.Format,
+				URL:    s.fileStorage.URL(variantKey),
+				Path:   variantKey,
+			},
+		}
+
+		_, err := s.fileStorage.Stat(ctx, variantKey)
+		if err != nil && !isNotFound(err) {
+			return nil, err
+		}
+		if err != nil {
+			var buf bytes.Buffer
+			if err := imageutil.Encode(&buf, imageutil.Resize(img.Decoded, cfg.Size), cfg.Format); err != nil {
+				return nil, errors.Wrap(errors.InternalError, "Failed to generate image variant", err)
+			}
+			variant.data = buf.Bytes()
+		}
+
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// variantsOfSize Configured variants of the given size, WebP first when accepted.
+// ok is false when no variant of the size is configured.
+func (s *UploadService) variantsOfSize(size int, acceptWebP bool) (candidates []config.ImageVariantConfig, ok bool) {
+	var webp, others []config.ImageVariantConfig
+	for _, variant := range s.cfg.Upload.Variants {
+		if variant.Size != size {
+			continue
+		}
+		ok = true
+		if variant.Format == imageutil.FormatWebP {
+			if acceptWebP {
+				webp = append(webp, variant)
+			}
+			continue
+		}
+		others = append(others, variant)
+	}
+	return append(webp, others...), ok
+}
+
+// isValidType Check whether the upload type is supported
+func (s *UploadService) isValidType(uploadType UploadType) bool {
+	return uploadType == UploadTypeUserAvatar || uploadType == UploadTypeBabyAvatar
+}
+
+// originalFilename Client-side file name kept on the upload record, trimmed to the column size
+func originalFilename(name string) string {
+	name = filepath.Base(name)
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	return name
+}
+
+// removeUpload 删除上传记录，没有其他记录引用时同时删除存储对象及其缩略图
+func (s *UploadService) removeUpload(ctx context.Context, upload *entity.Upload) error {
+	// 持有存储对象锁，统计引用数与删除对象之间不会写入新的引用
+	return s.withStorageKeyLock(ctx, upload.StorageKey, func() error {
+		if err := s.uploadRepo.Delete(ctx, upload.ID); err != nil {
+			return err
+		}
+
+		refs, err := s.uploadRepo.CountByStorageKey(ctx, upload.StorageKey)
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return nil
+		}
+
+		keys := []string{upload.StorageKey}
+		for _, variant := range s.cfg.Upload.Variants {
+			keys = append(keys, VariantKey(upload.StorageKey, variant.Size, variant.Format))
+		}
+		for _, key := range keys {
+			if err := s.fileStorage.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withStorageKeyLock 持有按内容命名的存储对象的锁执行 fn
+// 多实例间写入新引用与删除最后一个引用不能交错执行
+func (s *UploadService) withStorageKeyLock(ctx context.Context, key string, fn func() error) error {
+	return s.withLock(ctx, "upload_object:"+key, "File is being updated, please retry", fn)
+}
+
+// withQuotaLock 持有用户的存储配额锁执行 fn，未设置配额时直接执行
+// 同一用户的配额校验与上传记录写入串行执行，多实例并发上传时合计不超出配额
+func (s *UploadService) withQuotaLock(ctx context.Context, openID string, fn func() error) error {
+	if s.cfg.Upload.Quota <= 0 {
+		return fn()
+	}
+	return s.withLock(ctx, "upload_quota:"+openID, "Another upload is in progress, please retry", fn)
+}
+
+// withLock 持有分布式锁 name 执行 fn，等待超时返回 Conflict(busyMessage)
+func (s *UploadService) withLock(ctx context.Context, name, busyMessage string, fn func() error) error {
+	owner := strconv.FormatInt(snowflake.Generate(), 10)
+	deadline := time.Now().Add(storageKeyLockWait)
+	for {
+		acquired, err := s.lockRepo.Acquire(ctx, name, owner, storageKeyLockTTL)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.New(errors.Conflict, busyMessage)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(storageKeyLockRetry):
+		}
+	}
+	defer func() {
+		_ = s.lockRepo.Release(context.Background(), name, owner)
+	}()
+
+	return fn()
+}
+
+// checkQuota 校验上传后不超出用户的存储配额
+// 只有在 withQuotaLock 内调用并随后写入上传记录时才能保证不超额，其余调用仅用于提前拒绝
+func (s *UploadService) checkQuota(ctx context.Context, openID string, size int64) error {
+	quota := s.cfg.Upload.Quota
+	if quota <= 0 {
+		return nil
+	}
+
+	used, err := s.uploadRepo.SumSizeByOpenID(ctx, openID)
+	if err != nil {
+		return err
+	}
+	if used+size > quota {
+		return errors.New(errors.ParamError, fmt.Sprintf("Storage quota exceeded: %d of %d bytes used", used, quota))
+	}
+	return nil
+}
+
+// storageKeyOf 解析本存储签发的文件地址对应的存储对象键，其他地址返回 ok 为 false
+func (s *UploadService) storageKeyOf(fileURL string) (key string, ok bool) {
+	if i := strings.IndexAny(fileURL, "?#"); i >= 0 {
+		fileURL = fileURL[:i]
+	}
+
+	prefix := s.fileStorage.URL("")
+	if fileURL == "" || !strings.HasPrefix(fileURL, prefix) {
+		return "", false
+	}
+
+	key, err := url.PathUnescape(strings.TrimPrefix(fileURL, prefix))
+	if err != nil || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// toUploadDTO 将上传记录转换为DTO
+func (s *UploadService) toUploadDTO(upload *entity.Upload) *dto.UploadDTO {
+	return &dto.UploadDTO{
+		ID:          strconv.FormatInt(upload.ID, 10),
+		Type:        upload.Type,
+		RelatedID:   upload.RelatedID,
+		Filename:    upload.Filename,
+		URL:         s.fileStorage.URL(upload.StorageKey),
+		Path:        upload.StorageKey,
+		Size:        upload.Size,
+		SHA256:      upload.SHA256,
+		ContentType: upload.ContentType,
+		Orphaned:    upload.IsOrphaned(),
+		CreatedAt:   upload.CreatedAt,
+	}
+}
+
+// readFile Read uploaded file content
+func (s *UploadService) readFile(fileHeader *multipart.FileHeader) ([]byte, error) {
 	srcFile, err := fileHeader.Open()
 	if err != nil {
-		return errors.Wrap(errors.InternalError, "Failed to open file", err)
+		return nil, errors.Wrap(errors.InternalError, "Failed to open file", err)
 	}
 	defer srcFile.Close()
 
-	dstFile, err := os.Create(filePath)
+	// The declared size comes from the client, limit the actual read as well
+	data, err := io.ReadAll(io.LimitReader(srcFile, s.cfg.Upload.MaxSize+1))
 	if err != nil {
-		return errors.Wrap(errors.InternalError, "Failed to create file", err)
+		return nil, errors.Wrap(errors.InternalError, "Failed to read file", err)
+	}
+	if int64(len(data)) > s.cfg.Upload.MaxSize {
+		return nil, errors.New(errors.ParamError, "File size exceeds limit")
+	}
+	if len(data) == 0 {
+		return nil, errors.New(errors.ParamError, "File cannot be empty")
 	}
-	defer dstFile.Close()
 
-	// Copy file content
-	if _, err := dstFile.ReadFrom(srcFile); err != nil {
-		return errors.Wrap(errors.InternalError, "Failed to save file", err)
+	return data, nil
+}
+
+// isAllowedType Check whether the sniffed content type is allowed
+func (s *UploadService) isAllowedType(contentType string) bool {
+	for _, mimeType := range s.cfg.Upload.AllowedTypes {
+		mimeType = strings.ToLower(mimeType)
+		if mimeType == "image/jpg" {
+			mimeType = imageutil.MIMEJPEG
+		}
+		if mimeType == contentType {
+			return true
+		}
 	}
+	return false
+}
 
-	return nil
+// imageError Convert image processing errors to parameter errors
+func (s *UploadService) imageError(err error) error {
+	switch {
+	case errors.Is(err, imageutil.ErrDimensionsExceeded):
+		return errors.New(errors.ParamError, fmt.Sprintf("Image dimensions exceed limit of %dx%d pixels", s.cfg.Upload.MaxWidth, s.cfg.Upload.MaxHeight))
+	case errors.Is(err, imageutil.ErrFramesExceeded):
+		return errors.New(errors.ParamError, fmt.Sprintf("GIF exceeds limit of %d frames or %d pixels in total", s.cfg.Upload.MaxFrames, s.cfg.Upload.MaxPixels))
+	case errors.Is(err, imageutil.ErrMalformedImage):
+		return errors.New(errors.ParamError, "Image file is corrupted or malformed")
+	case errors.Is(err, imageutil.ErrUnsupportedFormat):
+		return errors.New(errors.ParamError, "Unsupported image format")
+	default:
+		return errors.Wrap(errors.InternalError, "Failed to process image", err)
+	}
+}
+
+// normalizeExt Normalize file extension, .jpeg is treated as .jpg
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext == ".jpeg" {
+		return ".jpg"
+	}
+	return ext
 }
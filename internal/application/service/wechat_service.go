@@ -1,142 +1,192 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
 
 	"github.com/silenceper/wechat/v2/miniprogram/qrcode"
 	"github.com/silenceper/wechat/v2/miniprogram/subscribe"
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/domain/repository"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
 	"github.com/wxlbd/polaris/internal/infrastructure/wechat"
 	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/utils"
 	"go.uber.org/zap"
 )
 
+const (
+	// subscribeAccept wx.requestSubscribeMessage 结果中表示用户同意订阅的值
+	subscribeAccept = "accept"
+
+	defaultQRCodeWidth  = 430 // 微信默认的小程序码宽度(像素)
+	maxSceneLength      = 32  // 不限数量小程序码 scene 的最大长度
+	maxQRCodePathLength = 128 // 有数量限制的小程序码 path 的最大长度
+)
+
 // WechatService 微信服务
 type WechatService struct {
 	wechatClient *wechat.Client
+	fileStorage  repository.FileStorage
+	quotaRepo    repository.SubscribeQuotaRepository
 	config       *config.Config
 	logger       *zap.Logger
 }
 
 // NewWechatService 创建微信服务实例
-func NewWechatService(wechatClient *wechat.Client, cfg *config.Config, logger *zap.Logger) *WechatService {
+func NewWechatService(
+	wechatClient *wechat.Client,
+	fileStorage repository.FileStorage,
+	quotaRepo repository.SubscribeQuotaRepository,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *WechatService {
 	return &WechatService{
 		wechatClient: wechatClient,
+		fileStorage:  fileStorage,
+		quotaRepo:    quotaRepo,
 		config:       cfg,
 		logger:       logger,
 	}
 }
 
 // GenerateQRCode 生成小程序码
-// scene: 场景值，最多32个字符，格式如 "c=ABC123"
-// page: 小程序页面路径，如 "pages/baby/join/join"
+// 按生成参数的哈希命名存储对象，相同参数再次请求时直接返回已存储的图片，不再调用微信接口；
+// key=value 格式的 scene 按键排序规范化，参数顺序不同的相同 scene 视为同一小程序码，其他格式原样使用
 // 返回: 小程序码图片的URL路径
-func (s *WechatService) GenerateQRCode(ctx context.Context, scene, page string) (string, error) {
-	s.logger.Info("🚀 [WechatService.GenerateQRCode] START - 开始生成小程序码",
-		zap.String("scene", scene),
-		zap.String("page", page),
-	)
+func (s *WechatService) GenerateQRCode(ctx context.Context, req *dto.GenerateQRCodeRequest) (*dto.QRCodeDTO, error) {
+	kind := wechat.CodeKind(req.Type)
+	if kind == "" {
+		kind = wechat.CodeUnlimited
+	}
+	scene := utils.NormalizeScene(req.Scene)
 
-	// 验证参数
-	if scene == "" {
-		return "", errors.New(errors.ParamError, "scene参数不能为空")
+	params := qrcode.QRCoder{
+		Width:      req.Width,
+		AutoColor:  req.AutoColor,
+		IsHyaline:  req.IsHyaline,
+		EnvVersion: req.EnvVersion,
 	}
-	if len(scene) > 32 {
-		return "", errors.New(errors.ParamError, fmt.Sprintf("scene参数长度不能超过32个字符，当前长度: %d", len(scene)))
+	if params.Width == 0 {
+		params.Width = defaultQRCodeWidth
 	}
-	if page == "" {
-		return "", errors.New(errors.ParamError, "page参数不能为空")
+	if req.LineColor != nil && !req.AutoColor {
+		params.LineColor = &qrcode.Color{
+			R: strconv.Itoa(req.LineColor.R),
+			G: strconv.Itoa(req.LineColor.G),
+			B: strconv.Itoa(req.LineColor.B),
+		}
 	}
 
-	// 获取小程序二维码实例
-	miniProgram := s.wechatClient.GetMiniProgram()
-	qrcodeService := miniProgram.GetQRCode()
+	result := &dto.QRCodeDTO{Type: string(kind), Scene: scene}
+	switch kind {
+	case wechat.CodeUnlimited:
+		// 参数只能放在 scene 中
+		if scene == "" {
+			return nil, errors.New(errors.ParamError, "scene参数不能为空")
+		}
+		if len(scene) > maxSceneLength {
+			return nil, errors.New(errors.ParamError, fmt.Sprintf("scene参数长度不能超过%d个字符，当前长度: %d", maxSceneLength, len(scene)))
+		}
+		params.Scene = scene
+		params.Page = req.Page
+		// 体验版、开发版的页面可能尚未发布，不校验页面是否存在
+		if req.EnvVersion != "" && req.EnvVersion != "release" {
+			checkPath := false
+			params.CheckPath = &checkPath
+		}
+		result.Path = req.Page
+	default:
+		// 参数以查询字符串形式拼接在 path 中
+		if req.Page == "" {
+			return nil, errors.New(errors.ParamError, "page参数不能为空")
+		}
+		params.Path = req.Page
+		if scene != "" {
+			params.Path += "?" + scene
+		}
+		if len(params.Path) > maxQRCodePathLength {
+			return nil, errors.New(errors.ParamError, fmt.Sprintf("page与scene合计长度不能超过%d个字符", maxQRCodePathLength))
+		}
+		result.Path = params.Path
+	}
 
-	// 构建二维码参数
-	qrcodeParams := qrcode.QRCoder{
-		Scene: scene,
-		Page:  page,
-		Width: 280, // 二维码宽度(像素)
+	key, err := qrcodeKey(kind, params)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "生成小程序码失败", err)
+	}
+	result.URL = s.fileStorage.URL(key)
+
+	// 相同参数的小程序码已生成过
+	if _, err := s.fileStorage.Stat(ctx, key); err == nil {
+		result.Cached = true
+		return result, nil
+	} else if !isNotFound(err) {
+		return nil, err
 	}
 
 	s.logger.Info("📦 [WechatService.GenerateQRCode] 调用微信API生成小程序码",
-		zap.Any("params", qrcodeParams),
+		zap.String("type", string(kind)),
+		zap.Any("params", params),
 	)
 
-	// 调用微信API生成小程序码 (返回二进制流)
-	imageBytes, err := qrcodeService.GetWXACodeUnlimit(qrcodeParams)
+	imageBytes, contentType, err := s.wechatClient.FetchCode(ctx, kind, params)
 	if err != nil {
 		s.logger.Error("❌ [WechatService.GenerateQRCode] 调用微信API失败",
 			zap.Error(err),
+			zap.String("type", string(kind)),
 			zap.String("scene", scene),
-			zap.String("page", page),
+			zap.String("path", result.Path),
 		)
-		return "", errors.Wrap(errors.InternalError, "生成小程序码失败", err)
+		return nil, wechat.MapError(err, "生成小程序码失败")
 	}
 
-	s.logger.Info("✅ [WechatService.GenerateQRCode] 微信API调用成功，图片大小",
-		zap.Int("bytes", len(imageBytes)),
-	)
-
-	// 生成文件名 (使用 scene 作为文件名的一部分)
-	filename := fmt.Sprintf("qrcode_%s.png", scene)
-
-	// 构建存储路径 (使用配置中的 upload.storage_path)
-	storagePath := s.config.Upload.StoragePath
-	if storagePath == "" {
-		storagePath = "uploads/"
-	}
-
-	// 创建二维码子目录
-	qrcodePath := filepath.Join(storagePath, "qrcodes")
-	if err := os.MkdirAll(qrcodePath, 0755); err != nil {
-		s.logger.Error("❌ [WechatService.GenerateQRCode] 创建目录失败",
-			zap.Error(err),
-			zap.String("path", qrcodePath),
-		)
-		return "", errors.Wrap(errors.InternalError, "创建存储目录失败", err)
-	}
-
-	// 完整文件路径
-	filePath := filepath.Join(qrcodePath, filename)
-
-	// 保存图片到文件
-	if err := os.WriteFile(filePath, imageBytes, 0644); err != nil {
+	if err := s.fileStorage.Put(ctx, key, bytes.NewReader(imageBytes), int64(len(imageBytes)), contentType); err != nil {
 		s.logger.Error("❌ [WechatService.GenerateQRCode] 保存图片失败",
 			zap.Error(err),
-			zap.String("filePath", filePath),
+			zap.String("key", key),
 		)
-		return "", errors.Wrap(errors.InternalError, "保存小程序码图片失败", err)
+		return nil, errors.Wrap(errors.InternalError, "保存小程序码图片失败", err)
 	}
 
-	s.logger.Info("✅ [WechatService.GenerateQRCode] 小程序码保存成功",
-		zap.String("filePath", filePath),
+	s.logger.Info("✅ [WechatService.GenerateQRCode] 小程序码生成完成",
+		zap.String("key", key),
+		zap.Int("bytes", len(imageBytes)),
 	)
 
-	// 拼接完整的URL访问地址
-	relativePath := fmt.Sprintf("/uploads/qrcodes/%s", filename)
+	return result, nil
+}
 
-	// 从配置中获取服务器基础URL
-	baseURL := s.config.Server.BaseURL
-	if baseURL == "" {
-		baseURL = fmt.Sprintf("http://localhost:%d", s.config.Server.Port)
+// qrcodeKey 按生成参数计算小程序码的存储对象键
+// 透明底色的小程序码为 PNG，其余为 JPEG
+func qrcodeKey(kind wechat.CodeKind, params qrcode.QRCoder) (string, error) {
+	canonical, err := json.Marshal(struct {
+		Kind   wechat.CodeKind `json:"kind"`
+		Params qrcode.QRCoder  `json:"params"`
+	}{kind, params})
+	if err != nil {
+		return "", err
 	}
 
-	// 返回完整的URL路径
-	imageURL := fmt.Sprintf("%s%s", baseURL, relativePath)
-
-	s.logger.Info("🎉 [WechatService.GenerateQRCode] 小程序码生成完成",
-		zap.String("imageURL", imageURL),
-	)
-
-	return imageURL, nil
+	ext := ".jpg"
+	if params.IsHyaline {
+		ext = ".png"
+	}
+	sum := sha256.Sum256(canonical)
+	return ContentKey("qrcodes", hex.EncodeToString(sum[:]), ext), nil
 }
 
 // SendSubscribeMessage 发送订阅消息
+// 发送前扣减用户在该模板上的订阅额度，额度不足时返回 errors.ErrWechatSubscribeQuota 且不调用微信接口；
+// 发送失败时退还额度，微信返回额度已用完时清空额度
 func (s *WechatService) SendSubscribeMessage(
+	ctx context.Context,
 	openid string,
 	templateID string,
 	data map[string]any,
@@ -151,6 +201,18 @@ func (s *WechatService) SendSubscribeMessage(
 		zap.Any("data", data),
 	)
 
+	consumed, err := s.quotaRepo.Consume(ctx, openid, templateID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		s.logger.Warn("⚠️ [WechatService.SendSubscribeMessage] 订阅额度不足，不发送",
+			zap.String("openid", openid),
+			zap.String("templateID", templateID),
+		)
+		return errors.ErrWechatSubscribeQuota
+	}
+
 	// 获取小程序订阅消息实例
 	miniProgram := s.wechatClient.GetMiniProgram()
 	subscribeService := miniProgram.GetSubscribe()
@@ -178,13 +240,14 @@ func (s *WechatService) SendSubscribeMessage(
 	)
 
 	// 发送订阅消息
-	err := subscribeService.Send(msg)
-	if err != nil {
+	if err := subscribeService.Send(msg); err != nil {
 		s.logger.Error("❌ [WechatService.SendSubscribeMessage] 发送失败",
 			zap.Error(err),
 			zap.String("openid", openid),
 			zap.String("templateID", templateID),
 		)
+		err = wechat.MapError(err, "发送订阅消息失败")
+		s.restoreQuota(ctx, openid, templateID, err)
 		return err
 	}
 
@@ -195,3 +258,73 @@ func (s *WechatService) SendSubscribeMessage(
 
 	return nil
 }
+
+// restoreQuota 发送失败后恢复额度：微信确认额度已用完时清空，其余情况退还本次扣减
+func (s *WechatService) restoreQuota(ctx context.Context, openid, templateID string, sendErr error) {
+	var err error
+	if errors.CodeOf(sendErr) == errors.WechatSubscribeQuota {
+		err = s.quotaRepo.Clear(ctx, openid, templateID)
+	} else {
+		err = s.quotaRepo.Refund(ctx, openid, templateID)
+	}
+	if err != nil {
+		s.logger.Error("❌ [WechatService.SendSubscribeMessage] 恢复订阅额度失败",
+			zap.Error(err),
+			zap.String("openid", openid),
+			zap.String("templateID", templateID),
+		)
+	}
+}
+
+// RecordSubscriptions 记录用户在 wx.requestSubscribeMessage 中同意订阅的模板，每个模板额度加一
+// 仅统计已配置的订阅消息模板，拒绝、被封禁或未配置的模板忽略
+func (s *WechatService) RecordSubscriptions(ctx context.Context, openID string, req *dto.RecordSubscriptionsRequest) ([]*dto.SubscribeQuotaDTO, error) {
+	configured := s.subscribeTemplateTypes()
+
+	accepted := make([]string, 0, len(req.Results))
+	for templateID, result := range req.Results {
+		if result == subscribeAccept && configured[templateID] != "" {
+			accepted = append(accepted, templateID)
+		}
+	}
+
+	if err := s.quotaRepo.Grant(ctx, openID, accepted); err != nil {
+		return nil, err
+	}
+
+	return s.GetSubscriptionQuotas(ctx, openID)
+}
+
+// GetSubscriptionQuotas 查询用户在各已配置模板上的剩余订阅额度
+func (s *WechatService) GetSubscriptionQuotas(ctx context.Context, openID string) ([]*dto.SubscribeQuotaDTO, error) {
+	quotas, err := s.quotaRepo.FindByOpenID(ctx, openID)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]string, 0, len(s.config.Wechat.SubscribeTemplates))
+	for templateType := range s.config.Wechat.SubscribeTemplates {
+		types = append(types, templateType)
+	}
+	sort.Strings(types)
+
+	records := make([]*dto.SubscribeQuotaDTO, 0, len(types))
+	for _, templateType := range types {
+		templateID := s.config.Wechat.SubscribeTemplates[templateType]
+		records = append(records, &dto.SubscribeQuotaDTO{
+			TemplateType: templateType,
+			TemplateID:   templateID,
+			Remaining:    quotas[templateID],
+		})
+	}
+	return records, nil
+}
+
+// subscribeTemplateTypes 已配置的订阅消息模板: templateID -> templateType
+func (s *WechatService) subscribeTemplateTypes() map[string]string {
+	types := make(map[string]string, len(s.config.Wechat.SubscribeTemplates))
+	for templateType, templateID := range s.config.Wechat.SubscribeTemplates {
+		types[templateID] = templateType
+	}
+	return types
+}
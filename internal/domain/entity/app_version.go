@@ -1,23 +1,96 @@
 package entity
 
-import "time"
+import (
+	"hash/fnv"
+	"time"
+)
+
+// 发布渠道
+const (
+	ChannelStable   = "stable"   // 正式渠道
+	ChannelBeta     = "beta"     // 公测渠道
+	ChannelInternal = "internal" // 内部渠道
+)
+
+// 灰度发布状态
+const (
+	RolloutStatusDraft      = "draft"       // 草稿，未发布
+	RolloutStatusActive     = "active"      // 发布中，按比例放量
+	RolloutStatusPaused     = "paused"      // 已暂停，不再向任何用户推送
+	RolloutStatusRolledBack = "rolled_back" // 已回滚，不可恢复
+)
 
 // AppVersion 应用版本实体
+// IsActive 不设置 gorm 默认值，否则创建时 false 会被当作零值忽略而落成数据库默认值 true；
+// RolloutPercentage 的数据库默认值为 0，存量版本在启动时按 IsActive 回填为全量发布或草稿
 type AppVersion struct {
-	ID           int64     `gorm:"primaryKey;column:id" json:"id"`
-	Version      string    `gorm:"column:version;type:varchar(20);uniqueIndex;not null" json:"version"`                   // 版本号
-	Name         string    `gorm:"column:name;type:varchar(100);not null;default:'宝宝喂养时刻'" json:"name"`              // 应用名称
-	Description  string    `gorm:"column:description;type:text" json:"description"`                                       // 版本描述
-	MinVersion   string    `gorm:"column:min_version;type:varchar(20)" json:"minVersion"`                                 // 最小支持版本
-	IsActive     bool      `gorm:"column:is_active;type:boolean;not null;default:true;index" json:"isActive"`            // 是否为活跃版本
-	ForceUpdate  bool      `gorm:"column:force_update;type:boolean;not null;default:false" json:"forceUpdate"`           // 是否强制更新
-	ReleaseNotes string    `gorm:"column:release_notes;type:text" json:"releaseNotes"`                                    // 发布说明
-	BuildTime    time.Time `gorm:"column:build_time;type:timestamp;default:CURRENT_TIMESTAMP" json:"buildTime"`          // 构建时间
-	CreatedAt    time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`          // 创建时间
-	UpdatedAt    time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`          // 更新时间
+	ID                int64     `gorm:"primaryKey;column:id" json:"id"`
+	Version           string    `gorm:"column:version;type:varchar(20);uniqueIndex;not null" json:"version"`                                                         // 版本号
+	Name              string    `gorm:"column:name;type:varchar(100);not null;default:'宝宝喂养时刻'" json:"name"`                                                         // 应用名称
+	Description       string    `gorm:"column:description;type:text" json:"description"`                                                                             // 版本描述
+	MinVersion        string    `gorm:"column:min_version;type:varchar(20)" json:"minVersion"`                                                                       // 最小支持版本
+	IsActive          bool      `gorm:"column:is_active;type:boolean;not null;index" json:"isActive"`                                                                // 是否为所在渠道/平台的全量版本
+	ForceUpdate       bool      `gorm:"column:force_update;type:boolean;not null;default:false" json:"forceUpdate"`                                                  // 是否强制更新
+	ReleaseNotes      string    `gorm:"column:release_notes;type:text" json:"releaseNotes"`                                                                          // 发布说明
+	Channel           string    `gorm:"column:channel;type:varchar(20);not null;default:'stable';index:idx_app_versions_channel_platform,priority:1" json:"channel"` // 发布渠道
+	Platform          string    `gorm:"column:platform;type:varchar(20);not null;default:'';index:idx_app_versions_channel_platform,priority:2" json:"platform"`     // 目标平台，空表示全平台
+	RolloutPercentage int       `gorm:"column:rollout_percentage;type:int;not null;default:0" json:"rolloutPercentage"`                                              // 灰度比例(0-100)
+	RolloutStatus     string    `gorm:"column:rollout_status;type:varchar(20);not null;default:'active'" json:"rolloutStatus"`                                       // 灰度发布状态
+	BuildTime         time.Time `gorm:"column:build_time;type:timestamp;default:CURRENT_TIMESTAMP" json:"buildTime"`                                                 // 构建时间
+	CreatedAt         time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`                                                 // 创建时间
+	UpdatedAt         time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`                                                 // 更新时间
 }
 
 // TableName 指定表名
 func (AppVersion) TableName() string {
 	return "app_versions"
 }
+
+// IsReleased 是否已对外发布(含暂停)
+func (v *AppVersion) IsReleased() bool {
+	return v.RolloutStatus == RolloutStatusActive || v.RolloutStatus == RolloutStatusPaused
+}
+
+// MatchesPlatform 是否面向指定平台发布
+func (v *AppVersion) MatchesPlatform(platform string) bool {
+	return v.Platform == "" || v.Platform == platform
+}
+
+// InRollout 指定用户是否在本版本的放量范围内
+// 匿名用户(userKey 为空)只能获取全量版本
+func (v *AppVersion) InRollout(userKey string) bool {
+	if v.RolloutStatus != RolloutStatusActive {
+		return false
+	}
+	if v.RolloutPercentage >= 100 {
+		return true
+	}
+	if userKey == "" {
+		return false
+	}
+	return RolloutBucket(v.Version, userKey) < v.RolloutPercentage
+}
+
+// RolloutBucket 计算用户在指定版本下的灰度分桶(0-99)
+// 分桶只由版本号和用户标识决定，放量比例增大时已命中的用户始终保持命中；
+// 以版本号加盐，避免每个版本都由同一批用户先行试用
+func RolloutBucket(version, userKey string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(version))
+	_, _ = h.Write([]byte{':'})
+	_, _ = h.Write([]byte(userKey))
+	return int(h.Sum32() % 100)
+}
+
+// VisibleChannels 返回指定渠道用户可接收的版本渠道
+// 公测用户同时接收正式版本，内部用户接收全部渠道
+func VisibleChannels(channel string) []string {
+	switch channel {
+	case ChannelInternal:
+		return []string{ChannelStable, ChannelBeta, ChannelInternal}
+	case ChannelBeta:
+		return []string{ChannelStable, ChannelBeta}
+	default:
+		return []string{ChannelStable}
+	}
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord 幂等请求记录
+// 携带 Idempotency-Key 的写请求首次处理时创建(处理中)，处理完成后保存响应，
+// 之后相同用户、相同幂等键且请求指纹一致的请求直接重放该响应
+type IdempotencyRecord struct {
+	Key         string      // 存储键，由用户与幂等键派生
+	Fingerprint string      // 请求指纹(方法、路径、查询参数与请求体的哈希)
+	Owner       string      // 处理中请求的持有者标识，仅持有者可以保存响应或释放记录
+	StatusCode  int         // 响应状态码，0 表示仍在处理中
+	Header      http.Header // 响应头
+	Body        []byte      // 响应体
+	Truncated   bool        // 响应体超出保存上限，只保存了状态码；重复请求既不重放也不重新处理
+	CreatedAt   time.Time   // 首次请求时间
+}
+
+// IsCompleted 是否已保存响应
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
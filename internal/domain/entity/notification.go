@@ -0,0 +1,39 @@
+package entity
+
+// 通知投递状态
+const (
+	NotificationStatusPending = "pending" // 等待投递(含等待重试)
+	NotificationStatusSending = "sending" // 已被投递任务领取
+	NotificationStatusSent    = "sent"    // 投递成功
+	NotificationStatusFailed  = "failed"  // 超过最大重试次数，放弃投递
+)
+
+// Notification 通知发件箱记录
+// 通知先落库再由后台任务投递，投递失败按指数退避重试
+type Notification struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`                                                     // 雪花ID主键
+	UserID        int64  `gorm:"column:user_id;default:0;index" json:"userId,string"`                                                           // 接收用户ID，未知时为 0
+	Channel       string `gorm:"column:channel;type:varchar(16);not null" json:"channel"`                                                       // 通知渠道: sms/email/push/wechat
+	Recipient     string `gorm:"column:recipient;type:varchar(255);not null;index" json:"recipient"`                                            // 接收方: 手机号/邮箱/OpenID/用户ID
+	TemplateID    string `gorm:"column:template_id;type:varchar(128)" json:"templateId"`                                                        // 消息模板ID(微信订阅消息)
+	Title         string `gorm:"column:title;type:varchar(255)" json:"title"`                                                                   // 标题
+	Content       string `gorm:"column:content;type:text" json:"content"`                                                                       // 内容
+	Payload       string `gorm:"column:payload;type:text" json:"payload"`                                                                       // 额外数据(JSON)
+	Status        string `gorm:"column:status;type:varchar(16);not null;index:idx_notifications_status_next,priority:1" json:"status"`          // 投递状态
+	Attempts      int    `gorm:"column:attempts;not null;default:0" json:"attempts"`                                                            // 已尝试投递次数
+	NextAttemptAt int64  `gorm:"column:next_attempt_at;not null;default:0;index:idx_notifications_status_next,priority:2" json:"nextAttemptAt"` // 下次可投递时间(毫秒时间戳)
+	LastError     string `gorm:"column:last_error;type:text" json:"lastError"`                                                                  // 最近一次投递错误
+	SentAt        int64  `gorm:"column:sent_at;not null;default:0" json:"sentAt"`                                                               // 投递成功时间(毫秒时间戳)
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                                             // 创建时间(毫秒时间戳)
+	UpdatedAt     int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`                                             // 更新时间(毫秒时间戳)
+}
+
+// IsFinished 是否已结束投递(成功或放弃)
+func (n *Notification) IsFinished() bool {
+	return n.Status == NotificationStatusSent || n.Status == NotificationStatusFailed
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotificationPreference 用户通知偏好
+// 包含免打扰时段及按通知类型、渠道的退订设置；用户未设置时使用默认值(全部接收、不免打扰)
+type NotificationPreference struct {
+	UserID          int64                        `gorm:"primaryKey;autoIncrement:false;column:user_id" json:"userId,string"` // 用户ID
+	Timezone        string                       `gorm:"column:timezone;type:varchar(64)" json:"timezone"`                   // IANA 时区，如 Asia/Shanghai，为空时使用系统默认时区
+	QuietHoursStart string                       `gorm:"column:quiet_hours_start;type:varchar(5)" json:"quietHoursStart"`    // 免打扰开始时间 HH:MM，为空表示不启用
+	QuietHoursEnd   string                       `gorm:"column:quiet_hours_end;type:varchar(5)" json:"quietHoursEnd"`        // 免打扰结束时间 HH:MM，早于开始时间表示跨越午夜
+	Channels        []NotificationChannelSetting `gorm:"foreignKey:UserID;references:UserID" json:"channels"`                // 渠道退订设置
+	CreatedAt       int64                        `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`  // 创建时间(毫秒时间戳)
+	UpdatedAt       int64                        `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`  // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// NotificationChannelSetting 用户对某类通知在某个渠道上的接收设置
+type NotificationChannelSetting struct {
+	UserID  int64  `gorm:"primaryKey;autoIncrement:false;column:user_id" json:"-"`    // 用户ID
+	Type    string `gorm:"primaryKey;column:type;type:varchar(32)" json:"type"`       // 通知类型
+	Channel string `gorm:"primaryKey;column:channel;type:varchar(16)" json:"channel"` // 通知渠道
+	Enabled bool   `gorm:"column:enabled;not null" json:"enabled"`                    // 是否接收
+}
+
+// TableName 指定表名
+func (NotificationChannelSetting) TableName() string {
+	return "notification_channel_settings"
+}
+
+// IsChannelEnabled 用户是否接收指定类型在指定渠道上的通知，未设置时默认接收
+func (p *NotificationPreference) IsChannelEnabled(notificationType, channel string) bool {
+	for _, setting := range p.Channels {
+		if setting.Type == notificationType && setting.Channel == channel {
+			return setting.Enabled
+		}
+	}
+	return true
+}
+
+// HasQuietHours 是否启用了免打扰时段
+func (p *NotificationPreference) HasQuietHours() bool {
+	return p.QuietHoursStart != "" && p.QuietHoursEnd != "" && p.QuietHoursStart != p.QuietHoursEnd
+}
+
+// InQuietHours 判断某一时刻是否处于用户的免打扰时段
+// 按用户时区换算为当地时间；时区为空或无效时使用 defaultLoc
+func (p *NotificationPreference) InQuietHours(t time.Time, defaultLoc *time.Location) bool {
+	if !p.HasQuietHours() {
+		return false
+	}
+	start, err := ParseClock(p.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(p.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	loc := defaultLoc
+	if p.Timezone != "" {
+		if l, err := time.LoadLocation(p.Timezone); err == nil {
+			loc = l
+		}
+	}
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+
+	// 结束时间早于开始时间表示跨越午夜，如 22:00-07:00
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// ParseClock 解析 HH:MM 格式的时刻，返回当天的分钟数
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("invalid clock %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
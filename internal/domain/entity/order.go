@@ -0,0 +1,34 @@
+package entity
+
+// 订单状态
+const (
+	OrderStatusPending  = "pending"  // 待支付
+	OrderStatusPaid     = "paid"     // 已支付(含部分退款)
+	OrderStatusRefunded = "refunded" // 已全额退款
+)
+
+// Order 订单
+// 订单记录购买的内容与应付金额，支付过程由关联的支付单记录；支付单状态变更时同步订单状态
+type Order struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`            // 雪花ID主键
+	OrderNo   string `gorm:"column:order_no;type:varchar(32);uniqueIndex;not null" json:"orderNo"` // 订单号
+	UserID    int64  `gorm:"column:user_id;not null;index" json:"userId,string"`                   // 下单用户ID
+	Subject   string `gorm:"column:subject;type:varchar(127);not null" json:"subject"`             // 商品描述
+	Amount    int64  `gorm:"column:amount;not null" json:"amount"`                                 // 应付金额(分)
+	Currency  string `gorm:"column:currency;type:varchar(3);not null" json:"currency"`             // 币种
+	Status    string `gorm:"column:status;type:varchar(16);not null" json:"status"`                // 订单状态
+	PaidAt    int64  `gorm:"column:paid_at;not null;default:0" json:"paidAt"`                      // 支付时间(毫秒时间戳)
+	Version   int64  `gorm:"column:version;not null;default:0" json:"version"`                     // 乐观锁版本号
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`    // 创建时间(毫秒时间戳)
+	UpdatedAt int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`    // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (Order) TableName() string {
+	return "orders"
+}
+
+// IsPayable 订单是否可以发起支付
+func (o *Order) IsPayable() bool {
+	return o.Status == OrderStatusPending
+}
@@ -0,0 +1,246 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+)
+
+// PaymentStatus 支付状态
+type PaymentStatus string
+
+const (
+	PaymentStatusPending           PaymentStatus = "pending"            // 待支付
+	PaymentStatusPaid              PaymentStatus = "paid"               // 已支付
+	PaymentStatusFailed            PaymentStatus = "failed"             // 支付失败
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded" // 部分退款
+	PaymentStatusRefunded          PaymentStatus = "refunded"           // 已全额退款
+	PaymentStatusCancelled         PaymentStatus = "cancelled"          // 已取消
+)
+
+// paymentTransitions 支付状态机，列出每个状态允许变更到的状态
+// pending → paid → partially_refunded → refunded，pending → cancelled/failed；其余状态为终态
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusPending:           {PaymentStatusPaid, PaymentStatusCancelled, PaymentStatusFailed},
+	PaymentStatusPaid:              {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+	PaymentStatusPartiallyRefunded: {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+}
+
+// CanTransitionTo 是否允许从当前状态变更到 to
+func (s PaymentStatus) CanTransitionTo(to PaymentStatus) bool {
+	for _, next := range paymentTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal 是否为终态
+func (s PaymentStatus) IsFinal() bool {
+	return len(paymentTransitions[s]) == 0
+}
+
+// IllegalTransitionError 非法的支付状态变更
+type IllegalTransitionError struct {
+	From PaymentStatus
+	To   PaymentStatus
+}
+
+// Error 实现error接口
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("illegal payment transition from %s to %s", e.From, e.To)
+}
+
+// Payment 支付单
+// 每次向支付渠道下单生成一条支付单，PaymentNo 作为渠道的商户订单号；
+// 状态只能按状态机变更，更新时按 Version 做乐观锁，每次变更追加一条支付事件；
+// 退款登记时占用 RefundingAmount，成功后转入 RefundedAmount，两者之和不超过支付金额
+type Payment struct {
+	ID              int64         `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`                                         // 雪花ID主键
+	PaymentNo       string        `gorm:"column:payment_no;type:varchar(32);uniqueIndex;not null" json:"paymentNo"`                          // 商户支付单号，即渠道的商户订单号
+	OrderID         int64         `gorm:"column:order_id;not null;index" json:"orderId,string"`                                              // 订单ID
+	UserID          int64         `gorm:"column:user_id;not null;index" json:"userId,string"`                                                // 付款用户ID
+	Method          string        `gorm:"column:method;type:varchar(16);not null" json:"method"`                                             // 支付方式
+	Amount          int64         `gorm:"column:amount;not null" json:"amount"`                                                              // 支付金额(分)
+	Currency        string        `gorm:"column:currency;type:varchar(3);not null" json:"currency"`                                          // 币种
+	Status          PaymentStatus `gorm:"column:status;type:varchar(20);not null;index:idx_payments_status_expire,priority:1" json:"status"` // 支付状态
+	TransactionID   string        `gorm:"column:transaction_id;type:varchar(64)" json:"transactionId"`                                       // 渠道交易号，支付成功后回填
+	ExpireAt        int64         `gorm:"column:expire_at;not null;default:0;index:idx_payments_status_expire,priority:2" json:"expireAt"`   // 支付过期时间(毫秒时间戳)
+	PaidAt          int64         `gorm:"column:paid_at;not null;default:0;index" json:"paidAt"`                                             // 支付成功时间(毫秒时间戳)，取支付渠道返回的时间
+	RefundedAmount  int64         `gorm:"column:refunded_amount;not null;default:0" json:"refundedAmount"`                                   // 累计已退款金额(分)
+	RefundingAmount int64         `gorm:"column:refunding_amount;not null;default:0" json:"refundingAmount"`                                 // 退款处理中占用的金额(分)
+	Version         int64         `gorm:"column:version;not null;default:0" json:"version"`                                                  // 乐观锁版本号
+	CreatedAt       int64         `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                                 // 创建时间(毫秒时间戳)
+	UpdatedAt       int64         `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`                                 // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// Money 支付金额
+func (p *Payment) Money() valueobject.Money {
+	money, _ := valueobject.NewMoney(p.Amount, valueobject.Currency(p.Currency))
+	return money
+}
+
+// RefundedMoney 累计已退款金额
+func (p *Payment) RefundedMoney() valueobject.Money {
+	money, _ := valueobject.NewMoney(p.RefundedAmount, valueobject.Currency(p.Currency))
+	return money
+}
+
+// RefundableMoney 剩余可退款金额，即支付金额减去已退款与退款处理中的金额
+func (p *Payment) RefundableMoney() valueobject.Money {
+	refunding, _ := valueobject.NewMoney(p.RefundingAmount, valueobject.Currency(p.Currency))
+	refundable, err := p.Money().Subtract(p.RefundedMoney())
+	if err == nil {
+		refundable, err = refundable.Subtract(refunding)
+	}
+	if err != nil {
+		refundable, _ = valueobject.NewMoney(0, valueobject.Currency(p.Currency))
+	}
+	return refundable
+}
+
+// IsRefundable 当前状态是否允许发起退款
+func (p *Payment) IsRefundable() bool {
+	return p.Status == PaymentStatusPaid || p.Status == PaymentStatusPartiallyRefunded
+}
+
+// ReserveRefund 为一笔退款占用退款额度，返回记录退款申请的支付事件
+// 支付状态不允许退款或金额超出剩余可退款金额时返回错误
+func (p *Payment) ReserveRefund(refund *Refund, now time.Time) (*PaymentEvent, error) {
+	if !p.IsRefundable() {
+		return nil, &IllegalTransitionError{From: p.Status, To: PaymentStatusPartiallyRefunded}
+	}
+	amount := refund.Money()
+	if amount.IsZero() || amount.Currency() != valueobject.Currency(p.Currency) {
+		return nil, fmt.Errorf("invalid refund amount %s", amount)
+	}
+	if amount.GreaterThan(p.RefundableMoney()) {
+		return nil, fmt.Errorf("refund amount %s exceeds refundable amount %s", amount, p.RefundableMoney())
+	}
+
+	p.RefundingAmount += amount.Amount()
+	p.UpdatedAt = now.UnixMilli()
+	return p.refundEvent(PaymentEventRefundRequested, refund, PaymentEventSourceAPI, now), nil
+}
+
+// ReleaseRefund 退款失败时释放占用的退款额度，返回记录退款失败的支付事件
+func (p *Payment) ReleaseRefund(refund *Refund, source string, now time.Time) *PaymentEvent {
+	p.RefundingAmount = max(p.RefundingAmount-refund.Amount, 0)
+	p.UpdatedAt = now.UnixMilli()
+	return p.refundEvent(PaymentEventRefundFailed, refund, source, now)
+}
+
+// ApplyRefund 退款成功时将占用的额度计入已退款金额，并变更为部分退款或已全额退款
+func (p *Payment) ApplyRefund(refund *Refund, source string, now time.Time) (*PaymentEvent, error) {
+	to := PaymentStatusPartiallyRefunded
+	if p.RefundedAmount+refund.Amount >= p.Amount {
+		to = PaymentStatusRefunded
+	}
+	detail := fmt.Sprintf("refund_no=%s amount=%d", refund.RefundNo, refund.Amount)
+	event, err := p.TransitionTo(to, source, detail, now)
+	if err != nil {
+		return nil, err
+	}
+
+	p.RefundingAmount = max(p.RefundingAmount-refund.Amount, 0)
+	p.RefundedAmount += refund.Amount
+	return event, nil
+}
+
+// refundEvent 生成不变更支付状态的退款事件
+func (p *Payment) refundEvent(eventType string, refund *Refund, source string, now time.Time) *PaymentEvent {
+	return &PaymentEvent{
+		PaymentID:  p.ID,
+		Type:       eventType,
+		FromStatus: p.Status,
+		ToStatus:   p.Status,
+		Source:     source,
+		Detail:     fmt.Sprintf("refund_no=%s amount=%d", refund.RefundNo, refund.Amount),
+		CreatedAt:  now.UnixMilli(),
+	}
+}
+
+// IsExpired 待支付的支付单是否已过期
+func (p *Payment) IsExpired(now time.Time) bool {
+	return p.Status == PaymentStatusPending && p.ExpireAt > 0 && p.ExpireAt <= now.UnixMilli()
+}
+
+// TransitionTo 按状态机变更支付状态，返回记录本次变更的支付事件
+// 非法变更返回 *IllegalTransitionError；变更到已支付且未记录支付时间(渠道未返回支付成功时间)时记为 now
+func (p *Payment) TransitionTo(to PaymentStatus, source, detail string, now time.Time) (*PaymentEvent, error) {
+	from := p.Status
+	if !from.CanTransitionTo(to) {
+		return nil, &IllegalTransitionError{From: from, To: to}
+	}
+
+	p.Status = to
+	p.UpdatedAt = now.UnixMilli()
+	if to == PaymentStatusPaid && p.PaidAt == 0 {
+		p.PaidAt = now.UnixMilli()
+	}
+
+	return &PaymentEvent{
+		PaymentID:  p.ID,
+		Type:       PaymentEventStatusChanged,
+		FromStatus: from,
+		ToStatus:   to,
+		Source:     source,
+		Detail:     detail,
+		CreatedAt:  now.UnixMilli(),
+	}, nil
+}
+
+// OrderStatus 支付单处于当前状态时订单应处的状态，不影响订单状态时返回空
+func (p *Payment) OrderStatus() string {
+	switch p.Status {
+	case PaymentStatusPaid, PaymentStatusPartiallyRefunded:
+		return OrderStatusPaid
+	case PaymentStatusRefunded:
+		return OrderStatusRefunded
+	default:
+		// 取消或失败的支付单不影响订单，订单可重新发起支付
+		return ""
+	}
+}
+
+// 支付事件类型
+const (
+	PaymentEventCreated         = "created"          // 创建支付单
+	PaymentEventStatusChanged   = "status_changed"   // 支付状态变更
+	PaymentEventRefundRequested = "refund_requested" // 申请退款，占用退款额度
+	PaymentEventRefundFailed    = "refund_failed"    // 退款失败，释放退款额度
+	PaymentEventPaidAfterClose  = "paid_after_close" // 支付单已取消或失败后收到支付成功通知，需退款或人工处理
+)
+
+// 支付事件来源
+const (
+	PaymentEventSourceAPI    = "api"    // 业务调用
+	PaymentEventSourceNotify = "notify" // 支付渠道回调通知
+	PaymentEventSourceQuery  = "query"  // 主动查询支付渠道
+	PaymentEventSourceJob    = "job"    // 后台任务
+)
+
+// PaymentEvent 支付事件
+// 只追加不修改，记录支付单的创建与每次状态变更，用于审计与对账排查
+type PaymentEvent struct {
+	ID         int64         `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`         // 雪花ID主键
+	PaymentID  int64         `gorm:"column:payment_id;not null;index" json:"paymentId,string"`          // 支付单ID
+	Type       string        `gorm:"column:type;type:varchar(32);not null" json:"type"`                 // 事件类型
+	FromStatus PaymentStatus `gorm:"column:from_status;type:varchar(20)" json:"fromStatus"`             // 变更前状态
+	ToStatus   PaymentStatus `gorm:"column:to_status;type:varchar(20)" json:"toStatus"`                 // 变更后状态
+	Source     string        `gorm:"column:source;type:varchar(16);not null" json:"source"`             // 事件来源
+	Detail     string        `gorm:"column:detail;type:text" json:"detail"`                             // 事件详情，如渠道通知ID、交易号
+	CreatedAt  int64         `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"` // 创建时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (PaymentEvent) TableName() string {
+	return "payment_events"
+}
@@ -0,0 +1,242 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+)
+
+var allPaymentStatuses = []PaymentStatus{
+	PaymentStatusPending,
+	PaymentStatusPaid,
+	PaymentStatusFailed,
+	PaymentStatusPartiallyRefunded,
+	PaymentStatusRefunded,
+	PaymentStatusCancelled,
+}
+
+func TestPaymentTransitionTo(t *testing.T) {
+	allowed := map[[2]PaymentStatus]bool{
+		{PaymentStatusPending, PaymentStatusPaid}:                        true,
+		{PaymentStatusPending, PaymentStatusCancelled}:                   true,
+		{PaymentStatusPending, PaymentStatusFailed}:                      true,
+		{PaymentStatusPaid, PaymentStatusPartiallyRefunded}:              true,
+		{PaymentStatusPaid, PaymentStatusRefunded}:                       true,
+		{PaymentStatusPartiallyRefunded, PaymentStatusPartiallyRefunded}: true,
+		{PaymentStatusPartiallyRefunded, PaymentStatusRefunded}:          true,
+	}
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	for _, from := range allPaymentStatuses {
+		for _, to := range allPaymentStatuses {
+			want := allowed[[2]PaymentStatus{from, to}]
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				if got := from.CanTransitionTo(to); got != want {
+					t.Fatalf("CanTransitionTo() = %v, want %v", got, want)
+				}
+
+				payment := &Payment{ID: 1, Status: from, Version: 3}
+				event, err := payment.TransitionTo(to, PaymentEventSourceAPI, "detail", now)
+				if !want {
+					var illegal *IllegalTransitionError
+					if !errors.As(err, &illegal) || illegal.From != from || illegal.To != to {
+						t.Fatalf("TransitionTo() error = %v, want IllegalTransitionError", err)
+					}
+					if event != nil || payment.Status != from || payment.UpdatedAt != 0 {
+						t.Errorf("rejected transition changed the payment: %+v, event %+v", payment, event)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("TransitionTo() error = %v", err)
+				}
+				if payment.Status != to || payment.UpdatedAt != now.UnixMilli() {
+					t.Errorf("payment = %+v, want status %s updated at %d", payment, to, now.UnixMilli())
+				}
+				// 版本号由仓储保存成功后递增
+				if payment.Version != 3 {
+					t.Errorf("Version = %d, want unchanged 3", payment.Version)
+				}
+				if event.Type != PaymentEventStatusChanged || event.PaymentID != 1 || event.FromStatus != from ||
+					event.ToStatus != to || event.Source != PaymentEventSourceAPI || event.Detail != "detail" {
+					t.Errorf("event = %+v", event)
+				}
+			})
+		}
+	}
+}
+
+func TestPaymentStatusIsFinal(t *testing.T) {
+	final := map[PaymentStatus]bool{
+		PaymentStatusFailed:    true,
+		PaymentStatusRefunded:  true,
+		PaymentStatusCancelled: true,
+	}
+	for _, status := range allPaymentStatuses {
+		if got := status.IsFinal(); got != final[status] {
+			t.Errorf("%s.IsFinal() = %v, want %v", status, got, final[status])
+		}
+	}
+}
+
+func TestPaymentTransitionToPaidAt(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	payment := &Payment{Status: PaymentStatusPending}
+	if _, err := payment.TransitionTo(PaymentStatusPaid, PaymentEventSourceNotify, "", now); err != nil {
+		t.Fatalf("TransitionTo() error = %v", err)
+	}
+	if payment.PaidAt != now.UnixMilli() {
+		t.Errorf("PaidAt = %d, want processing time %d", payment.PaidAt, now.UnixMilli())
+	}
+
+	// 已记录渠道的支付成功时间时保留
+	succeededAt := now.Add(-time.Minute).UnixMilli()
+	payment = &Payment{Status: PaymentStatusPending, PaidAt: succeededAt}
+	if _, err := payment.TransitionTo(PaymentStatusPaid, PaymentEventSourceNotify, "", now); err != nil {
+		t.Fatalf("TransitionTo() error = %v", err)
+	}
+	if payment.PaidAt != succeededAt {
+		t.Errorf("PaidAt = %d, want channel time %d", payment.PaidAt, succeededAt)
+	}
+}
+
+func TestPaymentOrderStatus(t *testing.T) {
+	tests := []struct {
+		status PaymentStatus
+		want   string
+	}{
+		{PaymentStatusPending, ""},
+		{PaymentStatusPaid, OrderStatusPaid},
+		{PaymentStatusPartiallyRefunded, OrderStatusPaid},
+		{PaymentStatusRefunded, OrderStatusRefunded},
+		{PaymentStatusCancelled, ""},
+		{PaymentStatusFailed, ""},
+	}
+	for _, tt := range tests {
+		payment := &Payment{Status: tt.status}
+		if got := payment.OrderStatus(); got != tt.want {
+			t.Errorf("OrderStatus() for %s = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentIsExpired(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		payment Payment
+		want    bool
+	}{
+		{"pending past expiry", Payment{Status: PaymentStatusPending, ExpireAt: now.UnixMilli()}, true},
+		{"pending before expiry", Payment{Status: PaymentStatusPending, ExpireAt: now.UnixMilli() + 1}, false},
+		{"pending without expiry", Payment{Status: PaymentStatusPending}, false},
+		{"paid past expiry", Payment{Status: PaymentStatusPaid, ExpireAt: now.UnixMilli() - 1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.payment.IsExpired(now); got != tt.want {
+			t.Errorf("%s: IsExpired() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newPaidPayment(amount int64) *Payment {
+	return &Payment{ID: 1, Amount: amount, Currency: string(valueobject.CurrencyCNY), Status: PaymentStatusPaid}
+}
+
+func newRefund(refundNo string, amount int64) *Refund {
+	return &Refund{RefundNo: refundNo, PaymentID: 1, Amount: amount, Currency: string(valueobject.CurrencyCNY)}
+}
+
+func TestPaymentReserveRefund(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		payment   *Payment
+		refund    *Refund
+		wantError bool
+	}{
+		{"full amount", newPaidPayment(100), newRefund("R1", 100), false},
+		{"over amount", newPaidPayment(100), newRefund("R1", 101), true},
+		{"zero amount", newPaidPayment(100), newRefund("R1", 0), true},
+		{"currency mismatch", newPaidPayment(100), &Refund{RefundNo: "R1", Amount: 10, Currency: string(valueobject.CurrencyUSD)}, true},
+		{"refunded and refunding count", &Payment{Amount: 100, Currency: "CNY", Status: PaymentStatusPartiallyRefunded, RefundedAmount: 30, RefundingAmount: 40}, newRefund("R1", 31), true},
+		{"remaining amount", &Payment{Amount: 100, Currency: "CNY", Status: PaymentStatusPartiallyRefunded, RefundedAmount: 30, RefundingAmount: 40}, newRefund("R1", 30), false},
+		{"pending payment", &Payment{Amount: 100, Currency: "CNY", Status: PaymentStatusPending}, newRefund("R1", 10), true},
+		{"fully refunded payment", &Payment{Amount: 100, Currency: "CNY", Status: PaymentStatusRefunded, RefundedAmount: 100}, newRefund("R1", 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refunding := tt.payment.RefundingAmount
+			event, err := tt.payment.ReserveRefund(tt.refund, now)
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("ReserveRefund() error = nil, want error")
+				}
+				if tt.payment.RefundingAmount != refunding {
+					t.Errorf("RefundingAmount = %d, want unchanged %d", tt.payment.RefundingAmount, refunding)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReserveRefund() error = %v", err)
+			}
+			if tt.payment.RefundingAmount != refunding+tt.refund.Amount {
+				t.Errorf("RefundingAmount = %d, want %d", tt.payment.RefundingAmount, refunding+tt.refund.Amount)
+			}
+			if event.Type != PaymentEventRefundRequested || event.FromStatus != event.ToStatus {
+				t.Errorf("event = %+v, want refund request without status change", event)
+			}
+		})
+	}
+}
+
+func TestPaymentPartialRefunds(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	payment := newPaidPayment(100)
+
+	first, second := newRefund("R1", 60), newRefund("R2", 50)
+	if _, err := payment.ReserveRefund(first, now); err != nil {
+		t.Fatalf("ReserveRefund(R1) error = %v", err)
+	}
+	// 第一笔退款处理中仍占用额度，合计超出支付金额的第二笔被拒绝
+	if _, err := payment.ReserveRefund(second, now); err == nil {
+		t.Fatal("ReserveRefund(R2) error = nil, want exceeding refundable amount")
+	}
+	if got := payment.RefundableMoney().Amount(); got != 40 {
+		t.Errorf("RefundableMoney() = %d, want 40", got)
+	}
+
+	// 第一笔失败后释放额度
+	event := payment.ReleaseRefund(first, PaymentEventSourceNotify, now)
+	if event.Type != PaymentEventRefundFailed || payment.RefundingAmount != 0 || payment.Status != PaymentStatusPaid {
+		t.Errorf("after release: payment = %+v, event = %+v", payment, event)
+	}
+	if _, err := payment.ReserveRefund(second, now); err != nil {
+		t.Fatalf("ReserveRefund(R2) after release error = %v", err)
+	}
+
+	if _, err := payment.ApplyRefund(second, PaymentEventSourceNotify, now); err != nil {
+		t.Fatalf("ApplyRefund(R2) error = %v", err)
+	}
+	if payment.Status != PaymentStatusPartiallyRefunded || payment.RefundedAmount != 50 || payment.RefundingAmount != 0 {
+		t.Errorf("after partial refund: payment = %+v", payment)
+	}
+
+	third := newRefund("R3", 50)
+	if _, err := payment.ReserveRefund(third, now); err != nil {
+		t.Fatalf("ReserveRefund(R3) error = %v", err)
+	}
+	if _, err := payment.ApplyRefund(third, PaymentEventSourceNotify, now); err != nil {
+		t.Fatalf("ApplyRefund(R3) error = %v", err)
+	}
+	if payment.Status != PaymentStatusRefunded || payment.RefundedAmount != 100 || !payment.RefundableMoney().IsZero() {
+		t.Errorf("after full refund: payment = %+v", payment)
+	}
+	if _, err := payment.ReserveRefund(newRefund("R4", 1), now); err == nil {
+		t.Error("ReserveRefund() on a fully refunded payment error = nil")
+	}
+}
@@ -0,0 +1,51 @@
+package entity
+
+// 对账差异类型
+const (
+	DiscrepancyMissingLocally  = "missing_locally"  // 渠道对账单有成功记录，本地不存在或未记为成功
+	DiscrepancyMissingRemotely = "missing_remotely" // 本地记为成功，渠道对账单中不存在
+	DiscrepancyAmountMismatch  = "amount_mismatch"  // 双方都存在但金额不一致
+	DiscrepancyPaidAfterClose  = "paid_after_close" // 支付单已取消或失败后收到支付成功通知，由支付回调登记
+)
+
+// ReconcileDiscrepancyTypes 由对账登记的差异类型，重新对账时替换
+var ReconcileDiscrepancyTypes = []string{DiscrepancyMissingLocally, DiscrepancyMissingRemotely, DiscrepancyAmountMismatch}
+
+// 对账记录类型
+const (
+	StatementRecordTrade  = "trade"  // 支付交易
+	StatementRecordRefund = "refund" // 退款
+)
+
+// 对账差异处理状态
+const (
+	DiscrepancyStatusOpen     = "open"     // 待处理
+	DiscrepancyStatusResolved = "resolved" // 已处理
+)
+
+// ReconciliationDiscrepancy 对账差异
+// 按账单日期将渠道对账单与本地支付单、退款单逐条核对，不一致的记录写入差异表供人工复核；
+// 同一账单日期重新对账时替换待处理的差异，已处理的差异保留
+type ReconciliationDiscrepancy struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`                                                                         // 雪花ID主键
+	BillDate      string `gorm:"column:bill_date;type:varchar(10);not null;uniqueIndex:idx_reconciliation_discrepancies_key,priority:1" json:"billDate"`            // 账单日期 YYYY-MM-DD
+	Type          string `gorm:"column:type;type:varchar(20);not null;uniqueIndex:idx_reconciliation_discrepancies_key,priority:2" json:"type"`                     // 差异类型
+	RecordType    string `gorm:"column:record_type;type:varchar(10);not null;uniqueIndex:idx_reconciliation_discrepancies_key,priority:3" json:"recordType"`        // 记录类型: trade/refund
+	PaymentNo     string `gorm:"column:payment_no;type:varchar(32);not null;uniqueIndex:idx_reconciliation_discrepancies_key,priority:4" json:"paymentNo"`          // 商户支付单号
+	RefundNo      string `gorm:"column:refund_no;type:varchar(32);not null;default:'';uniqueIndex:idx_reconciliation_discrepancies_key,priority:5" json:"refundNo"` // 商户退款单号，仅退款记录
+	TransactionID string `gorm:"column:transaction_id;type:varchar(64)" json:"transactionId"`                                                                       // 渠道交易号或渠道退款单号
+	LocalAmount   int64  `gorm:"column:local_amount;not null;default:0" json:"localAmount"`                                                                         // 本地金额(分)，本地不存在时为 0
+	RemoteAmount  int64  `gorm:"column:remote_amount;not null;default:0" json:"remoteAmount"`                                                                       // 渠道金额(分)，渠道不存在时为 0
+	Detail        string `gorm:"column:detail;type:varchar(255)" json:"detail"`                                                                                     // 差异说明，如本地状态
+	Status        string `gorm:"column:status;type:varchar(10);not null;index" json:"status"`                                                                       // 处理状态
+	Note          string `gorm:"column:note;type:varchar(255)" json:"note"`                                                                                         // 处理备注
+	ResolvedBy    string `gorm:"column:resolved_by;type:varchar(64)" json:"resolvedBy"`                                                                             // 处理人 OpenID
+	ResolvedAt    int64  `gorm:"column:resolved_at;not null;default:0" json:"resolvedAt"`                                                                           // 处理时间(毫秒时间戳)
+	CreatedAt     int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                                                                 // 创建时间(毫秒时间戳)
+	UpdatedAt     int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`                                                                 // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (ReconciliationDiscrepancy) TableName() string {
+	return "reconciliation_discrepancies"
+}
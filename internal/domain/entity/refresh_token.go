@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// RefreshToken 刷新令牌实体
+// 刷新令牌为不透明随机串，服务端只保存其哈希值；
+// 同一次登录派生出的所有刷新令牌属于同一个令牌族(会话)
+type RefreshToken struct {
+	TokenHash string    // 令牌哈希(SHA-256)
+	SessionID string    // 会话ID(令牌族ID)
+	OpenID    string    // 所属用户OpenID
+	IssuedAt  time.Time // 签发时间
+	ExpiresAt time.Time // 过期时间
+	UsedAt    time.Time // 使用时间，零值表示尚未使用
+}
+
+// IsUsed 是否已被使用(轮换)
+func (t *RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+// IsExpired 是否已过期
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
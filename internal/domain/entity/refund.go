@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+)
+
+// RefundStatus 退款状态
+type RefundStatus string
+
+const (
+	RefundStatusPending    RefundStatus = "pending"    // 已登记，尚未被支付渠道受理
+	RefundStatusProcessing RefundStatus = "processing" // 支付渠道处理中
+	RefundStatusSucceeded  RefundStatus = "succeeded"  // 退款成功
+	RefundStatusFailed     RefundStatus = "failed"     // 退款失败或被关闭，退款额度已释放
+)
+
+// IsFinal 是否为终态
+func (s RefundStatus) IsFinal() bool {
+	return s == RefundStatusSucceeded || s == RefundStatusFailed
+}
+
+// Refund 退款单
+// 同一支付单可多次部分退款；登记时即占用退款额度(Payment.RefundingAmount)，成功后计入已退款金额，失败时释放。
+// 退款由支付渠道异步处理，结果由退款通知或后台对账任务回写
+type Refund struct {
+	ID              int64        `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`                                                             // 雪花ID主键
+	RefundNo        string       `gorm:"column:refund_no;type:varchar(32);uniqueIndex;not null" json:"refundNo"`                                                // 商户退款单号，即渠道的商户退款单号
+	PaymentID       int64        `gorm:"column:payment_id;not null;uniqueIndex:idx_refunds_payment_key,priority:1" json:"paymentId,string"`                     // 支付单ID
+	IdempotencyKey  string       `gorm:"column:idempotency_key;type:varchar(64);not null;uniqueIndex:idx_refunds_payment_key,priority:2" json:"idempotencyKey"` // 幂等键，同一支付单内唯一
+	Amount          int64        `gorm:"column:amount;not null" json:"amount"`                                                                                  // 退款金额(分)
+	Currency        string       `gorm:"column:currency;type:varchar(3);not null" json:"currency"`                                                              // 币种
+	Reason          string       `gorm:"column:reason;type:varchar(80)" json:"reason"`                                                                          // 退款原因
+	Status          RefundStatus `gorm:"column:status;type:varchar(16);not null;index:idx_refunds_status_next,priority:1" json:"status"`                        // 退款状态
+	ChannelRefundID string       `gorm:"column:channel_refund_id;type:varchar(64)" json:"channelRefundId"`                                                      // 渠道退款单号
+	FailReason      string       `gorm:"column:fail_reason;type:varchar(255)" json:"failReason"`                                                                // 失败原因
+	Attempts        int          `gorm:"column:attempts;not null;default:0" json:"attempts"`                                                                    // 已查询或提交次数
+	NextQueryAt     int64        `gorm:"column:next_query_at;not null;default:0;index:idx_refunds_status_next,priority:2" json:"nextQueryAt"`                   // 下次对账时间(毫秒时间戳)
+	SucceededAt     int64        `gorm:"column:succeeded_at;not null;default:0;index" json:"succeededAt"`                                                       // 退款成功时间(毫秒时间戳)
+	CreatedAt       int64        `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                                                     // 创建时间(毫秒时间戳)
+	UpdatedAt       int64        `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`                                                     // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+// Money 退款金额
+func (r *Refund) Money() valueobject.Money {
+	money, _ := valueobject.NewMoney(r.Amount, valueobject.Currency(r.Currency))
+	return money
+}
+
+// ScheduleNextQuery 记录一次对账并按指数退避安排下次对账时间
+func (r *Refund) ScheduleNextQuery(now time.Time, base, maxInterval time.Duration) {
+	r.Attempts++
+	interval := base << min(r.Attempts-1, 16)
+	if interval <= 0 || interval > maxInterval {
+		interval = maxInterval
+	}
+	r.NextQueryAt = now.Add(interval).UnixMilli()
+	r.UpdatedAt = now.UnixMilli()
+}
@@ -0,0 +1,84 @@
+package entity
+
+import "strings"
+
+// 内置角色
+const (
+	RoleAdmin = "admin" // 管理员
+	RoleUser  = "user"  // 普通用户
+)
+
+// 权限标识，格式为 "资源:操作"
+const (
+	PermissionAll              = "*"                 // 全部权限
+	PermissionContentCreate    = "content:create"    // 创建内容
+	PermissionAppVersionRead   = "app_version:read"  // 查看应用版本
+	PermissionAppVersionWrite  = "app_version:write" // 管理应用版本
+	PermissionNotificationRead = "notification:read" // 查看通知投递记录
+	PermissionPaymentRead      = "payment:read"      // 查看支付单与退款单
+	PermissionPaymentRefund    = "payment:refund"    // 发起退款
+	PermissionPaymentReconcile = "payment:reconcile" // 执行对账与处理对账差异
+
+	PermissionAppVersionInternal = "app_version:internal" // 接收内部渠道版本
+)
+
+// Role 角色实体
+type Role struct {
+	ID          int64  `gorm:"primaryKey;column:id" json:"id"`
+	Code        string `gorm:"column:code;type:varchar(32);uniqueIndex;not null" json:"code"`     // 角色编码
+	Name        string `gorm:"column:name;type:varchar(64);not null" json:"name"`                 // 角色名称
+	Description string `gorm:"column:description;type:varchar(255)" json:"description"`           // 角色描述
+	CreatedAt   int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"` // 创建时间(毫秒时间戳)
+	UpdatedAt   int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"` // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission 角色权限
+type RolePermission struct {
+	ID         int64  `gorm:"primaryKey;column:id" json:"id"`
+	RoleID     int64  `gorm:"column:role_id;not null;uniqueIndex:idx_role_permissions_role_perm" json:"roleId"`                         // 角色ID
+	Permission string `gorm:"column:permission;type:varchar(64);not null;uniqueIndex:idx_role_permissions_role_perm" json:"permission"` // 权限标识
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                                        // 创建时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// UserRole 用户角色关联
+type UserRole struct {
+	ID        int64 `gorm:"primaryKey;column:id" json:"id"`
+	UserID    int64 `gorm:"column:user_id;not null;uniqueIndex:idx_user_roles_user_role" json:"userId"` // 用户ID
+	RoleID    int64 `gorm:"column:role_id;not null;uniqueIndex:idx_user_roles_user_role" json:"roleId"` // 角色ID
+	CreatedAt int64 `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`          // 创建时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+// EffectiveRoles 返回用户的有效角色，未分配任何角色的用户视为普通用户
+func EffectiveRoles(roles []string) []string {
+	if len(roles) == 0 {
+		return []string{RoleUser}
+	}
+	return roles
+}
+
+// HasPermission 判断已授予的权限是否包含所需权限
+// 支持全局通配 "*" 与资源通配 "resource:*"
+func HasPermission(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, ":")
+	for _, p := range granted {
+		if p == PermissionAll || p == required || p == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
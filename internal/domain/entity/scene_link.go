@@ -0,0 +1,30 @@
+package entity
+
+// SceneLink 小程序码场景短链
+// 小程序码 scene 最多 32 个字符，业务数据以短码登记在服务端，scene 中只放 c=<短码>，扫码后凭短码换取数据
+type SceneLink struct {
+	Code      string `gorm:"primaryKey;column:code;type:varchar(16)" json:"code"`               // 短码
+	CreatorID int64  `gorm:"column:creator_id;not null;index" json:"creatorId,string"`          // 创建者用户ID
+	Page      string `gorm:"column:page;type:varchar(128)" json:"page"`                         // 扫码进入的小程序页面
+	Payload   string `gorm:"column:payload;type:text;not null" json:"payload"`                  // 业务数据(JSON)
+	MaxUses   int    `gorm:"column:max_uses;not null;default:0" json:"maxUses"`                 // 最大使用次数，0 表示不限制
+	UseCount  int    `gorm:"column:use_count;not null;default:0" json:"useCount"`               // 已使用次数
+	ExpiresAt int64  `gorm:"column:expires_at;not null;default:0;index" json:"expiresAt"`       // 过期时间(毫秒时间戳)，0 表示永不过期
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"` // 创建时间(毫秒时间戳)
+	UpdatedAt int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"` // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (SceneLink) TableName() string {
+	return "scene_links"
+}
+
+// IsExpired 在 now(毫秒时间戳)时是否已过期
+func (l *SceneLink) IsExpired(now int64) bool {
+	return l.ExpiresAt > 0 && l.ExpiresAt <= now
+}
+
+// IsExhausted 使用次数是否已达上限
+func (l *SceneLink) IsExhausted() bool {
+	return l.MaxUses > 0 && l.UseCount >= l.MaxUses
+}
@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 定时通知状态
+const (
+	ScheduledNotificationStatusActive    = "active"    // 等待发送
+	ScheduledNotificationStatusCompleted = "completed" // 单次通知已发送
+	ScheduledNotificationStatusCancelled = "cancelled" // 已取消
+)
+
+// recurrenceParser 重复规则解析器，使用标准 5 段 cron 表达式(分 时 日 月 周)，支持 @daily 等描述符
+var recurrenceParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ScheduledNotification 定时通知
+// 到达 NextRunAt 时由后台任务领取并写入通知发件箱；单次通知发送后结束，重复通知按 cron 规则计算下次发送时间
+type ScheduledNotification struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`                                                       // 雪花ID主键
+	UserID     int64  `gorm:"column:user_id;not null;index" json:"userId,string"`                                                              // 接收用户ID
+	Type       string `gorm:"column:type;type:varchar(32);not null" json:"type"`                                                               // 通知类型，如 feeding_reminder
+	Channel    string `gorm:"column:channel;type:varchar(16);not null" json:"channel"`                                                         // 通知渠道
+	Locale     string `gorm:"column:locale;type:varchar(16)" json:"locale"`                                                                    // 模板语言
+	Variables  string `gorm:"column:variables;type:text" json:"variables"`                                                                     // 模板变量(JSON)
+	Title      string `gorm:"column:title;type:varchar(255)" json:"title"`                                                                     // 标题，未使用模板时直接发送
+	Content    string `gorm:"column:content;type:text" json:"content"`                                                                         // 内容，未使用模板时直接发送
+	Recurrence string `gorm:"column:recurrence;type:varchar(64)" json:"recurrence"`                                                            // 重复规则(cron 表达式)，为空表示单次发送
+	Timezone   string `gorm:"column:timezone;type:varchar(64);not null" json:"timezone"`                                                       // 计算重复规则使用的 IANA 时区
+	Status     string `gorm:"column:status;type:varchar(16);not null;index:idx_scheduled_notifications_status_next,priority:1" json:"status"`  // 状态
+	NextRunAt  int64  `gorm:"column:next_run_at;not null;default:0;index:idx_scheduled_notifications_status_next,priority:2" json:"nextRunAt"` // 下次发送时间(毫秒时间戳)
+	LastRunAt  int64  `gorm:"column:last_run_at;not null;default:0" json:"lastRunAt"`                                                          // 最近一次发送时间(毫秒时间戳)
+	RunCount   int    `gorm:"column:run_count;not null;default:0" json:"runCount"`                                                             // 已发送次数
+	CreatedAt  int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                                               // 创建时间(毫秒时间戳)
+	UpdatedAt  int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`                                               // 更新时间(毫秒时间戳)
+}
+
+// TableName 指定表名
+func (ScheduledNotification) TableName() string {
+	return "scheduled_notifications"
+}
+
+// IsRecurring 是否为重复通知
+func (n *ScheduledNotification) IsRecurring() bool {
+	return n.Recurrence != ""
+}
+
+// NextAfter 计算重复通知在 t 之后的下次发送时间，按通知时区解析 cron 规则
+func (n *ScheduledNotification) NextAfter(t time.Time) (time.Time, error) {
+	schedule, err := ParseRecurrence(n.Recurrence)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(n.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q: %w", n.Timezone, err)
+	}
+
+	next := schedule.Next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("recurrence %q never fires", n.Recurrence)
+	}
+	return next, nil
+}
+
+// Advance 记录一次发送并推进到下次发送时间
+// 单次通知标记为已完成；重复通知跳过 now 之前错过的时刻(如服务停机期间)，只补发一次
+func (n *ScheduledNotification) Advance(now time.Time) error {
+	n.LastRunAt = now.UnixMilli()
+	n.RunCount++
+
+	if !n.IsRecurring() {
+		n.Status = ScheduledNotificationStatusCompleted
+		return nil
+	}
+
+	next, err := n.NextAfter(now)
+	if err != nil {
+		// 规则已无法解析时结束该通知，避免每轮都被重复领取
+		n.Status = ScheduledNotificationStatusCompleted
+		return err
+	}
+	n.NextRunAt = next.UnixMilli()
+	return nil
+}
+
+// ParseRecurrence 解析重复规则
+func ParseRecurrence(expr string) (cron.Schedule, error) {
+	schedule, err := recurrenceParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence %q: %w", expr, err)
+	}
+	return schedule, nil
+}
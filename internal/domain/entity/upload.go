@@ -0,0 +1,28 @@
+package entity
+
+// Upload 上传文件记录
+// 文件按内容哈希存储，相同内容只保存一份；每次上传各自保留一条记录，用于归属、查询与清理
+type Upload struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement:false;column:id" json:"id,string"`                                      // 雪花ID主键
+	OpenID      string `gorm:"column:openid;type:varchar(64);not null;index:idx_uploads_openid_type,priority:1" json:"openid"` // 上传者OpenID
+	Type        string `gorm:"column:type;type:varchar(32);not null;index:idx_uploads_openid_type,priority:2" json:"type"`     // 上传类型
+	RelatedID   string `gorm:"column:related_id;type:varchar(64)" json:"relatedId"`                                            // 关联业务ID
+	StorageKey  string `gorm:"column:storage_key;type:varchar(255);not null;index" json:"storageKey"`                          // 存储对象键
+	Filename    string `gorm:"column:filename;type:varchar(255)" json:"filename"`                                              // 原始文件名
+	Size        int64  `gorm:"column:size;not null" json:"size"`                                                               // 文件大小(字节)
+	SHA256      string `gorm:"column:sha256;type:char(64);not null;index" json:"sha256"`                                       // 内容哈希(十六进制)
+	ContentType string `gorm:"column:content_type;type:varchar(100);not null" json:"contentType"`                              // MIME类型
+	OrphanedAt  int64  `gorm:"column:orphaned_at;default:0;index" json:"orphanedAt"`                                           // 不再被引用的时间(毫秒时间戳)，0 表示仍在使用
+	CreatedAt   int64  `gorm:"column:created_at;autoCreateTime:milli;default:0" json:"createdAt"`                              // 创建时间(毫秒时间戳)
+	UpdatedAt   int64  `gorm:"column:updated_at;autoUpdateTime:milli;default:0" json:"updatedAt"`                              // 更新时间(毫秒时间戳)
+}
+
+// IsOrphaned 是否已不再被业务数据引用
+func (u *Upload) IsOrphaned() bool {
+	return u.OrphanedAt > 0
+}
+
+// TableName 指定表名
+func (Upload) TableName() string {
+	return "uploads"
+}
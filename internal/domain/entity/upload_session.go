@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// UploadSession 断点续传上传会话
+// 客户端按偏移量分片上传，已接收的分片由文件存储保存，全部接收后合并为一次完整上传
+type UploadSession struct {
+	ID        string    // 会话ID(随机串)
+	OpenID    string    // 上传者OpenID
+	Type      string    // 上传类型
+	RelatedID string    // 关联业务ID
+	Filename  string    // 原始文件名
+	Length    int64     // 文件总字节数
+	Offset    int64     // 已接收字节数
+	Parts     []string  // 已接收分片的存储对象键，按偏移量顺序排列
+	DirectKey string    // 预签名直传的暂存对象键，非空表示客户端直接上传到存储而非分片上传
+	Result    []byte    // 上传完成后的结果(JSON)，非空表示已完成，分片已清理
+	CreatedAt time.Time // 创建时间
+	ExpiresAt time.Time // 过期时间，过期后未完成的会话由后台任务清理
+}
+
+// IsComplete 是否已接收全部数据
+func (s *UploadSession) IsComplete() bool {
+	return s.Offset >= s.Length
+}
+
+// IsFinished 是否已完成上传
+func (s *UploadSession) IsFinished() bool {
+	return len(s.Result) > 0
+}
+
+// IsDirect 是否为预签名直传会话
+func (s *UploadSession) IsDirect() bool {
+	return s.DirectKey != ""
+}
+
+// IsExpired 是否已过期
+func (s *UploadSession) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -8,7 +8,7 @@ import (
 
 // AppVersionRepository 应用版本仓储接口
 type AppVersionRepository interface {
-	// FindActive 获取当前活跃版本
+	// FindActive 获取正式渠道的当前活跃版本(已全量放开)
 	FindActive(ctx context.Context) (*entity.AppVersion, error)
 	// FindByVersion 根据版本号查找版本信息
 	FindByVersion(ctx context.Context, version string) (*entity.AppVersion, error)
@@ -16,6 +16,14 @@ type AppVersionRepository interface {
 	Create(ctx context.Context, appVersion *entity.AppVersion) error
 	// Update 更新版本信息
 	Update(ctx context.Context, appVersion *entity.AppVersion) error
-	// SetActive 设置版本为活跃版本（将其他版本设为非活跃）
+	// SetActive 全量发布指定版本（将同一渠道、同一平台的其他版本设为非活跃）
 	SetActive(ctx context.Context, version string) error
+	// List 分页查询版本列表(按创建时间倒序)
+	List(ctx context.Context, offset, limit int) ([]*entity.AppVersion, error)
+	// FindAll 查询全部版本
+	FindAll(ctx context.Context) ([]*entity.AppVersion, error)
+	// Count 统计版本总数
+	Count(ctx context.Context) (int64, error)
+	// Delete 删除版本
+	Delete(ctx context.Context, version string) error
 }
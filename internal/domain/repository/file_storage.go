@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+)
+
+// FileInfo 存储对象元信息
+type FileInfo struct {
+	Key         string    // 对象键，如 images/users/xxx.jpg
+	Size        int64     // 字节数
+	ContentType string    // MIME 类型
+	ModTime     time.Time // 最后修改时间
+}
+
+// FileStorage 文件存储接口
+// 对象键统一使用以 / 分隔的相对路径，不以 / 开头；对象不存在时返回 NotFound 错误
+type FileStorage interface {
+	// Put 写入对象，已存在时覆盖；size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *FileInfo, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// Stat 获取对象元信息
+	Stat(ctx context.Context, key string) (*FileInfo, error)
+	// URL 返回对象的公开访问地址
+	URL(key string) string
+}
+
+// PresignedRequest 预签名请求，客户端凭此直接向存储上传，无需经过应用服务器转发
+type PresignedRequest struct {
+	Method    string            // 请求方法
+	URL       string            // 带签名的请求地址
+	Headers   map[string]string // 客户端必须原样携带的请求头
+	ExpiresAt time.Time         // 签名过期时间
+}
+
+// UploadPresigner 预签名直传，由支持客户端直传的存储后端实现
+type UploadPresigner interface {
+	// PresignPut 生成上传对象的预签名请求，签名约束对象键、内容类型与字节数
+	PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error)
+}
+
+// PresignedUploadVerifier 校验预签名直传请求
+// 由需要应用自身接收直传数据的存储后端(本地存储)实现，校验通过后调用方通过 Put 写入对象
+type PresignedUploadVerifier interface {
+	// VerifyPut 校验请求签名、有效期与内容类型，返回签名允许的字节数
+	VerifyPut(key string, query url.Values, contentType string) (int64, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// IdempotencyRepository 幂等请求记录仓储接口
+type IdempotencyRepository interface {
+	// Acquire 原子地创建处理中的记录，lockTTL 后未完成的记录自动失效
+	// 记录已存在时返回已有记录与 false
+	Acquire(ctx context.Context, record *entity.IdempotencyRecord, lockTTL time.Duration) (*entity.IdempotencyRecord, bool, error)
+	// Extend 将处理中记录的有效期重置为 lockTTL，记录已不属于 owner(处理超时被其他请求取得)时返回 false
+	Extend(ctx context.Context, key, owner string, lockTTL time.Duration) (bool, error)
+	// Complete 保存响应并将记录保留 ttl，记录已不属于 record.Owner 时不保存并返回 false
+	Complete(ctx context.Context, record *entity.IdempotencyRecord, ttl time.Duration) (bool, error)
+	// Release 删除处理中的记录，使相同幂等键的请求可以重新处理；记录不属于 owner 时忽略
+	Release(ctx context.Context, key, owner string) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// LockRepository 分布式锁仓储接口
+// 多实例部署时保证同一时刻只有一个实例执行某项任务，锁在 ttl 后自动释放，持有者进程异常退出不会永久占用
+type LockRepository interface {
+	// Acquire 尝试以 owner 身份获取名为 name 的锁，锁已被占用时返回 false
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Extend 将 owner 持有的锁的有效期重置为 ttl，锁已不属于 owner(已过期或被其他实例取得)时返回 false
+	Extend(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Release 释放 owner 持有的锁，锁不属于 owner 时忽略
+	Release(ctx context.Context, name, owner string) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// NotificationPreferenceRepository 用户通知偏好仓储接口
+type NotificationPreferenceRepository interface {
+	// FindByUserID 查找用户的通知偏好(含渠道设置)，用户未设置时返回 NotFound
+	FindByUserID(ctx context.Context, userID int64) (*entity.NotificationPreference, error)
+	// Save 保存用户的通知偏好，渠道设置整体替换
+	Save(ctx context.Context, preference *entity.NotificationPreference) error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// NotificationFilter 通知查询条件，零值字段不参与过滤
+type NotificationFilter struct {
+	UserID    int64
+	Channel   string
+	Recipient string
+	Status    string
+}
+
+// NotificationRepository 通知发件箱仓储接口
+type NotificationRepository interface {
+	// Create 写入待投递通知
+	Create(ctx context.Context, notification *entity.Notification) error
+	// FindByID 根据ID查找通知
+	FindByID(ctx context.Context, id int64) (*entity.Notification, error)
+	// List 分页查询通知(按创建时间倒序)
+	List(ctx context.Context, filter NotificationFilter, offset, limit int) ([]*entity.Notification, error)
+	// Count 统计通知数
+	Count(ctx context.Context, filter NotificationFilter) (int64, error)
+	// ClaimDue 领取到期待投递的通知
+	// 领取后状态置为 sending、尝试次数加一，并在 leaseUntil 前不会被再次领取；
+	// 投递进程异常退出时，租约到期后通知会被重新领取
+	ClaimDue(ctx context.Context, now, leaseUntil int64, limit int) ([]*entity.Notification, error)
+	// MarkSent 标记投递成功，仅当通知仍处于本次领取状态时生效
+	MarkSent(ctx context.Context, notification *entity.Notification, sentAt int64) error
+	// MarkRetry 标记投递失败并安排在 nextAttemptAt 重试，仅当通知仍处于本次领取状态时生效
+	MarkRetry(ctx context.Context, notification *entity.Notification, nextAttemptAt int64, lastError string) error
+	// MarkFailed 标记放弃投递，仅当通知仍处于本次领取状态时生效
+	MarkFailed(ctx context.Context, notification *entity.Notification, lastError string) error
+}
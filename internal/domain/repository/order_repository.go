@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// OrderRepository 订单仓储接口
+type OrderRepository interface {
+	// Create 创建订单
+	Create(ctx context.Context, order *entity.Order) error
+	// FindByID 根据ID查找订单
+	FindByID(ctx context.Context, id int64) (*entity.Order, error)
+	// FindByOrderNo 根据订单号查找订单
+	FindByOrderNo(ctx context.Context, orderNo string) (*entity.Order, error)
+}
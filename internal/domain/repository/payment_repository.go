@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// PaymentRepository 支付单仓储接口
+// 支付单的创建与状态变更在同一事务内追加支付事件，并按乐观锁校验版本号，版本不符时返回 Conflict
+type PaymentRepository interface {
+	// Create 为订单创建支付单并追加创建事件
+	// 同时校验并递增订单版本号，同一订单并发发起支付时只有一个成功
+	Create(ctx context.Context, order *entity.Order, payment *entity.Payment, event *entity.PaymentEvent) error
+	// FindByID 根据ID查找支付单
+	FindByID(ctx context.Context, id int64) (*entity.Payment, error)
+	// FindByPaymentNo 根据商户支付单号查找支付单
+	FindByPaymentNo(ctx context.Context, paymentNo string) (*entity.Payment, error)
+	// ListByOrderID 查询订单的全部支付单(按创建时间升序)
+	ListByOrderID(ctx context.Context, orderID int64) ([]*entity.Payment, error)
+	// ListExpiredPending 查询已过期仍待支付的支付单
+	ListExpiredPending(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error)
+	// ListPaidBetween 查询支付方式为 method、支付成功时间在 [start, end) 内的支付单
+	ListPaidBetween(ctx context.Context, method string, start, end time.Time) ([]*entity.Payment, error)
+	// Transition 保存支付单的状态变更并追加支付事件，需要时同步订单状态
+	// 按 payment.Version 校验后递增版本号，成功后回写 payment.Version
+	Transition(ctx context.Context, payment *entity.Payment, event *entity.PaymentEvent) error
+	// ListEvents 查询支付单的事件(按时间升序)
+	ListEvents(ctx context.Context, paymentID int64) ([]*entity.PaymentEvent, error)
+}
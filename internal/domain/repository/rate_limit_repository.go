@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitRepository 频率限制仓储接口
+// 按固定时间窗口计数，多实例部署时共享计数
+type RateLimitRepository interface {
+	// Allow 在当前时间窗口内为 key 计数一次，计数超过 limit 时返回 false
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
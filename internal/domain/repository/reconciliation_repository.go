@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// DiscrepancyFilter 对账差异查询条件，零值字段不参与过滤
+type DiscrepancyFilter struct {
+	BillDate string
+	Type     string
+	Status   string
+}
+
+// ReconciliationRepository 对账差异仓储接口
+type ReconciliationRepository interface {
+	// ReplaceDiscrepancies 替换账单日期由对账登记的待处理差异
+	// 删除该日期待处理的对账差异后写入新的差异，与已处理差异重复的记录不再写入
+	ReplaceDiscrepancies(ctx context.Context, billDate string, discrepancies []*entity.ReconciliationDiscrepancy) error
+	// Flag 登记一条对账之外发现的差异，已存在相同差异时忽略
+	Flag(ctx context.Context, discrepancy *entity.ReconciliationDiscrepancy) error
+	// FindByID 根据ID查找对账差异
+	FindByID(ctx context.Context, id int64) (*entity.ReconciliationDiscrepancy, error)
+	// List 分页查询对账差异(按账单日期倒序)
+	List(ctx context.Context, filter DiscrepancyFilter, offset, limit int) ([]*entity.ReconciliationDiscrepancy, error)
+	// Count 统计对账差异数
+	Count(ctx context.Context, filter DiscrepancyFilter) (int64, error)
+	// Resolve 将待处理的差异标记为已处理，差异已处理时返回 Conflict
+	Resolve(ctx context.Context, discrepancy *entity.ReconciliationDiscrepancy) error
+}
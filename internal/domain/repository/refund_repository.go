@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// RefundRepository 退款单仓储接口
+// 退款单的登记与完成在同一事务内更新支付单的退款金额并追加支付事件，支付单按乐观锁校验版本号，版本不符时返回 Conflict
+type RefundRepository interface {
+	// Create 登记退款单，同时保存支付单占用的退款额度并追加退款申请事件
+	// 同一支付单的幂等键已存在时返回 Conflict
+	Create(ctx context.Context, payment *entity.Payment, refund *entity.Refund, event *entity.PaymentEvent) error
+	// FindByRefundNo 根据商户退款单号查找退款单
+	FindByRefundNo(ctx context.Context, refundNo string) (*entity.Refund, error)
+	// FindByIdempotencyKey 根据幂等键查找支付单的退款单
+	FindByIdempotencyKey(ctx context.Context, paymentID int64, key string) (*entity.Refund, error)
+	// ListByPaymentID 查询支付单的全部退款单(按创建时间升序)
+	ListByPaymentID(ctx context.Context, paymentID int64) ([]*entity.Refund, error)
+	// ListSucceededBetween 查询退款成功时间在 [start, end) 内的退款单
+	ListSucceededBetween(ctx context.Context, start, end time.Time) ([]*entity.Refund, error)
+	// ListDue 查询未完成且已到对账时间的退款单
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.Refund, error)
+	// Update 保存未完成退款单的处理进度(渠道退款单号、对账次数与时间)，退款单已完成时返回 Conflict
+	Update(ctx context.Context, refund *entity.Refund) error
+	// Complete 保存退款单的最终结果，同时保存支付单的状态与退款金额并追加支付事件
+	// 退款单已完成时返回 Conflict；成功后回写 payment.Version
+	Complete(ctx context.Context, payment *entity.Payment, refund *entity.Refund, event *entity.PaymentEvent) error
+}
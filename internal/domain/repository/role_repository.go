@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// RoleRepository 角色仓储接口
+type RoleRepository interface {
+	// FindByCode 根据角色编码查找角色
+	FindByCode(ctx context.Context, code string) (*entity.Role, error)
+	// FindRoleCodesByUserID 查找用户拥有的角色编码
+	FindRoleCodesByUserID(ctx context.Context, userID int64) ([]string, error)
+	// FindPermissionsByRoleCodes 查找角色集合拥有的全部权限(去重)
+	FindPermissionsByRoleCodes(ctx context.Context, roleCodes []string) ([]string, error)
+	// AssignToUser 为用户分配角色，已分配时忽略
+	AssignToUser(ctx context.Context, userID int64, roleCode string) error
+	// RemoveFromUser 移除用户的角色
+	RemoveFromUser(ctx context.Context, userID int64, roleCode string) error
+}
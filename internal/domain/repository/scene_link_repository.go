@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// SceneLinkRepository 小程序码场景短链仓储接口
+type SceneLinkRepository interface {
+	// Create 登记短链，短码已被占用时返回 false
+	Create(ctx context.Context, link *entity.SceneLink) (bool, error)
+	// FindByCode 根据短码查找短链
+	FindByCode(ctx context.Context, code string) (*entity.SceneLink, error)
+	// Consume 原子地记录一次使用，仅当短链在 now(毫秒时间戳)时未过期且未达使用上限时生效，返回是否成功
+	Consume(ctx context.Context, code string, now int64) (bool, error)
+	// Delete 删除短链
+	Delete(ctx context.Context, code string) error
+	// DeleteExpired 删除在 before(毫秒时间戳)之前过期的短链，返回删除条数
+	DeleteExpired(ctx context.Context, before int64, limit int) (int64, error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// ScheduledNotificationRepository 定时通知仓储接口
+type ScheduledNotificationRepository interface {
+	// Create 创建定时通知
+	Create(ctx context.Context, notification *entity.ScheduledNotification) error
+	// FindByID 根据ID查找定时通知
+	FindByID(ctx context.Context, id int64) (*entity.ScheduledNotification, error)
+	// ListByUserID 分页查询用户的定时通知(按下次发送时间升序)，status 为空表示全部
+	ListByUserID(ctx context.Context, userID int64, status string, offset, limit int) ([]*entity.ScheduledNotification, error)
+	// CountByUserID 统计用户的定时通知数，status 为空表示全部
+	CountByUserID(ctx context.Context, userID int64, status string) (int64, error)
+	// Cancel 取消定时通知，仅当通知仍处于等待发送状态时生效，返回是否取消成功
+	Cancel(ctx context.Context, id int64) (bool, error)
+	// ClaimDue 领取到期的定时通知
+	// 领取时在同一事务内推进到下次发送时间(单次通知标记为已完成)后提交，再由调用方发送；
+	// 多实例同时领取时互不重复，发送前进程退出则本次发送丢失，即至多发送一次
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]*entity.ScheduledNotification, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import "context"
+
+// SubscribeQuotaRepository 微信订阅消息额度仓储接口
+// 一次性订阅消息每次用户授权只允许发送一条，额度按用户与模板计数
+type SubscribeQuotaRepository interface {
+	// Grant 用户授权了模板，每个模板额度加一
+	Grant(ctx context.Context, openID string, templateIDs []string) error
+	// FindByOpenID 查询用户各模板的剩余额度，无额度的模板不返回
+	FindByOpenID(ctx context.Context, openID string) (map[string]int, error)
+	// Consume 原子地扣减一次额度，额度不足时返回 false
+	Consume(ctx context.Context, openID, templateID string) (bool, error)
+	// Refund 退还一次额度，用于发送失败时
+	Refund(ctx context.Context, openID, templateID string) error
+	// Clear 清空用户在模板上的额度，用于微信确认额度已用完时
+	Clear(ctx context.Context, openID, templateID string) error
+}
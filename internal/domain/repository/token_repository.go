@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// TokenRepository 令牌仓储接口
+// 负责刷新令牌的存储与轮换，以及访问令牌/会话的吊销列表
+type TokenRepository interface {
+	// SaveRefreshToken 保存刷新令牌，过期时间由令牌自身的 ExpiresAt 决定
+	SaveRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	// FindRefreshToken 根据令牌哈希查找刷新令牌
+	FindRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	// MarkRefreshTokenUsed 原子地标记刷新令牌已使用，返回 false 表示该令牌此前已被使用；令牌已过期时返回 InvalidToken
+	MarkRefreshTokenUsed(ctx context.Context, tokenHash string, usedAt time.Time) (bool, error)
+	// RevokeSession 吊销整个会话(令牌族)
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+	// IsSessionRevoked 检查会话是否已被吊销
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	// RevokeAccessToken 按 jti 吊销访问令牌，ttl 应不短于令牌剩余有效期
+	RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error
+	// IsAccessTokenRevoked 检查访问令牌本身或其所属会话是否已被吊销
+	IsAccessTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+}
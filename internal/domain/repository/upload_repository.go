@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// UploadRepository 上传文件记录仓储接口
+type UploadRepository interface {
+	// Create 创建上传记录
+	Create(ctx context.Context, upload *entity.Upload) error
+	// FindByID 根据ID查找上传记录
+	FindByID(ctx context.Context, id int64) (*entity.Upload, error)
+	// ListByOpenID 分页查询用户的上传记录(按创建时间倒序)，uploadType 为空表示全部类型
+	ListByOpenID(ctx context.Context, openID, uploadType string, offset, limit int) ([]*entity.Upload, error)
+	// CountByOpenID 统计用户的上传记录数，uploadType 为空表示全部类型
+	CountByOpenID(ctx context.Context, openID, uploadType string) (int64, error)
+	// SumSizeByOpenID 统计用户上传文件的总字节数
+	SumSizeByOpenID(ctx context.Context, openID string) (int64, error)
+	// CountByStorageKey 统计引用同一存储对象的上传记录数
+	CountByStorageKey(ctx context.Context, storageKey string) (int64, error)
+	// SetOrphanedAt 设置用户某类上传中指定存储对象的孤儿标记时间，0 表示重新被引用
+	SetOrphanedAt(ctx context.Context, openID, uploadType, storageKey string, orphanedAt int64) error
+	// FindOrphaned 查找在 before 之前被标记为孤儿的上传记录
+	FindOrphaned(ctx context.Context, before int64, limit int) ([]*entity.Upload, error)
+	// Delete 删除上传记录
+	Delete(ctx context.Context, id int64) error
+}
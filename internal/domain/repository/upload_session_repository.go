@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+)
+
+// UploadSessionRepository 断点续传会话仓储接口
+type UploadSessionRepository interface {
+	// Create 创建上传会话
+	Create(ctx context.Context, session *entity.UploadSession) error
+	// FindByID 根据ID查找上传会话(含分片列表)
+	FindByID(ctx context.Context, id string) (*entity.UploadSession, error)
+	// AppendPart 原子地追加分片：仅当当前偏移量等于 offset 时推进偏移量并记录分片，返回 false 表示偏移量已变化
+	AppendPart(ctx context.Context, id string, offset, size int64, partKey string) (bool, error)
+	// ClaimCompletion 标记会话正在合并，返回 false 表示已被其他请求标记
+	ClaimCompletion(ctx context.Context, id string) (bool, error)
+	// ReleaseCompletion 清除合并标记，以便重试
+	ReleaseCompletion(ctx context.Context, id string) error
+	// Finish 记录上传结果并清除分片列表，会话保留至原定的过期时间，以便重复完成请求返回相同结果
+	Finish(ctx context.Context, id string, result []byte) error
+	// Delete 删除上传会话
+	Delete(ctx context.Context, id string) error
+	// FindExpired 查找在 before 之前过期的会话ID
+	FindExpired(ctx context.Context, before time.Time, limit int) ([]string, error)
+}
@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
 	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	pkgerrors "github.com/wxlbd/polaris/pkg/errors"
 )
 
 // NotificationChannel 通知渠道
@@ -22,42 +26,124 @@ const (
 type NotificationSender interface {
 	// SendSMS 发送短信
 	SendSMS(ctx context.Context, phone valueobject.Phone, content string) error
-	// SendEmail 发送邮件
-	SendEmail(ctx context.Context, email valueobject.Email, subject, content string) error
+	// SendEmail 发送邮件，html 表示内容为 HTML
+	SendEmail(ctx context.Context, email valueobject.Email, subject, content string, html bool) error
 	// SendPush 发送APP推送
 	SendPush(ctx context.Context, userID int64, title, content string) error
 	// SendWechat 发送微信消息
 	SendWechat(ctx context.Context, openID, templateID string, data map[string]interface{}) error
+	// Supports 渠道是否已启用(配置了投递器)
+	Supports(channel NotificationChannel) bool
+}
+
+// NotificationTypeGeneral 未指定通知类型时的默认类型
+const NotificationTypeGeneral = "general"
+
+// ErrNotificationSuppressed 通知被用户偏好、免打扰时段或频率限制拦截
+var ErrNotificationSuppressed = errors.New("通知已被用户偏好或频率限制拦截")
+
+// NotificationRateLimiter 通知频率限制器接口
+type NotificationRateLimiter interface {
+	// Allow 在 window 时间窗口内为用户的某类通知计数，未超过 limit 时返回 true
+	Allow(ctx context.Context, userID int64, notificationType string, limit int, window time.Duration) (bool, error)
+}
+
+// NotificationRenderer 通知模板渲染器接口
+type NotificationRenderer interface {
+	// Render 按通知类型与语言渲染指定渠道的内容
+	// 类型未注册模板时 ok 为 false；变量缺失或类型不符时返回参数错误
+	Render(notificationType, locale string, channel NotificationChannel, variables map[string]interface{}) (rendered *RenderedNotification, ok bool, err error)
+}
+
+// RenderedNotification 渲染后的渠道内容
+type RenderedNotification struct {
+	Title      string                 // 标题(邮件主题、推送标题)
+	Content    string                 // 正文(短信、邮件、推送)
+	HTML       bool                   // 正文是否为 HTML
+	TemplateID string                 // 微信订阅消息模板ID
+	Page       string                 // 微信订阅消息跳转页面
+	Data       map[string]interface{} // 微信订阅消息模板数据
+}
+
+// RateLimit 频率限制规则，Limit 不大于 0 表示不限制
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// NotificationPolicy 通知发送策略
+type NotificationPolicy struct {
+	DefaultLocation  *time.Location       // 用户未设置时区时使用的时区
+	DefaultRateLimit RateLimit            // 默认的每用户每类型频率限制
+	TypeRateLimits   map[string]RateLimit // 按通知类型覆盖的频率限制
+	UrgentTypes      map[string]bool      // 紧急通知类型，不受免打扰时段与频率限制约束
 }
 
 // NotificationDomainService 通知领域服务
 // 处理与通知相关的领域逻辑
 type NotificationDomainService struct {
-	sender NotificationSender
+	sender         NotificationSender
+	renderer       NotificationRenderer
+	preferenceRepo repository.NotificationPreferenceRepository
+	rateLimiter    NotificationRateLimiter
+	policy         NotificationPolicy
 }
 
 // NewNotificationDomainService 创建通知领域服务
-func NewNotificationDomainService(sender NotificationSender) *NotificationDomainService {
+func NewNotificationDomainService(
+	sender NotificationSender,
+	renderer NotificationRenderer,
+	preferenceRepo repository.NotificationPreferenceRepository,
+	rateLimiter NotificationRateLimiter,
+	policy NotificationPolicy,
+) *NotificationDomainService {
+	if policy.DefaultLocation == nil {
+		policy.DefaultLocation = time.Local
+	}
 	return &NotificationDomainService{
-		sender: sender,
+		sender:         sender,
+		renderer:       renderer,
+		preferenceRepo: preferenceRepo,
+		rateLimiter:    rateLimiter,
+		policy:         policy,
 	}
 }
 
 // NotificationRequest 通知请求
 type NotificationRequest struct {
-	UserID    int64                  // 用户ID
+	UserID    int64                  // 用户ID，不为 0 时按用户偏好与频率限制检查
+	Type      string                 // 通知类型，如 feeding_reminder，为空时视为 general
 	Channel   NotificationChannel    // 通知渠道
 	Phone     valueobject.Phone      // 手机号（短信渠道）
 	Email     valueobject.Email      // 邮箱（邮件渠道）
 	OpenID    string                 // 微信OpenID（微信渠道）
-	Title     string                 // 标题
-	Content   string                 // 内容
+	Locale    string                 // 语言，如 zh-CN，为空时使用默认语言
+	Variables map[string]interface{} // 模板变量，通知类型注册了模板时按模板渲染
+	Title     string                 // 标题，未使用模板时直接发送
+	Content   string                 // 内容，未使用模板时直接发送
+	HTML      bool                   // 邮件内容是否为 HTML
 	ExtraData map[string]interface{} // 额外数据
 }
 
 // SendNotification 发送通知
-// 根据渠道类型选择合适的发送方式
+// 1. 通知类型注册了模板时按模板渲染渠道内容，变量缺失或类型不符直接返回参数错误
+// 2. 按用户偏好、免打扰时段与频率限制检查，被拦截时返回 ErrNotificationSuppressed
+// 3. 根据渠道类型选择合适的发送方式
 func (s *NotificationDomainService) SendNotification(ctx context.Context, req NotificationRequest) error {
+	if err := s.render(&req); err != nil {
+		return err
+	}
+
+	if req.UserID != 0 {
+		ok, err := s.ShouldNotify(ctx, req.UserID, req.Type, req.Channel)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotificationSuppressed
+		}
+	}
+
 	switch req.Channel {
 	case ChannelSMS:
 		if req.Phone.IsEmpty() {
@@ -69,7 +155,7 @@ func (s *NotificationDomainService) SendNotification(ctx context.Context, req No
 		if req.Email.IsEmpty() {
 			return errors.New("发送邮件需要提供邮箱地址")
 		}
-		return s.sender.SendEmail(ctx, req.Email, req.Title, req.Content)
+		return s.sender.SendEmail(ctx, req.Email, req.Title, req.Content, req.HTML)
 
 	case ChannelPush:
 		if req.UserID == 0 {
@@ -92,6 +178,49 @@ func (s *NotificationDomainService) SendNotification(ctx context.Context, req No
 	}
 }
 
+// render 按模板渲染通知内容，覆盖请求中的标题、正文与微信模板数据
+func (s *NotificationDomainService) render(req *NotificationRequest) error {
+	if req.Type == "" {
+		return nil
+	}
+
+	rendered, ok, err := s.renderer.Render(req.Type, req.Locale, req.Channel, req.Variables)
+	if err != nil || !ok {
+		return err
+	}
+
+	req.Title = rendered.Title
+	req.Content = rendered.Content
+	req.HTML = rendered.HTML
+	if req.Channel == ChannelWechat {
+		extra := make(map[string]interface{}, len(rendered.Data)+2)
+		for k, v := range rendered.Data {
+			extra[k] = v
+		}
+		extra["template_id"] = rendered.TemplateID
+		if rendered.Page != "" {
+			extra["page"] = rendered.Page
+		}
+		req.ExtraData = extra
+	}
+	return nil
+}
+
+// ValidateTemplate 校验通知能否按模板渲染，返回通知类型是否注册了模板
+// 用于定时通知等延后发送的场景，在创建时即发现变量缺失或类型不符
+func (s *NotificationDomainService) ValidateTemplate(req NotificationRequest) (bool, error) {
+	if req.Type == "" {
+		return false, nil
+	}
+	_, ok, err := s.renderer.Render(req.Type, req.Locale, req.Channel, req.Variables)
+	return ok, err
+}
+
+// SupportsChannel 渠道是否已启用，未启用渠道的通知无法投递
+func (s *NotificationDomainService) SupportsChannel(channel NotificationChannel) bool {
+	return s.sender.Supports(channel)
+}
+
 // SendMultiChannelNotification 多渠道发送通知
 // 尝试使用多个渠道发送通知，任一成功即返回
 func (s *NotificationDomainService) SendMultiChannelNotification(ctx context.Context, channels []NotificationChannel, req NotificationRequest) error {
@@ -114,15 +243,49 @@ func (s *NotificationDomainService) SendMultiChannelNotification(ctx context.Con
 	return errors.New("没有指定通知渠道")
 }
 
-// ShouldNotify 判断是否应该发送通知
-// 实现通知频率限制、用户偏好等领域规则
-func (s *NotificationDomainService) ShouldNotify(ctx context.Context, userID int64, notificationType string) (bool, error) {
-	// 这里可以实现更复杂的逻辑：
-	// 1. 检查用户的通知偏好设置
-	// 2. 检查通知频率限制
-	// 3. 检查用户是否在免打扰时段
-	// 4. 检查通知类型的重要程度
+// ShouldNotify 判断是否应该向用户发送某类通知
+// 依次检查：1. 用户是否退订了该类型在该渠道上的通知；2. 是否处于用户的免打扰时段；
+// 3. 是否超过该类型的频率限制。紧急通知类型不受 2、3 约束。
+// 频率计数在前两项通过后才会累加，被偏好拦截的通知不占用额度
+func (s *NotificationDomainService) ShouldNotify(ctx context.Context, userID int64, notificationType string, channel NotificationChannel) (bool, error) {
+	if notificationType == "" {
+		notificationType = NotificationTypeGeneral
+	}
+
+	preference, err := s.preferenceRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if !isNotFound(err) {
+			return false, errors.Wrap(err, "查询通知偏好失败")
+		}
+		preference = &entity.NotificationPreference{UserID: userID}
+	}
+
+	if !preference.IsChannelEnabled(notificationType, string(channel)) {
+		return false, nil
+	}
+	if s.policy.UrgentTypes[notificationType] {
+		return true, nil
+	}
+	if preference.InQuietHours(time.Now(), s.policy.DefaultLocation) {
+		return false, nil
+	}
+
+	limit, ok := s.policy.TypeRateLimits[notificationType]
+	if !ok {
+		limit = s.policy.DefaultRateLimit
+	}
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		return true, nil
+	}
+	allowed, err := s.rateLimiter.Allow(ctx, userID, notificationType, limit.Limit, limit.Window)
+	if err != nil {
+		return false, errors.Wrap(err, "检查通知频率限制失败")
+	}
+	return allowed, nil
+}
 
-	// 示例：简单返回 true
-	return true, nil
+// isNotFound 判断是否为资源不存在错误
+func isNotFound(err error) bool {
+	appErr, ok := err.(*pkgerrors.AppError)
+	return ok && appErr.Code == pkgerrors.NotFound
 }
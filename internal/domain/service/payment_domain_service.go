@@ -2,21 +2,38 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
 	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	pkgerrors "github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/snowflake"
 )
 
-// PaymentStatus 支付状态
-type PaymentStatus string
+// PaymentStatus 支付状态，状态机定义见 entity.PaymentStatus
+type PaymentStatus = entity.PaymentStatus
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"   // 待支付
-	PaymentStatusPaid      PaymentStatus = "paid"      // 已支付
-	PaymentStatusFailed    PaymentStatus = "failed"    // 支付失败
-	PaymentStatusRefunded  PaymentStatus = "refunded"  // 已退款
-	PaymentStatusCancelled PaymentStatus = "cancelled" // 已取消
+	PaymentStatusPending           = entity.PaymentStatusPending           // 待支付
+	PaymentStatusPaid              = entity.PaymentStatusPaid              // 已支付
+	PaymentStatusFailed            = entity.PaymentStatusFailed            // 支付失败
+	PaymentStatusPartiallyRefunded = entity.PaymentStatusPartiallyRefunded // 部分退款
+	PaymentStatusRefunded          = entity.PaymentStatusRefunded          // 已全额退款
+	PaymentStatusCancelled         = entity.PaymentStatusCancelled         // 已取消
+)
+
+// RefundStatus 退款状态，定义见 entity.RefundStatus
+type RefundStatus = entity.RefundStatus
+
+const (
+	RefundStatusPending    = entity.RefundStatusPending    // 已登记，尚未被支付渠道受理
+	RefundStatusProcessing = entity.RefundStatusProcessing // 支付渠道处理中
+	RefundStatusSucceeded  = entity.RefundStatusSucceeded  // 退款成功
+	RefundStatusFailed     = entity.RefundStatusFailed     // 退款失败
 )
 
 // PaymentMethod 支付方式
@@ -29,48 +46,174 @@ const (
 )
 
 // PaymentGateway 支付网关接口
-// 定义与外部支付服务的交互抽象
+// 定义与外部支付服务的交互抽象，transactionID 均为商户支付单号
 type PaymentGateway interface {
-	// CreatePayment 创建支付订单
-	CreatePayment(ctx context.Context, orderID string, amount valueobject.Money, method PaymentMethod) (string, error)
+	// CreatePayment 在支付渠道创建支付订单，返回客户端拉起支付所需的参数
+	CreatePayment(ctx context.Context, req PaymentRequest) (*PrepayResult, error)
 	// QueryPayment 查询支付状态
-	QueryPayment(ctx context.Context, transactionID string) (PaymentStatus, error)
-	// RefundPayment 退款
-	RefundPayment(ctx context.Context, transactionID string, amount valueobject.Money, reason string) error
+	QueryPayment(ctx context.Context, transactionID string) (*PaymentQueryResult, error)
+	// ClosePayment 关闭未支付的订单，关闭后用户无法再支付；订单已支付时返回错误
+	ClosePayment(ctx context.Context, transactionID string) error
+	// RefundPayment 申请退款，同一商户退款单号重复提交时支付渠道不会重复退款
+	RefundPayment(ctx context.Context, req RefundRequest) (*RefundResult, error)
+	// QueryRefund 按商户退款单号查询退款结果，退款单不存在时返回 NotFound
+	QueryRefund(ctx context.Context, refundNo string) (*RefundResult, error)
+	// DownloadStatement 下载账单日期(渠道所在时区)的交易与退款对账单，只返回支付成功的交易记录与退款记录
+	DownloadStatement(ctx context.Context, billDate time.Time) ([]*StatementRecord, error)
 }
 
+const (
+	// defaultPaymentExpireTime 未指定过期时间时支付订单的有效期
+	defaultPaymentExpireTime = 15 * time.Minute
+	// refundQueryBaseInterval 退款提交后首次对账的间隔，之后每次翻倍
+	refundQueryBaseInterval = time.Minute
+	// refundQueryMaxInterval 退款对账的最大间隔
+	refundQueryMaxInterval = time.Hour
+	// maxRefundSaveAttempts 保存退款结果遇到并发修改时的最大尝试次数
+	maxRefundSaveAttempts = 3
+	// maxIdempotencyKeyLength 退款幂等键的最大长度
+	maxIdempotencyKeyLength = 64
+)
+
 // PaymentDomainService 支付领域服务
-// 处理与支付相关的复杂领域逻辑
+// 处理与支付相关的复杂领域逻辑，支付单状态按状态机变更并记录支付事件
 type PaymentDomainService struct {
-	gateway PaymentGateway
+	gateway            PaymentGateway
+	orderRepo          repository.OrderRepository
+	paymentRepo        repository.PaymentRepository
+	refundRepo         repository.RefundRepository
+	reconciliationRepo repository.ReconciliationRepository
 }
 
 // NewPaymentDomainService 创建支付领域服务
-func NewPaymentDomainService(gateway PaymentGateway) *PaymentDomainService {
+func NewPaymentDomainService(
+	gateway PaymentGateway,
+	orderRepo repository.OrderRepository,
+	paymentRepo repository.PaymentRepository,
+	refundRepo repository.RefundRepository,
+	reconciliationRepo repository.ReconciliationRepository,
+) *PaymentDomainService {
 	return &PaymentDomainService{
-		gateway: gateway,
+		gateway:            gateway,
+		orderRepo:          orderRepo,
+		paymentRepo:        paymentRepo,
+		refundRepo:         refundRepo,
+		reconciliationRepo: reconciliationRepo,
 	}
 }
 
 // PaymentRequest 支付请求
 type PaymentRequest struct {
-	OrderID     string            // 订单ID
+	OrderID     string            // 订单号
+	PaymentNo   string            // 商户支付单号，由领域服务生成，作为支付渠道的商户订单号
 	UserID      int64             // 用户ID
 	Amount      valueobject.Money // 支付金额
 	Method      PaymentMethod     // 支付方式
 	Description string            // 支付描述
-	ExpireTime  time.Duration     // 过期时间
+	ExpireTime  time.Duration     // 有效期，由领域服务换算为 ExpireAt
+	ExpireAt    time.Time         // 过期时间，与支付单的过期时间一致，支付渠道按同一时间关闭订单
+	PayerOpenID string            // 付款人微信 OpenID（微信 JSAPI/小程序支付）
+}
+
+// PrepayResult 支付渠道下单结果
+type PrepayResult struct {
+	PrepayID  string            // 渠道预支付交易标识
+	PayURL    string            // 支付链接或二维码URL
+	PayParams map[string]string // 客户端拉起支付的参数，如小程序 wx.requestPayment 的参数
+}
+
+// PaymentQueryResult 支付渠道订单查询结果
+type PaymentQueryResult struct {
+	Status        PaymentStatus // 支付状态
+	TransactionID string        // 渠道交易号，支付成功后返回
+	Paying        bool          // 用户支付中(如正在输入密码)，此时订单不能关闭
+	SucceededAt   time.Time     // 支付成功时间，支付成功后返回
 }
 
 // PaymentResult 支付结果
 type PaymentResult struct {
-	TransactionID string        // 交易ID
-	PayURL        string        // 支付链接或二维码URL
-	Status        PaymentStatus // 支付状态
-	ExpireAt      time.Time     // 过期时间
+	TransactionID string            // 交易ID，即商户支付单号
+	PayURL        string            // 支付链接或二维码URL
+	PayParams     map[string]string // 客户端拉起支付的参数
+	Status        PaymentStatus     // 支付状态
+	ExpireAt      time.Time         // 过期时间
+}
+
+// RefundRequest 支付渠道退款请求
+type RefundRequest struct {
+	PaymentNo string            // 商户支付单号
+	RefundNo  string            // 商户退款单号，重复提交同一退款单号不会重复退款
+	Amount    valueobject.Money // 退款金额
+	Total     valueobject.Money // 原支付金额
+	Reason    string            // 退款原因
+}
+
+// RefundResult 支付渠道退款结果
+type RefundResult struct {
+	ChannelRefundID string       // 渠道退款单号
+	Status          RefundStatus // 退款状态，渠道已受理时为处理中、成功或失败
+	FailReason      string       // 失败原因
+	SucceededAt     time.Time    // 退款成功时间
+}
+
+// StatementRecord 支付渠道对账单记录
+type StatementRecord struct {
+	Type            string            // 记录类型: entity.StatementRecordTrade/entity.StatementRecordRefund
+	PaymentNo       string            // 商户支付单号
+	TransactionID   string            // 渠道交易号
+	RefundNo        string            // 商户退款单号，仅退款记录
+	ChannelRefundID string            // 渠道退款单号，仅退款记录
+	RefundStatus    RefundStatus      // 退款状态，仅退款记录
+	Amount          valueobject.Money // 交易记录为订单金额，退款记录为退款金额
+	OccurredAt      time.Time         // 交易或退款时间
+}
+
+// PaymentNotification 支付渠道的支付或退款结果通知
+type PaymentNotification struct {
+	ID              string            // 通知ID
+	EventType       string            // 事件类型，如 TRANSACTION.SUCCESS、REFUND.SUCCESS
+	PaymentNo       string            // 商户支付单号
+	TransactionID   string            // 渠道交易号
+	Status          PaymentStatus     // 支付状态，仅支付通知
+	Amount          valueobject.Money // 支付通知为订单金额，退款通知为退款金额
+	RefundNo        string            // 商户退款单号，仅退款通知
+	ChannelRefundID string            // 渠道退款单号，仅退款通知
+	RefundStatus    RefundStatus      // 退款状态，仅退款通知
+	SucceededAt     time.Time         // 支付或退款成功时间
+}
+
+// CreateOrder 创建待支付订单
+func (s *PaymentDomainService) CreateOrder(ctx context.Context, userID int64, subject string, amount valueobject.Money) (*entity.Order, error) {
+	if amount.IsZero() {
+		return nil, errors.New("订单金额不能为零")
+	}
+	if subject == "" {
+		return nil, errors.New("商品描述不能为空")
+	}
+
+	now := time.Now().UnixMilli()
+	id := snowflake.Generate()
+	order := &entity.Order{
+		ID:        id,
+		OrderNo:   strconv.FormatInt(id, 10),
+		UserID:    userID,
+		Subject:   subject,
+		Amount:    amount.Amount(),
+		Currency:  string(amount.Currency()),
+		Status:    entity.OrderStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.orderRepo.Create(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
 }
 
 // CreatePayment 创建支付
+// 1. 校验订单归属、状态与金额，订单已支付或存在未过期的待支付单时拒绝
+// 2. 先落库支付单(待支付)再调用支付网关，保证回调通知到达时能找到支付单
+// 3. 支付网关下单失败时支付单标记为支付失败，订单可重新发起支付
 func (s *PaymentDomainService) CreatePayment(ctx context.Context, req PaymentRequest) (*PaymentResult, error) {
 	// 验证支付金额
 	if req.Amount.IsZero() {
@@ -87,94 +230,553 @@ func (s *PaymentDomainService) CreatePayment(ctx context.Context, req PaymentReq
 		return nil, errors.Errorf("不支持的支付方式: %s", req.Method)
 	}
 
-	// 调用支付网关创建支付
-	payURL, err := s.gateway.CreatePayment(ctx, req.OrderID, req.Amount, req.Method)
+	order, err := s.orderRepo.FindByOrderNo(ctx, req.OrderID)
 	if err != nil {
-		return nil, errors.Wrap(err, "创建支付失败")
+		return nil, err
+	}
+	if order.UserID != req.UserID {
+		return nil, pkgerrors.New(pkgerrors.PermissionDenied, "订单不属于当前用户")
+	}
+	if !order.IsPayable() {
+		return nil, pkgerrors.New(pkgerrors.Conflict, fmt.Sprintf("订单状态不允许支付: %s", order.Status))
+	}
+	if req.Amount.Amount() != order.Amount || string(req.Amount.Currency()) != order.Currency {
+		return nil, pkgerrors.New(pkgerrors.ParamError, "支付金额与订单金额不符")
 	}
 
-	// 计算过期时间
-	expireAt := time.Now().Add(req.ExpireTime)
+	// 计算过期时间，支付渠道按同一时间关闭订单
 	if req.ExpireTime == 0 {
-		expireAt = time.Now().Add(15 * time.Minute) // 默认15分钟过期
+		req.ExpireTime = defaultPaymentExpireTime
+	}
+	now := time.Now()
+	expireAt := now.Add(req.ExpireTime)
+
+	if err := s.ensureNoActivePayment(ctx, order.ID, now); err != nil {
+		return nil, err
+	}
+
+	id := snowflake.Generate()
+	payment := &entity.Payment{
+		ID:        id,
+		PaymentNo: strconv.FormatInt(id, 10),
+		OrderID:   order.ID,
+		UserID:    req.UserID,
+		Method:    string(req.Method),
+		Amount:    req.Amount.Amount(),
+		Currency:  string(req.Amount.Currency()),
+		Status:    PaymentStatusPending,
+		ExpireAt:  expireAt.UnixMilli(),
+		CreatedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+	}
+	event := &entity.PaymentEvent{
+		ID:        snowflake.Generate(),
+		PaymentID: payment.ID,
+		Type:      entity.PaymentEventCreated,
+		ToStatus:  PaymentStatusPending,
+		Source:    entity.PaymentEventSourceAPI,
+		Detail:    fmt.Sprintf("order_no=%s method=%s amount=%d", order.OrderNo, req.Method, payment.Amount),
+		CreatedAt: now.UnixMilli(),
+	}
+	// 同一订单并发发起支付时订单版本号冲突，只有一个成功
+	if err := s.paymentRepo.Create(ctx, order, payment, event); err != nil {
+		return nil, err
+	}
+
+	// 调用支付网关创建支付
+	req.PaymentNo = payment.PaymentNo
+	req.ExpireAt = expireAt
+	prepay, err := s.gateway.CreatePayment(ctx, req)
+	if err != nil {
+		_ = s.transition(ctx, payment, PaymentStatusFailed, entity.PaymentEventSourceAPI, err.Error())
+		return nil, errors.Wrap(err, "创建支付失败")
 	}
 
 	return &PaymentResult{
-		TransactionID: req.OrderID,
-		PayURL:        payURL,
+		TransactionID: payment.PaymentNo,
+		PayURL:        prepay.PayURL,
+		PayParams:     prepay.PayParams,
 		Status:        PaymentStatusPending,
 		ExpireAt:      expireAt,
 	}, nil
 }
 
 // VerifyPayment 验证支付状态
+// 本地支付单仍待支付时查询支付渠道并同步状态，已有结果时直接返回本地状态
 func (s *PaymentDomainService) VerifyPayment(ctx context.Context, transactionID string) (PaymentStatus, error) {
 	if transactionID == "" {
 		return "", errors.New("交易ID不能为空")
 	}
 
-	status, err := s.gateway.QueryPayment(ctx, transactionID)
+	payment, err := s.paymentRepo.FindByPaymentNo(ctx, transactionID)
+	if err != nil {
+		return "", err
+	}
+	if payment.Status != PaymentStatusPending {
+		return payment.Status, nil
+	}
+
+	result, err := s.gateway.QueryPayment(ctx, transactionID)
 	if err != nil {
 		return "", errors.Wrap(err, "查询支付状态失败")
 	}
+	if err := s.syncStatus(ctx, payment, result.Status, entity.PaymentEventSourceQuery, result.TransactionID, result.SucceededAt, ""); err != nil {
+		return "", err
+	}
 
-	return status, nil
+	return payment.Status, nil
+}
+
+// HandleNotification 处理支付渠道的支付或退款结果通知
+// 通知可能重复或乱序到达，状态已是目标状态或为乱序到达的旧状态时直接忽略
+func (s *PaymentDomainService) HandleNotification(ctx context.Context, n *PaymentNotification) error {
+	payment, err := s.paymentRepo.FindByPaymentNo(ctx, n.PaymentNo)
+	if err != nil {
+		return err
+	}
+
+	if n.RefundNo != "" {
+		refund, err := s.refundRepo.FindByRefundNo(ctx, n.RefundNo)
+		if err != nil {
+			return err
+		}
+		if refund.PaymentID != payment.ID {
+			return pkgerrors.New(pkgerrors.ParamError, fmt.Sprintf("退款单 %s 不属于支付单 %s", n.RefundNo, n.PaymentNo))
+		}
+		if refund.Status.IsFinal() {
+			return nil
+		}
+		if n.RefundStatus == RefundStatusSucceeded && !n.Amount.Equals(refund.Money()) {
+			return pkgerrors.New(pkgerrors.ParamError, fmt.Sprintf("退款金额 %s 与退款单金额 %s 不符", n.Amount, refund.Money()))
+		}
+		result := &RefundResult{
+			ChannelRefundID: n.ChannelRefundID,
+			Status:          n.RefundStatus,
+			FailReason:      fmt.Sprintf("notify_id=%s event_type=%s", n.ID, n.EventType),
+			SucceededAt:     n.SucceededAt,
+		}
+		_, err = s.applyRefundResult(ctx, payment, refund, result, entity.PaymentEventSourceNotify)
+		return err
+	}
+
+	if n.Status == PaymentStatusPaid && !n.Amount.Equals(payment.Money()) {
+		return pkgerrors.New(pkgerrors.ParamError, fmt.Sprintf("支付金额 %s 与支付单金额 %s 不符", n.Amount, payment.Money()))
+	}
+	if n.Status == PaymentStatusPaid && (payment.Status == PaymentStatusCancelled || payment.Status == PaymentStatusFailed) {
+		return s.flagPaidAfterClose(ctx, payment, n)
+	}
+	detail := fmt.Sprintf("notify_id=%s transaction_id=%s", n.ID, n.TransactionID)
+	return s.syncStatus(ctx, payment, n.Status, entity.PaymentEventSourceNotify, n.TransactionID, n.SucceededAt, detail)
+}
+
+// CancelExpired 取消已过期仍待支付的支付单，返回取消的条数
+// 取消前查询支付渠道：过期前已支付(回调通知丢失)的支付单同步为已支付；用户支付中的保持待支付，下次再处理；
+// 未支付的先在支付渠道关闭订单再取消，关闭失败(如恰好支付成功)时不取消，避免之后的支付无法入账
+func (s *PaymentDomainService) CancelExpired(ctx context.Context, now time.Time, limit int) (int, error) {
+	payments, err := s.paymentRepo.ListExpiredPending(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	var lastErr error
+	for _, payment := range payments {
+		status, transactionID, paidAt := PaymentStatusCancelled, "", time.Time{}
+		result, err := s.gateway.QueryPayment(ctx, payment.PaymentNo)
+		switch {
+		case pkgerrors.CodeOf(err) == pkgerrors.NotFound:
+			// 渠道侧未创建订单(如下单请求未送达)
+		case err != nil:
+			lastErr = err
+			continue
+		case result.Paying:
+			continue
+		case result.Status == PaymentStatusPending:
+			if err := s.gateway.ClosePayment(ctx, payment.PaymentNo); err != nil {
+				lastErr = err
+				continue
+			}
+		default:
+			status, transactionID, paidAt = result.Status, result.TransactionID, result.SucceededAt
+		}
+
+		if err := s.syncStatus(ctx, payment, status, entity.PaymentEventSourceJob, transactionID, paidAt, "expired"); err != nil {
+			lastErr = err
+			continue
+		}
+		if payment.Status == PaymentStatusCancelled {
+			cancelled++
+		}
+	}
+
+	return cancelled, lastErr
 }
 
 // ProcessRefund 处理退款
-func (s *PaymentDomainService) ProcessRefund(ctx context.Context, transactionID string, amount valueobject.Money, reason string) error {
+// 1. 同一支付单内按幂等键去重，重复请求返回已有退款单，金额不同时返回 Conflict
+// 2. 登记退款单并占用退款额度，已退款与处理中的金额之和不超过支付金额，支持多次部分退款
+// 3. 提交支付渠道，渠道明确拒绝时退款失败并释放额度；请求结果未知时保持待处理，由对账任务重新提交
+// 退款由支付渠道异步处理，结果由退款通知或 ReconcileRefunds 回写
+func (s *PaymentDomainService) ProcessRefund(ctx context.Context, transactionID string, amount valueobject.Money, reason, idempotencyKey string) (*entity.Refund, error) {
 	if transactionID == "" {
-		return errors.New("交易ID不能为空")
+		return nil, errors.New("交易ID不能为空")
 	}
 
 	if amount.IsZero() {
-		return errors.New("退款金额不能为零")
+		return nil, errors.New("退款金额不能为零")
+	}
+
+	if idempotencyKey == "" || len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, pkgerrors.New(pkgerrors.ParamError, fmt.Sprintf("退款幂等键不能为空且不超过 %d 个字符", maxIdempotencyKeyLength))
 	}
 
 	if reason == "" {
 		reason = "用户申请退款"
 	}
 
-	// 先查询支付状态
-	status, err := s.gateway.QueryPayment(ctx, transactionID)
+	payment, err := s.paymentRepo.FindByPaymentNo(ctx, transactionID)
 	if err != nil {
-		return errors.Wrap(err, "查询支付状态失败")
+		return nil, err
 	}
 
-	// 只有已支付的订单才能退款
-	if status != PaymentStatusPaid {
-		return errors.Errorf("当前支付状态不支持退款: %s", status)
+	existing, err := s.refundRepo.FindByIdempotencyKey(ctx, payment.ID, idempotencyKey)
+	if err == nil {
+		return s.replayRefund(existing, amount)
+	}
+	if pkgerrors.CodeOf(err) != pkgerrors.NotFound {
+		return nil, err
 	}
 
-	// 调用支付网关退款
-	if err := s.gateway.RefundPayment(ctx, transactionID, amount, reason); err != nil {
-		return errors.Wrap(err, "退款失败")
+	// 只有已支付或部分退款的支付单才能退款
+	if !payment.IsRefundable() {
+		return nil, pkgerrors.New(pkgerrors.Conflict, fmt.Sprintf("当前支付状态不支持退款: %s", payment.Status))
+	}
+	if amount.Currency() != payment.Money().Currency() {
+		return nil, pkgerrors.New(pkgerrors.ParamError, "退款币种与支付币种不符")
+	}
+	if refundable := payment.RefundableMoney(); amount.GreaterThan(refundable) {
+		return nil, pkgerrors.New(pkgerrors.ParamError, fmt.Sprintf("退款金额超出可退款金额 %s", refundable))
 	}
 
-	return nil
+	now := time.Now()
+	id := snowflake.Generate()
+	refund := &entity.Refund{
+		ID:             id,
+		RefundNo:       "R" + strconv.FormatInt(id, 10),
+		PaymentID:      payment.ID,
+		IdempotencyKey: idempotencyKey,
+		Amount:         amount.Amount(),
+		Currency:       string(amount.Currency()),
+		Reason:         reason,
+		Status:         RefundStatusPending,
+		NextQueryAt:    now.Add(refundQueryBaseInterval).UnixMilli(),
+		CreatedAt:      now.UnixMilli(),
+		UpdatedAt:      now.UnixMilli(),
+	}
+	event, err := payment.ReserveRefund(refund, now)
+	if err != nil {
+		return nil, pkgerrors.Wrap(pkgerrors.Conflict, "支付状态不允许该操作", err)
+	}
+	event.ID = snowflake.Generate()
+
+	// 同一支付单并发退款时支付单版本号冲突，只有一个成功；同一幂等键并发请求时返回已登记的退款单
+	if err := s.refundRepo.Create(ctx, payment, refund, event); err != nil {
+		if pkgerrors.CodeOf(err) == pkgerrors.Conflict {
+			if existing, findErr := s.refundRepo.FindByIdempotencyKey(ctx, payment.ID, idempotencyKey); findErr == nil {
+				return s.replayRefund(existing, amount)
+			}
+		}
+		return nil, err
+	}
+
+	return s.submitRefund(ctx, payment, refund, entity.PaymentEventSourceAPI)
+}
+
+// ReconcileRefunds 对账未完成的退款单，返回已完成(成功或失败)的条数
+// 渠道仍在处理时按指数退避安排下次对账；渠道不存在该退款单(提交请求未送达)时重新提交
+func (s *PaymentDomainService) ReconcileRefunds(ctx context.Context, now time.Time, limit int) (int, error) {
+	refunds, err := s.refundRepo.ListDue(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	var lastErr error
+	for _, refund := range refunds {
+		payment, err := s.paymentRepo.FindByID(ctx, refund.PaymentID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		result, err := s.gateway.QueryRefund(ctx, refund.RefundNo)
+		switch {
+		case pkgerrors.CodeOf(err) == pkgerrors.NotFound && refund.Status == RefundStatusPending:
+			refund, err = s.submitRefund(ctx, payment, refund, entity.PaymentEventSourceJob)
+		case err != nil:
+			refund.ScheduleNextQuery(now, refundQueryBaseInterval, refundQueryMaxInterval)
+			if updateErr := s.refundRepo.Update(ctx, refund); updateErr != nil {
+				err = updateErr
+			}
+		default:
+			refund, err = s.applyRefundResult(ctx, payment, refund, result, entity.PaymentEventSourceJob)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if refund.Status.IsFinal() {
+			completed++
+		}
+	}
+
+	return completed, lastErr
 }
 
 // CanRefund 判断是否可以退款
-func (s *PaymentDomainService) CanRefund(ctx context.Context, transactionID string, refundAmount valueobject.Money, paidAmount valueobject.Money) (bool, string, error) {
-	// 查询支付状态
-	status, err := s.gateway.QueryPayment(ctx, transactionID)
+func (s *PaymentDomainService) CanRefund(ctx context.Context, transactionID string, refundAmount valueobject.Money) (bool, string, error) {
+	payment, err := s.paymentRepo.FindByPaymentNo(ctx, transactionID)
 	if err != nil {
-		return false, "", errors.Wrap(err, "查询支付状态失败")
+		return false, "", errors.Wrap(err, "查询支付单失败")
 	}
 
 	// 检查状态
-	if status != PaymentStatusPaid {
-		return false, "订单未支付或已退款", nil
+	if !payment.IsRefundable() {
+		return false, "订单未支付或已全额退款", nil
 	}
 
-	// 检查退款金额
-	if refundAmount.GreaterThan(paidAmount) {
-		return false, "退款金额不能大于支付金额", nil
+	// 检查退款金额，已退款与处理中的退款都占用额度
+	if refundable := payment.RefundableMoney(); refundAmount.Currency() != refundable.Currency() || refundAmount.GreaterThan(refundable) {
+		return false, fmt.Sprintf("退款金额超出可退款金额 %s", refundable), nil
 	}
 
 	return true, "", nil
 }
 
+// GetPayment 查询支付单
+func (s *PaymentDomainService) GetPayment(ctx context.Context, transactionID string) (*entity.Payment, error) {
+	return s.paymentRepo.FindByPaymentNo(ctx, transactionID)
+}
+
+// ListRefunds 查询支付单的退款单
+func (s *PaymentDomainService) ListRefunds(ctx context.Context, transactionID string) ([]*entity.Refund, error) {
+	payment, err := s.paymentRepo.FindByPaymentNo(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.refundRepo.ListByPaymentID(ctx, payment.ID)
+}
+
+// ListPaymentEvents 查询支付单的事件
+func (s *PaymentDomainService) ListPaymentEvents(ctx context.Context, transactionID string) ([]*entity.PaymentEvent, error) {
+	payment, err := s.paymentRepo.FindByPaymentNo(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.ListEvents(ctx, payment.ID)
+}
+
+// replayRefund 幂等键重复时返回已登记的退款单，金额不同说明幂等键被用于另一笔退款
+func (s *PaymentDomainService) replayRefund(refund *entity.Refund, amount valueobject.Money) (*entity.Refund, error) {
+	if !amount.Equals(refund.Money()) {
+		return nil, pkgerrors.New(pkgerrors.Conflict, "幂等键已用于其他退款请求")
+	}
+	return refund, nil
+}
+
+// submitRefund 向支付渠道提交退款并保存受理结果
+// 渠道明确拒绝(参数错误、状态冲突、订单不存在)时退款失败；网络错误等结果未知时保持待处理，等待对账重新提交
+func (s *PaymentDomainService) submitRefund(ctx context.Context, payment *entity.Payment, refund *entity.Refund, source string) (*entity.Refund, error) {
+	result, err := s.gateway.RefundPayment(ctx, RefundRequest{
+		PaymentNo: payment.PaymentNo,
+		RefundNo:  refund.RefundNo,
+		Amount:    refund.Money(),
+		Total:     payment.Money(),
+		Reason:    refund.Reason,
+	})
+	switch pkgerrors.CodeOf(err) {
+	case pkgerrors.Success:
+		return s.applyRefundResult(ctx, payment, refund, result, source)
+	case pkgerrors.ParamError, pkgerrors.Conflict, pkgerrors.NotFound:
+		failed := &RefundResult{Status: RefundStatusFailed, FailReason: err.Error()}
+		if _, saveErr := s.applyRefundResult(ctx, payment, refund, failed, source); saveErr != nil {
+			return nil, saveErr
+		}
+		return nil, errors.Wrap(err, "退款失败")
+	default:
+		refund.ScheduleNextQuery(time.Now(), refundQueryBaseInterval, refundQueryMaxInterval)
+		if updateErr := s.refundRepo.Update(ctx, refund); updateErr != nil && pkgerrors.CodeOf(updateErr) != pkgerrors.Conflict {
+			return nil, updateErr
+		}
+		return refund, nil
+	}
+}
+
+// applyRefundResult 保存支付渠道返回的退款结果，返回保存后的退款单
+// 退款成功时计入支付单已退款金额并变更支付状态，失败时释放退款额度，处理中时安排下次对账；
+// 退款单或支付单被并发修改(重复通知、对账任务、同一支付单的其他退款)时重新加载后重试
+func (s *PaymentDomainService) applyRefundResult(ctx context.Context, payment *entity.Payment, refund *entity.Refund, result *RefundResult, source string) (*entity.Refund, error) {
+	for attempt := 1; ; attempt++ {
+		err := s.saveRefundResult(ctx, payment, refund, result, source)
+		if pkgerrors.CodeOf(err) != pkgerrors.Conflict || attempt >= maxRefundSaveAttempts {
+			return refund, err
+		}
+
+		if refund, err = s.refundRepo.FindByRefundNo(ctx, refund.RefundNo); err != nil {
+			return nil, err
+		}
+		if refund.Status.IsFinal() {
+			return refund, nil
+		}
+		if payment, err = s.paymentRepo.FindByID(ctx, refund.PaymentID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// saveRefundResult 按退款结果更新退款单与支付单并保存
+func (s *PaymentDomainService) saveRefundResult(ctx context.Context, payment *entity.Payment, refund *entity.Refund, result *RefundResult, source string) error {
+	now := time.Now()
+	refund.UpdatedAt = now.UnixMilli()
+	if result.ChannelRefundID != "" {
+		refund.ChannelRefundID = result.ChannelRefundID
+	}
+
+	var event *entity.PaymentEvent
+	switch result.Status {
+	case RefundStatusSucceeded:
+		refund.Status = RefundStatusSucceeded
+		refund.SucceededAt = now.UnixMilli()
+		if !result.SucceededAt.IsZero() {
+			refund.SucceededAt = result.SucceededAt.UnixMilli()
+		}
+		var err error
+		if event, err = payment.ApplyRefund(refund, source, now); err != nil {
+			return pkgerrors.Wrap(pkgerrors.Conflict, "支付状态不允许该操作", err)
+		}
+
+	case RefundStatusFailed:
+		refund.Status = RefundStatusFailed
+		refund.FailReason = result.FailReason
+		event = payment.ReleaseRefund(refund, source, now)
+
+	default:
+		// 渠道已受理，仍在处理中
+		refund.Status = RefundStatusProcessing
+		refund.ScheduleNextQuery(now, refundQueryBaseInterval, refundQueryMaxInterval)
+		return s.refundRepo.Update(ctx, refund)
+	}
+
+	event.ID = snowflake.Generate()
+	return s.refundRepo.Complete(ctx, payment, refund, event)
+}
+
+// ensureNoActivePayment 校验订单没有已支付或未过期的待支付单，避免重复支付
+func (s *PaymentDomainService) ensureNoActivePayment(ctx context.Context, orderID int64, now time.Time) error {
+	payments, err := s.paymentRepo.ListByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, payment := range payments {
+		switch {
+		case payment.Status == PaymentStatusPending && !payment.IsExpired(now):
+			return pkgerrors.New(pkgerrors.Conflict, "订单已有待支付的支付单")
+		case payment.OrderStatus() != "":
+			return pkgerrors.New(pkgerrors.Conflict, "订单已支付")
+		}
+	}
+	return nil
+}
+
+// syncStatus 将支付渠道返回的支付状态同步到支付单
+// 支付渠道仍为待支付时不变更；状态已是目标状态或为乱序到达的旧状态时忽略；transactionID 不为空时回填渠道交易号，paidAt 为渠道的支付成功时间
+func (s *PaymentDomainService) syncStatus(ctx context.Context, payment *entity.Payment, status PaymentStatus, source, transactionID string, paidAt time.Time, detail string) error {
+	switch status {
+	case PaymentStatusPending, payment.Status:
+		return nil
+	case PaymentStatusRefunded, PaymentStatusPartiallyRefunded:
+		// 本地尚未记录支付成功时先补记，退款由退款通知更新
+		if payment.Status != PaymentStatusPending {
+			return nil
+		}
+		status = PaymentStatusPaid
+	case PaymentStatusCancelled, PaymentStatusFailed:
+		// 关闭与失败只发生在待支付时，支付单已不是待支付时为乱序到达的旧状态
+		if payment.Status != PaymentStatusPending {
+			return nil
+		}
+	}
+
+	if transactionID != "" {
+		payment.TransactionID = transactionID
+	}
+	// 记录支付渠道的支付成功时间而非处理时间，对账按该时间划分账单日期；渠道未返回时由 TransitionTo 记为当前时间
+	if status == PaymentStatusPaid && payment.PaidAt == 0 && !paidAt.IsZero() {
+		payment.PaidAt = paidAt.UnixMilli()
+	}
+	return s.transition(ctx, payment, status, source, detail)
+}
+
+// transition 按状态机变更支付状态并保存，状态已是目标状态时忽略(部分退款可重复变更)
+func (s *PaymentDomainService) transition(ctx context.Context, payment *entity.Payment, to PaymentStatus, source, detail string) error {
+	if payment.Status == to && to != PaymentStatusPartiallyRefunded {
+		return nil
+	}
+
+	event, err := payment.TransitionTo(to, source, detail, time.Now())
+	if err != nil {
+		return pkgerrors.Wrap(pkgerrors.Conflict, "支付状态不允许该操作", err)
+	}
+	event.ID = snowflake.Generate()
+
+	return s.paymentRepo.Transition(ctx, payment, event)
+}
+
+// flagPaidAfterClose 记录已取消或失败的支付单收到的支付成功通知
+// 支付单状态不再变更(订单可能已重新支付)，追加支付事件并登记对账差异，由人工退款或处理；重复通知忽略
+func (s *PaymentDomainService) flagPaidAfterClose(ctx context.Context, payment *entity.Payment, n *PaymentNotification) error {
+	if payment.TransactionID != "" && payment.TransactionID == n.TransactionID {
+		return nil
+	}
+
+	now := time.Now()
+	detail := fmt.Sprintf("notify_id=%s transaction_id=%s", n.ID, n.TransactionID)
+	payment.TransactionID = n.TransactionID
+	payment.UpdatedAt = now.UnixMilli()
+	event := &entity.PaymentEvent{
+		ID:         snowflake.Generate(),
+		PaymentID:  payment.ID,
+		Type:       entity.PaymentEventPaidAfterClose,
+		FromStatus: payment.Status,
+		ToStatus:   payment.Status,
+		Source:     entity.PaymentEventSourceNotify,
+		Detail:     detail,
+		CreatedAt:  now.UnixMilli(),
+	}
+	if err := s.paymentRepo.Transition(ctx, payment, event); err != nil {
+		return err
+	}
+
+	paidAt := n.SucceededAt
+	if paidAt.IsZero() {
+		paidAt = now
+	}
+	return s.reconciliationRepo.Flag(ctx, &entity.ReconciliationDiscrepancy{
+		ID:            snowflake.Generate(),
+		BillDate:      paidAt.In(BillLocation).Format("2006-01-02"),
+		Type:          entity.DiscrepancyPaidAfterClose,
+		RecordType:    entity.StatementRecordTrade,
+		PaymentNo:     payment.PaymentNo,
+		TransactionID: n.TransactionID,
+		LocalAmount:   payment.Amount,
+		RemoteAmount:  n.Amount.Amount(),
+		Detail:        fmt.Sprintf("本地支付状态: %s，需退款或人工处理", payment.Status),
+		Status:        entity.DiscrepancyStatusOpen,
+		CreatedAt:     now.UnixMilli(),
+		UpdatedAt:     now.UnixMilli(),
+	})
+}
+
 // isValidPaymentMethod 验证支付方式
 func (s *PaymentDomainService) isValidPaymentMethod(method PaymentMethod) bool {
 	switch method {
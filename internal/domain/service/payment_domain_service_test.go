@@ -0,0 +1,802 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	pkgerrors "github.com/wxlbd/polaris/pkg/errors"
+)
+
+// fakePaymentStore 内存中的支付单与退款单，按版本号模拟乐观锁
+type fakePaymentStore struct {
+	mu       sync.Mutex
+	payments map[string]*entity.Payment
+	refunds  map[string]*entity.Refund
+	events   []*entity.PaymentEvent
+	// discrepancies 登记的对账差异
+	discrepancies []*entity.ReconciliationDiscrepancy
+}
+
+// 各仓储的内存实现共用同一份数据，未用到的方法由嵌入的接口提供，调用时 panic
+type (
+	fakePaymentRepo struct {
+		repository.PaymentRepository
+		*fakePaymentStore
+	}
+	fakeRefundRepo struct {
+		repository.RefundRepository
+		*fakePaymentStore
+	}
+	fakeReconciliationRepo struct {
+		repository.ReconciliationRepository
+		*fakePaymentStore
+	}
+)
+
+func newFakePaymentStore() *fakePaymentStore {
+	return &fakePaymentStore{
+		payments: make(map[string]*entity.Payment),
+		refunds:  make(map[string]*entity.Refund),
+	}
+}
+
+func (f fakePaymentRepo) FindByID(ctx context.Context, id int64) (*entity.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, payment := range f.payments {
+		if payment.ID == id {
+			clone := *payment
+			return &clone, nil
+		}
+	}
+	return nil, pkgerrors.New(pkgerrors.NotFound, "payment not found")
+}
+
+func (f fakePaymentRepo) FindByPaymentNo(ctx context.Context, paymentNo string) (*entity.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payment, ok := f.payments[paymentNo]
+	if !ok {
+		return nil, pkgerrors.New(pkgerrors.NotFound, "payment not found")
+	}
+	clone := *payment
+	return &clone, nil
+}
+
+func (f fakePaymentRepo) ListExpiredPending(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var payments []*entity.Payment
+	for _, payment := range f.payments {
+		if payment.IsExpired(now) {
+			clone := *payment
+			payments = append(payments, &clone)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].PaymentNo < payments[j].PaymentNo })
+	return payments[:min(limit, len(payments))], nil
+}
+
+func (f fakePaymentRepo) Transition(ctx context.Context, payment *entity.Payment, event *entity.PaymentEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.savePayment(payment, event)
+}
+
+func (f fakeRefundRepo) Create(ctx context.Context, payment *entity.Payment, refund *entity.Refund, event *entity.PaymentEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkVersion(payment); err != nil {
+		return err
+	}
+	for _, existing := range f.refunds {
+		if existing.PaymentID == refund.PaymentID && existing.IdempotencyKey == refund.IdempotencyKey {
+			return pkgerrors.New(pkgerrors.Conflict, "duplicate idempotency key")
+		}
+	}
+	clone := *refund
+	f.refunds[refund.RefundNo] = &clone
+	return f.savePayment(payment, event)
+}
+
+func (f fakeRefundRepo) FindByRefundNo(ctx context.Context, refundNo string) (*entity.Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	refund, ok := f.refunds[refundNo]
+	if !ok {
+		return nil, pkgerrors.New(pkgerrors.NotFound, "refund not found")
+	}
+	clone := *refund
+	return &clone, nil
+}
+
+func (f fakeRefundRepo) FindByIdempotencyKey(ctx context.Context, paymentID int64, key string) (*entity.Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, refund := range f.refunds {
+		if refund.PaymentID == paymentID && refund.IdempotencyKey == key {
+			clone := *refund
+			return &clone, nil
+		}
+	}
+	return nil, pkgerrors.New(pkgerrors.NotFound, "refund not found")
+}
+
+func (f fakeRefundRepo) Update(ctx context.Context, refund *entity.Refund) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refunds[refund.RefundNo].Status.IsFinal() {
+		return pkgerrors.New(pkgerrors.Conflict, "refund already completed")
+	}
+	clone := *refund
+	f.refunds[refund.RefundNo] = &clone
+	return nil
+}
+
+func (f fakeRefundRepo) Complete(ctx context.Context, payment *entity.Payment, refund *entity.Refund, event *entity.PaymentEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refunds[refund.RefundNo].Status.IsFinal() {
+		return pkgerrors.New(pkgerrors.Conflict, "refund already completed")
+	}
+	if err := f.savePayment(payment, event); err != nil {
+		return err
+	}
+	clone := *refund
+	f.refunds[refund.RefundNo] = &clone
+	return nil
+}
+
+// Flag 登记对账差异，同一类型、支付单与渠道交易号只登记一次
+func (f fakeReconciliationRepo) Flag(ctx context.Context, discrepancy *entity.ReconciliationDiscrepancy) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.discrepancies {
+		if existing.Type == discrepancy.Type && existing.PaymentNo == discrepancy.PaymentNo && existing.TransactionID == discrepancy.TransactionID {
+			return nil
+		}
+	}
+	f.discrepancies = append(f.discrepancies, discrepancy)
+	return nil
+}
+
+// checkVersion 校验支付单版本号，调用方持有锁
+func (f *fakePaymentStore) checkVersion(payment *entity.Payment) error {
+	if f.payments[payment.PaymentNo].Version != payment.Version {
+		return pkgerrors.New(pkgerrors.Conflict, "payment version mismatch")
+	}
+	return nil
+}
+
+// savePayment 按版本号保存支付单并追加事件，调用方持有锁
+func (f *fakePaymentStore) savePayment(payment *entity.Payment, event *entity.PaymentEvent) error {
+	if err := f.checkVersion(payment); err != nil {
+		return err
+	}
+	payment.Version++
+	clone := *payment
+	f.payments[payment.PaymentNo] = &clone
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakePaymentStore) payment(paymentNo string) *entity.Payment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clone := *f.payments[paymentNo]
+	return &clone
+}
+
+func (f *fakePaymentStore) refund(refundNo string) *entity.Refund {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clone := *f.refunds[refundNo]
+	return &clone
+}
+
+func (f *fakePaymentStore) eventTypes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	types := make([]string, 0, len(f.events))
+	for _, event := range f.events {
+		types = append(types, event.Type+":"+string(event.ToStatus))
+	}
+	return types
+}
+
+// fakeGateway 支付渠道的内存实现，按商户支付单号返回预设的查询结果
+type fakeGateway struct {
+	PaymentGateway
+
+	mu       sync.Mutex
+	queries  map[string]*PaymentQueryResult
+	queryErr map[string]error
+	closeErr error
+	closed   []string
+	// onQuery 查询支付单时调用，用于模拟查询期间的并发修改
+	onQuery func(paymentNo string)
+
+	refundResult *RefundResult
+	refundErr    error
+	refunds      []RefundRequest
+	// onRefund 提交退款时调用，用于模拟退款处理期间的其他请求
+	onRefund func(req RefundRequest)
+}
+
+func newFakeGateway() *fakeGateway {
+	return &fakeGateway{
+		queries:  make(map[string]*PaymentQueryResult),
+		queryErr: make(map[string]error),
+	}
+}
+
+func (g *fakeGateway) QueryPayment(ctx context.Context, transactionID string) (*PaymentQueryResult, error) {
+	if g.onQuery != nil {
+		g.onQuery(transactionID)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.queryErr[transactionID]; err != nil {
+		return nil, err
+	}
+	if result, ok := g.queries[transactionID]; ok {
+		return result, nil
+	}
+	return &PaymentQueryResult{Status: PaymentStatusPending}, nil
+}
+
+func (g *fakeGateway) ClosePayment(ctx context.Context, transactionID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closeErr != nil {
+		return g.closeErr
+	}
+	g.closed = append(g.closed, transactionID)
+	return nil
+}
+
+func (g *fakeGateway) RefundPayment(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	if g.onRefund != nil {
+		g.onRefund(req)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refunds = append(g.refunds, req)
+	if g.refundErr != nil {
+		return nil, g.refundErr
+	}
+	if g.refundResult != nil {
+		return g.refundResult, nil
+	}
+	return &RefundResult{ChannelRefundID: "5000" + req.RefundNo, Status: RefundStatusProcessing}, nil
+}
+
+func (g *fakeGateway) refundRequests() []RefundRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]RefundRequest(nil), g.refunds...)
+}
+
+func (g *fakeGateway) closedPayments() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.closed...)
+}
+
+func newTestPaymentService(store *fakePaymentStore) *PaymentDomainService {
+	return newTestPaymentServiceWithGateway(store, nil)
+}
+
+func newTestPaymentServiceWithGateway(store *fakePaymentStore, gateway PaymentGateway) *PaymentDomainService {
+	return NewPaymentDomainService(gateway, nil, fakePaymentRepo{fakePaymentStore: store},
+		fakeRefundRepo{fakePaymentStore: store}, fakeReconciliationRepo{fakePaymentStore: store})
+}
+
+func newPendingPayment(store *fakePaymentStore) *entity.Payment {
+	return addPendingPayment(store, 1, "P1", time.Now().Add(15*time.Minute))
+}
+
+func addPendingPayment(store *fakePaymentStore, id int64, paymentNo string, expireAt time.Time) *entity.Payment {
+	payment := &entity.Payment{
+		ID:        id,
+		PaymentNo: paymentNo,
+		OrderID:   10,
+		Method:    string(PaymentMethodWechat),
+		Amount:    100,
+		Currency:  string(valueobject.CurrencyCNY),
+		Status:    PaymentStatusPending,
+		ExpireAt:  expireAt.UnixMilli(),
+	}
+	store.payments[payment.PaymentNo] = payment
+	return payment
+}
+
+func paidNotification(amount int64, succeededAt time.Time) *PaymentNotification {
+	money, _ := valueobject.NewMoney(amount, valueobject.CurrencyCNY)
+	return &PaymentNotification{
+		ID:            "N1",
+		EventType:     "TRANSACTION.SUCCESS",
+		PaymentNo:     "P1",
+		TransactionID: "4200000001",
+		Status:        PaymentStatusPaid,
+		Amount:        money,
+		SucceededAt:   succeededAt,
+	}
+}
+
+func TestHandleNotificationDuplicatePaid(t *testing.T) {
+	store := newFakePaymentStore()
+	newPendingPayment(store)
+	svc := newTestPaymentService(store)
+	succeededAt := time.Date(2026, 10, 17, 10, 0, 0, 0, BillLocation)
+
+	for i := 0; i < 3; i++ {
+		if err := svc.HandleNotification(t.Context(), paidNotification(100, succeededAt)); err != nil {
+			t.Fatalf("HandleNotification() #%d error = %v", i+1, err)
+		}
+	}
+
+	payment := store.payment("P1")
+	if payment.Status != PaymentStatusPaid || payment.TransactionID != "4200000001" {
+		t.Errorf("payment = %+v, want paid with transaction id", payment)
+	}
+	if payment.PaidAt != succeededAt.UnixMilli() {
+		t.Errorf("PaidAt = %d, want channel success time %d", payment.PaidAt, succeededAt.UnixMilli())
+	}
+	if types := store.eventTypes(); len(types) != 1 || types[0] != "status_changed:paid" {
+		t.Errorf("events = %v, want a single status change to paid", types)
+	}
+}
+
+func TestHandleNotificationAmountMismatch(t *testing.T) {
+	store := newFakePaymentStore()
+	newPendingPayment(store)
+	svc := newTestPaymentService(store)
+
+	err := svc.HandleNotification(t.Context(), paidNotification(1, time.Now()))
+	if code := pkgerrors.CodeOf(err); code != pkgerrors.ParamError {
+		t.Errorf("error code = %v, want ParamError (err = %v)", code, err)
+	}
+	if payment := store.payment("P1"); payment.Status != PaymentStatusPending {
+		t.Errorf("Status = %s, want pending", payment.Status)
+	}
+}
+
+func TestHandleNotificationStaleCloseAfterPaid(t *testing.T) {
+	store := newFakePaymentStore()
+	newPendingPayment(store)
+	svc := newTestPaymentService(store)
+
+	if err := svc.HandleNotification(t.Context(), paidNotification(100, time.Now())); err != nil {
+		t.Fatalf("HandleNotification(paid) error = %v", err)
+	}
+
+	// 关闭与失败的通知晚于支付成功到达时忽略
+	for _, status := range []PaymentStatus{PaymentStatusCancelled, PaymentStatusFailed, PaymentStatusPending} {
+		n := paidNotification(100, time.Time{})
+		n.Status = status
+		if err := svc.HandleNotification(t.Context(), n); err != nil {
+			t.Errorf("HandleNotification(%s) error = %v", status, err)
+		}
+	}
+
+	if payment := store.payment("P1"); payment.Status != PaymentStatusPaid {
+		t.Errorf("Status = %s, want paid", payment.Status)
+	}
+	if types := store.eventTypes(); len(types) != 1 {
+		t.Errorf("events = %v, want a single status change", types)
+	}
+}
+
+func TestHandleNotificationPaidAfterClose(t *testing.T) {
+	store := newFakePaymentStore()
+	newPendingPayment(store)
+	svc := newTestPaymentService(store)
+
+	closed := paidNotification(100, time.Time{})
+	closed.Status = PaymentStatusCancelled
+	closed.TransactionID = ""
+	if err := svc.HandleNotification(t.Context(), closed); err != nil {
+		t.Fatalf("HandleNotification(cancelled) error = %v", err)
+	}
+
+	// 订单关闭后才到达的支付成功通知不改变状态，登记对账差异；重复通知只登记一次
+	succeededAt := time.Date(2026, 10, 17, 23, 30, 0, 0, BillLocation)
+	for i := 0; i < 2; i++ {
+		if err := svc.HandleNotification(t.Context(), paidNotification(100, succeededAt)); err != nil {
+			t.Fatalf("HandleNotification(paid) #%d error = %v", i+1, err)
+		}
+	}
+
+	payment := store.payment("P1")
+	if payment.Status != PaymentStatusCancelled || payment.TransactionID != "4200000001" {
+		t.Errorf("payment = %+v, want cancelled with transaction id", payment)
+	}
+	want := []string{"status_changed:cancelled", entity.PaymentEventPaidAfterClose + ":cancelled"}
+	if types := store.eventTypes(); len(types) != len(want) || types[0] != want[0] || types[1] != want[1] {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+	if len(store.discrepancies) != 1 {
+		t.Fatalf("discrepancies = %d, want 1", len(store.discrepancies))
+	}
+	if d := store.discrepancies[0]; d.Type != entity.DiscrepancyPaidAfterClose || d.BillDate != "2026-10-17" || d.RemoteAmount != 100 {
+		t.Errorf("discrepancy = %+v", d)
+	}
+}
+
+func TestCancelExpired(t *testing.T) {
+	now := time.Now()
+	succeededAt := now.Add(-20 * time.Minute).Truncate(time.Second)
+	queryFailed := pkgerrors.New(pkgerrors.PaymentGatewayError, "system error")
+
+	tests := []struct {
+		name       string
+		query      *PaymentQueryResult
+		queryErr   error
+		closeErr   error
+		wantStatus PaymentStatus
+		wantClosed bool
+		wantCount  int
+		wantErr    bool
+	}{
+		{
+			name:       "closed at channel then cancelled",
+			query:      &PaymentQueryResult{Status: PaymentStatusPending},
+			wantStatus: PaymentStatusCancelled,
+			wantClosed: true,
+			wantCount:  1,
+		},
+		{
+			name:       "user still paying",
+			query:      &PaymentQueryResult{Status: PaymentStatusPending, Paying: true},
+			wantStatus: PaymentStatusPending,
+		},
+		{
+			name:       "paid before expiry with lost notification",
+			query:      &PaymentQueryResult{Status: PaymentStatusPaid, TransactionID: "4200000001", SucceededAt: succeededAt},
+			wantStatus: PaymentStatusPaid,
+		},
+		{
+			name:       "never created at channel",
+			queryErr:   pkgerrors.New(pkgerrors.NotFound, "ORDER_NOT_EXIST"),
+			wantStatus: PaymentStatusCancelled,
+			wantCount:  1,
+		},
+		{
+			name:       "query failed",
+			queryErr:   queryFailed,
+			wantStatus: PaymentStatusPending,
+			wantErr:    true,
+		},
+		{
+			name:       "close failed",
+			query:      &PaymentQueryResult{Status: PaymentStatusPending},
+			closeErr:   pkgerrors.New(pkgerrors.Conflict, "ORDERPAID"),
+			wantStatus: PaymentStatusPending,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakePaymentStore()
+			addPendingPayment(store, 1, "P1", now.Add(-time.Minute))
+			gateway := newFakeGateway()
+			if tt.query != nil {
+				gateway.queries["P1"] = tt.query
+			}
+			gateway.queryErr["P1"] = tt.queryErr
+			gateway.closeErr = tt.closeErr
+			svc := newTestPaymentServiceWithGateway(store, gateway)
+
+			cancelled, err := svc.CancelExpired(t.Context(), now, 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CancelExpired() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cancelled != tt.wantCount {
+				t.Errorf("cancelled = %d, want %d", cancelled, tt.wantCount)
+			}
+
+			payment := store.payment("P1")
+			if payment.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", payment.Status, tt.wantStatus)
+			}
+			if closed := len(gateway.closedPayments()) > 0; closed != tt.wantClosed {
+				t.Errorf("closed at channel = %v, want %v", closed, tt.wantClosed)
+			}
+			if tt.wantStatus == PaymentStatusPaid {
+				if payment.TransactionID != "4200000001" || payment.PaidAt != succeededAt.UnixMilli() {
+					t.Errorf("payment = %+v, want transaction id and channel success time", payment)
+				}
+			}
+		})
+	}
+}
+
+func TestCancelExpiredSkipsUnexpired(t *testing.T) {
+	now := time.Now()
+	store := newFakePaymentStore()
+	addPendingPayment(store, 1, "P1", now.Add(-time.Minute))
+	addPendingPayment(store, 2, "P2", now.Add(time.Minute))
+	gateway := newFakeGateway()
+	svc := newTestPaymentServiceWithGateway(store, gateway)
+
+	cancelled, err := svc.CancelExpired(t.Context(), now, 10)
+	if err != nil || cancelled != 1 {
+		t.Fatalf("CancelExpired() = %d, %v, want 1", cancelled, err)
+	}
+	if status := store.payment("P2").Status; status != PaymentStatusPending {
+		t.Errorf("unexpired payment status = %s, want pending", status)
+	}
+	if closed := gateway.closedPayments(); len(closed) != 1 || closed[0] != "P1" {
+		t.Errorf("closed = %v, want [P1]", closed)
+	}
+}
+
+func TestCancelExpiredVersionConflict(t *testing.T) {
+	now := time.Now()
+	store := newFakePaymentStore()
+	addPendingPayment(store, 1, "P1", now.Add(-time.Minute))
+	gateway := newFakeGateway()
+	svc := newTestPaymentServiceWithGateway(store, gateway)
+
+	// 取消任务查询渠道期间支付成功通知先到达，取消任务持有的支付单版本已过期
+	gateway.onQuery = func(string) {
+		if err := svc.HandleNotification(t.Context(), paidNotification(100, now)); err != nil {
+			t.Errorf("HandleNotification() error = %v", err)
+		}
+	}
+
+	cancelled, err := svc.CancelExpired(t.Context(), now, 10)
+	if code := pkgerrors.CodeOf(err); code != pkgerrors.Conflict || cancelled != 0 {
+		t.Fatalf("CancelExpired() = %d, %v, want Conflict", cancelled, err)
+	}
+	if payment := store.payment("P1"); payment.Status != PaymentStatusPaid || payment.Version != 1 {
+		t.Errorf("payment = %+v, want paid at version 1", payment)
+	}
+	if types := store.eventTypes(); len(types) != 1 || types[0] != "status_changed:paid" {
+		t.Errorf("events = %v, want only the paid transition", types)
+	}
+}
+
+func newPaidPayment(store *fakePaymentStore) *entity.Payment {
+	payment := newPendingPayment(store)
+	payment.Status = PaymentStatusPaid
+	payment.TransactionID = "4200000001"
+	return payment
+}
+
+func cny(amount int64) valueobject.Money {
+	money, _ := valueobject.NewMoney(amount, valueobject.CurrencyCNY)
+	return money
+}
+
+func refundNotification(refund *entity.Refund, status RefundStatus) *PaymentNotification {
+	return &PaymentNotification{
+		ID:              "N-" + refund.RefundNo,
+		EventType:       "REFUND." + strings.ToUpper(string(status)),
+		PaymentNo:       "P1",
+		RefundNo:        refund.RefundNo,
+		ChannelRefundID: "5000" + refund.RefundNo,
+		RefundStatus:    status,
+		Amount:          refund.Money(),
+		SucceededAt:     time.Now(),
+	}
+}
+
+func TestProcessRefundPartialRefundsCannotExceedAmount(t *testing.T) {
+	store := newFakePaymentStore()
+	newPaidPayment(store)
+	gateway := newFakeGateway()
+	svc := newTestPaymentServiceWithGateway(store, gateway)
+
+	first, err := svc.ProcessRefund(t.Context(), "P1", cny(60), "", "key-1")
+	if err != nil {
+		t.Fatalf("ProcessRefund(60) error = %v", err)
+	}
+	if first.Status != RefundStatusProcessing {
+		t.Fatalf("first refund status = %s, want processing", first.Status)
+	}
+
+	// 第一笔退款仍在处理中，合计超出支付金额的第二笔被拒绝，且不提交支付渠道
+	if _, err := svc.ProcessRefund(t.Context(), "P1", cny(50), "", "key-2"); pkgerrors.CodeOf(err) != pkgerrors.ParamError {
+		t.Fatalf("ProcessRefund(50) error = %v, want ParamError", err)
+	}
+	if requests := gateway.refundRequests(); len(requests) != 1 {
+		t.Fatalf("gateway refund requests = %d, want 1", len(requests))
+	}
+	if ok, _, err := svc.CanRefund(t.Context(), "P1", cny(41)); err != nil || ok {
+		t.Errorf("CanRefund(41) = %v, %v, want false", ok, err)
+	}
+
+	second, err := svc.ProcessRefund(t.Context(), "P1", cny(40), "", "key-2")
+	if err != nil {
+		t.Fatalf("ProcessRefund(40) error = %v", err)
+	}
+
+	for _, refund := range []*entity.Refund{first, second} {
+		if err := svc.HandleNotification(t.Context(), refundNotification(refund, RefundStatusSucceeded)); err != nil {
+			t.Fatalf("HandleNotification(%s) error = %v", refund.RefundNo, err)
+		}
+	}
+
+	payment := store.payment("P1")
+	if payment.Status != PaymentStatusRefunded || payment.RefundedAmount != 100 || payment.RefundingAmount != 0 {
+		t.Errorf("payment = %+v, want fully refunded", payment)
+	}
+	if _, err := svc.ProcessRefund(t.Context(), "P1", cny(1), "", "key-3"); pkgerrors.CodeOf(err) != pkgerrors.Conflict {
+		t.Errorf("ProcessRefund() on a fully refunded payment error = %v, want Conflict", err)
+	}
+}
+
+func TestProcessRefundSecondRejectedWhileFirstInFlight(t *testing.T) {
+	store := newFakePaymentStore()
+	newPaidPayment(store)
+	gateway := newFakeGateway()
+	svc := newTestPaymentServiceWithGateway(store, gateway)
+
+	// 第一笔退款提交支付渠道期间发起第二笔，额度已被第一笔占用
+	var secondErr error
+	gateway.onRefund = func(req RefundRequest) {
+		if req.Amount.Amount() == 70 {
+			_, secondErr = svc.ProcessRefund(t.Context(), "P1", cny(70), "", "key-2")
+		}
+	}
+	if _, err := svc.ProcessRefund(t.Context(), "P1", cny(70), "", "key-1"); err != nil {
+		t.Fatalf("ProcessRefund(first) error = %v", err)
+	}
+	if pkgerrors.CodeOf(secondErr) != pkgerrors.ParamError {
+		t.Errorf("ProcessRefund(second) error = %v, want ParamError", secondErr)
+	}
+	if payment := store.payment("P1"); payment.RefundingAmount != 70 {
+		t.Errorf("RefundingAmount = %d, want 70", payment.RefundingAmount)
+	}
+}
+
+func TestProcessRefundConcurrent(t *testing.T) {
+	store := newFakePaymentStore()
+	newPaidPayment(store)
+	svc := newTestPaymentServiceWithGateway(store, newFakeGateway())
+
+	// 并发退款按支付单版本号只有一个登记成功，其余返回冲突或超出额度，已占用额度不超过支付金额
+	var wg sync.WaitGroup
+	results := make([]error, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, results[i] = svc.ProcessRefund(context.Background(), "P1", cny(30), "", fmt.Sprintf("key-%d", i))
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range results {
+		switch pkgerrors.CodeOf(err) {
+		case pkgerrors.Success:
+			succeeded++
+		case pkgerrors.Conflict, pkgerrors.ParamError:
+		default:
+			t.Errorf("ProcessRefund() error = %v", err)
+		}
+	}
+	payment := store.payment("P1")
+	if payment.RefundingAmount != int64(succeeded)*30 || payment.RefundingAmount > payment.Amount {
+		t.Errorf("RefundingAmount = %d with %d refunds registered", payment.RefundingAmount, succeeded)
+	}
+}
+
+func TestProcessRefundReleasedOnFailure(t *testing.T) {
+	store := newFakePaymentStore()
+	newPaidPayment(store)
+	gateway := newFakeGateway()
+	svc := newTestPaymentServiceWithGateway(store, gateway)
+
+	// 支付渠道明确拒绝时退款失败并释放额度
+	gateway.refundErr = pkgerrors.New(pkgerrors.ParamError, "NOT_ENOUGH")
+	if _, err := svc.ProcessRefund(t.Context(), "P1", cny(100), "", "key-1"); err == nil {
+		t.Fatal("ProcessRefund() error = nil, want rejection")
+	}
+	failed, _ := fakeRefundRepo{fakePaymentStore: store}.FindByIdempotencyKey(t.Context(), 1, "key-1")
+	if failed.Status != RefundStatusFailed || failed.FailReason == "" {
+		t.Errorf("refund = %+v, want failed with reason", failed)
+	}
+	if payment := store.payment("P1"); payment.RefundingAmount != 0 || payment.Status != PaymentStatusPaid {
+		t.Errorf("payment = %+v, want quota released", payment)
+	}
+
+	// 渠道受理后异步失败时同样释放额度
+	gateway.refundErr = nil
+	refund, err := svc.ProcessRefund(t.Context(), "P1", cny(100), "", "key-2")
+	if err != nil {
+		t.Fatalf("ProcessRefund() after release error = %v", err)
+	}
+	if err := svc.HandleNotification(t.Context(), refundNotification(refund, RefundStatusFailed)); err != nil {
+		t.Fatalf("HandleNotification(failed) error = %v", err)
+	}
+	if payment := store.payment("P1"); payment.RefundingAmount != 0 || payment.RefundedAmount != 0 || payment.Status != PaymentStatusPaid {
+		t.Errorf("payment = %+v, want quota released", payment)
+	}
+
+	want := []string{"refund_requested:paid", "refund_failed:paid", "refund_requested:paid", "refund_failed:paid"}
+	if types := store.eventTypes(); strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+}
+
+func TestProcessRefundIdempotencyKey(t *testing.T) {
+	store := newFakePaymentStore()
+	newPaidPayment(store)
+	gateway := newFakeGateway()
+	svc := newTestPaymentServiceWithGateway(store, gateway)
+
+	first, err := svc.ProcessRefund(t.Context(), "P1", cny(30), "", "key-1")
+	if err != nil {
+		t.Fatalf("ProcessRefund() error = %v", err)
+	}
+
+	// 相同幂等键与金额返回已登记的退款单，不重复占用额度或提交渠道
+	replayed, err := svc.ProcessRefund(t.Context(), "P1", cny(30), "", "key-1")
+	if err != nil {
+		t.Fatalf("ProcessRefund() replay error = %v", err)
+	}
+	if replayed.RefundNo != first.RefundNo {
+		t.Errorf("replayed refund = %s, want %s", replayed.RefundNo, first.RefundNo)
+	}
+
+	// 相同幂等键用于不同金额时返回 Conflict
+	if _, err := svc.ProcessRefund(t.Context(), "P1", cny(20), "", "key-1"); pkgerrors.CodeOf(err) != pkgerrors.Conflict {
+		t.Errorf("ProcessRefund() with a different amount error = %v, want Conflict", err)
+	}
+
+	if payment := store.payment("P1"); payment.RefundingAmount != 30 {
+		t.Errorf("RefundingAmount = %d, want 30", payment.RefundingAmount)
+	}
+	if requests := gateway.refundRequests(); len(requests) != 1 {
+		t.Errorf("gateway refund requests = %d, want 1", len(requests))
+	}
+
+	if _, err := svc.ProcessRefund(t.Context(), "P1", cny(30), "", ""); pkgerrors.CodeOf(err) != pkgerrors.ParamError {
+		t.Errorf("ProcessRefund() without idempotency key error = %v, want ParamError", err)
+	}
+}
+
+func TestHandleNotificationDuplicateRefund(t *testing.T) {
+	store := newFakePaymentStore()
+	payment := newPaidPayment(store)
+	payment.RefundingAmount = 30
+	store.refunds["R1"] = &entity.Refund{
+		ID:        2,
+		RefundNo:  "R1",
+		PaymentID: payment.ID,
+		Amount:    30,
+		Currency:  string(valueobject.CurrencyCNY),
+		Status:    RefundStatusProcessing,
+	}
+	svc := newTestPaymentService(store)
+
+	n := refundNotification(store.refund("R1"), RefundStatusSucceeded)
+	for i := 0; i < 2; i++ {
+		if err := svc.HandleNotification(t.Context(), n); err != nil {
+			t.Fatalf("HandleNotification() #%d error = %v", i+1, err)
+		}
+	}
+
+	payment = store.payment("P1")
+	if payment.Status != PaymentStatusPartiallyRefunded || payment.RefundedAmount != 30 || payment.RefundingAmount != 0 {
+		t.Errorf("payment = %+v, want partially refunded 30", payment)
+	}
+	if refund := store.refund("R1"); refund.Status != RefundStatusSucceeded || refund.ChannelRefundID == "" {
+		t.Errorf("refund = %+v, want succeeded", refund)
+	}
+	if types := store.eventTypes(); len(types) != 1 || types[0] != "status_changed:partially_refunded" {
+		t.Errorf("events = %v, want a single refund status change", types)
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	pkgerrors "github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/snowflake"
+)
+
+// BillLocation 支付渠道账单日期所在时区，微信支付账单按北京时间划分日期
+var BillLocation = time.FixedZone("CST", 8*3600)
+
+// ReconciliationResult 对账结果
+type ReconciliationResult struct {
+	BillDate      string                              // 账单日期 YYYY-MM-DD
+	TradeCount    int                                 // 对账单交易记录数
+	RefundCount   int                                 // 对账单退款记录数
+	Matched       int                                 // 双方一致的记录数
+	Discrepancies []*entity.ReconciliationDiscrepancy // 差异记录
+}
+
+// ReconciliationDomainService 对账领域服务
+// 下载支付渠道的日对账单，与本地支付单、退款单逐条核对:
+// 对账单有成功记录而本地不存在或未记为成功的记为 missing_locally，
+// 本地当日支付或退款成功而对账单中不存在的记为 missing_remotely，金额不一致的记为 amount_mismatch
+type ReconciliationDomainService struct {
+	gateway            PaymentGateway
+	method             PaymentMethod
+	paymentRepo        repository.PaymentRepository
+	refundRepo         repository.RefundRepository
+	reconciliationRepo repository.ReconciliationRepository
+}
+
+// NewReconciliationDomainService 创建对账领域服务
+// 支付网关为微信支付，只核对微信支付方式的支付单
+func NewReconciliationDomainService(
+	gateway PaymentGateway,
+	paymentRepo repository.PaymentRepository,
+	refundRepo repository.RefundRepository,
+	reconciliationRepo repository.ReconciliationRepository,
+) *ReconciliationDomainService {
+	return &ReconciliationDomainService{
+		gateway:            gateway,
+		method:             PaymentMethodWechat,
+		paymentRepo:        paymentRepo,
+		refundRepo:         refundRepo,
+		reconciliationRepo: reconciliationRepo,
+	}
+}
+
+// Reconcile 对账指定账单日期，billDate 的时区为支付渠道账单所在时区
+// 同一日期可重复对账，待处理的差异被本次结果替换
+func (s *ReconciliationDomainService) Reconcile(ctx context.Context, billDate time.Time) (*ReconciliationResult, error) {
+	start := time.Date(billDate.Year(), billDate.Month(), billDate.Day(), 0, 0, 0, 0, billDate.Location())
+	end := start.AddDate(0, 0, 1)
+
+	records, err := s.gateway.DownloadStatement(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reconciler{
+		ctx:      ctx,
+		result:   &ReconciliationResult{BillDate: start.Format("2006-01-02")},
+		now:      time.Now(),
+		payments: make(map[string]bool),
+		refunds:  make(map[string]bool),
+	}
+	for _, record := range records {
+		switch record.Type {
+		case entity.StatementRecordTrade:
+			r.result.TradeCount++
+			err = s.matchTrade(r, record)
+		case entity.StatementRecordRefund:
+			r.result.RefundCount++
+			err = s.matchRefund(r, record)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.findMissingRemotely(r, start, end); err != nil {
+		return nil, err
+	}
+
+	if err := s.reconciliationRepo.ReplaceDiscrepancies(ctx, r.result.BillDate, r.result.Discrepancies); err != nil {
+		return nil, err
+	}
+	return r.result, nil
+}
+
+// reconciler 一次对账的进度
+type reconciler struct {
+	ctx      context.Context
+	result   *ReconciliationResult
+	now      time.Time
+	payments map[string]bool // 对账单中出现的商户支付单号
+	refunds  map[string]bool // 对账单中出现的商户退款单号
+}
+
+// add 记录一条差异
+func (r *reconciler) add(discrepancy *entity.ReconciliationDiscrepancy) {
+	discrepancy.ID = snowflake.Generate()
+	discrepancy.BillDate = r.result.BillDate
+	discrepancy.Status = entity.DiscrepancyStatusOpen
+	discrepancy.CreatedAt = r.now.UnixMilli()
+	discrepancy.UpdatedAt = r.now.UnixMilli()
+	r.result.Discrepancies = append(r.result.Discrepancies, discrepancy)
+}
+
+// matchTrade 核对一条交易记录
+func (s *ReconciliationDomainService) matchTrade(r *reconciler, record *StatementRecord) error {
+	r.payments[record.PaymentNo] = true
+	discrepancy := &entity.ReconciliationDiscrepancy{
+		RecordType:    entity.StatementRecordTrade,
+		PaymentNo:     record.PaymentNo,
+		TransactionID: record.TransactionID,
+		RemoteAmount:  record.Amount.Amount(),
+	}
+
+	payment, err := s.paymentRepo.FindByPaymentNo(r.ctx, record.PaymentNo)
+	switch {
+	case pkgerrors.CodeOf(err) == pkgerrors.NotFound:
+		discrepancy.Type = entity.DiscrepancyMissingLocally
+		discrepancy.Detail = "本地不存在该支付单"
+	case err != nil:
+		return err
+	case payment.PaidAt == 0:
+		discrepancy.Type = entity.DiscrepancyMissingLocally
+		discrepancy.LocalAmount = payment.Amount
+		discrepancy.Detail = fmt.Sprintf("本地支付状态: %s", payment.Status)
+	case !record.Amount.Equals(payment.Money()):
+		discrepancy.Type = entity.DiscrepancyAmountMismatch
+		discrepancy.LocalAmount = payment.Amount
+	default:
+		r.result.Matched++
+		return nil
+	}
+
+	r.add(discrepancy)
+	return nil
+}
+
+// matchRefund 核对一条退款记录，渠道仍在处理中的退款不核对
+func (s *ReconciliationDomainService) matchRefund(r *reconciler, record *StatementRecord) error {
+	r.refunds[record.RefundNo] = true
+	if record.RefundStatus != RefundStatusSucceeded {
+		return nil
+	}
+	discrepancy := &entity.ReconciliationDiscrepancy{
+		RecordType:    entity.StatementRecordRefund,
+		PaymentNo:     record.PaymentNo,
+		RefundNo:      record.RefundNo,
+		TransactionID: record.ChannelRefundID,
+		RemoteAmount:  record.Amount.Amount(),
+	}
+
+	refund, err := s.refundRepo.FindByRefundNo(r.ctx, record.RefundNo)
+	switch {
+	case pkgerrors.CodeOf(err) == pkgerrors.NotFound:
+		discrepancy.Type = entity.DiscrepancyMissingLocally
+		discrepancy.Detail = "本地不存在该退款单"
+	case err != nil:
+		return err
+	case refund.Status != RefundStatusSucceeded:
+		discrepancy.Type = entity.DiscrepancyMissingLocally
+		discrepancy.LocalAmount = refund.Amount
+		discrepancy.Detail = fmt.Sprintf("本地退款状态: %s", refund.Status)
+	case !record.Amount.Equals(refund.Money()):
+		discrepancy.Type = entity.DiscrepancyAmountMismatch
+		discrepancy.LocalAmount = refund.Amount
+	default:
+		r.result.Matched++
+		return nil
+	}
+
+	r.add(discrepancy)
+	return nil
+}
+
+// findMissingRemotely 查找本地当日支付或退款成功而对账单中不存在的记录
+func (s *ReconciliationDomainService) findMissingRemotely(r *reconciler, start, end time.Time) error {
+	payments, err := s.paymentRepo.ListPaidBetween(r.ctx, string(s.method), start, end)
+	if err != nil {
+		return err
+	}
+	for _, payment := range payments {
+		if r.payments[payment.PaymentNo] {
+			continue
+		}
+		r.add(&entity.ReconciliationDiscrepancy{
+			Type:          entity.DiscrepancyMissingRemotely,
+			RecordType:    entity.StatementRecordTrade,
+			PaymentNo:     payment.PaymentNo,
+			TransactionID: payment.TransactionID,
+			LocalAmount:   payment.Amount,
+			Detail:        fmt.Sprintf("本地支付状态: %s", payment.Status),
+		})
+	}
+
+	refunds, err := s.refundRepo.ListSucceededBetween(r.ctx, start, end)
+	if err != nil {
+		return err
+	}
+	for _, refund := range refunds {
+		if r.refunds[refund.RefundNo] {
+			continue
+		}
+		payment, err := s.paymentRepo.FindByID(r.ctx, refund.PaymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Method != string(s.method) {
+			continue
+		}
+		r.add(&entity.ReconciliationDiscrepancy{
+			Type:          entity.DiscrepancyMissingRemotely,
+			RecordType:    entity.StatementRecordRefund,
+			PaymentNo:     payment.PaymentNo,
+			RefundNo:      refund.RefundNo,
+			TransactionID: refund.ChannelRefundID,
+			LocalAmount:   refund.Amount,
+		})
+	}
+	return nil
+}
@@ -29,13 +29,15 @@ type PasswordHasher interface {
 // 3. 需要调用仓储或外部服务的领域逻辑
 type UserDomainService struct {
 	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
 	passwordHasher PasswordHasher
 }
 
 // NewUserDomainService 创建用户领域服务
-func NewUserDomainService(userRepo repository.UserRepository, passwordHasher PasswordHasher) *UserDomainService {
+func NewUserDomainService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, passwordHasher PasswordHasher) *UserDomainService {
 	return &UserDomainService{
 		userRepo:       userRepo,
+		roleRepo:       roleRepo,
 		passwordHasher: passwordHasher,
 	}
 }
@@ -51,7 +53,7 @@ func (s *UserDomainService) CheckUserExists(ctx context.Context, openID string)
 }
 
 // CanUserPerformAction 检查用户是否有权限执行某个操作
-// 这是一个领域规则，根据用户状态和业务规则判断
+// action 为权限标识(如 "app_version:write")，由用户角色所授予的权限决定
 func (s *UserDomainService) CanUserPerformAction(ctx context.Context, userID int64, action string) (bool, error) {
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
@@ -62,18 +64,17 @@ func (s *UserDomainService) CanUserPerformAction(ctx context.Context, userID int
 		return false, errors.New("用户不存在")
 	}
 
-	// 这里可以添加更复杂的权限检查逻辑
-	// 例如：检查用户角色、会员状态、账户状态等
-	switch action {
-	case "create_content":
-		// 所有登录用户都可以创建内容
-		return true, nil
-	case "admin_action":
-		// 只有管理员可以执行管理操作（这里需要扩展User实体添加角色字段）
-		return false, nil
-	default:
-		return false, nil
+	roles, err := s.roleRepo.FindRoleCodesByUserID(ctx, user.ID)
+	if err != nil {
+		return false, errors.Wrap(err, "查询用户角色失败")
 	}
+
+	permissions, err := s.roleRepo.FindPermissionsByRoleCodes(ctx, entity.EffectiveRoles(roles))
+	if err != nil {
+		return false, errors.Wrap(err, "查询角色权限失败")
+	}
+
+	return entity.HasPermission(permissions, action), nil
 }
 
 // IsUserActive 检查用户是否活跃（最近30天有登录）
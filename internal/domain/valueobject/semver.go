@@ -0,0 +1,230 @@
+package valueobject
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SemVer 语义化版本值对象
+// 遵循 Semantic Versioning 2.0.0：MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]
+// 为兼容客户端上报习惯，解析时允许可选的 "v" 前缀，输出时统一去掉
+type SemVer struct {
+	major      uint64
+	minor      uint64
+	patch      uint64
+	preRelease []string // 预发布标识，如 ["beta", "1"]
+	build      string   // 构建元数据，不参与比较
+}
+
+// ParseSemVer 解析语义化版本号
+func ParseSemVer(version string) (SemVer, error) {
+	version = strings.TrimSpace(version)
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return SemVer{}, errors.New("版本号不能为空")
+	}
+
+	var v SemVer
+
+	// 构建元数据
+	if idx := strings.IndexByte(version, '+'); idx >= 0 {
+		v.build = version[idx+1:]
+		version = version[:idx]
+		if err := validateIdentifiers(v.build, false); err != nil {
+			return SemVer{}, errors.Wrap(err, "构建元数据格式不正确")
+		}
+	}
+
+	// 预发布标识
+	if idx := strings.IndexByte(version, '-'); idx >= 0 {
+		preRelease := version[idx+1:]
+		version = version[:idx]
+		if err := validateIdentifiers(preRelease, true); err != nil {
+			return SemVer{}, errors.Wrap(err, "预发布标识格式不正确")
+		}
+		v.preRelease = strings.Split(preRelease, ".")
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return SemVer{}, errors.New("版本号格式不正确，应为 MAJOR.MINOR.PATCH")
+	}
+
+	numbers := make([]uint64, 3)
+	for i, part := range parts {
+		if !isNumeric(part) || (len(part) > 1 && part[0] == '0') {
+			return SemVer{}, errors.Errorf("版本号格式不正确: %q 不是合法的数字", part)
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return SemVer{}, errors.Errorf("版本号格式不正确: %q 超出范围", part)
+		}
+		numbers[i] = n
+	}
+	v.major, v.minor, v.patch = numbers[0], numbers[1], numbers[2]
+
+	return v, nil
+}
+
+// Major 主版本号
+func (v SemVer) Major() uint64 {
+	return v.major
+}
+
+// Minor 次版本号
+func (v SemVer) Minor() uint64 {
+	return v.minor
+}
+
+// Patch 修订号
+func (v SemVer) Patch() uint64 {
+	return v.patch
+}
+
+// PreRelease 预发布标识
+func (v SemVer) PreRelease() string {
+	return strings.Join(v.preRelease, ".")
+}
+
+// Build 构建元数据
+func (v SemVer) Build() string {
+	return v.build
+}
+
+// IsPreRelease 是否为预发布版本
+func (v SemVer) IsPreRelease() bool {
+	return len(v.preRelease) > 0
+}
+
+// Compare 按语义化版本优先级比较，返回 -1、0、1
+// 构建元数据不参与比较
+func (v SemVer) Compare(other SemVer) int {
+	if c := compareUint(v.major, other.major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.minor, other.minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.patch, other.patch); c != 0 {
+		return c
+	}
+
+	// 有预发布标识的版本优先级低于正式版本
+	switch {
+	case len(v.preRelease) == 0 && len(other.preRelease) == 0:
+		return 0
+	case len(v.preRelease) == 0:
+		return 1
+	case len(other.preRelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.preRelease) && i < len(other.preRelease); i++ {
+		if c := comparePreReleaseIdentifier(v.preRelease[i], other.preRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.preRelease)), uint64(len(other.preRelease)))
+}
+
+// LessThan 小于比较
+func (v SemVer) LessThan(other SemVer) bool {
+	return v.Compare(other) < 0
+}
+
+// GreaterThan 大于比较
+func (v SemVer) GreaterThan(other SemVer) bool {
+	return v.Compare(other) > 0
+}
+
+// Equals 优先级相等比较(忽略构建元数据)
+func (v SemVer) Equals(other SemVer) bool {
+	return v.Compare(other) == 0
+}
+
+// String 实现 Stringer 接口
+func (v SemVer) String() string {
+	var sb strings.Builder
+	sb.WriteString(strconv.FormatUint(v.major, 10))
+	sb.WriteByte('.')
+	sb.WriteString(strconv.FormatUint(v.minor, 10))
+	sb.WriteByte('.')
+	sb.WriteString(strconv.FormatUint(v.patch, 10))
+	if len(v.preRelease) > 0 {
+		sb.WriteByte('-')
+		sb.WriteString(v.PreRelease())
+	}
+	if v.build != "" {
+		sb.WriteByte('+')
+		sb.WriteString(v.build)
+	}
+	return sb.String()
+}
+
+// comparePreReleaseIdentifier 比较单个预发布标识
+// 纯数字标识按数值比较且优先级低于非数字标识，其余按 ASCII 顺序比较
+func comparePreReleaseIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// validateIdentifiers 校验以点分隔的标识符
+// 预发布标识中的数字标识不允许前导零
+func validateIdentifiers(s string, rejectLeadingZero bool) error {
+	if s == "" {
+		return errors.New("标识不能为空")
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return errors.New("标识不能为空")
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return errors.Errorf("标识 %q 包含非法字符", id)
+			}
+		}
+		if rejectLeadingZero && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return errors.Errorf("数字标识 %q 不能有前导零", id)
+		}
+	}
+	return nil
+}
+
+// isNumeric 是否为纯数字
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// compareUint 比较无符号整数
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
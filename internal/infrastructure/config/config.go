@@ -8,14 +8,17 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
-	Upload   UploadConfig   `mapstructure:"upload"`
-	Wechat   WechatConfig   `mapstructure:"wechat"`
-	AI       AIConfig       `mapstructure:"ai"` // AI配置
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Redis        RedisConfig        `mapstructure:"redis"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	Log          LogConfig          `mapstructure:"log"`
+	Upload       UploadConfig       `mapstructure:"upload"`
+	Wechat       WechatConfig       `mapstructure:"wechat"`
+	Notification NotificationConfig `mapstructure:"notification"` // 通知投递
+	Payment      PaymentConfig      `mapstructure:"payment"`      // 支付
+	Idempotency  IdempotencyConfig  `mapstructure:"idempotency"`  // 写请求幂等
+	AI           AIConfig           `mapstructure:"ai"`           // AI配置
 }
 
 // ServerConfig 服务器配置
@@ -71,8 +74,21 @@ func (r RedisConfig) Addr() string {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret      string `mapstructure:"secret"`
-	ExpireHours int    `mapstructure:"expire_hours"`
+	Secret             string         `mapstructure:"secret"`               // HS256 密钥，仅在未配置 keys 时使用
+	ExpireHours        int            `mapstructure:"expire_hours"`         // 访问令牌有效期(小时)
+	RefreshExpireHours int            `mapstructure:"refresh_expire_hours"` // 刷新令牌有效期(小时)
+	Issuer             string         `mapstructure:"issuer"`               // 签发者(iss)，配置后验证时强制校验
+	SigningKeyID       string         `mapstructure:"signing_key_id"`       // 当前用于签名的密钥 kid
+	Keys               []JWTKeyConfig `mapstructure:"keys"`                 // 非对称密钥列表，配置后禁用 HS256
+}
+
+// JWTKeyConfig JWT 非对称密钥配置
+type JWTKeyConfig struct {
+	KID            string `mapstructure:"kid"`              // 密钥ID，写入令牌头部的 kid
+	Algorithm      string `mapstructure:"algorithm"`        // 签名算法: RS256, ES256, EdDSA
+	PrivateKeyFile string `mapstructure:"private_key_file"` // 私钥 PEM 文件，签名密钥必填
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // 公钥 PEM 文件，仅验证的旧密钥可只配置公钥
+	RetireAt       string `mapstructure:"retire_at"`        // 退役时间(RFC3339)，此前仍可验证(宽限期)，之后不再接受
 }
 
 // LogConfig 日志配置
@@ -87,9 +103,57 @@ type LogConfig struct {
 
 // UploadConfig 上传配置
 type UploadConfig struct {
-	MaxSize      int64    `mapstructure:"max_size"`
-	AllowedTypes []string `mapstructure:"allowed_types"`
-	StoragePath  string   `mapstructure:"storage_path"`
+	MaxSize      int64                 `mapstructure:"max_size"`
+	AllowedTypes []string              `mapstructure:"allowed_types"`
+	MaxWidth     int                   `mapstructure:"max_width"`    // 图片最大宽度(像素)，0 表示不限制
+	MaxHeight    int                   `mapstructure:"max_height"`   // 图片最大高度(像素)，0 表示不限制
+	MaxFrames    int                   `mapstructure:"max_frames"`   // GIF 最大帧数，0 表示不限制
+	MaxPixels    int64                 `mapstructure:"max_pixels"`   // GIF 全部帧的像素总数上限，0 表示不限制
+	Variants     []ImageVariantConfig  `mapstructure:"variants"`     // 头像缩略图规格
+	Backend      string                `mapstructure:"backend"`      // 存储后端: local(默认) 或 s3
+	StoragePath  string                `mapstructure:"storage_path"` // 本地存储根目录，仅 local 后端使用
+	PublicURL    string                `mapstructure:"public_url"`   // 文件访问地址前缀，为空时 local 使用 server.base_url/uploads，s3 使用存储桶地址
+	S3           S3Config              `mapstructure:"s3"`
+	Resumable    ResumableUploadConfig `mapstructure:"resumable"` // 断点续传
+	Direct       DirectUploadConfig    `mapstructure:"direct"`    // 预签名直传
+	Quota        int64                 `mapstructure:"quota"`     // 每个用户的存储配额(字节)，0 表示不限制
+	Orphan       OrphanUploadConfig    `mapstructure:"orphan"`    // 孤儿文件清理
+}
+
+// OrphanUploadConfig 孤儿文件清理配置
+// 头像更换后旧文件不再被引用，保留一段时间后删除，避免仍缓存旧地址的客户端立即失效
+type OrphanUploadConfig struct {
+	RetentionHours int `mapstructure:"retention_hours"` // 孤儿文件保留时长(小时)
+	SweepInterval  int `mapstructure:"sweep_interval"`  // 清理间隔(分钟)，0 表示不清理
+}
+
+// DirectUploadConfig 预签名直传配置
+type DirectUploadConfig struct {
+	ExpireMinutes int    `mapstructure:"expire_minutes"` // 上传地址有效期(分钟)
+	SigningKey    string `mapstructure:"signing_key"`    // local 后端的签名密钥，为空时启动时随机生成
+}
+
+// ResumableUploadConfig 断点续传配置(tus 协议)
+type ResumableUploadConfig struct {
+	MaxChunkSize  int64 `mapstructure:"max_chunk_size"` // 单次 PATCH 请求的最大字节数
+	ExpireHours   int   `mapstructure:"expire_hours"`   // 会话有效期(小时)，过期未完成的上传会被清理
+	SweepInterval int   `mapstructure:"sweep_interval"` // 过期会话清理间隔(分钟)
+}
+
+// ImageVariantConfig 缩略图规格
+type ImageVariantConfig struct {
+	Size   int    `mapstructure:"size"`   // 长边像素
+	Format string `mapstructure:"format"` // 编码格式: jpeg 或 webp
+}
+
+// S3Config S3 兼容对象存储配置(AWS S3、MinIO、OSS/COS 的 S3 兼容接口等)
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"` // 服务地址，如 https://s3.ap-east-1.amazonaws.com 或 http://127.0.0.1:9000
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	UsePathStyle    bool   `mapstructure:"use_path_style"` // 使用路径风格访问(endpoint/bucket/key)，MinIO 需开启
 }
 
 // WechatConfig 微信配置
@@ -97,6 +161,96 @@ type WechatConfig struct {
 	AppID              string            `mapstructure:"app_id"`
 	AppSecret          string            `mapstructure:"app_secret"`
 	SubscribeTemplates map[string]string `mapstructure:"subscribe_templates"` // 订阅消息模板映射: templateType -> templateID
+	SceneLink          SceneLinkConfig   `mapstructure:"scene_link"`          // 小程序码场景短链
+	Pay                WechatPayConfig   `mapstructure:"pay"`                 // 微信支付
+}
+
+// WechatPayConfig 微信支付 API v3 配置，MchID 为空时不启用微信支付
+type WechatPayConfig struct {
+	MchID             string   `mapstructure:"mch_id"`              // 商户号
+	AppID             string   `mapstructure:"app_id"`              // 下单使用的 AppID，为空时使用小程序 AppID
+	CertSerialNo      string   `mapstructure:"cert_serial_no"`      // 商户 API 证书序列号
+	PrivateKeyPath    string   `mapstructure:"private_key_path"`    // 商户 API 私钥文件(PEM)
+	APIv3Key          string   `mapstructure:"api_v3_key"`          // APIv3 密钥，用于解密回调通知与平台证书
+	PlatformCertPaths []string `mapstructure:"platform_cert_paths"` // 平台证书文件(PEM)，未配置或遇到未知序列号时从 /v3/certificates 下载
+	PublicKeyID       string   `mapstructure:"public_key_id"`       // 微信支付公钥ID(PUB_KEY_ID_ 开头)，使用公钥验签时配置
+	PublicKeyPath     string   `mapstructure:"public_key_path"`     // 微信支付公钥文件(PEM)
+	NotifyURL         string   `mapstructure:"notify_url"`          // 支付与退款结果回调地址，为空时使用 server.base_url + /v1/payments/wechat/notify
+	BaseURL           string   `mapstructure:"base_url"`            // 接口地址，为空时使用 https://api.mch.weixin.qq.com，测试时可指向模拟服务
+	Timeout           int      `mapstructure:"timeout"`             // 请求超时(秒)
+}
+
+// SceneLinkConfig 小程序码场景短链配置
+type SceneLinkConfig struct {
+	DefaultTTL     int `mapstructure:"default_ttl"`      // 未指定有效期时的默认有效期(小时)
+	MaxTTL         int `mapstructure:"max_ttl"`          // 最长有效期(小时)
+	MaxPayloadSize int `mapstructure:"max_payload_size"` // 业务数据最大字节数
+	SweepInterval  int `mapstructure:"sweep_interval"`   // 过期短链清理间隔(分钟)，0 表示不清理
+	Retention      int `mapstructure:"retention"`        // 过期短链保留时长(小时)，保留期内解析返回已过期而非不存在
+
+	ResolveRateLimit RateLimitConfig `mapstructure:"resolve_rate_limit"` // 每个用户解析短链的频率限制，防止枚举短码
+}
+
+// PaymentConfig 支付配置
+type PaymentConfig struct {
+	CancelInterval          int    `mapstructure:"cancel_interval"`           // 过期待支付单取消任务间隔(秒)，0 表示不取消
+	RefundReconcileInterval int    `mapstructure:"refund_reconcile_interval"` // 未完成退款对账任务间隔(秒)，0 表示不对账
+	BatchSize               int    `mapstructure:"batch_size"`                // 每批处理的支付单或退款单数
+	StatementInterval       int    `mapstructure:"statement_interval"`        // 渠道日对账单核对任务的检查间隔(秒)，0 表示不核对
+	StatementTime           string `mapstructure:"statement_time"`            // 每天核对前一天账单的时刻(北京时间 HH:MM)，须晚于支付渠道生成账单的时间
+}
+
+// IdempotencyConfig 写请求幂等配置
+// 携带 Idempotency-Key 的 POST/PUT/PATCH 请求首次处理后保存响应，有效期内的重复请求直接重放
+type IdempotencyConfig struct {
+	TTL             int   `mapstructure:"ttl"`               // 响应保存时长(秒)，0 表示关闭幂等处理
+	LockTTL         int   `mapstructure:"lock_ttl"`          // 处理中记录的有效期(秒)，处理期间自动延长，处理进程异常退出后到期可重新处理
+	MaxResponseSize int64 `mapstructure:"max_response_size"` // 可保存的最大响应体字节数，超出时只保存状态码，重复请求返回 409
+}
+
+// NotificationConfig 通知投递配置
+// 通知先写入发件箱表，再由后台任务按批领取投递，失败按指数退避重试
+type NotificationConfig struct {
+	DispatchInterval int        `mapstructure:"dispatch_interval"` // 投递任务间隔(秒)，0 表示不投递
+	BatchSize        int        `mapstructure:"batch_size"`        // 每批领取的通知数
+	LeaseSeconds     int        `mapstructure:"lease_seconds"`     // 领取租约(秒)，投递进程异常退出后通知在租约到期后被重新领取
+	MaxAttempts      int        `mapstructure:"max_attempts"`      // 最大投递次数，超过后放弃
+	BackoffBase      int        `mapstructure:"backoff_base"`      // 首次重试间隔(秒)，之后每次翻倍
+	BackoffMax       int        `mapstructure:"backoff_max"`       // 最大重试间隔(秒)
+	SMTP             SMTPConfig `mapstructure:"smtp"`              // 邮件渠道
+
+	DefaultTimezone string                     `mapstructure:"default_timezone"` // 用户未设置时区时，按此时区计算免打扰时段
+	RateLimit       RateLimitConfig            `mapstructure:"rate_limit"`       // 默认的每用户每类型频率限制
+	TypeRateLimits  map[string]RateLimitConfig `mapstructure:"type_rate_limits"` // 按通知类型覆盖的频率限制
+	UrgentTypes     []string                   `mapstructure:"urgent_types"`     // 紧急通知类型，不受免打扰时段与频率限制约束
+
+	DefaultLocale string `mapstructure:"default_locale"` // 通知模板默认语言，请求未指定或模板不支持该语言时使用
+
+	Scheduled ScheduledNotificationConfig `mapstructure:"scheduled"` // 定时通知
+}
+
+// ScheduledNotificationConfig 定时通知配置
+type ScheduledNotificationConfig struct {
+	DispatchInterval int `mapstructure:"dispatch_interval"` // 到期检查间隔(秒)，0 表示不发送
+	BatchSize        int `mapstructure:"batch_size"`        // 每批领取的定时通知数
+	MaxPerUser       int `mapstructure:"max_per_user"`      // 每个用户等待发送的定时通知上限，0 表示不限制
+}
+
+// RateLimitConfig 频率限制配置
+type RateLimitConfig struct {
+	Limit  int `mapstructure:"limit"`  // 窗口内最多发送次数，0 表示不限制
+	Window int `mapstructure:"window"` // 窗口长度(秒)
+}
+
+// SMTPConfig 邮件发送配置，Host 为空表示不启用邮件渠道
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`    // 发件人，如 "Polaris <noreply@example.com>"
+	SSL      bool   `mapstructure:"ssl"`     // 使用隐式 TLS(通常为 465 端口)；关闭时服务端支持则使用 STARTTLS
+	Timeout  int    `mapstructure:"timeout"` // 单封邮件发送超时(秒)
 }
 
 // AIConfig AI配置
@@ -211,8 +365,9 @@ func GetDefaultConfig() *Config {
 			PoolSize: 100,
 		},
 		JWT: JWTConfig{
-			Secret:      "your-secret-key",
-			ExpireHours: 72,
+			Secret:             "your-secret-key",
+			ExpireHours:        72,
+			RefreshExpireHours: 720,
 		},
 		Log: LogConfig{
 			Level:      "info",
@@ -225,12 +380,91 @@ func GetDefaultConfig() *Config {
 		Upload: UploadConfig{
 			MaxSize:      10 * 1024 * 1024, // 10MB
 			AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
-			StoragePath:  "uploads/",
+			MaxWidth:     8192,
+			MaxHeight:    8192,
+			MaxFrames:    300,
+			MaxPixels:    100_000_000,
+			Variants: []ImageVariantConfig{
+				{Size: 64, Format: "webp"},
+				{Size: 64, Format: "jpeg"},
+				{Size: 256, Format: "webp"},
+				{Size: 256, Format: "jpeg"},
+			},
+			Backend:     "local",
+			StoragePath: "uploads/",
+			S3: S3Config{
+				Region:       "us-east-1",
+				UsePathStyle: true,
+			},
+			Resumable: ResumableUploadConfig{
+				MaxChunkSize:  5 * 1024 * 1024, // 5MB
+				ExpireHours:   24,
+				SweepInterval: 10,
+			},
+			Direct: DirectUploadConfig{
+				ExpireMinutes: 15,
+			},
+			Quota: 100 * 1024 * 1024, // 100MB
+			Orphan: OrphanUploadConfig{
+				RetentionHours: 24,
+				SweepInterval:  60,
+			},
 		},
 		Wechat: WechatConfig{
 			AppID:              "",
 			AppSecret:          "",
 			SubscribeTemplates: map[string]string{},
+			SceneLink: SceneLinkConfig{
+				DefaultTTL:     24 * 7,
+				MaxTTL:         24 * 365,
+				MaxPayloadSize: 4096,
+				SweepInterval:  60,
+				Retention:      24 * 30,
+				ResolveRateLimit: RateLimitConfig{
+					Limit:  30,
+					Window: 60,
+				},
+			},
+			Pay: WechatPayConfig{
+				BaseURL: "https://api.mch.weixin.qq.com",
+				Timeout: 10,
+			},
+		},
+		Payment: PaymentConfig{
+			CancelInterval:          60,
+			RefundReconcileInterval: 60,
+			BatchSize:               100,
+			StatementInterval:       600,
+			StatementTime:           "10:30",
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:             86400,
+			LockTTL:         60,
+			MaxResponseSize: 1 << 20,
+		},
+		Notification: NotificationConfig{
+			DispatchInterval: 5,
+			BatchSize:        50,
+			LeaseSeconds:     60,
+			MaxAttempts:      8,
+			BackoffBase:      30,
+			BackoffMax:       3600,
+			SMTP: SMTPConfig{
+				Port:    587,
+				Timeout: 30,
+			},
+			DefaultTimezone: "Asia/Shanghai",
+			RateLimit: RateLimitConfig{
+				Limit:  10,
+				Window: 3600,
+			},
+			TypeRateLimits: map[string]RateLimitConfig{},
+			DefaultLocale:  "zh-CN",
+			Scheduled: ScheduledNotificationConfig{
+				DispatchInterval: 30,
+				BatchSize:        100,
+				MaxPerUser:       50,
+			},
 		},
 		AI: GetDefaultAIConfig(),
 	}
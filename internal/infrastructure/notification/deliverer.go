@@ -0,0 +1,80 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// Deliverer 通知渠道投递器
+// 负责把发件箱中的一条通知真正发出，由 Dispatcher 调用
+type Deliverer interface {
+	Deliver(ctx context.Context, notification *entity.Notification) error
+}
+
+// Deliverers 已启用的渠道投递器，键为通知渠道
+type Deliverers map[string]Deliverer
+
+// NewDeliverers 根据配置创建各渠道投递器，未配置的渠道不启用
+func NewDeliverers(cfg *config.Config, wechatSender SubscribeMessageSender, logger *zap.Logger) Deliverers {
+	deliverers := Deliverers{}
+
+	if cfg.Notification.SMTP.Host != "" {
+		deliverers[string(service.ChannelEmail)] = NewSMTPDeliverer(cfg.Notification.SMTP)
+	}
+	if cfg.Wechat.AppID != "" {
+		deliverers[string(service.ChannelWechat)] = NewWechatDeliverer(wechatSender)
+	}
+
+	channels := make([]string, 0, len(deliverers))
+	for channel := range deliverers {
+		channels = append(channels, channel)
+	}
+	logger.Info("Notification channels enabled", zap.Strings("channels", channels))
+
+	return deliverers
+}
+
+// Supports 是否启用了指定渠道
+func (d Deliverers) Supports(channel string) bool {
+	_, ok := d[channel]
+	return ok
+}
+
+// permanentError 不可重试的投递错误，如接收方无效、模板参数错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent 将错误标记为不可重试，Dispatcher 遇到此类错误直接放弃投递
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 是否为不可重试的投递错误
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// errChannelDisabled 渠道未启用
+func errChannelDisabled(channel string) error {
+	return errors.New(errors.InternalError, fmt.Sprintf("notification channel %q is not enabled", channel))
+}
@@ -0,0 +1,135 @@
+package notification
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+)
+
+// maxLastErrorLength 记录的投递错误最大长度
+const maxLastErrorLength = 1000
+
+// Dispatcher 发件箱投递器
+// 按批领取到期通知并交给对应渠道投递；失败按指数退避重试，超过最大次数或遇到不可重试错误时放弃
+type Dispatcher struct {
+	notificationRepo repository.NotificationRepository
+	deliverers       Deliverers
+	cfg              config.NotificationConfig
+	logger           *zap.Logger
+}
+
+// NewDispatcher 创建发件箱投递器
+func NewDispatcher(
+	cfg *config.Config,
+	notificationRepo repository.NotificationRepository,
+	deliverers Deliverers,
+	logger *zap.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		notificationRepo: notificationRepo,
+		deliverers:       deliverers,
+		cfg:              cfg.Notification,
+		logger:           logger,
+	}
+}
+
+// Dispatch 投递全部到期通知，返回投递成功的条数
+// 逐批领取直到没有到期通知；多实例同时运行时各自领取不同的通知
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	batchSize := max(d.cfg.BatchSize, 1)
+	lease := time.Duration(max(d.cfg.LeaseSeconds, 1)) * time.Second
+
+	sent := 0
+	for ctx.Err() == nil {
+		now := time.Now()
+		notifications, err := d.notificationRepo.ClaimDue(ctx, now.UnixMilli(), now.Add(lease).UnixMilli(), batchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, n := range notifications {
+			if ctx.Err() != nil {
+				// 未投递的通知在租约到期后会被重新领取
+				break
+			}
+			if d.deliver(ctx, n) {
+				sent++
+			}
+		}
+
+		if len(notifications) < batchSize {
+			break
+		}
+	}
+
+	return sent, nil
+}
+
+// deliver 投递一条通知并记录结果，返回是否投递成功
+func (d *Dispatcher) deliver(ctx context.Context, n *entity.Notification) bool {
+	var err error
+	if deliverer, ok := d.deliverers[n.Channel]; ok {
+		err = deliverer.Deliver(ctx, n)
+	} else {
+		err = Permanent(errChannelDisabled(n.Channel))
+	}
+
+	if err == nil {
+		if markErr := d.notificationRepo.MarkSent(ctx, n, time.Now().UnixMilli()); markErr != nil {
+			d.logger.Error("Failed to mark notification sent", zap.Int64("id", n.ID), zap.Error(markErr))
+		}
+		return true
+	}
+
+	lastError := err.Error()
+	if len(lastError) > maxLastErrorLength {
+		lastError = lastError[:maxLastErrorLength]
+	}
+
+	if IsPermanent(err) || n.Attempts >= d.cfg.MaxAttempts {
+		d.logger.Warn("Notification delivery failed permanently",
+			zap.Int64("id", n.ID),
+			zap.String("channel", n.Channel),
+			zap.Int("attempts", n.Attempts),
+			zap.Error(err),
+		)
+		if markErr := d.notificationRepo.MarkFailed(ctx, n, lastError); markErr != nil {
+			d.logger.Error("Failed to mark notification failed", zap.Int64("id", n.ID), zap.Error(markErr))
+		}
+		return false
+	}
+
+	next := time.Now().Add(d.backoff(n.Attempts))
+	d.logger.Info("Notification delivery failed, will retry",
+		zap.Int64("id", n.ID),
+		zap.String("channel", n.Channel),
+		zap.Int("attempts", n.Attempts),
+		zap.Time("nextAttemptAt", next),
+		zap.Error(err),
+	)
+	if markErr := d.notificationRepo.MarkRetry(ctx, n, next.UnixMilli(), lastError); markErr != nil {
+		d.logger.Error("Failed to schedule notification retry", zap.Int64("id", n.ID), zap.Error(markErr))
+	}
+	return false
+}
+
+// backoff 第 attempts 次失败后的重试间隔
+// 以 BackoffBase 为起点逐次翻倍，不超过 BackoffMax，并叠加至多 20% 的随机抖动，避免大量通知同时重试
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	base := time.Duration(max(d.cfg.BackoffBase, 1)) * time.Second
+	limit := time.Duration(max(d.cfg.BackoffMax, d.cfg.BackoffBase, 1)) * time.Second
+
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+
+	return delay + rand.N(delay/5+1)
+}
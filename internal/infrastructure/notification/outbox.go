@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/snowflake"
+)
+
+// OutboxSender 基于发件箱的通知发送器，实现 service.NotificationSender
+// 发送时只把通知写入发件箱表即返回，由 Dispatcher 异步投递；
+// 调用方不会因第三方渠道超时而阻塞，进程重启也不会丢失未投递的通知
+type OutboxSender struct {
+	notificationRepo repository.NotificationRepository
+	deliverers       Deliverers
+}
+
+var _ service.NotificationSender = (*OutboxSender)(nil)
+
+// NewOutboxSender 创建发件箱通知发送器
+func NewOutboxSender(notificationRepo repository.NotificationRepository, deliverers Deliverers) *OutboxSender {
+	return &OutboxSender{
+		notificationRepo: notificationRepo,
+		deliverers:       deliverers,
+	}
+}
+
+// SendSMS 发送短信
+func (s *OutboxSender) SendSMS(ctx context.Context, phone valueobject.Phone, content string) error {
+	return s.enqueue(ctx, &entity.Notification{
+		Channel:   string(service.ChannelSMS),
+		Recipient: phone.FullNumber(),
+		Content:   content,
+	})
+}
+
+// SendEmail 发送邮件
+func (s *OutboxSender) SendEmail(ctx context.Context, email valueobject.Email, subject, content string, html bool) error {
+	notification := &entity.Notification{
+		Channel:   string(service.ChannelEmail),
+		Recipient: email.Value(),
+		Title:     subject,
+		Content:   content,
+	}
+	if html {
+		payload, _ := json.Marshal(emailPayload{ContentType: mimeTextHTML})
+		notification.Payload = string(payload)
+	}
+	return s.enqueue(ctx, notification)
+}
+
+// SendPush 发送APP推送
+func (s *OutboxSender) SendPush(ctx context.Context, userID int64, title, content string) error {
+	return s.enqueue(ctx, &entity.Notification{
+		UserID:    userID,
+		Channel:   string(service.ChannelPush),
+		Recipient: strconv.FormatInt(userID, 10),
+		Title:     title,
+		Content:   content,
+	})
+}
+
+// SendWechat 发送微信订阅消息
+// data 中的 page、miniprogram_state 作为跳转参数，其余字段作为模板数据
+func (s *OutboxSender) SendWechat(ctx context.Context, openID, templateID string, data map[string]interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(errors.ParamError, "invalid wechat message data", err)
+	}
+
+	return s.enqueue(ctx, &entity.Notification{
+		Channel:    string(service.ChannelWechat),
+		Recipient:  openID,
+		TemplateID: templateID,
+		Payload:    string(payload),
+	})
+}
+
+// Supports 渠道是否配置了投递器
+func (s *OutboxSender) Supports(channel service.NotificationChannel) bool {
+	return s.deliverers.Supports(string(channel))
+}
+
+// enqueue 写入发件箱，立即可被投递
+// 未启用的渠道直接报错，避免写入注定无法投递的通知
+func (s *OutboxSender) enqueue(ctx context.Context, notification *entity.Notification) error {
+	if !s.deliverers.Supports(notification.Channel) {
+		return errChannelDisabled(notification.Channel)
+	}
+
+	notification.ID = snowflake.Generate()
+	notification.Status = entity.NotificationStatusPending
+	notification.CreatedAt = time.Now().UnixMilli()
+	return s.notificationRepo.Create(ctx, notification)
+}
@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+)
+
+// NewPolicy 根据配置创建通知发送策略
+func NewPolicy(cfg *config.Config) (service.NotificationPolicy, error) {
+	policy := service.NotificationPolicy{
+		DefaultLocation:  time.Local,
+		DefaultRateLimit: toRateLimit(cfg.Notification.RateLimit),
+		TypeRateLimits:   make(map[string]service.RateLimit, len(cfg.Notification.TypeRateLimits)),
+		UrgentTypes:      make(map[string]bool, len(cfg.Notification.UrgentTypes)),
+	}
+
+	if tz := cfg.Notification.DefaultTimezone; tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return policy, fmt.Errorf("notification: invalid default_timezone %q: %w", tz, err)
+		}
+		policy.DefaultLocation = loc
+	}
+	for notificationType, limit := range cfg.Notification.TypeRateLimits {
+		policy.TypeRateLimits[notificationType] = toRateLimit(limit)
+	}
+	for _, notificationType := range cfg.Notification.UrgentTypes {
+		policy.UrgentTypes[notificationType] = true
+	}
+
+	return policy, nil
+}
+
+// toRateLimit 转换频率限制配置
+func toRateLimit(cfg config.RateLimitConfig) service.RateLimit {
+	return service.RateLimit{
+		Limit:  cfg.Limit,
+		Window: time.Duration(cfg.Window) * time.Second,
+	}
+}
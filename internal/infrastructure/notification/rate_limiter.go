@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const rateLimitKeyPrefix = "notification:rate:"
+
+// incrWindowScript 计数加一，首次计数时设置窗口过期时间
+var incrWindowScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// RedisRateLimiter 基于 Redis 固定窗口计数的通知频率限制器
+type RedisRateLimiter struct {
+	client *redis.Client
+}
+
+var _ service.NotificationRateLimiter = (*RedisRateLimiter)(nil)
+
+// NewRedisRateLimiter 创建通知频率限制器
+func NewRedisRateLimiter(client *redis.Client) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+// Allow 在当前时间窗口内为用户的某类通知计数
+func (l *RedisRateLimiter) Allow(ctx context.Context, userID int64, notificationType string, limit int, window time.Duration) (bool, error) {
+	windowStart := time.Now().UnixMilli() / window.Milliseconds()
+	key := fmt.Sprintf("%s%d:%s:%d", rateLimitKeyPrefix, userID, notificationType, windowStart)
+
+	count, err := incrWindowScript.Run(ctx, l.client, []string{key}, window.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to count notification", err)
+	}
+	return count <= limit, nil
+}
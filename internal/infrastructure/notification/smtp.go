@@ -0,0 +1,189 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// 邮件正文类型
+const (
+	mimeTextPlain = "text/plain"
+	mimeTextHTML  = "text/html"
+)
+
+// emailPayload 邮件通知的附加数据
+type emailPayload struct {
+	ContentType string `json:"content_type,omitempty"` // 正文类型，默认 text/plain
+}
+
+// SMTPDeliverer 邮件投递器
+type SMTPDeliverer struct {
+	cfg     config.SMTPConfig
+	timeout time.Duration
+}
+
+// NewSMTPDeliverer 创建邮件投递器
+func NewSMTPDeliverer(cfg config.SMTPConfig) *SMTPDeliverer {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &SMTPDeliverer{cfg: cfg, timeout: timeout}
+}
+
+// Deliver 发送邮件
+// 服务端返回 5xx 永久性错误(如收件人不存在)时不再重试
+func (d *SMTPDeliverer) Deliver(ctx context.Context, n *entity.Notification) error {
+	from, err := mail.ParseAddress(d.cfg.From)
+	if err != nil {
+		return Permanent(errors.Wrap(errors.ParamError, "invalid smtp sender address", err))
+	}
+	to, err := mail.ParseAddress(n.Recipient)
+	if err != nil {
+		return Permanent(errors.Wrap(errors.ParamError, "invalid email recipient", err))
+	}
+
+	payload := emailPayload{ContentType: mimeTextPlain}
+	if n.Payload != "" {
+		if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+			return Permanent(errors.Wrap(errors.ParamError, "invalid email notification payload", err))
+		}
+	}
+
+	msg, err := buildMessage(from, to, n.Title, n.Content, payload.ContentType)
+	if err != nil {
+		return err
+	}
+
+	err = d.send(ctx, from.Address, to.Address, msg)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+// send 建立连接并投递一封邮件
+func (d *SMTPDeliverer) send(ctx context.Context, from, to string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(d.cfg.Host, strconv.Itoa(d.cfg.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial %s: %w", addr, err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	tlsConfig := &tls.Config{ServerName: d.cfg.Host}
+	if d.cfg.SSL {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, d.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if !d.cfg.SSL {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("smtp starttls: %w", err)
+			}
+		}
+	}
+	if d.cfg.Username != "" {
+		auth := smtp.PlainAuth("", d.cfg.Username, d.cfg.Password, d.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp rcpt to: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage 构建 MIME 邮件，正文以 base64 编码避免非 ASCII 字符与长行问题
+func buildMessage(from, to *mail.Address, subject, body, contentType string) ([]byte, error) {
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.BEncoding.Encode("UTF-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", contentType + "; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "base64"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+
+	return buf.Bytes(), nil
+}
+
+// newMessageID 生成邮件 Message-ID
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(errors.InternalError, "failed to generate message id", err)
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
@@ -0,0 +1,475 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+//go:embed templates/*.yaml
+var templateFS embed.FS
+
+// 模板变量类型
+const (
+	varString = "string"
+	varInt    = "int"
+	varFloat  = "float"
+	varBool   = "bool"
+	varTime   = "time" // time.Time 或 RFC3339 字符串
+)
+
+// templateFile 模板文件结构，每个文件定义一个通知类型
+type templateFile struct {
+	Type      string                          `yaml:"type"`
+	Variables map[string]string               `yaml:"variables"`
+	Locales   map[string]localeTemplateSource `yaml:"locales"`
+}
+
+// localeTemplateSource 某一语言下各渠道的模板源码
+type localeTemplateSource struct {
+	SMS   string `yaml:"sms"`
+	Email *struct {
+		Subject string `yaml:"subject"`
+		HTML    string `yaml:"html"`
+	} `yaml:"email"`
+	Push *struct {
+		Title string `yaml:"title"`
+		Body  string `yaml:"body"`
+	} `yaml:"push"`
+	Wechat *struct {
+		Template string            `yaml:"template"` // wechat.subscribe_templates 中的模板类型，默认与通知类型相同
+		Page     string            `yaml:"page"`
+		Data     map[string]string `yaml:"data"`
+	} `yaml:"wechat"`
+}
+
+// executor text/template 与 html/template 的共同接口
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// channelTemplate 编译后的单个渠道模板
+type channelTemplate struct {
+	title   executor
+	content executor
+	html    bool
+	// 微信订阅消息
+	wechatTemplate string
+	page           string
+	data           map[string]executor
+}
+
+// notificationTemplate 编译后的通知类型模板
+type notificationTemplate struct {
+	variables map[string]string
+	locales   map[string]map[service.NotificationChannel]*channelTemplate
+}
+
+// TemplateRegistry 通知模板注册表
+// 模板以 YAML 定义并随程序嵌入，按通知类型与语言组织；变量声明类型，渲染前统一校验
+type TemplateRegistry struct {
+	templates       map[string]*notificationTemplate
+	defaultLocale   string
+	wechatTemplates map[string]string
+	location        *time.Location
+}
+
+var _ service.NotificationRenderer = (*TemplateRegistry)(nil)
+
+// NewTemplateRegistry 加载嵌入的通知模板
+// 启动时以各变量类型的零值试渲染全部模板，模板引用了未声明的变量会直接报错
+func NewTemplateRegistry(cfg *config.Config, policy service.NotificationPolicy) (*TemplateRegistry, error) {
+	r := &TemplateRegistry{
+		templates:       make(map[string]*notificationTemplate),
+		defaultLocale:   cfg.Notification.DefaultLocale,
+		wechatTemplates: cfg.Wechat.SubscribeTemplates,
+		location:        policy.DefaultLocation,
+	}
+	if r.location == nil {
+		r.location = time.Local
+	}
+
+	files, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("notification: read templates: %w", err)
+	}
+	for _, file := range files {
+		if err := r.load(path.Join("templates", file.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Render 按通知类型与语言渲染指定渠道的内容
+func (r *TemplateRegistry) Render(notificationType, locale string, channel service.NotificationChannel, variables map[string]interface{}) (*service.RenderedNotification, bool, error) {
+	tpl, ok := r.templates[notificationType]
+	if !ok {
+		return nil, false, nil
+	}
+
+	data, err := bindVariables(tpl.variables, variables)
+	if err != nil {
+		return nil, true, errors.New(errors.ParamError, fmt.Sprintf("通知 %s 的模板变量错误: %v", notificationType, err))
+	}
+
+	ct, ok := tpl.locales[r.resolveLocale(tpl, locale)][channel]
+	if !ok {
+		// 该语言未定义此渠道时回退到默认语言，如英文模板未提供微信订阅消息
+		ct, ok = tpl.locales[normalizeLocale(r.defaultLocale)][channel]
+	}
+	if !ok {
+		return nil, true, errors.New(errors.ParamError, fmt.Sprintf("通知 %s 未定义 %s 渠道的模板", notificationType, channel))
+	}
+
+	rendered, err := ct.render(data)
+	if err != nil {
+		return nil, true, errors.Wrap(errors.ParamError, fmt.Sprintf("渲染通知 %s 失败", notificationType), err)
+	}
+
+	if channel == service.ChannelWechat {
+		key := ct.wechatTemplate
+		if key == "" {
+			key = notificationType
+		}
+		rendered.TemplateID = r.wechatTemplates[key]
+		if rendered.TemplateID == "" {
+			return nil, true, errors.New(errors.InternalError, fmt.Sprintf("未配置微信订阅消息模板: %s", key))
+		}
+	}
+
+	return rendered, true, nil
+}
+
+// resolveLocale 选择最匹配的语言
+// 依次尝试：完全匹配、相同主语言(如 en 匹配 en-US)、默认语言、任意已定义语言
+func (r *TemplateRegistry) resolveLocale(tpl *notificationTemplate, locale string) string {
+	locale = normalizeLocale(locale)
+	if _, ok := tpl.locales[locale]; ok {
+		return locale
+	}
+
+	if lang, _, _ := strings.Cut(locale, "-"); lang != "" {
+		for _, candidate := range sortedLocales(tpl) {
+			if l, _, _ := strings.Cut(candidate, "-"); l == lang {
+				return candidate
+			}
+		}
+	}
+
+	if _, ok := tpl.locales[normalizeLocale(r.defaultLocale)]; ok {
+		return normalizeLocale(r.defaultLocale)
+	}
+	return sortedLocales(tpl)[0]
+}
+
+// load 加载并编译一个模板文件
+func (r *TemplateRegistry) load(name string) error {
+	raw, err := templateFS.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("notification: read template %s: %w", name, err)
+	}
+
+	var file templateFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("notification: parse template %s: %w", name, err)
+	}
+	if file.Type == "" || len(file.Locales) == 0 {
+		return fmt.Errorf("notification: template %s must define type and locales", name)
+	}
+	if _, ok := r.templates[file.Type]; ok {
+		return fmt.Errorf("notification: duplicate template for type %s", file.Type)
+	}
+	for variable, kind := range file.Variables {
+		if zeroValue(kind) == nil {
+			return fmt.Errorf("notification: template %s: variable %s has unknown type %q", name, variable, kind)
+		}
+	}
+
+	tpl := &notificationTemplate{
+		variables: file.Variables,
+		locales:   make(map[string]map[service.NotificationChannel]*channelTemplate, len(file.Locales)),
+	}
+	for locale, source := range file.Locales {
+		channels, err := r.compile(source)
+		if err != nil {
+			return fmt.Errorf("notification: template %s [%s]: %w", name, locale, err)
+		}
+		tpl.locales[normalizeLocale(locale)] = channels
+	}
+
+	// 以零值试渲染，尽早发现引用了未声明变量的模板
+	zero := make(map[string]any, len(file.Variables))
+	for variable, kind := range file.Variables {
+		zero[variable] = zeroValue(kind)
+	}
+	for locale, channels := range tpl.locales {
+		for channel, ct := range channels {
+			if _, err := ct.render(zero); err != nil {
+				return fmt.Errorf("notification: template %s [%s/%s]: %w", name, locale, channel, err)
+			}
+		}
+	}
+
+	r.templates[file.Type] = tpl
+	return nil
+}
+
+// compile 编译某一语言下的各渠道模板
+func (r *TemplateRegistry) compile(source localeTemplateSource) (map[service.NotificationChannel]*channelTemplate, error) {
+	channels := make(map[service.NotificationChannel]*channelTemplate)
+	var err error
+
+	if source.SMS != "" {
+		ct := &channelTemplate{}
+		if ct.content, err = r.parseText("sms", source.SMS); err != nil {
+			return nil, err
+		}
+		channels[service.ChannelSMS] = ct
+	}
+
+	if source.Email != nil {
+		// 邮件正文为 HTML，使用 html/template 对变量自动转义
+		ct := &channelTemplate{html: true}
+		if ct.title, err = r.parseText("email.subject", source.Email.Subject); err != nil {
+			return nil, err
+		}
+		if ct.content, err = htmltemplate.New("email.html").
+			Option("missingkey=error").
+			Funcs(htmltemplate.FuncMap(r.funcs())).
+			Parse(source.Email.HTML); err != nil {
+			return nil, err
+		}
+		channels[service.ChannelEmail] = ct
+	}
+
+	if source.Push != nil {
+		ct := &channelTemplate{}
+		if ct.title, err = r.parseText("push.title", source.Push.Title); err != nil {
+			return nil, err
+		}
+		if ct.content, err = r.parseText("push.body", source.Push.Body); err != nil {
+			return nil, err
+		}
+		channels[service.ChannelPush] = ct
+	}
+
+	if source.Wechat != nil {
+		ct := &channelTemplate{
+			wechatTemplate: source.Wechat.Template,
+			page:           source.Wechat.Page,
+			data:           make(map[string]executor, len(source.Wechat.Data)),
+		}
+		for key, text := range source.Wechat.Data {
+			if ct.data[key], err = r.parseText("wechat."+key, text); err != nil {
+				return nil, err
+			}
+		}
+		channels[service.ChannelWechat] = ct
+	}
+
+	return channels, nil
+}
+
+// parseText 编译纯文本模板，引用不存在的变量时执行报错
+func (r *TemplateRegistry) parseText(name, text string) (executor, error) {
+	return template.New(name).Option("missingkey=error").Funcs(r.funcs()).Parse(text)
+}
+
+// funcs 模板函数
+func (r *TemplateRegistry) funcs() template.FuncMap {
+	return template.FuncMap{
+		// formatTime 按布局格式化时间，使用默认时区
+		"formatTime": func(layout string, t time.Time) string {
+			return t.In(r.location).Format(layout)
+		},
+		// truncate 按字符数截断，微信订阅消息的 thing 类字段最多 20 个字符
+		"truncate": func(n int, s string) string {
+			runes := []rune(s)
+			if len(runes) <= n {
+				return s
+			}
+			return string(runes[:n])
+		},
+	}
+}
+
+// render 执行渠道模板
+func (ct *channelTemplate) render(data map[string]any) (*service.RenderedNotification, error) {
+	rendered := &service.RenderedNotification{HTML: ct.html, Page: ct.page}
+
+	var err error
+	if rendered.Title, err = execute(ct.title, data); err != nil {
+		return nil, err
+	}
+	if rendered.Content, err = execute(ct.content, data); err != nil {
+		return nil, err
+	}
+	if ct.data != nil {
+		rendered.Data = make(map[string]interface{}, len(ct.data))
+		for key, tpl := range ct.data {
+			value, err := execute(tpl, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered.Data[key] = value
+		}
+	}
+
+	return rendered, nil
+}
+
+// execute 执行模板，模板为空时返回空字符串
+func execute(tpl executor, data map[string]any) (string, error) {
+	if tpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// bindVariables 按声明校验并转换模板变量
+// 缺少声明的变量、传入未声明的变量或类型不符时返回错误
+func bindVariables(declared map[string]string, variables map[string]interface{}) (map[string]any, error) {
+	data := make(map[string]any, len(declared))
+
+	var missing []string
+	for name, kind := range declared {
+		value, ok := variables[name]
+		if !ok || value == nil {
+			missing = append(missing, name)
+			continue
+		}
+		converted, err := convertVariable(kind, value)
+		if err != nil {
+			return nil, fmt.Errorf("变量 %s: %w", name, err)
+		}
+		data[name] = converted
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("缺少变量 %s", strings.Join(missing, ", "))
+	}
+
+	for name := range variables {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("未声明的变量 %s", name)
+		}
+	}
+
+	return data, nil
+}
+
+// convertVariable 将变量转换为声明的类型
+// 兼容 JSON 解码得到的数值(float64、json.Number)及 RFC3339 时间字符串
+func convertVariable(kind string, value any) (any, error) {
+	switch kind {
+	case varString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case varInt:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+		}
+	case varFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f, nil
+			}
+		}
+	case varBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case varTime:
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("应为 %s 类型，实际为 %T", kind, value)
+}
+
+// zeroValue 变量类型的零值，类型未知时返回 nil
+func zeroValue(kind string) any {
+	switch kind {
+	case varString:
+		return ""
+	case varInt:
+		return int64(0)
+	case varFloat:
+		return float64(0)
+	case varBool:
+		return false
+	case varTime:
+		return time.Time{}
+	default:
+		return nil
+	}
+}
+
+// normalizeLocale 规范化语言标识，如 zh_cn -> zh-CN
+func normalizeLocale(locale string) string {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	lang, region, ok := strings.Cut(locale, "-")
+	if !ok {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
+
+// sortedLocales 模板已定义的语言，按字典序排列以保证回退结果稳定
+func sortedLocales(tpl *notificationTemplate) []string {
+	locales := make([]string, 0, len(tpl.locales))
+	for locale := range tpl.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
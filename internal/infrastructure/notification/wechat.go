@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// 微信订阅消息附加数据中的保留字段，其余字段作为模板数据发送
+const (
+	wechatKeyTemplateID       = "template_id"
+	wechatKeyPage             = "page"              // 点击消息跳转的小程序页面
+	wechatKeyMiniprogramState = "miniprogram_state" // 跳转的小程序版本: developer/trial/formal
+)
+
+// 不可重试的微信接口错误
+var wechatPermanentErrCodes = map[errors.ErrorCode]bool{
+	errors.WechatInvalidOpenID:      true,
+	errors.WechatInvalidTemplate:    true,
+	errors.WechatSubscribeQuota:     true, // 用户未订阅或订阅次数已用完，重试也不会成功
+	errors.WechatInvalidMessageData: true,
+	errors.ParamError:               true,
+}
+
+// SubscribeMessageSender 微信订阅消息发送接口，由 service.WechatService 实现
+type SubscribeMessageSender interface {
+	SendSubscribeMessage(ctx context.Context, openid, templateID string, data map[string]any, page, miniprogramState string) error
+}
+
+// WechatDeliverer 微信订阅消息投递器
+type WechatDeliverer struct {
+	sender SubscribeMessageSender
+}
+
+// NewWechatDeliverer 创建微信订阅消息投递器
+func NewWechatDeliverer(sender SubscribeMessageSender) *WechatDeliverer {
+	return &WechatDeliverer{sender: sender}
+}
+
+// Deliver 发送微信订阅消息
+func (d *WechatDeliverer) Deliver(ctx context.Context, n *entity.Notification) error {
+	data := map[string]any{}
+	if n.Payload != "" {
+		if err := json.Unmarshal([]byte(n.Payload), &data); err != nil {
+			return Permanent(errors.Wrap(errors.ParamError, "invalid wechat notification payload", err))
+		}
+	}
+
+	page, _ := data[wechatKeyPage].(string)
+	state, _ := data[wechatKeyMiniprogramState].(string)
+	delete(data, wechatKeyTemplateID)
+	delete(data, wechatKeyPage)
+	delete(data, wechatKeyMiniprogramState)
+
+	err := d.sender.SendSubscribeMessage(ctx, n.Recipient, n.TemplateID, data, page, state)
+	if err != nil && wechatPermanentErrCodes[errors.CodeOf(err)] {
+		return Permanent(err)
+	}
+	return err
+}
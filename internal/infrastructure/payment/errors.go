@@ -0,0 +1,51 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+var errWechatPayNotConfigured = errors.New(errors.PaymentNotConfigured, "微信支付未配置")
+
+// 微信支付错误码到应用错误码的映射，未列出的错误码统一视为 PaymentGatewayError
+var wechatPayErrCodeMapping = map[string]errors.ErrorCode{
+	"ORDER_NOT_EXIST":       errors.NotFound,          // 订单不存在
+	"RESOURCE_NOT_EXISTS":   errors.NotFound,          // 资源不存在
+	"PARAM_ERROR":           errors.ParamError,        // 参数错误
+	"INVALID_REQUEST":       errors.ParamError,        // 请求不符合业务规则
+	"APPID_MCHID_NOT_MATCH": errors.ParamError,        // AppID 与商户号不匹配
+	"ORDERPAID":             errors.Conflict,          // 订单已支付
+	"ORDER_CLOSED":          errors.Conflict,          // 订单已关闭
+	"OUT_TRADE_NO_USED":     errors.Conflict,          // 商户订单号重复
+	"NOT_ENOUGH":            errors.Conflict,          // 商户账户余额不足，无法退款
+	"FREQUENCY_LIMITED":     errors.WechatRateLimited, // 请求频率超限
+	"RATELIMIT_EXCEEDED":    errors.WechatRateLimited, // 请求频率超限
+}
+
+// WechatPayError 微信支付接口返回的错误
+type WechatPayError struct {
+	StatusCode int    `json:"-"`       // HTTP 状态码
+	Code       string `json:"code"`    // 详细错误码，如 ORDER_NOT_EXIST
+	Message    string `json:"message"` // 错误描述
+}
+
+// Error 实现error接口
+func (e *WechatPayError) Error() string {
+	return fmt.Sprintf("wechatpay: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// newWechatPayError 将微信支付的非 2xx 应答转换为应用错误，保留原始错误以便排查
+func newWechatPayError(statusCode int, body []byte) error {
+	apiErr := &WechatPayError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Code == "" {
+		apiErr.Message = string(body)
+	}
+
+	code, ok := wechatPayErrCodeMapping[apiErr.Code]
+	if !ok {
+		code = errors.PaymentGatewayError
+	}
+	return errors.Wrap(code, "微信支付接口调用失败", apiErr)
+}
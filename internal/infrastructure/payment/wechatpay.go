@@ -0,0 +1,641 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const (
+	wechatPayDefaultBaseURL = "https://api.mch.weixin.qq.com"
+	// WechatPayNotifyPath 微信支付回调通知路由
+	WechatPayNotifyPath = "/v1/payments/wechat/notify"
+
+	wechatPayAuthSchema = "WECHATPAY2-SHA256-RSA2048"
+	// wechatPayPublicKeyPrefix 微信支付公钥ID前缀，应答与回调的 Wechatpay-Serial 为公钥ID时使用公钥验签
+	wechatPayPublicKeyPrefix = "PUB_KEY_ID_"
+	// wechatPayMaxSkew 应答与回调时间戳允许的最大偏差，超出视为重放
+	wechatPayMaxSkew = 5 * time.Minute
+	// wechatPayCertRefreshInterval 遇到未知证书序列号时两次下载平台证书的最小间隔
+	wechatPayCertRefreshInterval = time.Minute
+	// wechatPayMaxResponseSize 应答体最大字节数
+	wechatPayMaxResponseSize = 1 << 20
+	// wechatPayMaxBillSize 对账单文件最大字节数
+	wechatPayMaxBillSize = 256 << 20
+	// wechatPayBillDateFormat 账单日期格式
+	wechatPayBillDateFormat = "2006-01-02"
+	// wechatPayTimeFormat 请求中的时间格式，时区必须为数字偏移
+	wechatPayTimeFormat = "2006-01-02T15:04:05-07:00"
+)
+
+var _ domainservice.PaymentGateway = (*WechatPayGateway)(nil)
+
+// WechatPayGateway 微信支付 API v3 网关
+// 直接基于 REST 接口实现：请求以商户私钥签名，应答与回调以平台证书(或微信支付公钥)验签，
+// 回调资源以 APIv3 密钥 AES-256-GCM 解密。未配置商户号时所有操作返回 PaymentNotConfigured
+type WechatPayGateway struct {
+	enabled    bool
+	mchID      string
+	appID      string
+	serialNo   string
+	apiV3Key   string
+	notifyURL  string
+	baseURL    string
+	privateKey *rsa.PrivateKey
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	mu          sync.RWMutex
+	verifyKeys  map[string]*rsa.PublicKey // 平台证书序列号或公钥ID -> 验签公钥
+	refreshedAt time.Time                 // 最近一次下载平台证书的时间
+}
+
+// NewWechatPayGateway 创建微信支付网关
+// 商户私钥、平台证书与微信支付公钥在启动时加载，配置错误直接返回错误；平台证书未配置时在首次验签时下载
+func NewWechatPayGateway(cfg *config.Config, logger *zap.Logger) (*WechatPayGateway, error) {
+	payCfg := cfg.Wechat.Pay
+	g := &WechatPayGateway{
+		mchID:      payCfg.MchID,
+		appID:      payCfg.AppID,
+		serialNo:   payCfg.CertSerialNo,
+		apiV3Key:   payCfg.APIv3Key,
+		notifyURL:  payCfg.NotifyURL,
+		baseURL:    strings.TrimRight(payCfg.BaseURL, "/"),
+		httpClient: &http.Client{Timeout: time.Duration(payCfg.Timeout) * time.Second},
+		logger:     logger,
+		verifyKeys: make(map[string]*rsa.PublicKey),
+	}
+	if payCfg.MchID == "" {
+		return g, nil
+	}
+	g.enabled = true
+
+	if g.appID == "" {
+		g.appID = cfg.Wechat.AppID
+	}
+	if g.notifyURL == "" {
+		g.notifyURL = strings.TrimRight(cfg.Server.BaseURL, "/") + WechatPayNotifyPath
+	}
+	if g.baseURL == "" {
+		g.baseURL = wechatPayDefaultBaseURL
+	}
+	if g.httpClient.Timeout <= 0 {
+		g.httpClient.Timeout = 10 * time.Second
+	}
+
+	if payCfg.CertSerialNo == "" || payCfg.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("wechatpay: cert_serial_no and private_key_path are required")
+	}
+	if len(payCfg.APIv3Key) != 32 {
+		return nil, fmt.Errorf("wechatpay: api_v3_key must be 32 bytes")
+	}
+
+	privateKey, err := loadPrivateKey(payCfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: %w", err)
+	}
+	g.privateKey = privateKey
+
+	for _, path := range payCfg.PlatformCertPaths {
+		serial, key, err := loadCertificate(path)
+		if err != nil {
+			return nil, fmt.Errorf("wechatpay: %w", err)
+		}
+		g.verifyKeys[serial] = key
+	}
+	if payCfg.PublicKeyID != "" {
+		key, err := loadPublicKey(payCfg.PublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("wechatpay: %w", err)
+		}
+		g.verifyKeys[payCfg.PublicKeyID] = key
+	}
+
+	return g, nil
+}
+
+// wechatPayAmount 订单金额
+type wechatPayAmount struct {
+	Total      int64  `json:"total"`
+	PayerTotal int64  `json:"payer_total,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+// wechatPayTransaction 支付订单，查询应答与支付通知资源
+type wechatPayTransaction struct {
+	OutTradeNo    string          `json:"out_trade_no"`
+	TransactionID string          `json:"transaction_id"`
+	TradeState    string          `json:"trade_state"`
+	SuccessTime   string          `json:"success_time"`
+	Amount        wechatPayAmount `json:"amount"`
+}
+
+// wechatPayRefund 退款单，退款应答与退款通知资源
+type wechatPayRefund struct {
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	OutRefundNo   string `json:"out_refund_no"`
+	RefundID      string `json:"refund_id"`
+	Status        string `json:"status"`        // 退款应答与查询应答中的退款状态
+	RefundStatus  string `json:"refund_status"` // 退款通知中的退款状态
+	SuccessTime   string `json:"success_time"`
+	Amount        struct {
+		Total    int64  `json:"total"`
+		Refund   int64  `json:"refund"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+}
+
+// CreatePayment 小程序/JSAPI 下单，返回 wx.requestPayment 所需的参数
+func (g *WechatPayGateway) CreatePayment(ctx context.Context, req domainservice.PaymentRequest) (*domainservice.PrepayResult, error) {
+	if !g.enabled {
+		return nil, errWechatPayNotConfigured
+	}
+	if req.Method != domainservice.PaymentMethodWechat {
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("微信支付不支持支付方式: %s", req.Method))
+	}
+	if req.PaymentNo == "" {
+		return nil, errors.New(errors.ParamError, "缺少商户支付单号")
+	}
+	if req.PayerOpenID == "" {
+		return nil, errors.New(errors.ParamError, "微信支付需要提供付款人 OpenID")
+	}
+	if req.Amount.Currency() != valueobject.CurrencyCNY {
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("微信支付不支持币种: %s", req.Amount.Currency()))
+	}
+
+	description := req.Description
+	if description == "" {
+		description = req.OrderID
+	}
+	body := map[string]interface{}{
+		"appid":        g.appID,
+		"mchid":        g.mchID,
+		"description":  description,
+		"out_trade_no": req.PaymentNo,
+		"notify_url":   g.notifyURL,
+		"amount":       wechatPayAmount{Total: req.Amount.Amount(), Currency: string(valueobject.CurrencyCNY)},
+		"payer":        map[string]string{"openid": req.PayerOpenID},
+	}
+	if !req.ExpireAt.IsZero() {
+		body["time_expire"] = req.ExpireAt.Format(wechatPayTimeFormat)
+	}
+
+	var resp struct {
+		PrepayID string `json:"prepay_id"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/v3/pay/transactions/jsapi", body, &resp); err != nil {
+		return nil, err
+	}
+
+	params, err := g.payParams(resp.PrepayID)
+	if err != nil {
+		return nil, err
+	}
+	return &domainservice.PrepayResult{PrepayID: resp.PrepayID, PayParams: params}, nil
+}
+
+// QueryPayment 按商户订单号查询支付状态
+func (g *WechatPayGateway) QueryPayment(ctx context.Context, transactionID string) (*domainservice.PaymentQueryResult, error) {
+	transaction, err := g.queryTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return &domainservice.PaymentQueryResult{
+		Status:        tradeStatus(transaction.TradeState),
+		TransactionID: transaction.TransactionID,
+		Paying:        transaction.TradeState == "USERPAYING",
+		SucceededAt:   parseWechatPayTime(transaction.SuccessTime),
+	}, nil
+}
+
+// ClosePayment 按商户订单号关闭订单，订单已支付时微信支付返回 ORDERPAID 错误
+func (g *WechatPayGateway) ClosePayment(ctx context.Context, transactionID string) error {
+	if !g.enabled {
+		return errWechatPayNotConfigured
+	}
+
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(transactionID) + "/close"
+	return g.do(ctx, http.MethodPost, path, map[string]string{"mchid": g.mchID}, nil)
+}
+
+// RefundPayment 按商户订单号申请退款
+// 商户退款单号由调用方生成，重复提交同一退款单号时微信支付返回原退款单，不会重复退款
+func (g *WechatPayGateway) RefundPayment(ctx context.Context, req domainservice.RefundRequest) (*domainservice.RefundResult, error) {
+	if !g.enabled {
+		return nil, errWechatPayNotConfigured
+	}
+	if req.PaymentNo == "" || req.RefundNo == "" {
+		return nil, errors.New(errors.ParamError, "缺少商户支付单号或退款单号")
+	}
+	if req.Amount.Currency() != valueobject.CurrencyCNY {
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("微信支付不支持币种: %s", req.Amount.Currency()))
+	}
+
+	body := map[string]interface{}{
+		"out_trade_no":  req.PaymentNo,
+		"out_refund_no": req.RefundNo,
+		"reason":        req.Reason,
+		"notify_url":    g.notifyURL,
+		"amount": map[string]interface{}{
+			"refund":   req.Amount.Amount(),
+			"total":    req.Total.Amount(),
+			"currency": string(valueobject.CurrencyCNY),
+		},
+	}
+
+	var resp wechatPayRefund
+	if err := g.do(ctx, http.MethodPost, "/v3/refund/domestic/refunds", body, &resp); err != nil {
+		return nil, err
+	}
+	return refundResult(&resp), nil
+}
+
+// QueryRefund 按商户退款单号查询退款结果
+func (g *WechatPayGateway) QueryRefund(ctx context.Context, refundNo string) (*domainservice.RefundResult, error) {
+	if !g.enabled {
+		return nil, errWechatPayNotConfigured
+	}
+
+	var resp wechatPayRefund
+	if err := g.do(ctx, http.MethodGet, "/v3/refund/domestic/refunds/"+url.PathEscape(refundNo), nil, &resp); err != nil {
+		return nil, err
+	}
+	return refundResult(&resp), nil
+}
+
+// DownloadStatement 下载交易账单(全部订单)并解析
+// 申请账单得到下载地址与摘要，下载后校验摘要；账单文件的下载应答不签名，无需验签
+func (g *WechatPayGateway) DownloadStatement(ctx context.Context, billDate time.Time) ([]*domainservice.StatementRecord, error) {
+	if !g.enabled {
+		return nil, errWechatPayNotConfigured
+	}
+
+	path := "/v3/bill/tradebill?bill_date=" + billDate.Format(wechatPayBillDateFormat) + "&bill_type=ALL"
+	var bill struct {
+		HashType    string `json:"hash_type"`
+		HashValue   string `json:"hash_value"`
+		DownloadURL string `json:"download_url"`
+	}
+	if err := g.do(ctx, http.MethodGet, path, nil, &bill); err != nil {
+		return nil, err
+	}
+
+	downloadURL, err := url.Parse(bill.DownloadURL)
+	if err != nil || bill.DownloadURL == "" {
+		return nil, errors.New(errors.PaymentGatewayError, "无效的微信支付账单下载地址")
+	}
+	_, data, err := g.send(ctx, http.MethodGet, downloadURL.RequestURI(), nil, wechatPayMaxBillSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyBillHash(data, bill.HashType, bill.HashValue); err != nil {
+		return nil, err
+	}
+
+	return parseWechatPayBill(data)
+}
+
+// ParseNotification 验签并解密微信支付回调通知
+// 支持支付成功(TRANSACTION.*)与退款结果(REFUND.*)通知，验签失败返回 PaymentInvalidSignature
+func (g *WechatPayGateway) ParseNotification(ctx context.Context, header http.Header, body []byte) (*domainservice.PaymentNotification, error) {
+	if !g.enabled {
+		return nil, errWechatPayNotConfigured
+	}
+	if err := g.verify(ctx, header, body); err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		ID        string             `json:"id"`
+		EventType string             `json:"event_type"`
+		Resource  wechatPayEncrypted `json:"resource"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, errors.Wrap(errors.ParamError, "无效的微信支付通知", err)
+	}
+
+	plaintext, err := envelope.Resource.decrypt(g.apiV3Key)
+	if err != nil {
+		return nil, errors.Wrap(errors.PaymentInvalidSignature, "微信支付通知解密失败", err)
+	}
+
+	notification := &domainservice.PaymentNotification{
+		ID:        envelope.ID,
+		EventType: envelope.EventType,
+	}
+	switch {
+	case strings.HasPrefix(envelope.EventType, "TRANSACTION."):
+		var transaction wechatPayTransaction
+		if err := json.Unmarshal(plaintext, &transaction); err != nil {
+			return nil, errors.Wrap(errors.ParamError, "无效的微信支付通知资源", err)
+		}
+		notification.PaymentNo = transaction.OutTradeNo
+		notification.TransactionID = transaction.TransactionID
+		notification.Status = tradeStatus(transaction.TradeState)
+		notification.Amount, _ = valueobject.NewMoney(transaction.Amount.Total, valueobject.CurrencyCNY)
+		notification.SucceededAt = parseWechatPayTime(transaction.SuccessTime)
+
+	case strings.HasPrefix(envelope.EventType, "REFUND."):
+		var refund wechatPayRefund
+		if err := json.Unmarshal(plaintext, &refund); err != nil {
+			return nil, errors.Wrap(errors.ParamError, "无效的微信退款通知资源", err)
+		}
+		notification.PaymentNo = refund.OutTradeNo
+		notification.TransactionID = refund.TransactionID
+		notification.RefundNo = refund.OutRefundNo
+		notification.ChannelRefundID = refund.RefundID
+		notification.RefundStatus = refundStatus(refund.RefundStatus)
+		notification.Amount, _ = valueobject.NewMoney(refund.Amount.Refund, valueobject.CurrencyCNY)
+		notification.SucceededAt = parseWechatPayTime(refund.SuccessTime)
+
+	default:
+		return nil, errors.New(errors.ParamError, fmt.Sprintf("不支持的微信支付通知类型: %s", envelope.EventType))
+	}
+
+	return notification, nil
+}
+
+// queryTransaction 按商户订单号查询订单
+func (g *WechatPayGateway) queryTransaction(ctx context.Context, outTradeNo string) (*wechatPayTransaction, error) {
+	if !g.enabled {
+		return nil, errWechatPayNotConfigured
+	}
+
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo) + "?mchid=" + url.QueryEscape(g.mchID)
+	var transaction wechatPayTransaction
+	if err := g.do(ctx, http.MethodGet, path, nil, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// payParams 生成小程序 wx.requestPayment 参数
+func (g *WechatPayGateway) payParams(prepayID string) (map[string]string, error) {
+	nonce, err := nonceStr()
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "failed to generate nonce", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	pkg := "prepay_id=" + prepayID
+
+	signature, err := signSHA256WithRSA(g.privateKey, signMessage(g.appID, timestamp, nonce, pkg))
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "failed to sign pay params", err)
+	}
+
+	return map[string]string{
+		"appId":     g.appID,
+		"timeStamp": timestamp,
+		"nonceStr":  nonce,
+		"package":   pkg,
+		"signType":  "RSA",
+		"paySign":   signature,
+	}, nil
+}
+
+// do 发送签名请求并验证应答签名，out 不为 nil 时解析应答体
+func (g *WechatPayGateway) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	header, data, err := g.send(ctx, method, path, body, wechatPayMaxResponseSize)
+	if err != nil {
+		return err
+	}
+	if err := g.verify(ctx, header, data); err != nil {
+		return err
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return errors.Wrap(errors.PaymentGatewayError, "无效的微信支付应答", err)
+		}
+	}
+	return nil
+}
+
+// send 发送签名请求，返回 2xx 应答的头与应答体(至多 maxSize 字节)，非 2xx 应答转换为错误
+func (g *WechatPayGateway) send(ctx context.Context, method, path string, body interface{}, maxSize int64) (http.Header, []byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, nil, errors.Wrap(errors.InternalError, "failed to encode wechatpay request", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.InternalError, "failed to create wechatpay request", err)
+	}
+	authorization, err := g.authorization(method, path, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.PaymentGatewayError, "微信支付请求失败", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.PaymentGatewayError, "读取微信支付应答失败", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, newWechatPayError(resp.StatusCode, data)
+	}
+	return resp.Header, data, nil
+}
+
+// authorization 生成请求的 Authorization 头
+func (g *WechatPayGateway) authorization(method, path string, payload []byte) (string, error) {
+	nonce, err := nonceStr()
+	if err != nil {
+		return "", errors.Wrap(errors.InternalError, "failed to generate nonce", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	signature, err := signSHA256WithRSA(g.privateKey, signMessage(method, path, timestamp, nonce, string(payload)))
+	if err != nil {
+		return "", errors.Wrap(errors.InternalError, "failed to sign wechatpay request", err)
+	}
+
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		wechatPayAuthSchema, g.mchID, nonce, signature, timestamp, g.serialNo), nil
+}
+
+// verify 验证应答或回调的签名与时间戳
+func (g *WechatPayGateway) verify(ctx context.Context, header http.Header, body []byte) error {
+	serial := header.Get("Wechatpay-Serial")
+	signature := header.Get("Wechatpay-Signature")
+	timestamp := header.Get("Wechatpay-Timestamp")
+	nonce := header.Get("Wechatpay-Nonce")
+	if serial == "" || signature == "" || timestamp == "" || nonce == "" {
+		return errors.New(errors.PaymentInvalidSignature, "微信支付签名信息缺失")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New(errors.PaymentInvalidSignature, "无效的微信支付签名时间戳")
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > wechatPayMaxSkew || skew < -wechatPayMaxSkew {
+		return errors.New(errors.PaymentInvalidSignature, "微信支付签名已过期")
+	}
+
+	key, err := g.verifyKey(ctx, serial)
+	if err != nil {
+		return err
+	}
+	if err := verifySHA256WithRSA(key, signMessage(timestamp, nonce, string(body)), signature); err != nil {
+		return errors.Wrap(errors.PaymentInvalidSignature, "微信支付签名验证失败", err)
+	}
+	return nil
+}
+
+// verifyKey 按序列号查找验签公钥，平台证书序列号未知时重新下载平台证书
+func (g *WechatPayGateway) verifyKey(ctx context.Context, serial string) (*rsa.PublicKey, error) {
+	g.mu.RLock()
+	key, ok := g.verifyKeys[serial]
+	g.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if strings.HasPrefix(serial, wechatPayPublicKeyPrefix) {
+		return nil, errors.New(errors.PaymentInvalidSignature, fmt.Sprintf("未配置微信支付公钥: %s", serial))
+	}
+
+	if err := g.refreshCertificates(ctx); err != nil {
+		return nil, err
+	}
+
+	g.mu.RLock()
+	key, ok = g.verifyKeys[serial]
+	g.mu.RUnlock()
+	if !ok {
+		return nil, errors.New(errors.PaymentInvalidSignature, fmt.Sprintf("未知的微信支付平台证书: %s", serial))
+	}
+	return key, nil
+}
+
+// refreshCertificates 下载平台证书
+// 证书以 APIv3 密钥加密，能解密即说明来自微信支付；应答签名使用下载到的证书验证
+func (g *WechatPayGateway) refreshCertificates(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if time.Since(g.refreshedAt) < wechatPayCertRefreshInterval {
+		return nil
+	}
+	g.refreshedAt = time.Now()
+
+	header, data, err := g.send(ctx, http.MethodGet, "/v3/certificates", nil, wechatPayMaxResponseSize)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data []struct {
+			SerialNo           string             `json:"serial_no"`
+			EncryptCertificate wechatPayEncrypted `json:"encrypt_certificate"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return errors.Wrap(errors.PaymentGatewayError, "无效的微信支付平台证书应答", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(resp.Data))
+	for _, item := range resp.Data {
+		pemData, err := item.EncryptCertificate.decrypt(g.apiV3Key)
+		if err != nil {
+			return errors.Wrap(errors.PaymentInvalidSignature, "微信支付平台证书解密失败", err)
+		}
+		serial, key, err := parseCertificate(pemData)
+		if err != nil {
+			return errors.Wrap(errors.PaymentGatewayError, "无效的微信支付平台证书", err)
+		}
+		keys[serial] = key
+	}
+
+	key, ok := keys[header.Get("Wechatpay-Serial")]
+	if !ok {
+		return errors.New(errors.PaymentInvalidSignature, "微信支付平台证书应答签名证书未知")
+	}
+	message := signMessage(header.Get("Wechatpay-Timestamp"), header.Get("Wechatpay-Nonce"), string(data))
+	if err := verifySHA256WithRSA(key, message, header.Get("Wechatpay-Signature")); err != nil {
+		return errors.Wrap(errors.PaymentInvalidSignature, "微信支付平台证书应答签名验证失败", err)
+	}
+
+	for serial, key := range keys {
+		g.verifyKeys[serial] = key
+	}
+	g.logger.Info("Refreshed wechatpay platform certificates", zap.Int("count", len(keys)))
+	return nil
+}
+
+// tradeStatus 微信支付交易状态转换为支付状态
+func tradeStatus(tradeState string) domainservice.PaymentStatus {
+	switch tradeState {
+	case "SUCCESS":
+		return domainservice.PaymentStatusPaid
+	case "REFUND":
+		return domainservice.PaymentStatusRefunded
+	case "CLOSED", "REVOKED":
+		return domainservice.PaymentStatusCancelled
+	case "PAYERROR":
+		return domainservice.PaymentStatusFailed
+	default: // NOTPAY、USERPAYING
+		return domainservice.PaymentStatusPending
+	}
+}
+
+// refundStatus 微信退款状态转换为退款状态
+func refundStatus(status string) domainservice.RefundStatus {
+	switch status {
+	case "SUCCESS":
+		return domainservice.RefundStatusSucceeded
+	case "CLOSED", "ABNORMAL":
+		return domainservice.RefundStatusFailed
+	default: // PROCESSING
+		return domainservice.RefundStatusProcessing
+	}
+}
+
+// refundResult 退款应答或查询应答转换为退款结果
+func refundResult(refund *wechatPayRefund) *domainservice.RefundResult {
+	result := &domainservice.RefundResult{
+		ChannelRefundID: refund.RefundID,
+		Status:          refundStatus(refund.Status),
+		SucceededAt:     parseWechatPayTime(refund.SuccessTime),
+	}
+	if result.Status == domainservice.RefundStatusFailed {
+		result.FailReason = "微信退款状态: " + refund.Status
+	}
+	return result
+}
+
+// parseWechatPayTime 解析 RFC3339 格式的时间，格式错误时返回零值
+func parseWechatPayTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
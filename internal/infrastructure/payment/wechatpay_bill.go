@@ -0,0 +1,173 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// wechatPayBillLocation 微信支付账单时间所在时区(北京时间)
+var wechatPayBillLocation = time.FixedZone("CST", 8*3600)
+
+// 微信支付交易账单列名
+const (
+	billColumnTradeTime       = "交易时间"
+	billColumnTransactionID   = "微信订单号"
+	billColumnOutTradeNo      = "商户订单号"
+	billColumnTradeState      = "交易状态"
+	billColumnCurrency        = "货币种类"
+	billColumnTotal           = "订单金额"
+	billColumnSettlementTotal = "应结订单金额"
+	billColumnRefundID        = "微信退款单号"
+	billColumnOutRefundNo     = "商户退款单号"
+	billColumnRefundApplied   = "申请退款金额"
+	billColumnRefund          = "退款金额"
+	billColumnRefundStatus    = "退款状态"
+	// billSummaryColumn 汇总部分的首列，之后的行不是订单明细
+	billSummaryColumn = "总交易单数"
+)
+
+// verifyBillHash 校验账单文件摘要
+func verifyBillHash(data []byte, hashType, hashValue string) error {
+	if !strings.EqualFold(hashType, "SHA1") {
+		return errors.New(errors.PaymentGatewayError, fmt.Sprintf("不支持的微信支付账单摘要算法: %s", hashType))
+	}
+	sum := sha1.Sum(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), hashValue) {
+		return errors.New(errors.PaymentGatewayError, "微信支付账单摘要校验失败")
+	}
+	return nil
+}
+
+// parseWechatPayBill 解析微信支付交易账单(CSV)
+// 首行为列名，之后每行一笔订单，字段值以反引号开头；明细之后是以"总交易单数"开头的汇总部分。
+// 支付成功(SUCCESS)的行解析为交易记录，转入退款(REFUND)的行解析为退款记录，其余行(如已撤销)忽略
+func parseWechatPayBill(data []byte) ([]*domainservice.StatementRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.PaymentGatewayError, "无效的微信支付账单", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{billColumnTradeTime, billColumnTransactionID, billColumnOutTradeNo, billColumnTradeState} {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.New(errors.PaymentGatewayError, fmt.Sprintf("微信支付账单缺少列: %s", name))
+		}
+	}
+
+	var records []*domainservice.StatementRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(errors.PaymentGatewayError, fmt.Sprintf("无效的微信支付账单第 %d 行", line), err)
+		}
+		if len(row) > 0 && strings.TrimSpace(row[0]) == billSummaryColumn {
+			break
+		}
+
+		record, err := parseBillRow(billRow{row: row, columns: columns})
+		if err != nil {
+			return nil, errors.Wrap(errors.PaymentGatewayError, fmt.Sprintf("无效的微信支付账单第 %d 行", line), err)
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// billRow 账单明细行
+type billRow struct {
+	row     []string
+	columns map[string]int
+}
+
+// get 按列名取值，去掉字段值前的反引号，列不存在时返回空
+func (r billRow) get(names ...string) string {
+	for _, name := range names {
+		if i, ok := r.columns[name]; ok && i < len(r.row) {
+			return strings.TrimPrefix(strings.TrimSpace(r.row[i]), "`")
+		}
+	}
+	return ""
+}
+
+// parseBillRow 解析账单明细行，不需要核对的行返回 nil
+func parseBillRow(r billRow) (*domainservice.StatementRecord, error) {
+	record := &domainservice.StatementRecord{
+		PaymentNo:     r.get(billColumnOutTradeNo),
+		TransactionID: r.get(billColumnTransactionID),
+	}
+	occurredAt, err := time.ParseInLocation("2006-01-02 15:04:05", r.get(billColumnTradeTime), wechatPayBillLocation)
+	if err != nil {
+		return nil, err
+	}
+	record.OccurredAt = occurredAt
+
+	var amount string
+	switch r.get(billColumnTradeState) {
+	case "SUCCESS":
+		record.Type = entity.StatementRecordTrade
+		amount = r.get(billColumnTotal, billColumnSettlementTotal)
+	case "REFUND":
+		record.Type = entity.StatementRecordRefund
+		record.RefundNo = r.get(billColumnOutRefundNo)
+		record.ChannelRefundID = r.get(billColumnRefundID)
+		record.RefundStatus = refundStatus(r.get(billColumnRefundStatus))
+		amount = r.get(billColumnRefundApplied, billColumnRefund)
+	default:
+		return nil, nil
+	}
+
+	cents, err := parseYuan(amount)
+	if err != nil {
+		return nil, err
+	}
+	// 货币种类为空时按人民币处理
+	if record.Amount, err = valueobject.NewMoney(cents, valueobject.Currency(r.get(billColumnCurrency))); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// parseYuan 将以元为单位的金额(如 12.30)精确转换为分
+func parseYuan(value string) (int64, error) {
+	yuan, fraction, _ := strings.Cut(value, ".")
+	if yuan == "" || len(fraction) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	y, err := strconv.ParseInt(yuan, 10, 64)
+	if err != nil || y < 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	f, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return y*100 + f, nil
+}
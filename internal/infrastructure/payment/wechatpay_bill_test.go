@@ -0,0 +1,181 @@
+package payment
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+)
+
+const testBillHeader = "交易时间,微信订单号,商户订单号,交易状态,货币种类,订单金额\n"
+
+func TestParseWechatPayBill(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "wechatpay_trade_bill.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("\xef\xbb\xbf")) {
+		t.Fatal("fixture should start with a UTF-8 BOM")
+	}
+
+	records, err := parseWechatPayBill(data)
+	if err != nil {
+		t.Fatalf("parseWechatPayBill() error = %v", err)
+	}
+
+	// 已撤销(REVOKED)的行与汇总部分不产生记录
+	want := []domainservice.StatementRecord{
+		{
+			Type:          entity.StatementRecordTrade,
+			PaymentNo:     "P001",
+			TransactionID: "4200000001202610160001",
+			Amount:        cnyCents(t, 10),
+			OccurredAt:    time.Date(2026, 10, 16, 9, 30, 0, 0, wechatPayBillLocation),
+		},
+		{
+			Type:          entity.StatementRecordTrade,
+			PaymentNo:     "P002",
+			TransactionID: "4200000001202610160002",
+			Amount:        cnyCents(t, 1230),
+			OccurredAt:    time.Date(2026, 10, 16, 10, 15, 20, 0, wechatPayBillLocation),
+		},
+		{
+			Type:            entity.StatementRecordRefund,
+			PaymentNo:       "P001",
+			TransactionID:   "4200000001202610160001",
+			RefundNo:        "R001",
+			ChannelRefundID: "50300000012026101600001",
+			RefundStatus:    domainservice.RefundStatusSucceeded,
+			Amount:          cnyCents(t, 10),
+			OccurredAt:      time.Date(2026, 10, 16, 11, 0, 0, 0, wechatPayBillLocation),
+		},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records = %d, want %d: %+v", len(records), len(want), records)
+	}
+	for i, got := range records {
+		w := want[i]
+		if got.Type != w.Type || got.PaymentNo != w.PaymentNo || got.TransactionID != w.TransactionID ||
+			got.RefundNo != w.RefundNo || got.ChannelRefundID != w.ChannelRefundID || got.RefundStatus != w.RefundStatus ||
+			!got.Amount.Equals(w.Amount) || !got.OccurredAt.Equal(w.OccurredAt) {
+			t.Errorf("records[%d] = %+v, want %+v", i, *got, w)
+		}
+	}
+}
+
+func TestParseWechatPayBillErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantError string
+	}{
+		{"missing column", "交易时间,微信订单号,交易状态\n", "商户订单号"},
+		{"sub-cent amount", testBillHeader + "`2026-10-16 09:30:00,`4200000001,`P001,`SUCCESS,`CNY,`1.234\n", "第 2 行"},
+		{"invalid trade time", testBillHeader + "`2026/10/16 09:30,`4200000001,`P001,`SUCCESS,`CNY,`1.00\n", "第 2 行"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := parseWechatPayBill([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("parseWechatPayBill() = %v, %v, want error containing %q", records, err, tt.wantError)
+			}
+		})
+	}
+
+	// 无法解析的行位于汇总部分之后时不影响明细
+	data := testBillHeader + "`2026-10-16 09:30:00,`4200000001,`P001,`SUCCESS,`CNY,`1.00\n" +
+		"总交易单数,应结订单总金额\n`1,`1.234\n"
+	records, err := parseWechatPayBill([]byte(data))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("parseWechatPayBill() = %v, %v, want one record", records, err)
+	}
+
+	if records, err := parseWechatPayBill(nil); err != nil || records != nil {
+		t.Errorf("parseWechatPayBill(nil) = %v, %v, want empty", records, err)
+	}
+}
+
+func TestParseBillRow(t *testing.T) {
+	columns := func(names ...string) map[string]int {
+		m := make(map[string]int, len(names))
+		for i, name := range names {
+			m[name] = i
+		}
+		return m
+	}
+
+	// 缺少订单金额列时取应结订单金额，货币种类为空时按人民币处理
+	record, err := parseBillRow(billRow{
+		row:     []string{"`2026-10-16 09:30:00", "`4200000001", "`P001", "`SUCCESS", "`", "`0.50"},
+		columns: columns(billColumnTradeTime, billColumnTransactionID, billColumnOutTradeNo, billColumnTradeState, billColumnCurrency, billColumnSettlementTotal),
+	})
+	if err != nil {
+		t.Fatalf("parseBillRow() error = %v", err)
+	}
+	if !record.Amount.Equals(cnyCents(t, 50)) {
+		t.Errorf("Amount = %s, want 50 CNY cents", record.Amount)
+	}
+
+	// 缺少申请退款金额列时取退款金额
+	record, err = parseBillRow(billRow{
+		row:     []string{"`2026-10-16 11:00:00", "`4200000001", "`P001", "`REFUND", "`503000001", "`R001", "`PROCESSING", "`0.30"},
+		columns: columns(billColumnTradeTime, billColumnTransactionID, billColumnOutTradeNo, billColumnTradeState, billColumnRefundID, billColumnOutRefundNo, billColumnRefundStatus, billColumnRefund),
+	})
+	if err != nil {
+		t.Fatalf("parseBillRow() error = %v", err)
+	}
+	if record.Type != entity.StatementRecordRefund || record.RefundStatus != domainservice.RefundStatusProcessing ||
+		record.RefundNo != "R001" || !record.Amount.Equals(cnyCents(t, 30)) {
+		t.Errorf("refund record = %+v", record)
+	}
+
+	record, err = parseBillRow(billRow{
+		row:     []string{"`2026-10-16 12:00:00", "`4200000001", "`P001", "`REVOKED"},
+		columns: columns(billColumnTradeTime, billColumnTransactionID, billColumnOutTradeNo, billColumnTradeState),
+	})
+	if err != nil || record != nil {
+		t.Errorf("parseBillRow(REVOKED) = %+v, %v, want skipped", record, err)
+	}
+}
+
+func TestParseYuan(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      int64
+		wantError bool
+	}{
+		{"0.1", 10, false},
+		{"12.30", 1230, false},
+		{"12", 1200, false},
+		{"0.00", 0, false},
+		{"1.", 100, false},
+		{"1.234", 0, true},
+		{"", 0, true},
+		{".5", 0, true},
+		{"-1.00", 0, true},
+		{"1.-5", 0, true},
+		{"1.2x", 0, true},
+		{"1,000.00", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseYuan(tt.value)
+		if (err != nil) != tt.wantError || got != tt.want {
+			t.Errorf("parseYuan(%q) = %d, %v, want %d (error %v)", tt.value, got, err, tt.want, tt.wantError)
+		}
+	}
+}
+
+func cnyCents(t *testing.T, cents int64) valueobject.Money {
+	t.Helper()
+	money, err := valueobject.NewMoney(cents, valueobject.CurrencyCNY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return money
+}
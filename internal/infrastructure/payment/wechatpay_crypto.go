@@ -0,0 +1,171 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// wechatPayAlgorithm 回调通知与平台证书的加密算法
+const wechatPayAlgorithm = "AEAD_AES_256_GCM"
+
+// wechatPayEncrypted 以 APIv3 密钥加密的数据，用于回调通知资源与平台证书
+type wechatPayEncrypted struct {
+	Algorithm      string `json:"algorithm"`
+	Ciphertext     string `json:"ciphertext"`
+	AssociatedData string `json:"associated_data"`
+	Nonce          string `json:"nonce"`
+}
+
+// decrypt 使用 APIv3 密钥以 AES-256-GCM 解密
+func (e *wechatPayEncrypted) decrypt(apiV3Key string) ([]byte, error) {
+	if e.Algorithm != wechatPayAlgorithm {
+		return nil, fmt.Errorf("unsupported algorithm %q", e.Algorithm)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	block, err := aes.NewCipher([]byte(apiV3Key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(e.Nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, []byte(e.Nonce), ciphertext, []byte(e.AssociatedData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// signMessage 按微信支付签名串格式拼接，每个字段以换行结尾
+func signMessage(fields ...string) string {
+	return strings.Join(fields, "\n") + "\n"
+}
+
+// signSHA256WithRSA 使用商户私钥对签名串做 SHA256-RSA 签名，返回 Base64 编码
+func signSHA256WithRSA(key *rsa.PrivateKey, message string) (string, error) {
+	digest := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verifySHA256WithRSA 使用平台证书公钥验证 Base64 编码的 SHA256-RSA 签名
+func verifySHA256WithRSA(key *rsa.PublicKey, message, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	digest := sha256.Sum256([]byte(message))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], raw)
+}
+
+// nonceStr 生成 32 位随机串
+func nonceStr() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// loadPrivateKey 读取商户 API 私钥，支持 PKCS#8 与 PKCS#1 格式
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an RSA private key", path)
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid private key: %w", path, err)
+	}
+	return key, nil
+}
+
+// loadPublicKey 读取微信支付公钥
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid public key: %w", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA public key", path)
+	}
+	return rsaKey, nil
+}
+
+// loadCertificate 读取平台证书，返回证书序列号与公钥
+func loadCertificate(path string) (string, *rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	serial, key, err := parseCertificate(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return serial, key, nil
+}
+
+// parseCertificate 解析 PEM 格式的平台证书，返回证书序列号(大写十六进制)与公钥
+func parseCertificate(data []byte) (string, *rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", nil, fmt.Errorf("invalid PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return "", nil, fmt.Errorf("certificate does not contain an RSA public key")
+	}
+	return strings.ToUpper(cert.SerialNumber.Text(16)), key, nil
+}
+
+// readPEM 读取 PEM 文件的第一个块
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
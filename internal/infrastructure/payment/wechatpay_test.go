@@ -0,0 +1,581 @@
+package payment
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
+	"github.com/wxlbd/polaris/internal/domain/valueobject"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const (
+	testMchID          = "1900000001"
+	testAppID          = "wx1234567890abcdef"
+	testMerchantSerial = "3775B6A45ACD588826D15E583A95F5DD"
+	testAPIv3Key       = "0123456789abcdef0123456789abcdef"
+)
+
+// fakeWechatPay 模拟微信支付 API v3 服务
+// 校验请求签名，应答以平台证书私钥签名，/v3/certificates 返回以 APIv3 密钥加密的平台证书
+type fakeWechatPay struct {
+	t              *testing.T
+	server         *httptest.Server
+	merchantKey    *rsa.PrivateKey
+	platformKey    *rsa.PrivateKey
+	platformCert   []byte
+	platformSerial string
+
+	mu           sync.Mutex
+	routes       map[string]func(body []byte) (int, interface{})
+	certRequests int
+	// tamper 修改已签名的应答体，用于模拟应答被篡改
+	tamper func(body []byte) []byte
+	// signedAt 应答签名时间，零值为当前时间
+	signedAt time.Time
+}
+
+func newFakeWechatPay(t *testing.T) *fakeWechatPay {
+	t.Helper()
+	f := &fakeWechatPay{
+		t:           t,
+		merchantKey: generateKey(t),
+		platformKey: generateKey(t),
+		routes:      make(map[string]func(body []byte) (int, interface{})),
+	}
+	f.platformCert, f.platformSerial = selfSignedCertificate(t, f.platformKey)
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// handle 注册接口应答，key 为 "METHOD /path"(不含查询参数)
+func (f *fakeWechatPay) handle(key string, fn func(body []byte) (int, interface{})) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routes[key] = fn
+}
+
+func (f *fakeWechatPay) setTamper(fn func(body []byte) []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tamper = fn
+}
+
+func (f *fakeWechatPay) setSignedAt(signedAt time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signedAt = signedAt
+}
+
+// certificateDownloads 平台证书的下载次数
+func (f *fakeWechatPay) certificateDownloads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.certRequests
+}
+
+// gateway 创建指向模拟服务的网关
+func (f *fakeWechatPay) gateway() *WechatPayGateway {
+	f.t.Helper()
+	keyPath := filepath.Join(f.t.TempDir(), "apiclient_key.pem")
+	der, err := x509.MarshalPKCS8PrivateKey(f.merchantKey)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		f.t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Wechat.AppID = testAppID
+	cfg.Wechat.Pay = config.WechatPayConfig{
+		MchID:          testMchID,
+		CertSerialNo:   testMerchantSerial,
+		PrivateKeyPath: keyPath,
+		APIv3Key:       testAPIv3Key,
+		NotifyURL:      "https://example.com" + WechatPayNotifyPath,
+		BaseURL:        f.server.URL,
+		Timeout:        5,
+	}
+	g, err := NewWechatPayGateway(cfg, zap.NewNop())
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return g
+}
+
+func (f *fakeWechatPay) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := f.verifyRequest(r, body); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.mu.Lock()
+	route := f.routes[r.Method+" "+r.URL.Path]
+	if r.URL.Path == "/v3/certificates" {
+		f.certRequests++
+	}
+	f.mu.Unlock()
+
+	var status int
+	var resp interface{}
+	switch {
+	case r.URL.Path == "/v3/certificates":
+		status, resp = http.StatusOK, f.certificatesResponse()
+	case route != nil:
+		status, resp = route(body)
+	default:
+		status, resp = http.StatusNotFound, map[string]string{"code": "RESOURCE_NOT_EXISTS", "message": "not found"}
+	}
+
+	var data []byte
+	if resp != nil {
+		data, _ = json.Marshal(resp)
+	}
+	f.writeSigned(w, status, data)
+}
+
+// verifyRequest 按商户公钥校验请求的 Authorization 头
+func (f *fakeWechatPay) verifyRequest(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, wechatPayAuthSchema+" ") {
+		return fmt.Errorf("unexpected authorization schema: %q", auth)
+	}
+	fields := parseAuthorization(strings.TrimPrefix(auth, wechatPayAuthSchema+" "))
+	if fields["mchid"] != testMchID || fields["serial_no"] != testMerchantSerial {
+		return fmt.Errorf("unexpected merchant fields: %v", fields)
+	}
+	message := signMessage(r.Method, r.URL.RequestURI(), fields["timestamp"], fields["nonce_str"], string(body))
+	if err := verifySHA256WithRSA(&f.merchantKey.PublicKey, message, fields["signature"]); err != nil {
+		return fmt.Errorf("invalid request signature: %v", err)
+	}
+	return nil
+}
+
+// writeSigned 以平台证书私钥签名应答
+func (f *fakeWechatPay) writeSigned(w http.ResponseWriter, status int, body []byte) {
+	f.mu.Lock()
+	signedAt, tamper := f.signedAt, f.tamper
+	f.mu.Unlock()
+	if signedAt.IsZero() {
+		signedAt = time.Now()
+	}
+
+	for key, value := range f.sign(signedAt, body) {
+		w.Header().Set(key, value)
+	}
+	if tamper != nil {
+		body = tamper(body)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// sign 生成应答或回调的签名头
+func (f *fakeWechatPay) sign(signedAt time.Time, body []byte) map[string]string {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	nonce := "fake-nonce-" + timestamp
+	signature, err := signSHA256WithRSA(f.platformKey, signMessage(timestamp, nonce, string(body)))
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return map[string]string{
+		"Wechatpay-Serial":    f.platformSerial,
+		"Wechatpay-Timestamp": timestamp,
+		"Wechatpay-Nonce":     nonce,
+		"Wechatpay-Signature": signature,
+	}
+}
+
+func (f *fakeWechatPay) certificatesResponse() interface{} {
+	return map[string]interface{}{
+		"data": []map[string]interface{}{{
+			"serial_no":           f.platformSerial,
+			"effective_time":      time.Now().Add(-time.Hour).Format(time.RFC3339),
+			"expire_time":         time.Now().Add(time.Hour).Format(time.RFC3339),
+			"encrypt_certificate": encryptResource(f.t, f.platformCert, "certificate"),
+		}},
+	}
+}
+
+// notification 生成已签名的回调通知
+func (f *fakeWechatPay) notification(eventType string, resource interface{}) (http.Header, []byte) {
+	plaintext, err := json.Marshal(resource)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"id":            "EV-2018022511223320873",
+		"create_time":   time.Now().Format(time.RFC3339),
+		"resource_type": "encrypt-resource",
+		"event_type":    eventType,
+		"resource":      encryptResource(f.t, plaintext, "transaction"),
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	header := http.Header{}
+	for key, value := range f.sign(time.Now(), body) {
+		header.Set(key, value)
+	}
+	return header, body
+}
+
+func TestWechatPayCreatePaymentSignsRequest(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	expireAt := time.Date(2026, 10, 17, 12, 30, 0, 0, time.FixedZone("CST", 8*3600))
+
+	var received map[string]interface{}
+	fake.handle("POST /v3/pay/transactions/jsapi", func(body []byte) (int, interface{}) {
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		return http.StatusOK, map[string]string{"prepay_id": "wx201410272009395522657a690389285100"}
+	})
+
+	g := fake.gateway()
+	amount, _ := valueobject.NewMoney(100, valueobject.CurrencyCNY)
+	result, err := g.CreatePayment(t.Context(), domainservice.PaymentRequest{
+		OrderID:     "1001",
+		PaymentNo:   "P20261017000001",
+		Amount:      amount,
+		Method:      domainservice.PaymentMethodWechat,
+		Description: "会员",
+		ExpireAt:    expireAt,
+		PayerOpenID: "openid-1",
+	})
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+
+	if received["out_trade_no"] != "P20261017000001" || received["mchid"] != testMchID || received["appid"] != testAppID {
+		t.Errorf("unexpected request body: %v", received)
+	}
+	if received["time_expire"] != "2026-10-17T12:30:00+08:00" {
+		t.Errorf("time_expire = %v, want 2026-10-17T12:30:00+08:00", received["time_expire"])
+	}
+	if result.PrepayID != "wx201410272009395522657a690389285100" {
+		t.Errorf("PrepayID = %q", result.PrepayID)
+	}
+
+	// 小程序拉起支付的参数以商户私钥签名
+	params := result.PayParams
+	if params["package"] != "prepay_id="+result.PrepayID || params["signType"] != "RSA" {
+		t.Errorf("unexpected pay params: %v", params)
+	}
+	message := signMessage(params["appId"], params["timeStamp"], params["nonceStr"], params["package"])
+	if err := verifySHA256WithRSA(&fake.merchantKey.PublicKey, message, params["paySign"]); err != nil {
+		t.Errorf("invalid paySign: %v", err)
+	}
+	if fake.certificateDownloads() != 1 {
+		t.Errorf("certificate downloads = %d, want 1", fake.certificateDownloads())
+	}
+}
+
+func TestWechatPayVerifiesResponse(t *testing.T) {
+	transaction := func(body []byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"out_trade_no":   "P1",
+			"transaction_id": "4200000001",
+			"trade_state":    "SUCCESS",
+			"success_time":   "2026-10-17T10:00:00+08:00",
+		}
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		fake := newFakeWechatPay(t)
+		fake.handle("GET /v3/pay/transactions/out-trade-no/P1", transaction)
+		g := fake.gateway()
+
+		result, err := g.QueryPayment(t.Context(), "P1")
+		if err != nil {
+			t.Fatalf("QueryPayment() error = %v", err)
+		}
+		if result.Status != domainservice.PaymentStatusPaid || result.TransactionID != "4200000001" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if want := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC); !result.SucceededAt.Equal(want) {
+			t.Errorf("SucceededAt = %v, want %v", result.SucceededAt, want)
+		}
+
+		// 平台证书已缓存，不再重复下载
+		if _, err := g.QueryPayment(t.Context(), "P1"); err != nil {
+			t.Fatalf("QueryPayment() error = %v", err)
+		}
+		if fake.certificateDownloads() != 1 {
+			t.Errorf("certificate downloads = %d, want 1", fake.certificateDownloads())
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		fake := newFakeWechatPay(t)
+		fake.handle("GET /v3/pay/transactions/out-trade-no/P1", transaction)
+		g := fake.gateway()
+		if _, err := g.QueryPayment(t.Context(), "P1"); err != nil {
+			t.Fatalf("QueryPayment() error = %v", err)
+		}
+
+		fake.setTamper(func(body []byte) []byte {
+			return []byte(strings.Replace(string(body), "SUCCESS", "CLOSED", 1))
+		})
+		_, err := g.QueryPayment(t.Context(), "P1")
+		if code := errors.CodeOf(err); code != errors.PaymentInvalidSignature {
+			t.Errorf("error code = %v, want PaymentInvalidSignature (err = %v)", code, err)
+		}
+	})
+
+	t.Run("stale timestamp", func(t *testing.T) {
+		fake := newFakeWechatPay(t)
+		fake.handle("GET /v3/pay/transactions/out-trade-no/P1", transaction)
+		g := fake.gateway()
+		if _, err := g.QueryPayment(t.Context(), "P1"); err != nil {
+			t.Fatalf("QueryPayment() error = %v", err)
+		}
+
+		fake.setSignedAt(time.Now().Add(-2 * wechatPayMaxSkew))
+		_, err := g.QueryPayment(t.Context(), "P1")
+		if code := errors.CodeOf(err); code != errors.PaymentInvalidSignature {
+			t.Errorf("error code = %v, want PaymentInvalidSignature (err = %v)", code, err)
+		}
+	})
+
+	t.Run("unknown platform certificate", func(t *testing.T) {
+		fake := newFakeWechatPay(t)
+		fake.handle("GET /v3/pay/transactions/out-trade-no/P1", transaction)
+		g := fake.gateway()
+
+		// 应答由另一张证书签名时，下载的平台证书中没有该序列号
+		other := newFakeWechatPay(t)
+		header, body := other.notification("TRANSACTION.SUCCESS", map[string]string{})
+		_, err := g.ParseNotification(t.Context(), header, body)
+		if code := errors.CodeOf(err); code != errors.PaymentInvalidSignature {
+			t.Errorf("error code = %v, want PaymentInvalidSignature (err = %v)", code, err)
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		fake := newFakeWechatPay(t)
+		fake.handle("GET /v3/pay/transactions/out-trade-no/P1", func(body []byte) (int, interface{}) {
+			return http.StatusNotFound, map[string]string{"code": "ORDER_NOT_EXIST", "message": "订单不存在"}
+		})
+		g := fake.gateway()
+
+		_, err := g.QueryPayment(t.Context(), "P1")
+		if code := errors.CodeOf(err); code != errors.NotFound {
+			t.Errorf("error code = %v, want NotFound (err = %v)", code, err)
+		}
+	})
+}
+
+func TestWechatPayParseNotification(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	g := fake.gateway()
+
+	t.Run("transaction", func(t *testing.T) {
+		header, body := fake.notification("TRANSACTION.SUCCESS", map[string]interface{}{
+			"out_trade_no":   "P1",
+			"transaction_id": "4200000001",
+			"trade_state":    "SUCCESS",
+			"success_time":   "2026-10-17T10:00:00+08:00",
+			"amount":         map[string]interface{}{"total": 100, "payer_total": 100, "currency": "CNY"},
+		})
+
+		n, err := g.ParseNotification(t.Context(), header, body)
+		if err != nil {
+			t.Fatalf("ParseNotification() error = %v", err)
+		}
+		if n.PaymentNo != "P1" || n.TransactionID != "4200000001" || n.Status != domainservice.PaymentStatusPaid {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+		if n.Amount.Amount() != 100 || n.EventType != "TRANSACTION.SUCCESS" {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+	})
+
+	t.Run("refund", func(t *testing.T) {
+		header, body := fake.notification("REFUND.SUCCESS", map[string]interface{}{
+			"out_trade_no":   "P1",
+			"transaction_id": "4200000001",
+			"out_refund_no":  "R1",
+			"refund_id":      "50000000382019052709732678859",
+			"refund_status":  "SUCCESS",
+			"success_time":   "2026-10-17T11:00:00+08:00",
+			"amount":         map[string]interface{}{"total": 100, "refund": 30},
+		})
+
+		n, err := g.ParseNotification(t.Context(), header, body)
+		if err != nil {
+			t.Fatalf("ParseNotification() error = %v", err)
+		}
+		if n.RefundNo != "R1" || n.RefundStatus != domainservice.RefundStatusSucceeded || n.Amount.Amount() != 30 {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+		header, body := fake.notification("TRANSACTION.SUCCESS", map[string]string{"out_trade_no": "P1"})
+
+		// 重新签名被篡改的通知，验签通过但 GCM 认证失败
+		var envelope map[string]interface{}
+		_ = json.Unmarshal(body, &envelope)
+		resource := envelope["resource"].(map[string]interface{})
+		ciphertext, _ := base64.StdEncoding.DecodeString(resource["ciphertext"].(string))
+		ciphertext[0] ^= 0xff
+		resource["ciphertext"] = base64.StdEncoding.EncodeToString(ciphertext)
+		body, _ = json.Marshal(envelope)
+		header = http.Header{}
+		for key, value := range fake.sign(time.Now(), body) {
+			header.Set(key, value)
+		}
+
+		_, err := g.ParseNotification(t.Context(), header, body)
+		if code := errors.CodeOf(err); code != errors.PaymentInvalidSignature {
+			t.Errorf("error code = %v, want PaymentInvalidSignature (err = %v)", code, err)
+		}
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		header, body := fake.notification("TRANSACTION.SUCCESS", map[string]string{"out_trade_no": "P1"})
+		header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString([]byte("forged")))
+
+		_, err := g.ParseNotification(t.Context(), header, body)
+		if code := errors.CodeOf(err); code != errors.PaymentInvalidSignature {
+			t.Errorf("error code = %v, want PaymentInvalidSignature (err = %v)", code, err)
+		}
+	})
+}
+
+func TestWechatPayEncryptedDecrypt(t *testing.T) {
+	encrypted := encryptResource(t, []byte(`{"out_trade_no":"P1"}`), "transaction")
+
+	plaintext, err := encrypted.decrypt(testAPIv3Key)
+	if err != nil {
+		t.Fatalf("decrypt() error = %v", err)
+	}
+	if string(plaintext) != `{"out_trade_no":"P1"}` {
+		t.Errorf("decrypt() = %s", plaintext)
+	}
+
+	if _, err := encrypted.decrypt(strings.Repeat("x", 32)); err == nil {
+		t.Error("decrypt() with wrong key succeeded")
+	}
+
+	wrongAD := encrypted
+	wrongAD.AssociatedData = "refund"
+	if _, err := wrongAD.decrypt(testAPIv3Key); err == nil {
+		t.Error("decrypt() with wrong associated data succeeded")
+	}
+
+	wrongAlgorithm := encrypted
+	wrongAlgorithm.Algorithm = "AES_256_CBC"
+	if _, err := wrongAlgorithm.decrypt(testAPIv3Key); err == nil {
+		t.Error("decrypt() with unsupported algorithm succeeded")
+	}
+}
+
+func TestTradeStatus(t *testing.T) {
+	tests := []struct {
+		tradeState string
+		want       domainservice.PaymentStatus
+	}{
+		{"SUCCESS", domainservice.PaymentStatusPaid},
+		{"REFUND", domainservice.PaymentStatusRefunded},
+		{"CLOSED", domainservice.PaymentStatusCancelled},
+		{"REVOKED", domainservice.PaymentStatusCancelled},
+		{"PAYERROR", domainservice.PaymentStatusFailed},
+		{"NOTPAY", domainservice.PaymentStatusPending},
+		{"USERPAYING", domainservice.PaymentStatusPending},
+		{"", domainservice.PaymentStatusPending},
+	}
+	for _, tt := range tests {
+		if got := tradeStatus(tt.tradeState); got != tt.want {
+			t.Errorf("tradeStatus(%q) = %s, want %s", tt.tradeState, got, tt.want)
+		}
+	}
+}
+
+// encryptResource 以 APIv3 密钥 AES-256-GCM 加密
+func encryptResource(t *testing.T, plaintext []byte, associatedData string) wechatPayEncrypted {
+	t.Helper()
+	block, err := aes.NewCipher([]byte(testAPIv3Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := "fGQY1xmN2sL3"
+	return wechatPayEncrypted{
+		Algorithm:      wechatPayAlgorithm,
+		Ciphertext:     base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(nonce), plaintext, []byte(associatedData))),
+		AssociatedData: associatedData,
+		Nonce:          nonce,
+	}
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// selfSignedCertificate 生成平台证书，返回 PEM 与大写十六进制序列号
+func selfSignedCertificate(t *testing.T, key *rsa.PrivateKey) ([]byte, string) {
+	t.Helper()
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), strings.ToUpper(serial.Text(16))
+}
+
+// parseAuthorization 解析 Authorization 头中的 key="value" 字段
+func parseAuthorization(value string) map[string]string {
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(pair, "=")
+		fields[strings.TrimSpace(key)] = strings.Trim(val, `"`)
+	}
+	return fields
+}
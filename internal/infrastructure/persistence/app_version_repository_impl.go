@@ -20,11 +20,13 @@ func NewAppVersionRepository(db *gorm.DB) repository.AppVersionRepository {
 	return &appVersionRepositoryImpl{db: db}
 }
 
-// FindActive 获取当前活跃版本
+// FindActive 获取正式渠道的当前活跃版本
+// 只返回已全量放开的版本，灰度中或已暂停的版本即使仍标记为活跃也不返回
 func (r *appVersionRepositoryImpl) FindActive(ctx context.Context) (*entity.AppVersion, error) {
 	var appVersion entity.AppVersion
 	err := r.db.WithContext(ctx).
-		Where("is_active = ?", true).
+		Where("is_active = ? AND channel = ?", true, entity.ChannelStable).
+		Where("rollout_status = ? AND rollout_percentage >= ?", entity.RolloutStatusActive, 100).
 		Order("created_at DESC").
 		First(&appVersion).Error
 
@@ -71,24 +73,87 @@ func (r *appVersionRepositoryImpl) Update(ctx context.Context, appVersion *entit
 	return nil
 }
 
-// SetActive 设置版本为活跃版本（将其他版本设为非活跃）
+// SetActive 全量发布指定版本
+// 仅将同一渠道、同一平台的其他版本设为非活跃，不影响其他发布线
 func (r *appVersionRepositoryImpl) SetActive(ctx context.Context, version string) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 1. 将所有版本设为非活跃
-		if err := tx.WithContext(ctx).
-			Model(&entity.AppVersion{}).
+		var appVersion entity.AppVersion
+		err := tx.Where("version = ?", version).First(&appVersion).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(errors.NotFound, "app version not found")
+		}
+		if err != nil {
+			return errors.Wrap(errors.DatabaseError, "failed to find app version", err)
+		}
+
+		// 1. 将同一发布线的其他版本设为非活跃
+		if err := tx.Model(&entity.AppVersion{}).
+			Where("channel = ? AND platform = ? AND version <> ?", appVersion.Channel, appVersion.Platform, version).
 			Update("is_active", false).Error; err != nil {
-			return errors.Wrap(errors.DatabaseError, "failed to deactivate all versions", err)
+			return errors.Wrap(errors.DatabaseError, "failed to deactivate versions", err)
 		}
 
-		// 2. 将指定版本设为活跃
-		if err := tx.WithContext(ctx).
-			Model(&entity.AppVersion{}).
+		// 2. 将指定版本设为活跃并全量放开
+		if err := tx.Model(&entity.AppVersion{}).
 			Where("version = ?", version).
-			Update("is_active", true).Error; err != nil {
+			Updates(map[string]interface{}{
+				"is_active":          true,
+				"rollout_status":     entity.RolloutStatusActive,
+				"rollout_percentage": 100,
+			}).Error; err != nil {
 			return errors.Wrap(errors.DatabaseError, "failed to activate version", err)
 		}
 
 		return nil
 	})
 }
+
+// List 分页查询版本列表
+func (r *appVersionRepositoryImpl) List(ctx context.Context, offset, limit int) ([]*entity.AppVersion, error) {
+	var appVersions []*entity.AppVersion
+	err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&appVersions).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list app versions", err)
+	}
+
+	return appVersions, nil
+}
+
+// FindAll 查询全部版本
+func (r *appVersionRepositoryImpl) FindAll(ctx context.Context) ([]*entity.AppVersion, error) {
+	var appVersions []*entity.AppVersion
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&appVersions).Error; err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find app versions", err)
+	}
+	return appVersions, nil
+}
+
+// Count 统计版本总数
+func (r *appVersionRepositoryImpl) Count(ctx context.Context) (int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&entity.AppVersion{}).Count(&total).Error; err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to count app versions", err)
+	}
+	return total, nil
+}
+
+// Delete 删除版本
+func (r *appVersionRepositoryImpl) Delete(ctx context.Context, version string) error {
+	result := r.db.WithContext(ctx).
+		Where("version = ?", version).
+		Delete(&entity.AppVersion{})
+
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to delete app version", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.NotFound, "app version not found")
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	gormlogger "gorm.io/gorm/logger"
 
 	"github.com/wxlbd/polaris/internal/domain/entity"
@@ -48,6 +49,16 @@ func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
+	// 初始化内置角色
+	if err := seedRoles(db); err != nil {
+		return nil, fmt.Errorf("failed to seed roles: %w", err)
+	}
+
+	// 回填灰度发布字段
+	if err := backfillAppVersionRollout(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill app version rollout: %w", err)
+	}
+
 	logger.Info("Database connected successfully")
 
 	return db, nil
@@ -58,5 +69,69 @@ func autoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&entity.User{},
 		&entity.AppVersion{},
+		&entity.Role{},
+		&entity.RolePermission{},
+		&entity.UserRole{},
+		&entity.Upload{},
+		&entity.Notification{},
+		&entity.NotificationPreference{},
+		&entity.NotificationChannelSetting{},
+		&entity.ScheduledNotification{},
+		&entity.SceneLink{},
+		&entity.Order{},
+		&entity.Payment{},
+		&entity.PaymentEvent{},
+		&entity.Refund{},
+		&entity.ReconciliationDiscrepancy{},
 	)
 }
+
+// builtinRoles 内置角色及其默认权限
+var builtinRoles = []struct {
+	role        entity.Role
+	permissions []string
+}{
+	{
+		role:        entity.Role{Code: entity.RoleAdmin, Name: "管理员", Description: "拥有全部权限"},
+		permissions: []string{entity.PermissionAll},
+	},
+	{
+		role:        entity.Role{Code: entity.RoleUser, Name: "普通用户", Description: "默认角色"},
+		permissions: []string{entity.PermissionContentCreate},
+	},
+}
+
+// seedRoles 初始化内置角色与权限（幂等）
+func seedRoles(db *gorm.DB) error {
+	for _, item := range builtinRoles {
+		role := item.role
+		if err := db.Where(entity.Role{Code: role.Code}).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+		for _, permission := range item.permissions {
+			err := db.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&entity.RolePermission{RoleID: role.ID, Permission: permission}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backfillAppVersionRollout 按 is_active 回填引入灰度发布前的存量版本（幂等）
+// 新增列时存量数据的状态取默认值 active、比例取 0，而正常发布中的版本比例至少为 1；
+// 其中已启用的版本视为全量发布，未启用的版本回填为草稿，避免被推送给客户端
+func backfillAppVersionRollout(db *gorm.DB) error {
+	const legacy = "rollout_status = ? AND rollout_percentage = ? AND is_active = ?"
+
+	err := db.Model(&entity.AppVersion{}).
+		Where(legacy, entity.RolloutStatusActive, 0, false).
+		Update("rollout_status", entity.RolloutStatusDraft).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&entity.AppVersion{}).
+		Where(legacy, entity.RolloutStatusActive, 0, true).
+		Update("rollout_percentage", 100).Error
+}
@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const idempotencyKeyPrefix = "idempotency:"
+
+// acquireIdempotencyScript 记录不存在时创建处理中的记录，返回 1 表示创建成功，0 表示记录已存在
+var acquireIdempotencyScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'fingerprint', ARGV[1], 'owner', ARGV[2], 'created_at', ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+// extendIdempotencyScript 记录仍属于 owner 且未完成时重置有效期
+var extendIdempotencyScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'owner') ~= ARGV[1] or redis.call('HEXISTS', KEYS[1], 'status_code') == 1 then
+	return 0
+end
+return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+`)
+
+// completeIdempotencyScript 记录仍属于 owner 时保存响应并延长有效期
+var completeIdempotencyScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'owner') ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'status_code', ARGV[2], 'header', ARGV[3], 'body', ARGV[4], 'truncated', ARGV[5])
+redis.call('PEXPIRE', KEYS[1], ARGV[6])
+return 1
+`)
+
+// releaseIdempotencyScript 记录仍属于 owner 且未完成时删除
+var releaseIdempotencyScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'owner') ~= ARGV[1] or redis.call('HEXISTS', KEYS[1], 'status_code') == 1 then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+// idempotencyRepositoryImpl 幂等请求记录仓储实现(Redis)
+type idempotencyRepositoryImpl struct {
+	client *redis.Client
+}
+
+// NewIdempotencyRepository 创建幂等请求记录仓储
+func NewIdempotencyRepository(client *redis.Client) repository.IdempotencyRepository {
+	return &idempotencyRepositoryImpl{client: client}
+}
+
+// Acquire 原子地创建处理中的记录
+func (r *idempotencyRepositoryImpl) Acquire(ctx context.Context, record *entity.IdempotencyRecord, lockTTL time.Duration) (*entity.IdempotencyRecord, bool, error) {
+	key := idempotencyKeyPrefix + record.Key
+	// 已有记录恰好在两次调用之间过期时重新创建
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := acquireIdempotencyScript.Run(ctx, r.client, []string{key},
+			record.Fingerprint, record.Owner, record.CreatedAt.UnixMilli(), lockTTL.Milliseconds()).Int()
+		if err != nil {
+			return nil, false, errors.Wrap(errors.CacheError, "failed to acquire idempotency key", err)
+		}
+		if created == 1 {
+			return nil, true, nil
+		}
+
+		values, err := r.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, false, errors.Wrap(errors.CacheError, "failed to find idempotency record", err)
+		}
+		if len(values) > 0 {
+			existing, err := parseIdempotencyRecord(record.Key, values)
+			if err != nil {
+				return nil, false, err
+			}
+			return existing, false, nil
+		}
+	}
+	return nil, false, errors.New(errors.CacheError, "failed to acquire idempotency key")
+}
+
+// Extend 重置处理中记录的有效期
+func (r *idempotencyRepositoryImpl) Extend(ctx context.Context, key, owner string, lockTTL time.Duration) (bool, error) {
+	extended, err := extendIdempotencyScript.Run(ctx, r.client, []string{idempotencyKeyPrefix + key}, owner, lockTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to extend idempotency key", err)
+	}
+	return extended == 1, nil
+}
+
+// Complete 保存响应
+func (r *idempotencyRepositoryImpl) Complete(ctx context.Context, record *entity.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return false, errors.Wrap(errors.InternalError, "failed to encode response header", err)
+	}
+	truncated := 0
+	if record.Truncated {
+		truncated = 1
+	}
+
+	saved, err := completeIdempotencyScript.Run(ctx, r.client, []string{idempotencyKeyPrefix + record.Key},
+		record.Owner, record.StatusCode, header, record.Body, truncated, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to save idempotent response", err)
+	}
+	return saved == 1, nil
+}
+
+// Release 删除处理中的记录
+func (r *idempotencyRepositoryImpl) Release(ctx context.Context, key, owner string) error {
+	if err := releaseIdempotencyScript.Run(ctx, r.client, []string{idempotencyKeyPrefix + key}, owner).Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to release idempotency key", err)
+	}
+	return nil
+}
+
+// parseIdempotencyRecord 解析 Redis 哈希中的幂等请求记录
+func parseIdempotencyRecord(key string, values map[string]string) (*entity.IdempotencyRecord, error) {
+	record := &entity.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: values["fingerprint"],
+		Owner:       values["owner"],
+		Body:        []byte(values["body"]),
+		Truncated:   values["truncated"] == "1",
+		CreatedAt:   parseMilli(values["created_at"]),
+	}
+	if statusCode := values["status_code"]; statusCode != "" {
+		record.StatusCode, _ = strconv.Atoi(statusCode)
+	}
+	if header := values["header"]; header != "" {
+		if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+			return nil, errors.Wrap(errors.CacheError, "invalid idempotent response header", err)
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,65 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const lockKeyPrefix = "lock:"
+
+// extendLockScript 锁仍属于 owner 时重置有效期，返回 1 表示成功
+var extendLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+`)
+
+// releaseLockScript 锁仍属于 owner 时删除
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+// lockRepositoryImpl 分布式锁仓储实现(Redis)
+type lockRepositoryImpl struct {
+	client *redis.Client
+}
+
+// NewLockRepository 创建分布式锁仓储
+func NewLockRepository(client *redis.Client) repository.LockRepository {
+	return &lockRepositoryImpl{client: client}
+}
+
+// Acquire 以 SET NX 获取锁
+func (r *lockRepositoryImpl) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, lockKeyPrefix+name, owner, ttl).Result()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to acquire lock", err)
+	}
+	return ok, nil
+}
+
+// Extend 重置 owner 持有的锁的有效期
+func (r *lockRepositoryImpl) Extend(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	extended, err := extendLockScript.Run(ctx, r.client, []string{lockKeyPrefix + name}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to extend lock", err)
+	}
+	return extended == 1, nil
+}
+
+// Release 释放 owner 持有的锁
+func (r *lockRepositoryImpl) Release(ctx context.Context, name, owner string) error {
+	if err := releaseLockScript.Run(ctx, r.client, []string{lockKeyPrefix + name}, owner).Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to release lock", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// notificationPreferenceRepositoryImpl 用户通知偏好仓储实现
+type notificationPreferenceRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewNotificationPreferenceRepository 创建用户通知偏好仓储
+func NewNotificationPreferenceRepository(db *gorm.DB) repository.NotificationPreferenceRepository {
+	return &notificationPreferenceRepositoryImpl{db: db}
+}
+
+// FindByUserID 查找用户的通知偏好
+func (r *notificationPreferenceRepositoryImpl) FindByUserID(ctx context.Context, userID int64) (*entity.NotificationPreference, error) {
+	var preference entity.NotificationPreference
+	err := r.db.WithContext(ctx).
+		Preload("Channels").
+		Where("user_id = ?", userID).
+		First(&preference).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "notification preference not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find notification preference", err)
+	}
+
+	return &preference, nil
+}
+
+// Save 保存用户的通知偏好
+func (r *notificationPreferenceRepositoryImpl) Save(ctx context.Context, preference *entity.NotificationPreference) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 写入或更新偏好本身，渠道设置单独处理
+		err := tx.Omit("Channels").
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"timezone", "quiet_hours_start", "quiet_hours_end", "updated_at"}),
+			}).
+			Create(preference).Error
+		if err != nil {
+			return errors.Wrap(errors.DatabaseError, "failed to save notification preference", err)
+		}
+
+		// 2. 整体替换渠道设置
+		if err := tx.Where("user_id = ?", preference.UserID).Delete(&entity.NotificationChannelSetting{}).Error; err != nil {
+			return errors.Wrap(errors.DatabaseError, "failed to delete notification channel settings", err)
+		}
+		if len(preference.Channels) == 0 {
+			return nil
+		}
+		for i := range preference.Channels {
+			preference.Channels[i].UserID = preference.UserID
+		}
+		if err := tx.Create(&preference.Channels).Error; err != nil {
+			return errors.Wrap(errors.DatabaseError, "failed to save notification channel settings", err)
+		}
+
+		return nil
+	})
+}
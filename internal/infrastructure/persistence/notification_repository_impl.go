@@ -0,0 +1,168 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// notificationRepositoryImpl 通知发件箱仓储实现
+type notificationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository 创建通知发件箱仓储
+func NewNotificationRepository(db *gorm.DB) repository.NotificationRepository {
+	return &notificationRepositoryImpl{db: db}
+}
+
+// Create 写入待投递通知
+func (r *notificationRepositoryImpl) Create(ctx context.Context, notification *entity.Notification) error {
+	if err := r.db.WithContext(ctx).Create(notification).Error; err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to create notification", err)
+	}
+	return nil
+}
+
+// FindByID 根据ID查找通知
+func (r *notificationRepositoryImpl) FindByID(ctx context.Context, id int64) (*entity.Notification, error) {
+	var notification entity.Notification
+	err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&notification).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "notification not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find notification", err)
+	}
+
+	return &notification, nil
+}
+
+// List 分页查询通知
+func (r *notificationRepositoryImpl) List(ctx context.Context, filter repository.NotificationFilter, offset, limit int) ([]*entity.Notification, error) {
+	var notifications []*entity.Notification
+	err := r.scopeByFilter(ctx, filter).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&notifications).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list notifications", err)
+	}
+
+	return notifications, nil
+}
+
+// Count 统计通知数
+func (r *notificationRepositoryImpl) Count(ctx context.Context, filter repository.NotificationFilter) (int64, error) {
+	var total int64
+	if err := r.scopeByFilter(ctx, filter).Count(&total).Error; err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to count notifications", err)
+	}
+	return total, nil
+}
+
+// ClaimDue 领取到期待投递的通知
+// 使用 FOR UPDATE SKIP LOCKED，多实例同时领取时互不阻塞且不会领到同一条
+func (r *notificationRepositoryImpl) ClaimDue(ctx context.Context, now, leaseUntil int64, limit int) ([]*entity.Notification, error) {
+	var notifications []*entity.Notification
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?",
+				[]string{entity.NotificationStatusPending, entity.NotificationStatusSending}, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&notifications).Error
+		if err != nil || len(notifications) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(notifications))
+		for i, n := range notifications {
+			ids[i] = n.ID
+			n.Status = entity.NotificationStatusSending
+			n.Attempts++
+			n.NextAttemptAt = leaseUntil
+		}
+
+		return tx.Model(&entity.Notification{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":          entity.NotificationStatusSending,
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": leaseUntil,
+			}).Error
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to claim notifications", err)
+	}
+	return notifications, nil
+}
+
+// MarkSent 标记投递成功
+func (r *notificationRepositoryImpl) MarkSent(ctx context.Context, notification *entity.Notification, sentAt int64) error {
+	return r.finish(ctx, notification, map[string]interface{}{
+		"status":     entity.NotificationStatusSent,
+		"sent_at":    sentAt,
+		"last_error": "",
+	})
+}
+
+// MarkRetry 标记投递失败并安排重试
+func (r *notificationRepositoryImpl) MarkRetry(ctx context.Context, notification *entity.Notification, nextAttemptAt int64, lastError string) error {
+	return r.finish(ctx, notification, map[string]interface{}{
+		"status":          entity.NotificationStatusPending,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	})
+}
+
+// MarkFailed 标记放弃投递
+func (r *notificationRepositoryImpl) MarkFailed(ctx context.Context, notification *entity.Notification, lastError string) error {
+	return r.finish(ctx, notification, map[string]interface{}{
+		"status":     entity.NotificationStatusFailed,
+		"last_error": lastError,
+	})
+}
+
+// finish 更新本次领取的投递结果
+// 以尝试次数作为领取凭证：租约过期后被其他实例重新领取的通知，不会被本次结果覆盖
+func (r *notificationRepositoryImpl) finish(ctx context.Context, notification *entity.Notification, updates map[string]interface{}) error {
+	err := r.db.WithContext(ctx).
+		Model(&entity.Notification{}).
+		Where("id = ? AND status = ? AND attempts = ?", notification.ID, entity.NotificationStatusSending, notification.Attempts).
+		Updates(updates).Error
+
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to update notification", err)
+	}
+	return nil
+}
+
+// scopeByFilter 按查询条件过滤
+func (r *notificationRepositoryImpl) scopeByFilter(ctx context.Context, filter repository.NotificationFilter) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&entity.Notification{})
+	if filter.UserID != 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Channel != "" {
+		db = db.Where("channel = ?", filter.Channel)
+	}
+	if filter.Recipient != "" {
+		db = db.Where("recipient = ?", filter.Recipient)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	return db
+}
@@ -0,0 +1,56 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// orderRepositoryImpl 订单仓储实现
+type orderRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewOrderRepository 创建订单仓储
+func NewOrderRepository(db *gorm.DB) repository.OrderRepository {
+	return &orderRepositoryImpl{db: db}
+}
+
+// Create 创建订单
+func (r *orderRepositoryImpl) Create(ctx context.Context, order *entity.Order) error {
+	if err := r.db.WithContext(ctx).Create(order).Error; err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to create order", err)
+	}
+	return nil
+}
+
+// FindByID 根据ID查找订单
+func (r *orderRepositoryImpl) FindByID(ctx context.Context, id int64) (*entity.Order, error) {
+	return r.findOne(ctx, "id = ?", id)
+}
+
+// FindByOrderNo 根据订单号查找订单
+func (r *orderRepositoryImpl) FindByOrderNo(ctx context.Context, orderNo string) (*entity.Order, error) {
+	return r.findOne(ctx, "order_no = ?", orderNo)
+}
+
+// findOne 按条件查找单个订单
+func (r *orderRepositoryImpl) findOne(ctx context.Context, query string, args ...interface{}) (*entity.Order, error) {
+	var order entity.Order
+	err := r.db.WithContext(ctx).
+		Where(query, args...).
+		First(&order).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "order not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find order", err)
+	}
+
+	return &order, nil
+}
@@ -0,0 +1,199 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// errStaleVersion 乐观锁版本号不符，记录已被并发修改
+var errStaleVersion = errors.New(errors.Conflict, "record was modified concurrently, please retry")
+
+// paymentRepositoryImpl 支付单仓储实现
+type paymentRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPaymentRepository 创建支付单仓储
+func NewPaymentRepository(db *gorm.DB) repository.PaymentRepository {
+	return &paymentRepositoryImpl{db: db}
+}
+
+// Create 为订单创建支付单并追加创建事件
+func (r *paymentRepositoryImpl) Create(ctx context.Context, order *entity.Order, payment *entity.Payment, event *entity.PaymentEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := bumpOrderVersion(tx, order, map[string]interface{}{"updated_at": payment.CreatedAt}); err != nil {
+			return err
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+
+	if errors.Is(err, errStaleVersion) {
+		return err
+	}
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to create payment", err)
+	}
+	order.Version++
+	return nil
+}
+
+// FindByID 根据ID查找支付单
+func (r *paymentRepositoryImpl) FindByID(ctx context.Context, id int64) (*entity.Payment, error) {
+	return r.findOne(ctx, "id = ?", id)
+}
+
+// FindByPaymentNo 根据商户支付单号查找支付单
+func (r *paymentRepositoryImpl) FindByPaymentNo(ctx context.Context, paymentNo string) (*entity.Payment, error) {
+	return r.findOne(ctx, "payment_no = ?", paymentNo)
+}
+
+// findOne 按条件查找单个支付单
+func (r *paymentRepositoryImpl) findOne(ctx context.Context, query string, args ...interface{}) (*entity.Payment, error) {
+	var payment entity.Payment
+	err := r.db.WithContext(ctx).Where(query, args...).First(&payment).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "payment not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find payment", err)
+	}
+
+	return &payment, nil
+}
+
+// ListByOrderID 查询订单的全部支付单
+func (r *paymentRepositoryImpl) ListByOrderID(ctx context.Context, orderID int64) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("created_at ASC, id ASC").
+		Find(&payments).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list payments", err)
+	}
+	return payments, nil
+}
+
+// ListExpiredPending 查询已过期仍待支付的支付单
+func (r *paymentRepositoryImpl) ListExpiredPending(ctx context.Context, now time.Time, limit int) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expire_at > 0 AND expire_at <= ?", entity.PaymentStatusPending, now.UnixMilli()).
+		Order("expire_at ASC").
+		Limit(limit).
+		Find(&payments).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list expired payments", err)
+	}
+	return payments, nil
+}
+
+// ListPaidBetween 查询支付成功时间在时间段内的支付单
+func (r *paymentRepositoryImpl) ListPaidBetween(ctx context.Context, method string, start, end time.Time) ([]*entity.Payment, error) {
+	var payments []*entity.Payment
+	err := r.db.WithContext(ctx).
+		Where("method = ? AND paid_at >= ? AND paid_at < ?", method, start.UnixMilli(), end.UnixMilli()).
+		Order("paid_at ASC").
+		Find(&payments).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list paid payments", err)
+	}
+	return payments, nil
+}
+
+// Transition 保存支付单的状态变更并追加支付事件
+func (r *paymentRepositoryImpl) Transition(ctx context.Context, payment *entity.Payment, event *entity.PaymentEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := savePayment(tx, payment); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+
+	if errors.Is(err, errStaleVersion) {
+		return err
+	}
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to update payment", err)
+	}
+	payment.Version++
+	return nil
+}
+
+// ListEvents 查询支付单的事件
+func (r *paymentRepositoryImpl) ListEvents(ctx context.Context, paymentID int64) ([]*entity.PaymentEvent, error) {
+	var events []*entity.PaymentEvent
+	err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("created_at ASC, id ASC").
+		Find(&events).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list payment events", err)
+	}
+	return events, nil
+}
+
+// savePayment 按乐观锁保存支付单的状态与金额，需要时同步订单状态，版本不符时返回 errStaleVersion
+// 只更新数据库，调用方在事务提交后递增 payment.Version
+func savePayment(tx *gorm.DB, payment *entity.Payment) error {
+	result := tx.Model(&entity.Payment{}).
+		Where("id = ? AND version = ?", payment.ID, payment.Version).
+		Updates(map[string]interface{}{
+			"status":           payment.Status,
+			"transaction_id":   payment.TransactionID,
+			"paid_at":          payment.PaidAt,
+			"refunded_amount":  payment.RefundedAmount,
+			"refunding_amount": payment.RefundingAmount,
+			"updated_at":       payment.UpdatedAt,
+			"version":          gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errStaleVersion
+	}
+
+	orderStatus := payment.OrderStatus()
+	if orderStatus == "" {
+		return nil
+	}
+	updates := map[string]interface{}{
+		"status":     orderStatus,
+		"updated_at": payment.UpdatedAt,
+		"version":    gorm.Expr("version + 1"),
+	}
+	if orderStatus == entity.OrderStatusPaid {
+		updates["paid_at"] = gorm.Expr("CASE WHEN paid_at = 0 THEN ? ELSE paid_at END", payment.PaidAt)
+	}
+	return tx.Model(&entity.Order{}).Where("id = ?", payment.OrderID).Updates(updates).Error
+}
+
+// bumpOrderVersion 按乐观锁递增订单版本号，版本不符时返回 errStaleVersion
+func bumpOrderVersion(tx *gorm.DB, order *entity.Order, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	result := tx.Model(&entity.Order{}).
+		Where("id = ? AND version = ?", order.ID, order.Version).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errStaleVersion
+	}
+	return nil
+}
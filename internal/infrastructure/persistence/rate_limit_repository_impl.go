@@ -0,0 +1,45 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const rateLimitKeyPrefix = "rate_limit:"
+
+// incrRateLimitScript 计数加一，首次计数时设置窗口过期时间
+var incrRateLimitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// rateLimitRepositoryImpl 频率限制仓储实现(Redis)
+type rateLimitRepositoryImpl struct {
+	client *redis.Client
+}
+
+// NewRateLimitRepository 创建频率限制仓储
+func NewRateLimitRepository(client *redis.Client) repository.RateLimitRepository {
+	return &rateLimitRepositoryImpl{client: client}
+}
+
+// Allow 在当前时间窗口内为 key 计数一次
+func (r *rateLimitRepositoryImpl) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	windowStart := time.Now().UnixMilli() / window.Milliseconds()
+	redisKey := fmt.Sprintf("%s%s:%d", rateLimitKeyPrefix, key, windowStart)
+
+	count, err := incrRateLimitScript.Run(ctx, r.client, []string{redisKey}, window.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to count request", err)
+	}
+	return count <= limit, nil
+}
@@ -0,0 +1,126 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// reconciliationRepositoryImpl 对账差异仓储实现
+type reconciliationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewReconciliationRepository 创建对账差异仓储
+func NewReconciliationRepository(db *gorm.DB) repository.ReconciliationRepository {
+	return &reconciliationRepositoryImpl{db: db}
+}
+
+// ReplaceDiscrepancies 替换账单日期由对账登记的待处理差异
+func (r *reconciliationRepositoryImpl) ReplaceDiscrepancies(ctx context.Context, billDate string, discrepancies []*entity.ReconciliationDiscrepancy) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("bill_date = ? AND status = ? AND type IN ?", billDate, entity.DiscrepancyStatusOpen, entity.ReconcileDiscrepancyTypes).
+			Delete(&entity.ReconciliationDiscrepancy{}).Error
+		if err != nil {
+			return err
+		}
+		if len(discrepancies) == 0 {
+			return nil
+		}
+		// 已处理的差异再次出现时保留原记录
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(discrepancies, 500).Error
+	})
+
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to save reconciliation discrepancies", err)
+	}
+	return nil
+}
+
+// Flag 登记一条对账之外发现的差异
+func (r *reconciliationRepositoryImpl) Flag(ctx context.Context, discrepancy *entity.ReconciliationDiscrepancy) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(discrepancy).Error
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to flag reconciliation discrepancy", err)
+	}
+	return nil
+}
+
+// FindByID 根据ID查找对账差异
+func (r *reconciliationRepositoryImpl) FindByID(ctx context.Context, id int64) (*entity.ReconciliationDiscrepancy, error) {
+	var discrepancy entity.ReconciliationDiscrepancy
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&discrepancy).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "reconciliation discrepancy not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find reconciliation discrepancy", err)
+	}
+	return &discrepancy, nil
+}
+
+// List 分页查询对账差异
+func (r *reconciliationRepositoryImpl) List(ctx context.Context, filter repository.DiscrepancyFilter, offset, limit int) ([]*entity.ReconciliationDiscrepancy, error) {
+	var discrepancies []*entity.ReconciliationDiscrepancy
+	err := r.scopeByFilter(ctx, filter).
+		Order("bill_date DESC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&discrepancies).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list reconciliation discrepancies", err)
+	}
+	return discrepancies, nil
+}
+
+// Count 统计对账差异数
+func (r *reconciliationRepositoryImpl) Count(ctx context.Context, filter repository.DiscrepancyFilter) (int64, error) {
+	var total int64
+	if err := r.scopeByFilter(ctx, filter).Count(&total).Error; err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to count reconciliation discrepancies", err)
+	}
+	return total, nil
+}
+
+// Resolve 将待处理的差异标记为已处理
+func (r *reconciliationRepositoryImpl) Resolve(ctx context.Context, discrepancy *entity.ReconciliationDiscrepancy) error {
+	result := r.db.WithContext(ctx).Model(&entity.ReconciliationDiscrepancy{}).
+		Where("id = ? AND status = ?", discrepancy.ID, entity.DiscrepancyStatusOpen).
+		Updates(map[string]interface{}{
+			"status":      entity.DiscrepancyStatusResolved,
+			"note":        discrepancy.Note,
+			"resolved_by": discrepancy.ResolvedBy,
+			"resolved_at": discrepancy.ResolvedAt,
+			"updated_at":  discrepancy.UpdatedAt,
+		})
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to resolve reconciliation discrepancy", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.Conflict, "reconciliation discrepancy already resolved")
+	}
+	discrepancy.Status = entity.DiscrepancyStatusResolved
+	return nil
+}
+
+// scopeByFilter 按查询条件过滤
+func (r *reconciliationRepositoryImpl) scopeByFilter(ctx context.Context, filter repository.DiscrepancyFilter) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&entity.ReconciliationDiscrepancy{})
+	if filter.BillDate != "" {
+		db = db.Where("bill_date = ?", filter.BillDate)
+	}
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	return db
+}
@@ -0,0 +1,182 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+var (
+	// errDuplicateRefund 同一支付单的退款幂等键已被使用
+	errDuplicateRefund = errors.New(errors.Conflict, "refund idempotency key already used")
+	// errRefundCompleted 退款单已完成，不能再修改
+	errRefundCompleted = errors.New(errors.Conflict, "refund already completed")
+)
+
+// refundRepositoryImpl 退款单仓储实现
+type refundRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewRefundRepository 创建退款单仓储
+func NewRefundRepository(db *gorm.DB) repository.RefundRepository {
+	return &refundRepositoryImpl{db: db}
+}
+
+// Create 登记退款单并保存支付单占用的退款额度
+func (r *refundRepositoryImpl) Create(ctx context.Context, payment *entity.Payment, refund *entity.Refund, event *entity.PaymentEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := savePayment(tx, payment); err != nil {
+			return err
+		}
+		// 幂等键冲突时不插入，事务回滚后由调用方按幂等键查询已有退款单
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(refund)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errDuplicateRefund
+		}
+		return tx.Create(event).Error
+	})
+
+	if errors.Is(err, errStaleVersion) || errors.Is(err, errDuplicateRefund) {
+		return err
+	}
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to create refund", err)
+	}
+	payment.Version++
+	return nil
+}
+
+// FindByRefundNo 根据商户退款单号查找退款单
+func (r *refundRepositoryImpl) FindByRefundNo(ctx context.Context, refundNo string) (*entity.Refund, error) {
+	return r.findOne(ctx, "refund_no = ?", refundNo)
+}
+
+// FindByIdempotencyKey 根据幂等键查找支付单的退款单
+func (r *refundRepositoryImpl) FindByIdempotencyKey(ctx context.Context, paymentID int64, key string) (*entity.Refund, error) {
+	return r.findOne(ctx, "payment_id = ? AND idempotency_key = ?", paymentID, key)
+}
+
+// ListByPaymentID 查询支付单的全部退款单
+func (r *refundRepositoryImpl) ListByPaymentID(ctx context.Context, paymentID int64) ([]*entity.Refund, error) {
+	var refunds []*entity.Refund
+	err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("created_at ASC, id ASC").
+		Find(&refunds).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list refunds", err)
+	}
+	return refunds, nil
+}
+
+// ListSucceededBetween 查询退款成功时间在时间段内的退款单
+func (r *refundRepositoryImpl) ListSucceededBetween(ctx context.Context, start, end time.Time) ([]*entity.Refund, error) {
+	var refunds []*entity.Refund
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND succeeded_at >= ? AND succeeded_at < ?", entity.RefundStatusSucceeded, start.UnixMilli(), end.UnixMilli()).
+		Order("succeeded_at ASC").
+		Find(&refunds).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list succeeded refunds", err)
+	}
+	return refunds, nil
+}
+
+// ListDue 查询未完成且已到对账时间的退款单
+func (r *refundRepositoryImpl) ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.Refund, error) {
+	var refunds []*entity.Refund
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND next_query_at <= ?",
+			[]entity.RefundStatus{entity.RefundStatusPending, entity.RefundStatusProcessing}, now.UnixMilli()).
+		Order("next_query_at ASC").
+		Limit(limit).
+		Find(&refunds).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list due refunds", err)
+	}
+	return refunds, nil
+}
+
+// Update 保存未完成退款单的处理进度
+func (r *refundRepositoryImpl) Update(ctx context.Context, refund *entity.Refund) error {
+	err := updateOpenRefund(r.db.WithContext(ctx), refund)
+	if errors.Is(err, errRefundCompleted) {
+		return err
+	}
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to update refund", err)
+	}
+	return nil
+}
+
+// Complete 保存退款单的最终结果与支付单的状态、退款金额
+func (r *refundRepositoryImpl) Complete(ctx context.Context, payment *entity.Payment, refund *entity.Refund, event *entity.PaymentEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateOpenRefund(tx, refund); err != nil {
+			return err
+		}
+		if err := savePayment(tx, payment); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+
+	if errors.Is(err, errStaleVersion) || errors.Is(err, errRefundCompleted) {
+		return err
+	}
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to complete refund", err)
+	}
+	payment.Version++
+	return nil
+}
+
+// findOne 按条件查找单个退款单
+func (r *refundRepositoryImpl) findOne(ctx context.Context, query string, args ...interface{}) (*entity.Refund, error) {
+	var refund entity.Refund
+	err := r.db.WithContext(ctx).Where(query, args...).First(&refund).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "refund not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find refund", err)
+	}
+	return &refund, nil
+}
+
+// updateOpenRefund 更新未完成的退款单，退款单已完成(被并发的通知或对账处理)时返回 errRefundCompleted
+func updateOpenRefund(tx *gorm.DB, refund *entity.Refund) error {
+	result := tx.Model(&entity.Refund{}).
+		Where("id = ? AND status IN ?", refund.ID,
+			[]entity.RefundStatus{entity.RefundStatusPending, entity.RefundStatusProcessing}).
+		Updates(map[string]interface{}{
+			"status":            refund.Status,
+			"channel_refund_id": refund.ChannelRefundID,
+			"fail_reason":       refund.FailReason,
+			"attempts":          refund.Attempts,
+			"next_query_at":     refund.NextQueryAt,
+			"succeeded_at":      refund.SucceededAt,
+			"updated_at":        refund.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errRefundCompleted
+	}
+	return nil
+}
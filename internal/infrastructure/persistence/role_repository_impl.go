@@ -0,0 +1,113 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// roleRepositoryImpl 角色仓储实现
+type roleRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository 创建角色仓储
+func NewRoleRepository(db *gorm.DB) repository.RoleRepository {
+	return &roleRepositoryImpl{db: db}
+}
+
+// FindByCode 根据角色编码查找角色
+func (r *roleRepositoryImpl) FindByCode(ctx context.Context, code string) (*entity.Role, error) {
+	var role entity.Role
+	err := r.db.WithContext(ctx).
+		Where("code = ?", code).
+		First(&role).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "role not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find role", err)
+	}
+
+	return &role, nil
+}
+
+// FindRoleCodesByUserID 查找用户拥有的角色编码
+func (r *roleRepositoryImpl) FindRoleCodesByUserID(ctx context.Context, userID int64) ([]string, error) {
+	var codes []string
+	err := r.db.WithContext(ctx).
+		Model(&entity.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.code").
+		Pluck("roles.code", &codes).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find user roles", err)
+	}
+
+	return codes, nil
+}
+
+// FindPermissionsByRoleCodes 查找角色集合拥有的全部权限
+func (r *roleRepositoryImpl) FindPermissionsByRoleCodes(ctx context.Context, roleCodes []string) ([]string, error) {
+	if len(roleCodes) == 0 {
+		return []string{}, nil
+	}
+
+	var permissions []string
+	err := r.db.WithContext(ctx).
+		Model(&entity.RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.code IN ?", roleCodes).
+		Pluck("role_permissions.permission", &permissions).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find role permissions", err)
+	}
+
+	return permissions, nil
+}
+
+// AssignToUser 为用户分配角色
+func (r *roleRepositoryImpl) AssignToUser(ctx context.Context, userID int64, roleCode string) error {
+	role, err := r.FindByCode(ctx, roleCode)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.UserRole{UserID: userID, RoleID: role.ID}).Error
+
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to assign role", err)
+	}
+
+	return nil
+}
+
+// RemoveFromUser 移除用户的角色
+func (r *roleRepositoryImpl) RemoveFromUser(ctx context.Context, userID int64, roleCode string) error {
+	role, err := r.FindByCode(ctx, roleCode)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.WithContext(ctx).
+		Where("user_id = ? AND role_id = ?", userID, role.ID).
+		Delete(&entity.UserRole{}).Error
+
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to remove role", err)
+	}
+
+	return nil
+}
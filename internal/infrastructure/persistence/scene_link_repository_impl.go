@@ -0,0 +1,92 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// sceneLinkRepositoryImpl 小程序码场景短链仓储实现
+type sceneLinkRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewSceneLinkRepository 创建小程序码场景短链仓储
+func NewSceneLinkRepository(db *gorm.DB) repository.SceneLinkRepository {
+	return &sceneLinkRepositoryImpl{db: db}
+}
+
+// Create 登记短链
+func (r *sceneLinkRepositoryImpl) Create(ctx context.Context, link *entity.SceneLink) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(link)
+
+	if result.Error != nil {
+		return false, errors.Wrap(errors.DatabaseError, "failed to create scene link", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindByCode 根据短码查找短链
+func (r *sceneLinkRepositoryImpl) FindByCode(ctx context.Context, code string) (*entity.SceneLink, error) {
+	var link entity.SceneLink
+	err := r.db.WithContext(ctx).
+		Where("code = ?", code).
+		First(&link).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "scene link not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find scene link", err)
+	}
+
+	return &link, nil
+}
+
+// Consume 原子地记录一次使用
+func (r *sceneLinkRepositoryImpl) Consume(ctx context.Context, code string, now int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entity.SceneLink{}).
+		Where("code = ?", code).
+		Where("expires_at = 0 OR expires_at > ?", now).
+		Where("max_uses = 0 OR use_count < max_uses").
+		Updates(map[string]interface{}{
+			"use_count":  gorm.Expr("use_count + 1"),
+			"updated_at": now,
+		})
+
+	if result.Error != nil {
+		return false, errors.Wrap(errors.DatabaseError, "failed to consume scene link", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Delete 删除短链
+func (r *sceneLinkRepositoryImpl) Delete(ctx context.Context, code string) error {
+	if err := r.db.WithContext(ctx).Where("code = ?", code).Delete(&entity.SceneLink{}).Error; err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to delete scene link", err)
+	}
+	return nil
+}
+
+// DeleteExpired 删除过期的短链
+func (r *sceneLinkRepositoryImpl) DeleteExpired(ctx context.Context, before int64, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("code IN (?)", r.db.Model(&entity.SceneLink{}).
+			Select("code").
+			Where("expires_at > 0 AND expires_at <= ?", before).
+			Limit(limit)).
+		Delete(&entity.SceneLink{})
+
+	if result.Error != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to delete expired scene links", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// scheduledNotificationRepositoryImpl 定时通知仓储实现
+type scheduledNotificationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewScheduledNotificationRepository 创建定时通知仓储
+func NewScheduledNotificationRepository(db *gorm.DB) repository.ScheduledNotificationRepository {
+	return &scheduledNotificationRepositoryImpl{db: db}
+}
+
+// Create 创建定时通知
+func (r *scheduledNotificationRepositoryImpl) Create(ctx context.Context, notification *entity.ScheduledNotification) error {
+	if err := r.db.WithContext(ctx).Create(notification).Error; err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to create scheduled notification", err)
+	}
+	return nil
+}
+
+// FindByID 根据ID查找定时通知
+func (r *scheduledNotificationRepositoryImpl) FindByID(ctx context.Context, id int64) (*entity.ScheduledNotification, error) {
+	var notification entity.ScheduledNotification
+	err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&notification).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "scheduled notification not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find scheduled notification", err)
+	}
+
+	return &notification, nil
+}
+
+// ListByUserID 分页查询用户的定时通知
+func (r *scheduledNotificationRepositoryImpl) ListByUserID(ctx context.Context, userID int64, status string, offset, limit int) ([]*entity.ScheduledNotification, error) {
+	var notifications []*entity.ScheduledNotification
+	err := r.scopeByUser(ctx, userID, status).
+		Order("next_run_at ASC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&notifications).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list scheduled notifications", err)
+	}
+
+	return notifications, nil
+}
+
+// CountByUserID 统计用户的定时通知数
+func (r *scheduledNotificationRepositoryImpl) CountByUserID(ctx context.Context, userID int64, status string) (int64, error) {
+	var total int64
+	if err := r.scopeByUser(ctx, userID, status).Count(&total).Error; err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to count scheduled notifications", err)
+	}
+	return total, nil
+}
+
+// Cancel 取消定时通知
+func (r *scheduledNotificationRepositoryImpl) Cancel(ctx context.Context, id int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entity.ScheduledNotification{}).
+		Where("id = ? AND status = ?", id, entity.ScheduledNotificationStatusActive).
+		Updates(map[string]interface{}{
+			"status":     entity.ScheduledNotificationStatusCancelled,
+			"updated_at": time.Now().UnixMilli(),
+		})
+
+	if result.Error != nil {
+		return false, errors.Wrap(errors.DatabaseError, "failed to cancel scheduled notification", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ClaimDue 领取到期的定时通知
+// 使用 FOR UPDATE SKIP LOCKED，多实例同时领取时互不阻塞且不会领到同一条；
+// 推进后的状态随事务提交，之后即使发送失败也不会被再次领取
+func (r *scheduledNotificationRepositoryImpl) ClaimDue(ctx context.Context, now time.Time, limit int) ([]*entity.ScheduledNotification, error) {
+	var notifications []*entity.ScheduledNotification
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", entity.ScheduledNotificationStatusActive, now.UnixMilli()).
+			Order("next_run_at ASC").
+			Limit(limit).
+			Find(&notifications).Error
+		if err != nil {
+			return err
+		}
+
+		for _, n := range notifications {
+			// 重复规则无法解析时 Advance 已将通知标记为完成，本次仍照常发送
+			_ = n.Advance(now)
+			n.UpdatedAt = now.UnixMilli()
+
+			err := tx.Model(n).
+				Select("status", "next_run_at", "last_run_at", "run_count", "updated_at").
+				Updates(n).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to claim scheduled notifications", err)
+	}
+	return notifications, nil
+}
+
+// scopeByUser 按用户与状态过滤
+func (r *scheduledNotificationRepositoryImpl) scopeByUser(ctx context.Context, userID int64, status string) *gorm.DB {
+	db := r.db.WithContext(ctx).
+		Model(&entity.ScheduledNotification{}).
+		Where("user_id = ?", userID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	return db
+}
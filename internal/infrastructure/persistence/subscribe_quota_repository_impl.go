@@ -0,0 +1,98 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// subscribeQuotaKeyPrefix 用户订阅消息额度，Hash: templateID -> 剩余次数
+const subscribeQuotaKeyPrefix = "wechat:subscribe:quota:"
+
+// consumeQuotaScript 额度大于 0 时扣减一次，返回 1 表示扣减成功，0 表示额度不足
+var consumeQuotaScript = redis.NewScript(`
+local remaining = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if remaining <= 0 then
+	return 0
+end
+if remaining == 1 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+else
+	redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+end
+return 1
+`)
+
+// subscribeQuotaRepositoryImpl 微信订阅消息额度仓储实现(Redis)
+type subscribeQuotaRepositoryImpl struct {
+	client *redis.Client
+}
+
+// NewSubscribeQuotaRepository 创建微信订阅消息额度仓储
+func NewSubscribeQuotaRepository(client *redis.Client) repository.SubscribeQuotaRepository {
+	return &subscribeQuotaRepositoryImpl{client: client}
+}
+
+// Grant 用户授权了模板，每个模板额度加一
+func (r *subscribeQuotaRepositoryImpl) Grant(ctx context.Context, openID string, templateIDs []string) error {
+	if len(templateIDs) == 0 {
+		return nil
+	}
+
+	key := subscribeQuotaKeyPrefix + openID
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, templateID := range templateIDs {
+			pipe.HIncrBy(ctx, key, templateID, 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(errors.CacheError, "failed to grant subscribe quota", err)
+	}
+	return nil
+}
+
+// FindByOpenID 查询用户各模板的剩余额度
+func (r *subscribeQuotaRepositoryImpl) FindByOpenID(ctx context.Context, openID string) (map[string]int, error) {
+	values, err := r.client.HGetAll(ctx, subscribeQuotaKeyPrefix+openID).Result()
+	if err != nil {
+		return nil, errors.Wrap(errors.CacheError, "failed to get subscribe quota", err)
+	}
+
+	quotas := make(map[string]int, len(values))
+	for templateID, value := range values {
+		if remaining, err := strconv.Atoi(value); err == nil && remaining > 0 {
+			quotas[templateID] = remaining
+		}
+	}
+	return quotas, nil
+}
+
+// Consume 原子地扣减一次额度
+func (r *subscribeQuotaRepositoryImpl) Consume(ctx context.Context, openID, templateID string) (bool, error) {
+	consumed, err := consumeQuotaScript.Run(ctx, r.client, []string{subscribeQuotaKeyPrefix + openID}, templateID).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to consume subscribe quota", err)
+	}
+	return consumed == 1, nil
+}
+
+// Refund 退还一次额度
+func (r *subscribeQuotaRepositoryImpl) Refund(ctx context.Context, openID, templateID string) error {
+	if err := r.client.HIncrBy(ctx, subscribeQuotaKeyPrefix+openID, templateID, 1).Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to refund subscribe quota", err)
+	}
+	return nil
+}
+
+// Clear 清空用户在模板上的额度
+func (r *subscribeQuotaRepositoryImpl) Clear(ctx context.Context, openID, templateID string) error {
+	if err := r.client.HDel(ctx, subscribeQuotaKeyPrefix+openID, templateID).Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to clear subscribe quota", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const (
+	refreshTokenKeyPrefix   = "auth:refresh:"
+	revokedSessionKeyPrefix = "auth:revoked:sid:"
+	revokedTokenKeyPrefix   = "auth:revoked:jti:"
+)
+
+// markRefreshTokenUsedScript 刷新令牌存在且未使用时记录使用时间
+// 先判断键是否存在，避免令牌恰好过期时 HSETNX 重建出没有过期时间的键；返回 -1 表示令牌不存在，0 表示已使用，1 表示成功
+var markRefreshTokenUsedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+return redis.call('HSETNX', KEYS[1], 'used_at', ARGV[1])
+`)
+
+// tokenRepositoryImpl 令牌仓储实现(Redis)
+type tokenRepositoryImpl struct {
+	client *redis.Client
+}
+
+// NewTokenRepository 创建令牌仓储
+func NewTokenRepository(client *redis.Client) repository.TokenRepository {
+	return &tokenRepositoryImpl{client: client}
+}
+
+// SaveRefreshToken 保存刷新令牌
+func (r *tokenRepositoryImpl) SaveRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	key := refreshTokenKeyPrefix + token.TokenHash
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"sid", token.SessionID,
+			"openid", token.OpenID,
+			"issued_at", token.IssuedAt.UnixMilli(),
+			"expires_at", token.ExpiresAt.UnixMilli(),
+		)
+		pipe.ExpireAt(ctx, key, token.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(errors.CacheError, "failed to save refresh token", err)
+	}
+	return nil
+}
+
+// FindRefreshToken 根据令牌哈希查找刷新令牌
+func (r *tokenRepositoryImpl) FindRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	values, err := r.client.HGetAll(ctx, refreshTokenKeyPrefix+tokenHash).Result()
+	if err != nil {
+		return nil, errors.Wrap(errors.CacheError, "failed to find refresh token", err)
+	}
+	if len(values) == 0 {
+		return nil, errors.New(errors.InvalidToken, "刷新令牌无效或已过期")
+	}
+
+	token := &entity.RefreshToken{
+		TokenHash: tokenHash,
+		SessionID: values["sid"],
+		OpenID:    values["openid"],
+		IssuedAt:  parseMilli(values["issued_at"]),
+		ExpiresAt: parseMilli(values["expires_at"]),
+		UsedAt:    parseMilli(values["used_at"]),
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenUsed 原子地标记刷新令牌已使用
+func (r *tokenRepositoryImpl) MarkRefreshTokenUsed(ctx context.Context, tokenHash string, usedAt time.Time) (bool, error) {
+	result, err := markRefreshTokenUsedScript.Run(ctx, r.client, []string{refreshTokenKeyPrefix + tokenHash}, usedAt.UnixMilli()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to mark refresh token used", err)
+	}
+	if result < 0 {
+		return false, errors.New(errors.InvalidToken, "刷新令牌无效或已过期")
+	}
+	return result == 1, nil
+}
+
+// RevokeSession 吊销整个会话
+func (r *tokenRepositoryImpl) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, revokedSessionKeyPrefix+sessionID, 1, ttl).Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to revoke session", err)
+	}
+	return nil
+}
+
+// IsSessionRevoked 检查会话是否已被吊销
+func (r *tokenRepositoryImpl) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := r.client.Exists(ctx, revokedSessionKeyPrefix+sessionID).Result()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to check session revocation", err)
+	}
+	return n > 0, nil
+}
+
+// RevokeAccessToken 按 jti 吊销访问令牌
+func (r *tokenRepositoryImpl) RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// 令牌已自然过期，无需加入吊销列表
+		return nil
+	}
+	if err := r.client.Set(ctx, revokedTokenKeyPrefix+jti, 1, ttl).Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to revoke access token", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked 检查访问令牌本身或其所属会话是否已被吊销
+func (r *tokenRepositoryImpl) IsAccessTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	keys := make([]string, 0, 2)
+	if jti != "" {
+		keys = append(keys, revokedTokenKeyPrefix+jti)
+	}
+	if sessionID != "" {
+		keys = append(keys, revokedSessionKeyPrefix+sessionID)
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	n, err := r.client.Exists(ctx, keys...).Result()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to check token revocation", err)
+	}
+	return n > 0, nil
+}
+
+// parseMilli 将毫秒时间戳字符串解析为时间，空值返回零值
+func parseMilli(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
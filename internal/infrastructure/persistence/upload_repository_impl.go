@@ -0,0 +1,155 @@
+package persistence
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// uploadRepositoryImpl 上传文件记录仓储实现
+type uploadRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewUploadRepository 创建上传文件记录仓储
+func NewUploadRepository(db *gorm.DB) repository.UploadRepository {
+	return &uploadRepositoryImpl{db: db}
+}
+
+// Create 创建上传记录
+func (r *uploadRepositoryImpl) Create(ctx context.Context, upload *entity.Upload) error {
+	if err := r.db.WithContext(ctx).Create(upload).Error; err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to create upload", err)
+	}
+	return nil
+}
+
+// FindByID 根据ID查找上传记录
+func (r *uploadRepositoryImpl) FindByID(ctx context.Context, id int64) (*entity.Upload, error) {
+	var upload entity.Upload
+	err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&upload).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errors.NotFound, "upload not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find upload", err)
+	}
+
+	return &upload, nil
+}
+
+// ListByOpenID 分页查询用户的上传记录
+func (r *uploadRepositoryImpl) ListByOpenID(ctx context.Context, openID, uploadType string, offset, limit int) ([]*entity.Upload, error) {
+	var uploads []*entity.Upload
+	err := r.scopeByOpenID(ctx, openID, uploadType).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&uploads).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to list uploads", err)
+	}
+
+	return uploads, nil
+}
+
+// CountByOpenID 统计用户的上传记录数
+func (r *uploadRepositoryImpl) CountByOpenID(ctx context.Context, openID, uploadType string) (int64, error) {
+	var total int64
+	if err := r.scopeByOpenID(ctx, openID, uploadType).Count(&total).Error; err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to count uploads", err)
+	}
+	return total, nil
+}
+
+// SumSizeByOpenID 统计用户上传文件的总字节数
+func (r *uploadRepositoryImpl) SumSizeByOpenID(ctx context.Context, openID string) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.Upload{}).
+		Where("openid = ?", openID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&total).Error
+
+	if err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to sum upload size", err)
+	}
+	return total, nil
+}
+
+// CountByStorageKey 统计引用同一存储对象的上传记录数
+func (r *uploadRepositoryImpl) CountByStorageKey(ctx context.Context, storageKey string) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.Upload{}).
+		Where("storage_key = ?", storageKey).
+		Count(&total).Error
+
+	if err != nil {
+		return 0, errors.Wrap(errors.DatabaseError, "failed to count uploads", err)
+	}
+	return total, nil
+}
+
+// SetOrphanedAt 设置孤儿标记时间
+func (r *uploadRepositoryImpl) SetOrphanedAt(ctx context.Context, openID, uploadType, storageKey string, orphanedAt int64) error {
+	err := r.db.WithContext(ctx).
+		Model(&entity.Upload{}).
+		Where("openid = ? AND type = ? AND storage_key = ?", openID, uploadType, storageKey).
+		Update("orphaned_at", orphanedAt).Error
+
+	if err != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to update upload", err)
+	}
+	return nil
+}
+
+// FindOrphaned 查找在 before 之前被标记为孤儿的上传记录
+func (r *uploadRepositoryImpl) FindOrphaned(ctx context.Context, before int64, limit int) ([]*entity.Upload, error) {
+	var uploads []*entity.Upload
+	err := r.db.WithContext(ctx).
+		Where("orphaned_at > 0 AND orphaned_at < ?", before).
+		Order("orphaned_at ASC").
+		Limit(limit).
+		Find(&uploads).Error
+
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseError, "failed to find orphaned uploads", err)
+	}
+	return uploads, nil
+}
+
+// Delete 删除上传记录
+func (r *uploadRepositoryImpl) Delete(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		Delete(&entity.Upload{})
+
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseError, "failed to delete upload", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.NotFound, "upload not found")
+	}
+
+	return nil
+}
+
+// scopeByOpenID 按用户与上传类型过滤
+func (r *uploadRepositoryImpl) scopeByOpenID(ctx context.Context, openID, uploadType string) *gorm.DB {
+	db := r.db.WithContext(ctx).
+		Model(&entity.Upload{}).
+		Where("openid = ?", openID)
+	if uploadType != "" {
+		db = db.Where("type = ?", uploadType)
+	}
+	return db
+}
@@ -0,0 +1,201 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+const (
+	uploadSessionKeyPrefix = "upload:session:"
+	uploadSessionExpiryKey = "upload:sessions:expiry"
+
+	// uploadSessionRetention 会话过期后在 Redis 中的保留时长
+	// 过期会话由后台任务清理分片后删除，保留期内即使清理任务短暂停止也不会丢失分片列表
+	uploadSessionRetention = 24 * time.Hour
+)
+
+// appendPartScript 偏移量一致时推进偏移量并记录分片，分片列表与会话同时过期
+// 返回 -1 表示会话不存在，0 表示偏移量不一致，1 表示成功
+var appendPartScript = redis.NewScript(`
+local offset = redis.call('HGET', KEYS[1], 'offset')
+if not offset then
+	return -1
+end
+if tonumber(offset) ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'offset', tonumber(ARGV[1]) + tonumber(ARGV[2]))
+redis.call('RPUSH', KEYS[2], ARGV[3])
+redis.call('PEXPIREAT', KEYS[2], tonumber(redis.call('HGET', KEYS[1], 'expires_at')) + tonumber(ARGV[4]))
+return 1
+`)
+
+// finishSessionScript 会话存在时记录上传结果、清除合并标记与分片列表并移出过期索引
+// 会话已被删除时不写入，避免重建出没有过期时间的键；返回 0 表示会话不存在
+var finishSessionScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'result', ARGV[1])
+redis.call('HDEL', KEYS[1], 'completing')
+redis.call('DEL', KEYS[2])
+redis.call('ZREM', KEYS[3], ARGV[2])
+return 1
+`)
+
+// uploadSessionRepositoryImpl 断点续传会话仓储实现(Redis)
+type uploadSessionRepositoryImpl struct {
+	client *redis.Client
+}
+
+// NewUploadSessionRepository 创建断点续传会话仓储
+func NewUploadSessionRepository(client *redis.Client) repository.UploadSessionRepository {
+	return &uploadSessionRepositoryImpl{client: client}
+}
+
+// Create 创建上传会话
+func (r *uploadSessionRepositoryImpl) Create(ctx context.Context, session *entity.UploadSession) error {
+	key := uploadSessionKeyPrefix + session.ID
+	expireAt := session.ExpiresAt.Add(uploadSessionRetention)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"openid", session.OpenID,
+			"type", session.Type,
+			"related_id", session.RelatedID,
+			"filename", session.Filename,
+			"direct_key", session.DirectKey,
+			"length", session.Length,
+			"offset", session.Offset,
+			"created_at", session.CreatedAt.UnixMilli(),
+			"expires_at", session.ExpiresAt.UnixMilli(),
+		)
+		pipe.ExpireAt(ctx, key, expireAt)
+		pipe.ZAdd(ctx, uploadSessionExpiryKey, redis.Z{
+			Score:  float64(session.ExpiresAt.UnixMilli()),
+			Member: session.ID,
+		})
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(errors.CacheError, "failed to create upload session", err)
+	}
+	return nil
+}
+
+// FindByID 根据ID查找上传会话
+func (r *uploadSessionRepositoryImpl) FindByID(ctx context.Context, id string) (*entity.UploadSession, error) {
+	key := uploadSessionKeyPrefix + id
+
+	var values *redis.MapStringStringCmd
+	var parts *redis.StringSliceCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.HGetAll(ctx, key)
+		parts = pipe.LRange(ctx, key+":parts", 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.CacheError, "failed to find upload session", err)
+	}
+	if len(values.Val()) == 0 {
+		return nil, errors.New(errors.NotFound, "upload session not found")
+	}
+
+	v := values.Val()
+	length, _ := strconv.ParseInt(v["length"], 10, 64)
+	offset, _ := strconv.ParseInt(v["offset"], 10, 64)
+	session := &entity.UploadSession{
+		ID:        id,
+		OpenID:    v["openid"],
+		Type:      v["type"],
+		RelatedID: v["related_id"],
+		Filename:  v["filename"],
+		Length:    length,
+		Offset:    offset,
+		Parts:     parts.Val(),
+		DirectKey: v["direct_key"],
+		Result:    []byte(v["result"]),
+		CreatedAt: parseMilli(v["created_at"]),
+		ExpiresAt: parseMilli(v["expires_at"]),
+	}
+	return session, nil
+}
+
+// AppendPart 原子地追加分片
+func (r *uploadSessionRepositoryImpl) AppendPart(ctx context.Context, id string, offset, size int64, partKey string) (bool, error) {
+	key := uploadSessionKeyPrefix + id
+	result, err := appendPartScript.Run(ctx, r.client, []string{key, key + ":parts"},
+		offset, size, partKey, uploadSessionRetention.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to append upload part", err)
+	}
+	if result < 0 {
+		return false, errors.New(errors.NotFound, "upload session not found")
+	}
+	return result == 1, nil
+}
+
+// ClaimCompletion 标记会话正在合并
+func (r *uploadSessionRepositoryImpl) ClaimCompletion(ctx context.Context, id string) (bool, error) {
+	ok, err := r.client.HSetNX(ctx, uploadSessionKeyPrefix+id, "completing", 1).Result()
+	if err != nil {
+		return false, errors.Wrap(errors.CacheError, "failed to claim upload session", err)
+	}
+	return ok, nil
+}
+
+// ReleaseCompletion 清除合并标记
+func (r *uploadSessionRepositoryImpl) ReleaseCompletion(ctx context.Context, id string) error {
+	if err := r.client.HDel(ctx, uploadSessionKeyPrefix+id, "completing").Err(); err != nil {
+		return errors.Wrap(errors.CacheError, "failed to release upload session", err)
+	}
+	return nil
+}
+
+// Finish 记录上传结果并清除分片列表
+// 已完成的会话不再需要清理分片，从过期索引中移除，随 Redis 键过期自动删除
+func (r *uploadSessionRepositoryImpl) Finish(ctx context.Context, id string, result []byte) error {
+	key := uploadSessionKeyPrefix + id
+	ok, err := finishSessionScript.Run(ctx, r.client, []string{key, key + ":parts", uploadSessionExpiryKey},
+		result, id).Bool()
+	if err != nil {
+		return errors.Wrap(errors.CacheError, "failed to finish upload session", err)
+	}
+	if !ok {
+		return errors.New(errors.NotFound, "upload session not found")
+	}
+	return nil
+}
+
+// Delete 删除上传会话
+func (r *uploadSessionRepositoryImpl) Delete(ctx context.Context, id string) error {
+	key := uploadSessionKeyPrefix + id
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key, key+":parts")
+		pipe.ZRem(ctx, uploadSessionExpiryKey, id)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(errors.CacheError, "failed to delete upload session", err)
+	}
+	return nil
+}
+
+// FindExpired 查找在 before 之前过期的会话ID
+func (r *uploadSessionRepositoryImpl) FindExpired(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	ids, err := r.client.ZRangeByScore(ctx, uploadSessionExpiryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(before.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, errors.Wrap(errors.CacheError, "failed to find expired upload sessions", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// LocalStorage 本地文件系统存储
+// 仅适用于单实例部署，多副本时各实例磁盘互不可见
+type LocalStorage struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewLocalStorage 创建本地文件系统存储
+// signingKey 用于预签名直传地址的 HMAC 签名
+func NewLocalStorage(root, publicURL string, signingKey []byte) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, errors.Wrap(errors.InternalError, "failed to create storage directory", err)
+	}
+	return &LocalStorage{root: root, publicURL: publicURL, signingKey: signingKey}, nil
+}
+
+// Put 写入对象
+// 先写入临时文件再重命名，避免读取方看到写了一半的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.Wrap(errors.InternalError, "failed to create directory", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return errors.Wrap(errors.InternalError, "failed to create file", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return errors.Wrap(errors.InternalError, "failed to save file", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(errors.InternalError, "failed to save file", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Wrap(errors.InternalError, "failed to save file", err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return errors.Wrap(errors.InternalError, "failed to save file", err)
+	}
+
+	return nil
+}
+
+// Get 读取对象
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *repository.FileInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil, errors.New(errors.NotFound, "file not found")
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.InternalError, "failed to open file", err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, errors.Wrap(errors.InternalError, "failed to stat file", err)
+	}
+	if stat.IsDir() {
+		f.Close()
+		return nil, nil, errors.New(errors.NotFound, "file not found")
+	}
+
+	return f, s.fileInfo(key, stat), nil
+}
+
+// Delete 删除对象
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(errors.InternalError, "failed to delete file", err)
+	}
+	return nil
+}
+
+// Stat 获取对象元信息
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*repository.FileInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, errors.New(errors.NotFound, "file not found")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "failed to stat file", err)
+	}
+	if stat.IsDir() {
+		return nil, errors.New(errors.NotFound, "file not found")
+	}
+
+	return s.fileInfo(key, stat), nil
+}
+
+// URL 返回对象的公开访问地址
+func (s *LocalStorage) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
+
+// PresignPut 生成上传对象的预签名请求
+// 地址即对象的访问地址加签名参数，由应用的直传处理器校验签名后写入
+func (s *LocalStorage) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*repository.PresignedRequest, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(expires)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("signature", s.sign(cleaned, contentType, size, expiresAt.Unix()))
+
+	return &repository.PresignedRequest{
+		Method:    http.MethodPut,
+		URL:       s.URL(cleaned) + "?" + query.Encode(),
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// VerifyPut 校验预签名直传请求
+func (s *LocalStorage) VerifyPut(key string, query url.Values, contentType string) (int64, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return 0, errors.New(errors.PermissionDenied, "invalid upload signature")
+	}
+	size, err := strconv.ParseInt(query.Get("size"), 10, 64)
+	if err != nil || size < 0 {
+		return 0, errors.New(errors.PermissionDenied, "invalid upload signature")
+	}
+
+	// 内容类型参与签名，与签发时不一致同样视为签名无效
+	expected := s.sign(cleaned, contentType, size, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return 0, errors.New(errors.PermissionDenied, "invalid upload signature")
+	}
+	if time.Now().Unix() > expires {
+		return 0, errors.New(errors.PermissionDenied, "upload signature expired")
+	}
+
+	return size, nil
+}
+
+// sign 计算预签名直传签名
+func (s *LocalStorage) sign(key, contentType string, size, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(http.MethodPut + "\n" + key + "\n" + contentType + "\n" +
+		strconv.FormatInt(size, 10) + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path 将对象键映射为本地文件路径
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// fileInfo 构建对象元信息
+func (s *LocalStorage) fileInfo(key string, stat os.FileInfo) *repository.FileInfo {
+	return &repository.FileInfo{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: contentTypeByKey(key),
+		ModTime:     stat.ModTime(),
+	}
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// S3Storage S3 兼容对象存储
+// 直接基于 REST 接口与 SigV4 签名实现，兼容 AWS S3、MinIO 及各云厂商的 S3 兼容接口
+type S3Storage struct {
+	endpoint     *url.URL
+	bucket       string
+	usePathStyle bool
+	publicURL    string
+	signer       *sigV4Signer
+	httpClient   *http.Client
+}
+
+// NewS3Storage 创建 S3 兼容对象存储
+func NewS3Storage(cfg config.S3Config, publicURL string) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage: s3 endpoint and bucket are required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("storage: s3 access_key_id and secret_access_key are required")
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint %q", cfg.Endpoint)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	s := &S3Storage{
+		endpoint:     endpoint,
+		bucket:       cfg.Bucket,
+		usePathStyle: cfg.UsePathStyle,
+		publicURL:    publicURL,
+		signer: &sigV4Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          region,
+		},
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+	if s.publicURL == "" {
+		s.publicURL = s.bucketURL().String()
+	}
+
+	return s, nil
+}
+
+// Put 写入对象
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	// S3 的 PUT Object 需要 Content-Length，长度未知时先读入内存
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return errors.Wrap(errors.InternalError, "failed to read file", err)
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	if contentType == "" {
+		contentType = contentTypeByKey(key)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp, "failed to put object")
+	}
+	return nil
+}
+
+// Get 读取对象
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *repository.FileInfo, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, s.responseError(resp, "failed to get object")
+	}
+
+	return resp.Body, fileInfoFromHeader(key, resp), nil
+}
+
+// Delete 删除对象
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// S3 删除不存在的对象同样返回 204
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError(resp, "failed to delete object")
+	}
+	return nil
+}
+
+// Stat 获取对象元信息
+func (s *S3Storage) Stat(ctx context.Context, key string) (*repository.FileInfo, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError(resp, "failed to stat object")
+	}
+
+	return fileInfoFromHeader(key, resp), nil
+}
+
+// URL 返回对象的公开访问地址
+func (s *S3Storage) URL(key string) string {
+	return joinURL(s.publicURL, uriEncode(key, false))
+}
+
+// PresignPut 生成上传对象的预签名请求
+// 内容类型与字节数参与签名，客户端上传时与签名不一致会被 S3 拒绝
+func (s *S3Storage) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*repository.PresignedRequest, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	signedHeaders := map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.FormatInt(size, 10),
+	}
+	return &repository.PresignedRequest{
+		Method: http.MethodPut,
+		URL:    s.signer.presign(http.MethodPut, u, signedHeaders, now, expires),
+		// Content-Length 由客户端按实际内容自动设置
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: now.Add(expires),
+	}, nil
+}
+
+// bucketURL 存储桶访问地址
+func (s *S3Storage) bucketURL() *url.URL {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + s.bucket
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.RawPath = ""
+	return &u
+}
+
+// objectURL 对象访问地址
+func (s *S3Storage) objectURL(key string) (*url.URL, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	u := s.bucketURL()
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + cleaned
+	u.RawPath = uriEncode(u.Path, false)
+	return u, nil
+}
+
+// newRequest 构建对象请求
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "failed to build storage request", err)
+	}
+	return req, nil
+}
+
+// do 签名并发送请求
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	s.signer.sign(req, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(errors.InternalError, "storage request failed", err)
+	}
+	return resp, nil
+}
+
+// responseError 将非成功响应转换为错误
+func (s *S3Storage) responseError(resp *http.Response, message string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New(errors.NotFound, "file not found")
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.Wrap(errors.InternalError, message,
+		fmt.Errorf("s3 status %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+}
+
+// fileInfoFromHeader 从响应头解析对象元信息
+func fileInfoFromHeader(key string, resp *http.Response) *repository.FileInfo {
+	info := &repository.FileInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if info.Size < 0 {
+		info.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info
+}
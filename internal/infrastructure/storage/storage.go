@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"crypto/rand"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// 存储后端
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// NewFileStorage 根据上传配置创建文件存储
+func NewFileStorage(cfg *config.Config) (repository.FileStorage, error) {
+	switch cfg.Upload.Backend {
+	case "", BackendLocal:
+		signingKey, err := localSigningKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewLocalStorage(LocalRoot(cfg), localPublicURL(cfg), signingKey)
+	case BackendS3:
+		return NewS3Storage(cfg.Upload.S3, cfg.Upload.PublicURL)
+	default:
+		return nil, fmt.Errorf("storage: unsupported backend %q", cfg.Upload.Backend)
+	}
+}
+
+// IsLocal 是否使用本地存储后端
+func IsLocal(cfg *config.Config) bool {
+	return cfg.Upload.Backend == "" || cfg.Upload.Backend == BackendLocal
+}
+
+// LocalRoot 本地存储根目录
+func LocalRoot(cfg *config.Config) string {
+	if cfg.Upload.StoragePath == "" {
+		return "uploads/"
+	}
+	return cfg.Upload.StoragePath
+}
+
+// localPublicURL 本地存储的访问地址前缀
+func localPublicURL(cfg *config.Config) string {
+	if cfg.Upload.PublicURL != "" {
+		return cfg.Upload.PublicURL
+	}
+	baseURL := cfg.Server.BaseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+	}
+	return strings.TrimRight(baseURL, "/") + "/uploads"
+}
+
+// localSigningKey 本地存储预签名直传的签名密钥
+// 未配置时随机生成，重启后此前签发的地址失效；本地存储仅支持单实例，无需在实例间共享
+func localSigningKey(cfg *config.Config) ([]byte, error) {
+	if cfg.Upload.Direct.SigningKey != "" {
+		return []byte(cfg.Upload.Direct.SigningKey), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("storage: failed to generate signing key: %w", err)
+	}
+	return key, nil
+}
+
+// cleanKey 规范化对象键并拒绝越界路径
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", errors.New(errors.ParamError, "invalid storage key")
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.New(errors.ParamError, "invalid storage key")
+	}
+	return cleaned, nil
+}
+
+// contentTypeByKey 根据扩展名推断 MIME 类型
+func contentTypeByKey(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// joinURL 拼接访问地址
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
@@ -0,0 +1,11 @@
+package token
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims 访问令牌声明
+// 在标准声明之外携带会话ID(用于按令牌族整体吊销)和用户角色
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string   `json:"sid"`             // 会话ID(刷新令牌族ID)
+	Roles     []string `json:"roles,omitempty"` // 用户角色
+}
@@ -0,0 +1,257 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+)
+
+// signingKey 单个签名/验证密钥
+type signingKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey // 仅验证的密钥为 nil
+	publicKey  crypto.PublicKey
+	retireAt   time.Time // 零值表示不退役
+}
+
+// usable 密钥在指定时间是否仍可用于验证
+func (k *signingKey) usable(now time.Time) bool {
+	return k.retireAt.IsZero() || now.Before(k.retireAt)
+}
+
+// KeyManager JWT 密钥管理器
+// 负责按 kid 管理多把密钥：当前签名密钥用于签发，其余未退役的密钥在宽限期内继续用于验证。
+// 未配置非对称密钥时退化为使用 JWTConfig.Secret 的 HS256
+type KeyManager struct {
+	issuer     string
+	signer     *signingKey
+	keys       map[string]*signingKey
+	algorithms []string
+	hmacSecret []byte
+}
+
+// NewKeyManager 根据配置加载密钥
+func NewKeyManager(cfg *config.Config) (*KeyManager, error) {
+	m := &KeyManager{
+		issuer: cfg.JWT.Issuer,
+		keys:   make(map[string]*signingKey),
+	}
+
+	if len(cfg.JWT.Keys) == 0 {
+		if cfg.JWT.Secret == "" {
+			return nil, fmt.Errorf("jwt: either secret or keys must be configured")
+		}
+		m.hmacSecret = []byte(cfg.JWT.Secret)
+		m.algorithms = []string{jwt.SigningMethodHS256.Alg()}
+		return m, nil
+	}
+
+	algSet := make(map[string]struct{})
+	for _, keyCfg := range cfg.JWT.Keys {
+		key, err := loadKey(keyCfg)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := m.keys[key.kid]; exists {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", key.kid)
+		}
+		m.keys[key.kid] = key
+		if _, ok := algSet[key.method.Alg()]; !ok {
+			algSet[key.method.Alg()] = struct{}{}
+			m.algorithms = append(m.algorithms, key.method.Alg())
+		}
+	}
+
+	// 选择签名密钥：优先使用 signing_key_id，否则取第一把带私钥的密钥
+	if cfg.JWT.SigningKeyID != "" {
+		m.signer = m.keys[cfg.JWT.SigningKeyID]
+		if m.signer == nil {
+			return nil, fmt.Errorf("jwt: signing key %q not found in keys", cfg.JWT.SigningKeyID)
+		}
+	} else {
+		for _, keyCfg := range cfg.JWT.Keys {
+			if k := m.keys[keyCfg.KID]; k.privateKey != nil {
+				m.signer = k
+				break
+			}
+		}
+	}
+	if m.signer == nil || m.signer.privateKey == nil {
+		return nil, fmt.Errorf("jwt: no signing key with a private key configured")
+	}
+	if !m.signer.usable(time.Now()) {
+		return nil, fmt.Errorf("jwt: signing key %q is already retired", m.signer.kid)
+	}
+
+	return m, nil
+}
+
+// Sign 使用当前签名密钥签发令牌
+func (m *KeyManager) Sign(claims *Claims) (string, error) {
+	if m.issuer != "" && claims.Issuer == "" {
+		claims.Issuer = m.issuer
+	}
+
+	if m.signer == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.hmacSecret)
+	}
+
+	t := jwt.NewWithClaims(m.signer.method, claims)
+	t.Header["kid"] = m.signer.kid
+	return t.SignedString(m.signer.privateKey)
+}
+
+// Parse 验证并解析令牌
+// 根据头部 kid 选择密钥，拒绝未配置的算法以及与密钥不匹配的算法
+func (m *KeyManager) Parse(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(m.algorithms),
+		jwt.WithExpirationRequired(),
+	}
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+
+	claims := &Claims{}
+	t, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Valid {
+		return nil, fmt.Errorf("jwt: invalid token")
+	}
+	return claims, nil
+}
+
+// keyFunc 按 kid 查找验证密钥
+func (m *KeyManager) keyFunc(t *jwt.Token) (interface{}, error) {
+	if m.signer == nil {
+		return m.hmacSecret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("jwt: missing kid header")
+	}
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("jwt: unknown kid %q", kid)
+	}
+	if !key.usable(time.Now()) {
+		return nil, fmt.Errorf("jwt: key %q has been retired", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("jwt: unexpected algorithm %s for key %q", t.Method.Alg(), kid)
+	}
+	return key.publicKey, nil
+}
+
+// loadKey 从 PEM 文件加载密钥并校验算法与密钥类型是否匹配
+func loadKey(keyCfg config.JWTKeyConfig) (*signingKey, error) {
+	if keyCfg.KID == "" {
+		return nil, fmt.Errorf("jwt: key id is required")
+	}
+	if keyCfg.PrivateKeyFile == "" && keyCfg.PublicKeyFile == "" {
+		return nil, fmt.Errorf("jwt: key %q has neither private_key_file nor public_key_file", keyCfg.KID)
+	}
+
+	key := &signingKey{kid: keyCfg.KID}
+	if keyCfg.RetireAt != "" {
+		retireAt, err := time.Parse(time.RFC3339, keyCfg.RetireAt)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q has invalid retire_at: %w", keyCfg.KID, err)
+		}
+		key.retireAt = retireAt
+	}
+
+	var privatePEM, publicPEM []byte
+	var err error
+	if keyCfg.PrivateKeyFile != "" {
+		if privatePEM, err = os.ReadFile(keyCfg.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("jwt: read private key %q: %w", keyCfg.KID, err)
+		}
+	}
+	if keyCfg.PublicKeyFile != "" {
+		if publicPEM, err = os.ReadFile(keyCfg.PublicKeyFile); err != nil {
+			return nil, fmt.Errorf("jwt: read public key %q: %w", keyCfg.KID, err)
+		}
+	}
+
+	switch keyCfg.Algorithm {
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if privatePEM != nil {
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: parse private key %q: %w", keyCfg.KID, err)
+			}
+			key.privateKey, key.publicKey = priv, &priv.PublicKey
+		} else {
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: parse public key %q: %w", keyCfg.KID, err)
+			}
+			key.publicKey = pub
+		}
+		if key.publicKey.(*rsa.PublicKey).N.BitLen() < 2048 {
+			return nil, fmt.Errorf("jwt: RSA key %q must be at least 2048 bits", keyCfg.KID)
+		}
+
+	case "ES256":
+		key.method = jwt.SigningMethodES256
+		if privatePEM != nil {
+			priv, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: parse private key %q: %w", keyCfg.KID, err)
+			}
+			key.privateKey, key.publicKey = priv, &priv.PublicKey
+		} else {
+			pub, err := jwt.ParseECPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: parse public key %q: %w", keyCfg.KID, err)
+			}
+			key.publicKey = pub
+		}
+		if key.publicKey.(*ecdsa.PublicKey).Curve != elliptic.P256() {
+			return nil, fmt.Errorf("jwt: ES256 key %q must use the P-256 curve", keyCfg.KID)
+		}
+
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if privatePEM != nil {
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: parse private key %q: %w", keyCfg.KID, err)
+			}
+			edPriv, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("jwt: key %q is not an Ed25519 private key", keyCfg.KID)
+			}
+			key.privateKey, key.publicKey = edPriv, edPriv.Public()
+		} else {
+			pub, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: parse public key %q: %w", keyCfg.KID, err)
+			}
+			if _, ok := pub.(ed25519.PublicKey); !ok {
+				return nil, fmt.Errorf("jwt: key %q is not an Ed25519 public key", keyCfg.KID)
+			}
+			key.publicKey = pub
+		}
+
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q for key %q", keyCfg.Algorithm, keyCfg.KID)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,38 @@
+package wechat
+
+import (
+	"github.com/silenceper/wechat/v2/util"
+
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+// 微信接口错误码到应用错误码的映射，未列出的错误码统一视为 WechatAPIError
+var errCodeMapping = map[int64]errors.ErrorCode{
+	40003: errors.WechatInvalidOpenID,      // openid 无效
+	40037: errors.WechatInvalidTemplate,    // 模板ID无效
+	43101: errors.WechatSubscribeQuota,     // 用户拒绝接受消息(未订阅或订阅次数已用完)
+	47003: errors.WechatInvalidMessageData, // 模板参数不合法
+	45009: errors.WechatRateLimited,        // 接口调用超过每日限额
+	45011: errors.WechatRateLimited,        // 接口调用过于频繁
+	41030: errors.ParamError,               // 小程序页面路径不存在或未发布
+	40097: errors.ParamError,               // 参数错误
+}
+
+// MapError 将微信接口返回的错误转换为应用错误，保留原始错误以便排查
+func MapError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *util.CommonError
+	if !errors.As(err, &apiErr) {
+		// 网络错误、access_token 获取失败等
+		return errors.Wrap(errors.WechatAPIError, message, err)
+	}
+
+	code, ok := errCodeMapping[apiErr.ErrCode]
+	if !ok {
+		code = errors.WechatAPIError
+	}
+	return errors.Wrap(code, message, err)
+}
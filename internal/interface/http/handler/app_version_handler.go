@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// AppVersionHandler 应用版本管理处理器
+type AppVersionHandler struct {
+	appVersionService *service.AppVersionService
+}
+
+// NewAppVersionHandler 创建应用版本管理处理器
+func NewAppVersionHandler(appVersionService *service.AppVersionService) *AppVersionHandler {
+	return &AppVersionHandler{appVersionService: appVersionService}
+}
+
+// List 分页查询版本列表
+// @Router /admin/app-versions [get]
+func (h *AppVersionHandler) List(c *gin.Context) {
+	var req dto.ListAppVersionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	records, total, err := h.appVersionService.ListVersions(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessPaginated(c, records, total, req.Page, req.PageSize)
+}
+
+// Get 根据版本号获取版本信息
+// @Router /admin/app-versions/{version} [get]
+func (h *AppVersionHandler) Get(c *gin.Context) {
+	versionDTO, err := h.appVersionService.GetVersionByNumber(c.Request.Context(), c.Param("version"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, versionDTO)
+}
+
+// Create 创建版本
+// @Router /admin/app-versions [post]
+func (h *AppVersionHandler) Create(c *gin.Context) {
+	var req dto.CreateAppVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	versionDTO, err := h.appVersionService.CreateVersion(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, versionDTO)
+}
+
+// Update 更新版本信息
+// @Router /admin/app-versions/{version} [put]
+func (h *AppVersionHandler) Update(c *gin.Context) {
+	var req dto.UpdateAppVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	versionDTO, err := h.appVersionService.UpdateVersion(c.Request.Context(), c.Param("version"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, versionDTO)
+}
+
+// SetActive 全量发布版本
+// @Router /admin/app-versions/active [post]
+func (h *AppVersionHandler) SetActive(c *gin.Context) {
+	var req dto.SetActiveVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.appVersionService.SetActiveVersion(c.Request.Context(), req.Version); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Rollout 开始、调整或恢复灰度放量
+// @Router /admin/app-versions/{version}/rollout [post]
+func (h *AppVersionHandler) Rollout(c *gin.Context) {
+	var req dto.RolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	versionDTO, err := h.appVersionService.StartRollout(c.Request.Context(), c.Param("version"), req.Percentage)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, versionDTO)
+}
+
+// PauseRollout 暂停灰度
+// @Router /admin/app-versions/{version}/pause [post]
+func (h *AppVersionHandler) PauseRollout(c *gin.Context) {
+	versionDTO, err := h.appVersionService.PauseRollout(c.Request.Context(), c.Param("version"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, versionDTO)
+}
+
+// Rollback 回滚版本
+// @Router /admin/app-versions/{version}/rollback [post]
+func (h *AppVersionHandler) Rollback(c *gin.Context) {
+	versionDTO, err := h.appVersionService.RollbackRollout(c.Request.Context(), c.Param("version"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, versionDTO)
+}
+
+// Delete 删除版本
+// @Router /admin/app-versions/{version} [delete]
+func (h *AppVersionHandler) Delete(c *gin.Context) {
+	if err := h.appVersionService.DeleteVersion(c.Request.Context(), c.Param("version")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// CheckUpdate 客户端检查更新（认证可选）
+// 携带有效令牌时按用户灰度分桶，否则只返回全量版本
+// @Router /app/update-check [get]
+func (h *AppVersionHandler) CheckUpdate(c *gin.Context) {
+	var req dto.UpdateCheckRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	allowInternal := entity.HasPermission(c.GetStringSlice("permissions"), entity.PermissionAppVersionInternal)
+	result, err := h.appVersionService.CheckUpdate(c.Request.Context(), &req, c.GetString("openid"), allowInternal)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/wxlbd/polaris/internal/application/dto"
@@ -43,10 +45,13 @@ func (h *AuthHandler) WechatLogin(c *gin.Context) {
 // RefreshToken 刷新Token
 // @Router /auth/refresh-token [post]
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	// 从context获取当前用户openid (由Auth中间件设置)
-	openID := c.GetString("openid")
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, 1001, "参数错误: "+err.Error())
+		return
+	}
 
-	resp, err := h.authService.RefreshToken(c.Request.Context(), openID)
+	resp, err := h.authService.RefreshToken(c.Request.Context(), &req)
 	if err != nil {
 		response.Error(c, err)
 		return
@@ -55,6 +60,22 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	response.Success(c, resp)
 }
 
+// Logout 退出登录
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	// 从context获取当前令牌信息 (由Auth中间件设置)
+	jti := c.GetString("jti")
+	sessionID := c.GetString("sid")
+	expiresAt := c.GetTime("token_expires_at")
+
+	if err := h.authService.Logout(c.Request.Context(), jti, sessionID, expiresAt); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 // GetUserInfo 获取用户信息
 // @Router /auth/user-info [get]
 func (h *AuthHandler) GetUserInfo(c *gin.Context) {
@@ -102,3 +123,11 @@ func (h *AuthHandler) GetAppVersion(c *gin.Context) {
 
 	response.Success(c, versionDTO)
 }
+
+// JWKS 发布访问令牌验证公钥（无需认证）
+// 按 RFC 7517 直接返回 JWK Set，不使用统一响应包装，便于其他服务的 JWT 库直接消费
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
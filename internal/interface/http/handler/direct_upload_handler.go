@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// DirectUploadHandler 预签名直传处理器
+// 上传流程：申请签名地址 -> 客户端按返回的 method/url/headers 直接上传 -> 回调完成接口登记文件
+type DirectUploadHandler struct {
+	directUploadService *service.DirectUploadService
+}
+
+// NewDirectUploadHandler 创建预签名直传处理器
+func NewDirectUploadHandler(directUploadService *service.DirectUploadService) *DirectUploadHandler {
+	return &DirectUploadHandler{directUploadService: directUploadService}
+}
+
+// Presign 申请预签名直传地址
+// @Router /upload/presign [post]
+func (h *DirectUploadHandler) Presign(c *gin.Context) {
+	var req dto.PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.directUploadService.Presign(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Complete 直传完成回调，返回与普通上传相同的结果
+// @Router /upload/presign/{id}/complete [post]
+func (h *DirectUploadHandler) Complete(c *gin.Context) {
+	result, err := h.directUploadService.Complete(c.Request.Context(), c.GetString("openid"), c.Param("id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, uploadResultData(result))
+}
+
+// PutObject 接收预签名直传的文件内容（仅本地存储后端挂载）
+// 签名即授权，无需登录
+// @Router /uploads/{filepath} [put]
+func (h *DirectUploadHandler) PutObject(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+
+	err := h.directUploadService.PutSigned(c.Request.Context(), key, c.Request.URL.Query(), c.GetHeader("Content-Type"), c.Request.Body)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
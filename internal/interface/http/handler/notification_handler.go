@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// NotificationHandler 通知处理器
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+// NewNotificationHandler 创建通知处理器
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// List 分页查询通知投递记录
+// @Router /admin/notifications [get]
+func (h *NotificationHandler) List(c *gin.Context) {
+	var req dto.ListNotificationsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	records, total, err := h.notificationService.ListNotifications(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessPaginated(c, records, total, req.Page, req.PageSize)
+}
+
+// Get 查询通知投递状态
+// @Router /admin/notifications/{id} [get]
+func (h *NotificationHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid notification id")
+		return
+	}
+
+	record, err := h.notificationService.GetNotification(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, record)
+}
+
+// GetPreferences 查询当前用户的通知偏好
+// @Router /notification-preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	preferences, err := h.notificationService.GetPreferences(c.Request.Context(), c.GetString("openid"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, preferences)
+}
+
+// UpdatePreferences 更新当前用户的通知偏好
+// @Router /notification-preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req dto.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	preferences, err := h.notificationService.UpdatePreferences(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, preferences)
+}
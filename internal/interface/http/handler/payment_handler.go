@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// maxPaymentNotifySize 支付回调通知最大字节数
+const maxPaymentNotifySize = 64 << 10
+
+// PaymentHandler 支付处理器
+type PaymentHandler struct {
+	paymentService *service.PaymentService
+}
+
+// NewPaymentHandler 创建支付处理器
+func NewPaymentHandler(paymentService *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{paymentService: paymentService}
+}
+
+// WechatNotify 微信支付回调通知
+// 由微信支付服务器调用，签名即授权，无需登录；应答格式遵循微信支付约定而非统一响应格式，
+// 处理成功应答 204，失败应答 4XX/5XX 与 {"code":"FAIL"}，微信支付会重新通知
+// @Router /payments/wechat/notify [post]
+func (h *PaymentHandler) WechatNotify(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPaymentNotifySize))
+	if err != nil {
+		wechatNotifyFail(c, http.StatusBadRequest, "读取通知失败")
+		return
+	}
+
+	if err := h.paymentService.HandleWechatNotify(c.Request.Context(), c.Request.Header, body); err != nil {
+		status := http.StatusInternalServerError
+		switch errors.CodeOf(err) {
+		case errors.PaymentInvalidSignature:
+			status = http.StatusUnauthorized
+		case errors.ParamError:
+			status = http.StatusBadRequest
+		}
+		wechatNotifyFail(c, status, err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Get 查询支付单详情，含退款单与支付事件
+// @Router /admin/payments/{paymentNo} [get]
+func (h *PaymentHandler) Get(c *gin.Context) {
+	detail, err := h.paymentService.GetPaymentDetail(c.Request.Context(), c.Param("paymentNo"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, detail)
+}
+
+// Refund 申请退款，支持多次部分退款
+// 必须携带 Idempotency-Key 请求头，重试时使用相同的值，避免重复退款
+// @Router /admin/payments/{paymentNo}/refunds [post]
+func (h *PaymentHandler) Refund(c *gin.Context) {
+	var req dto.CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		response.ErrorWithMessage(c, errors.ParamError, "缺少 Idempotency-Key 请求头")
+		return
+	}
+
+	refund, err := h.paymentService.Refund(c.Request.Context(), c.Param("paymentNo"), idempotencyKey, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, refund)
+}
+
+// wechatNotifyFail 按微信支付约定应答回调处理失败
+func wechatNotifyFail(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{"code": "FAIL", "message": message})
+}
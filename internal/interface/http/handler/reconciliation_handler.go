@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// ReconciliationHandler 支付对账处理器
+type ReconciliationHandler struct {
+	reconciliationService *service.ReconciliationService
+}
+
+// NewReconciliationHandler 创建支付对账处理器
+func NewReconciliationHandler(reconciliationService *service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+// ListDiscrepancies 分页查询对账差异
+// @Router /admin/reconciliation/discrepancies [get]
+func (h *ReconciliationHandler) ListDiscrepancies(c *gin.Context) {
+	var req dto.ListDiscrepanciesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	records, total, err := h.reconciliationService.ListDiscrepancies(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessPaginated(c, records, total, req.Page, req.PageSize)
+}
+
+// ResolveDiscrepancy 将对账差异标记为已处理
+// @Router /admin/reconciliation/discrepancies/{id}/resolve [post]
+func (h *ReconciliationHandler) ResolveDiscrepancy(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid discrepancy id")
+		return
+	}
+
+	var req dto.ResolveDiscrepancyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	record, err := h.reconciliationService.ResolveDiscrepancy(c.Request.Context(), id, c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, record)
+}
+
+// Run 核对指定日期的渠道对账单，重新核对会覆盖该日期未处理的差异
+// @Router /admin/reconciliation/runs [post]
+func (h *ReconciliationHandler) Run(c *gin.Context) {
+	var req dto.RunReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.reconciliationService.Reconcile(c.Request.Context(), req.BillDate)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
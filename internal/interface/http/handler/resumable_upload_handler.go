@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// tus 协议支持的扩展
+const tusExtensions = "creation,expiration,termination"
+
+// tusContentType PATCH 请求体的内容类型
+const tusContentType = "application/offset+octet-stream"
+
+// ResumableUploadHandler 断点续传处理器(tus 1.0.0 协议)
+// 上传流程：POST 创建会话 -> PATCH 按偏移量上传分片(中断后先 HEAD 查询偏移量再续传)，
+// 最后一个分片接收后自动完成上传 -> POST /complete 获取上传结果；DELETE 终止上传
+type ResumableUploadHandler struct {
+	resumableUploadService *service.ResumableUploadService
+}
+
+// NewResumableUploadHandler 创建断点续传处理器
+func NewResumableUploadHandler(resumableUploadService *service.ResumableUploadService) *ResumableUploadHandler {
+	return &ResumableUploadHandler{resumableUploadService: resumableUploadService}
+}
+
+// Options 查询服务端支持的协议版本与扩展
+// @Router /uploads/resumable [options]
+func (h *ResumableUploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.resumableUploadService.MaxSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// Create 创建上传会话
+// 请求头 Upload-Length 为文件总字节数，Upload-Metadata 携带 filename、type、related_id(值为 Base64 编码)
+// @Router /uploads/resumable [post]
+func (h *ResumableUploadHandler) Create(c *gin.Context) {
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		response.ErrorWithMessage(c, errors.ParamError, "Upload-Defer-Length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid Upload-Length header")
+		return
+	}
+	if length > h.resumableUploadService.MaxSize() {
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid Upload-Metadata header")
+		return
+	}
+
+	session, err := h.resumableUploadService.CreateSession(c.Request.Context(), c.GetString("openid"),
+		length, service.UploadType(metadata["type"]), metadata["related_id"], metadata["filename"])
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("Location", strings.TrimRight(c.Request.URL.Path, "/")+"/"+session.ID)
+	setUploadExpires(c, session)
+	c.Status(http.StatusCreated)
+}
+
+// Head 查询已接收的偏移量，用于中断后续传
+// @Router /uploads/resumable/{id} [head]
+func (h *ResumableUploadHandler) Head(c *gin.Context) {
+	session, err := h.resumableUploadService.GetSession(c.Request.Context(), c.GetString("openid"), c.Param("id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Header("Cache-Control", "no-store")
+	setUploadExpires(c, session)
+	c.Status(http.StatusOK)
+}
+
+// Patch 从 Upload-Offset 处上传分片，接收到全部数据时合并完成上传
+// @Router /uploads/resumable/{id} [patch]
+func (h *ResumableUploadHandler) Patch(c *gin.Context) {
+	if c.ContentType() != tusContentType {
+		c.AbortWithStatus(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid Upload-Offset header")
+		return
+	}
+
+	session, err := h.resumableUploadService.WriteChunk(c.Request.Context(), c.GetString("openid"), c.Param("id"), offset, c.Request.Body)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	setUploadExpires(c, session)
+	c.Status(http.StatusNoContent)
+}
+
+// Complete 完成上传并返回与普通上传相同的结果，已完成的上传重复调用返回相同结果
+// @Router /uploads/resumable/{id}/complete [post]
+func (h *ResumableUploadHandler) Complete(c *gin.Context) {
+	result, err := h.resumableUploadService.Complete(c.Request.Context(), c.GetString("openid"), c.Param("id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, uploadResultData(result))
+}
+
+// Delete 终止上传并删除已接收的数据
+// @Router /uploads/resumable/{id} [delete]
+func (h *ResumableUploadHandler) Delete(c *gin.Context) {
+	if err := h.resumableUploadService.Abort(c.Request.Context(), c.GetString("openid"), c.Param("id")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setUploadExpires 设置会话过期时间响应头
+func setUploadExpires(c *gin.Context, session *entity.UploadSession) {
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseUploadMetadata 解析 Upload-Metadata 请求头
+// 格式为逗号分隔的键值对，键与值以空格分隔，值为 Base64 编码，值可省略
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// SceneLinkHandler 小程序码场景短链处理器
+type SceneLinkHandler struct {
+	sceneLinkService *service.SceneLinkService
+}
+
+// NewSceneLinkHandler 创建小程序码场景短链处理器
+func NewSceneLinkHandler(sceneLinkService *service.SceneLinkService) *SceneLinkHandler {
+	return &SceneLinkHandler{sceneLinkService: sceneLinkService}
+}
+
+// Create 登记场景短链
+// 业务数据保存在服务端，小程序码 scene 中只携带 c=<短码>，可同时生成小程序码
+// @Router /scene-links [post]
+func (h *SceneLinkHandler) Create(c *gin.Context) {
+	var req dto.CreateSceneLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	link, err := h.sceneLinkService.Create(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, link)
+}
+
+// Resolve 解析场景短链
+// 小程序扫码进入后用 scene 中的短码换取业务数据，每次解析计一次使用；已过期或次数用完返回 410，解析过于频繁返回 429
+// @Router /scene/{code} [get]
+func (h *SceneLinkHandler) Resolve(c *gin.Context) {
+	link, err := h.sceneLinkService.Resolve(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, link)
+}
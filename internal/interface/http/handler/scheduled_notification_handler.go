@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// ScheduledNotificationHandler 定时通知处理器
+type ScheduledNotificationHandler struct {
+	scheduledNotificationService *service.ScheduledNotificationService
+}
+
+// NewScheduledNotificationHandler 创建定时通知处理器
+func NewScheduledNotificationHandler(scheduledNotificationService *service.ScheduledNotificationService) *ScheduledNotificationHandler {
+	return &ScheduledNotificationHandler{scheduledNotificationService: scheduledNotificationService}
+}
+
+// Create 创建定时通知
+// @Router /scheduled-notifications [post]
+func (h *ScheduledNotificationHandler) Create(c *gin.Context) {
+	var req dto.CreateScheduledNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	record, err := h.scheduledNotificationService.Create(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, record)
+}
+
+// List 分页查询当前用户的定时通知
+// @Router /scheduled-notifications [get]
+func (h *ScheduledNotificationHandler) List(c *gin.Context) {
+	var req dto.ListScheduledNotificationsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	records, total, err := h.scheduledNotificationService.List(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessPaginated(c, records, total, req.Page, req.PageSize)
+}
+
+// Cancel 取消定时通知
+// @Router /scheduled-notifications/{id} [delete]
+func (h *ScheduledNotificationHandler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid scheduled notification id")
+		return
+	}
+
+	if err := h.scheduledNotificationService.Cancel(c.Request.Context(), c.GetString("openid"), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -1,8 +1,15 @@
 package handler
 
 import (
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/wxlbd/polaris/internal/application/dto"
 	"github.com/wxlbd/polaris/internal/application/service"
 	"github.com/wxlbd/polaris/pkg/errors"
 	"github.com/wxlbd/polaris/pkg/response"
@@ -45,17 +52,112 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 	}
 
 	// Upload file
-	result, err := h.uploadService.UploadFile(c.Request.Context(), fileHeader, uploadType, relatedID)
+	result, err := h.uploadService.UploadFile(c.Request.Context(), c.GetString("openid"), fileHeader, uploadType, relatedID)
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
 	// Return success response
-	response.Success(c, gin.H{
+	response.Success(c, uploadResultData(result))
+}
+
+// List 分页查询当前用户的上传文件
+// @Router /uploads [get]
+func (h *UploadHandler) List(c *gin.Context) {
+	var req dto.ListUploadsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	records, total, err := h.uploadService.ListUploads(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessPaginated(c, records, total, req.Page, req.PageSize)
+}
+
+// Usage 查询当前用户的存储用量与配额
+// @Router /uploads/usage [get]
+func (h *UploadHandler) Usage(c *gin.Context) {
+	usage, err := h.uploadService.GetUsage(c.Request.Context(), c.GetString("openid"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, usage)
+}
+
+// Delete 删除当前用户的上传文件，正在用作头像的文件返回冲突
+// @Router /uploads/{id} [delete]
+func (h *UploadHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "Invalid upload id")
+		return
+	}
+
+	if err := h.uploadService.DeleteUpload(c.Request.Context(), c.GetString("openid"), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ServeFile 访问已上传的文件（仅本地存储后端挂载）
+// 通过 ?size=64 获取对应规格的缩略图
+// @Router /uploads/{filepath} [get]
+func (h *UploadHandler) ServeFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+
+	size := 0
+	if v := c.Query("size"); v != "" {
+		var err error
+		if size, err = strconv.Atoi(v); err != nil || size <= 0 {
+			response.ErrorWithMessage(c, errors.ParamError, "Invalid size parameter")
+			return
+		}
+	}
+	acceptWebP := strings.Contains(c.GetHeader("Accept"), "image/webp")
+
+	rc, info, err := h.uploadService.OpenFile(c.Request.Context(), key, size, acceptWebP)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	defer rc.Close()
+
+	if size > 0 {
+		// 同一地址按 Accept 返回不同格式
+		c.Header("Vary", "Accept")
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		if info.ContentType != "" {
+			c.Header("Content-Type", info.ContentType)
+		}
+		http.ServeContent(c.Writer, c.Request, path.Base(info.Key), info.ModTime, rs)
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, rc, nil)
+}
+
+// uploadResultData 上传结果响应数据
+func uploadResultData(result *service.UploadResult) gin.H {
+	return gin.H{
+		"id":       strconv.FormatInt(result.ID, 10),
 		"url":      result.URL,
 		"path":     result.Path,
 		"filename": result.Filename,
 		"size":     result.Size,
-	})
+		"sha256":   result.SHA256,
+		"variants": result.Variants,
+	}
 }
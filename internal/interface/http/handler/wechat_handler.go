@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/application/dto"
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// WechatHandler 微信处理器
+type WechatHandler struct {
+	wechatService *service.WechatService
+}
+
+// NewWechatHandler 创建微信处理器
+func NewWechatHandler(wechatService *service.WechatService) *WechatHandler {
+	return &WechatHandler{wechatService: wechatService}
+}
+
+// RecordSubscriptions 记录订阅消息授权结果
+// 客户端在 wx.requestSubscribeMessage 成功回调后调用，每个同意的模板增加一次发送额度
+// @Router /wechat/subscriptions [post]
+func (h *WechatHandler) RecordSubscriptions(c *gin.Context) {
+	var req dto.RecordSubscriptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	quotas, err := h.wechatService.RecordSubscriptions(c.Request.Context(), c.GetString("openid"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, quotas)
+}
+
+// GetSubscriptions 查询当前用户的订阅消息剩余额度
+// @Router /wechat/subscriptions [get]
+func (h *WechatHandler) GetSubscriptions(c *gin.Context) {
+	quotas, err := h.wechatService.GetSubscriptionQuotas(c.Request.Context(), c.GetString("openid"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, quotas)
+}
+
+// GenerateQRCode 生成小程序码
+// 相同参数的小程序码只生成一次，再次请求返回已存储的图片
+// @Router /wechat/qrcode [post]
+func (h *WechatHandler) GenerateQRCode(c *gin.Context) {
+	var req dto.GenerateQRCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, errors.ParamError, "参数错误: "+err.Error())
+		return
+	}
+
+	qrcode, err := h.wechatService.GenerateQRCode(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, qrcode)
+}
@@ -4,7 +4,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/storage"
+	"github.com/wxlbd/polaris/internal/infrastructure/token"
 	"github.com/wxlbd/polaris/internal/interface/http/handler"
 	"github.com/wxlbd/polaris/internal/interface/middleware"
 )
@@ -15,6 +20,20 @@ func NewRouter(
 	cfg *config.Config,
 	authHandler *handler.AuthHandler,
 	uploadHandler *handler.UploadHandler,
+	resumableUploadHandler *handler.ResumableUploadHandler,
+	directUploadHandler *handler.DirectUploadHandler,
+	appVersionHandler *handler.AppVersionHandler,
+	notificationHandler *handler.NotificationHandler,
+	scheduledNotificationHandler *handler.ScheduledNotificationHandler,
+	wechatHandler *handler.WechatHandler,
+	sceneLinkHandler *handler.SceneLinkHandler,
+	paymentHandler *handler.PaymentHandler,
+	reconciliationHandler *handler.ReconciliationHandler,
+	tokenRepo repository.TokenRepository,
+	idempotencyRepo repository.IdempotencyRepository,
+	rateLimitRepo repository.RateLimitRepository,
+	keyManager *token.KeyManager,
+	permissionService *service.PermissionService,
 	logger *zap.Logger,
 ) *gin.Engine {
 	// 设置Gin运行模式
@@ -27,8 +46,15 @@ func NewRouter(
 	r.Use(middleware.Logger())
 	r.Use(gin.Recovery())
 
-	// 静态文件服务
-	r.Static("/uploads", "./uploads")
+	// 静态文件服务与预签名直传，仅本地存储后端需要，对象存储由存储服务直接提供
+	if storage.IsLocal(cfg) {
+		r.GET("/uploads/*filepath", uploadHandler.ServeFile)
+		r.HEAD("/uploads/*filepath", uploadHandler.ServeFile)
+		r.PUT("/uploads/*filepath", directUploadHandler.PutObject)
+	}
+
+	// 访问令牌验证公钥
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	// API v1 路由组
 	v1 := r.Group("/v1")
@@ -37,20 +63,116 @@ func NewRouter(
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/wechat-login", authHandler.WechatLogin)
+			auth.POST("/refresh-token", authHandler.RefreshToken)
 			auth.GET("/app-version", authHandler.GetAppVersion)
 		}
 
+		// 客户端版本检查（认证可选，用于灰度分桶）
+		app := v1.Group("/app")
+		app.Use(middleware.OptionalAuth(keyManager, tokenRepo, permissionService))
+		{
+			app.GET("/update-check", appVersionHandler.CheckUpdate)
+		}
+
+		// 断点续传能力探测（tus 协议，无需认证）
+		v1.OPTIONS("/uploads/resumable", middleware.TusResumable(), resumableUploadHandler.Options)
+
+		// 微信支付回调通知（验签即授权，无需认证）
+		v1.POST("/payments/wechat/notify", paymentHandler.WechatNotify)
+
 		// 需要认证的路由
 		authRequired := v1.Group("")
-		authRequired.Use(middleware.Auth(cfg))
+		authRequired.Use(middleware.Auth(keyManager, tokenRepo, permissionService))
+		// 写请求幂等（携带 Idempotency-Key 时重复请求重放首次响应）
+		authRequired.Use(middleware.Idempotency(idempotencyRepo, cfg.Idempotency))
 		{
 			// 认证相关（需要token）
-			authRequired.POST("/auth/refresh-token", authHandler.RefreshToken)
+			authRequired.POST("/auth/logout", authHandler.Logout)
 			authRequired.GET("/auth/user-info", authHandler.GetUserInfo)
 			authRequired.PUT("/auth/user-info", authHandler.UpdateUserInfo)
 
 			// 文件上传
 			authRequired.POST("/upload", uploadHandler.Upload)
+			authRequired.POST("/upload/presign", directUploadHandler.Presign)
+			authRequired.POST("/upload/presign/:id/complete", directUploadHandler.Complete)
+			authRequired.GET("/uploads", uploadHandler.List)
+			authRequired.GET("/uploads/usage", uploadHandler.Usage)
+			authRequired.DELETE("/uploads/:id", uploadHandler.Delete)
+
+			// 通知偏好
+			authRequired.GET("/notification-preferences", notificationHandler.GetPreferences)
+			authRequired.PUT("/notification-preferences", notificationHandler.UpdatePreferences)
+
+			// 定时通知
+			authRequired.POST("/scheduled-notifications", scheduledNotificationHandler.Create)
+			authRequired.GET("/scheduled-notifications", scheduledNotificationHandler.List)
+			authRequired.DELETE("/scheduled-notifications/:id", scheduledNotificationHandler.Cancel)
+
+			// 微信订阅消息额度与小程序码
+			authRequired.POST("/wechat/subscriptions", wechatHandler.RecordSubscriptions)
+			authRequired.GET("/wechat/subscriptions", wechatHandler.GetSubscriptions)
+			authRequired.POST("/wechat/qrcode", wechatHandler.GenerateQRCode)
+
+			// 小程序码场景短链
+			authRequired.POST("/scene-links", sceneLinkHandler.Create)
+			authRequired.GET("/scene/:code", middleware.RateLimit(rateLimitRepo, "scene_resolve", cfg.Wechat.SceneLink.ResolveRateLimit), sceneLinkHandler.Resolve)
+
+			// 断点续传（tus 协议）
+			resumable := authRequired.Group("/uploads/resumable")
+			resumable.Use(middleware.TusResumable())
+			{
+				resumable.POST("", resumableUploadHandler.Create)
+				resumable.HEAD("/:id", resumableUploadHandler.Head)
+				resumable.PATCH("/:id", resumableUploadHandler.Patch)
+				resumable.DELETE("/:id", resumableUploadHandler.Delete)
+				resumable.POST("/:id/complete", resumableUploadHandler.Complete)
+			}
+
+			// 管理后台
+			admin := authRequired.Group("/admin")
+			{
+				// 应用版本管理
+				appVersions := admin.Group("/app-versions")
+				{
+					read := middleware.RequirePermission(entity.PermissionAppVersionRead)
+					write := middleware.RequirePermission(entity.PermissionAppVersionWrite)
+
+					appVersions.GET("", read, appVersionHandler.List)
+					appVersions.GET("/:version", read, appVersionHandler.Get)
+					appVersions.POST("", write, appVersionHandler.Create)
+					appVersions.PUT("/:version", write, appVersionHandler.Update)
+					appVersions.POST("/active", write, appVersionHandler.SetActive)
+					appVersions.POST("/:version/rollout", write, appVersionHandler.Rollout)
+					appVersions.POST("/:version/pause", write, appVersionHandler.PauseRollout)
+					appVersions.POST("/:version/rollback", write, appVersionHandler.Rollback)
+					appVersions.DELETE("/:version", write, appVersionHandler.Delete)
+				}
+
+				// 通知投递记录
+				notifications := admin.Group("/notifications")
+				notifications.Use(middleware.RequirePermission(entity.PermissionNotificationRead))
+				{
+					notifications.GET("", notificationHandler.List)
+					notifications.GET("/:id", notificationHandler.Get)
+				}
+
+				// 支付单与退款
+				payments := admin.Group("/payments")
+				{
+					payments.GET("/:paymentNo", middleware.RequirePermission(entity.PermissionPaymentRead), paymentHandler.Get)
+					payments.POST("/:paymentNo/refunds", middleware.RequirePermission(entity.PermissionPaymentRefund), paymentHandler.Refund)
+				}
+
+				// 支付对账
+				reconciliation := admin.Group("/reconciliation")
+				{
+					reconcile := middleware.RequirePermission(entity.PermissionPaymentReconcile)
+
+					reconciliation.GET("/discrepancies", middleware.RequirePermission(entity.PermissionPaymentRead), reconciliationHandler.ListDiscrepancies)
+					reconciliation.POST("/discrepancies/:id/resolve", reconcile, reconciliationHandler.ResolveDiscrepancy)
+					reconciliation.POST("/runs", reconcile, reconciliationHandler.Run)
+				}
+			}
 		}
 	}
 
@@ -0,0 +1,203 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/application/service"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/notification"
+)
+
+// Job 周期性后台任务
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler 后台任务调度器
+// 每个任务在独立的 goroutine 中按固定间隔运行；多实例部署时每个实例都会运行，任务需保证可重复执行
+type Scheduler struct {
+	jobs   []Job
+	logger *zap.Logger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler 创建后台任务调度器并注册任务
+func NewScheduler(
+	cfg *config.Config,
+	uploadService *service.UploadService,
+	resumableUploadService *service.ResumableUploadService,
+	notificationDispatcher *notification.Dispatcher,
+	scheduledNotificationService *service.ScheduledNotificationService,
+	sceneLinkService *service.SceneLinkService,
+	paymentService *service.PaymentService,
+	reconciliationService *service.ReconciliationService,
+	logger *zap.Logger,
+) *Scheduler {
+	s := &Scheduler{logger: logger}
+
+	// 清理过期未完成的断点续传会话
+	s.register(Job{
+		Name:     "resumable_upload_sweeper",
+		Interval: time.Duration(cfg.Upload.Resumable.SweepInterval) * time.Minute,
+		Run: func(ctx context.Context) error {
+			swept, err := resumableUploadService.SweepExpired(ctx)
+			if swept > 0 {
+				logger.Info("Swept expired resumable uploads", zap.Int("count", swept))
+			}
+			return err
+		},
+	})
+
+	// 清理更换头像后不再被引用的旧文件
+	s.register(Job{
+		Name:     "orphan_upload_sweeper",
+		Interval: time.Duration(cfg.Upload.Orphan.SweepInterval) * time.Minute,
+		Run: func(ctx context.Context) error {
+			swept, err := uploadService.SweepOrphans(ctx)
+			if swept > 0 {
+				logger.Info("Swept orphaned uploads", zap.Int("count", swept))
+			}
+			return err
+		},
+	})
+
+	// 投递发件箱中的通知
+	s.register(Job{
+		Name:     "notification_dispatcher",
+		Interval: time.Duration(cfg.Notification.DispatchInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			sent, err := notificationDispatcher.Dispatch(ctx)
+			if sent > 0 {
+				logger.Debug("Dispatched notifications", zap.Int("count", sent))
+			}
+			return err
+		},
+	})
+
+	// 发送到期的定时通知
+	s.register(Job{
+		Name:     "scheduled_notification_dispatcher",
+		Interval: time.Duration(cfg.Notification.Scheduled.DispatchInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			sent, err := scheduledNotificationService.DispatchDue(ctx)
+			if sent > 0 {
+				logger.Info("Sent scheduled notifications", zap.Int("count", sent))
+			}
+			return err
+		},
+	})
+
+	// 清理过期超过保留期的小程序码场景短链
+	s.register(Job{
+		Name:     "scene_link_sweeper",
+		Interval: time.Duration(cfg.Wechat.SceneLink.SweepInterval) * time.Minute,
+		Run: func(ctx context.Context) error {
+			swept, err := sceneLinkService.SweepExpired(ctx)
+			if swept > 0 {
+				logger.Info("Swept expired scene links", zap.Int("count", swept))
+			}
+			return err
+		},
+	})
+
+	// 取消过期仍待支付的支付单
+	s.register(Job{
+		Name:     "expired_payment_canceller",
+		Interval: time.Duration(cfg.Payment.CancelInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			cancelled, err := paymentService.CancelExpired(ctx)
+			if cancelled > 0 {
+				logger.Info("Cancelled expired payments", zap.Int("count", cancelled))
+			}
+			return err
+		},
+	})
+
+	// 对账未完成的退款单
+	s.register(Job{
+		Name:     "refund_reconciler",
+		Interval: time.Duration(cfg.Payment.RefundReconcileInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			completed, err := paymentService.ReconcileRefunds(ctx)
+			if completed > 0 {
+				logger.Info("Reconciled refunds", zap.Int("count", completed))
+			}
+			return err
+		},
+	})
+
+	// 每天在账单生成后核对一次前一天的渠道对账单，多实例部署时只有一个实例核对
+	s.register(Job{
+		Name:     "payment_statement_reconciler",
+		Interval: time.Duration(cfg.Payment.StatementInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			_, err := reconciliationService.ReconcileDaily(ctx)
+			return err
+		},
+	})
+
+	return s
+}
+
+// register 注册任务，间隔不大于 0 的任务视为禁用
+func (s *Scheduler) register(job Job) {
+	if job.Interval <= 0 {
+		s.logger.Info("Background job disabled", zap.String("job", job.Name))
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start 启动全部任务
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop 停止全部任务并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// loop 按间隔循环执行任务
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(ctx, job)
+		}
+	}
+}
+
+// run 执行一次任务，任务出错或 panic 不影响后续执行
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Background job panicked", zap.String("job", job.Name), zap.Any("panic", r))
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+		s.logger.Error("Background job failed", zap.String("job", job.Name), zap.Error(err))
+	}
+}
@@ -4,56 +4,86 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 
-	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/token"
 	"github.com/wxlbd/polaris/pkg/errors"
 	"github.com/wxlbd/polaris/pkg/response"
 )
 
 // Auth JWT认证中间件
-func Auth(cfg *config.Config) gin.HandlerFunc {
+// 按令牌头部 kid 选择验证密钥并拒绝非预期算法；
+// 除校验签名与有效期外，还会查询吊销列表，已退出或被吊销会话的令牌在过期前即被拒绝；
+// 通过校验后将令牌中的角色解析为权限集合，供 RequirePermission 使用
+func Auth(keyManager *token.KeyManager, tokenRepo repository.TokenRepository, permissionResolver PermissionResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			response.Error(c, errors.ErrUnauthorized)
+		if err := authenticate(c, keyManager, tokenRepo, permissionResolver); err != nil {
+			response.Error(c, err)
 			c.Abort()
 			return
 		}
 
-		// 验证Bearer前缀
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			response.Error(c, errors.ErrUnauthorized)
-			c.Abort()
-			return
+		c.Next()
+	}
+}
+
+// OptionalAuth 可选认证中间件
+// 携带有效令牌时与 Auth 一样写入用户信息，未携带或令牌无效时按匿名请求继续处理
+func OptionalAuth(keyManager *token.KeyManager, tokenRepo repository.TokenRepository, permissionResolver PermissionResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			_ = authenticate(c, keyManager, tokenRepo, permissionResolver)
 		}
 
-		tokenString := parts[1]
+		c.Next()
+	}
+}
 
-		// 解析Token
-		token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWT.Secret), nil
-		})
+// authenticate 校验访问令牌并将用户信息写入 context
+func authenticate(c *gin.Context, keyManager *token.KeyManager, tokenRepo repository.TokenRepository, permissionResolver PermissionResolver) error {
+	// 获取Authorization header
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return errors.ErrUnauthorized
+	}
 
-		if err != nil || !token.Valid {
-			response.Error(c, errors.ErrInvalidToken)
-			c.Abort()
-			return
-		}
+	// 验证Bearer前缀
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return errors.ErrUnauthorized
+	}
 
-		// 获取Claims
-		claims, ok := token.Claims.(*jwt.RegisteredClaims)
-		if !ok {
-			response.Error(c, errors.ErrInvalidToken)
-			c.Abort()
-			return
-		}
+	tokenString := parts[1]
 
-		// 设置用户信息到context
-		c.Set("openid", claims.Subject)
+	// 解析Token
+	claims, err := keyManager.Parse(tokenString)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
 
-		c.Next()
+	// 检查吊销列表
+	revoked, err := tokenRepo.IsAccessTokenRevoked(c.Request.Context(), claims.ID, claims.SessionID)
+	if err != nil {
+		return err
 	}
+	if revoked {
+		return errors.ErrTokenRevoked
+	}
+
+	permissions, err := permissionResolver.ResolvePermissions(c.Request.Context(), claims.Roles)
+	if err != nil {
+		return err
+	}
+
+	// 设置用户信息到context
+	c.Set("openid", claims.Subject)
+	c.Set("roles", claims.Roles)
+	c.Set("permissions", permissions)
+	c.Set("jti", claims.ID)
+	c.Set("sid", claims.SessionID)
+	if claims.ExpiresAt != nil {
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
+	}
+
+	return nil
 }
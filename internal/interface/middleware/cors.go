@@ -14,10 +14,12 @@ func CORS() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Methods", "*")
 		c.Header("Access-Control-Allow-Headers", "*")
 		//c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type")
+		// 幂等重放标记与断点续传(tus)客户端需要读取的响应头
+		c.Header("Access-Control-Expose-Headers", "Idempotent-Replayed, Retry-After, Location, Upload-Offset, Upload-Length, Upload-Expires, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size")
 		//c.Header("Access-Control-Allow-Credentials", "true")
 
-		// 放行所有OPTIONS方法
-		if method == "OPTIONS" {
+		// 放行所有预检请求；非预检的 OPTIONS(如 tus 协议的能力探测)交由路由处理
+		if method == "OPTIONS" && c.GetHeader("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(204)
 			return
 		}
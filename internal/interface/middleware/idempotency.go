@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/logger"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+	"github.com/wxlbd/polaris/pkg/snowflake"
+)
+
+const (
+	// IdempotencyKeyHeader 幂等键请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 重放的响应携带该响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength 幂等键最大长度
+	maxIdempotencyKeyLength = 255
+	// idempotencyMemoryBodySize 请求体超过该大小时暂存到临时文件，避免大文件上传占用内存
+	idempotencyMemoryBodySize = 1 << 20
+	// defaultIdempotencyLockTTL 未配置时处理中记录的有效期
+	defaultIdempotencyLockTTL = time.Minute
+)
+
+// Idempotency 写请求幂等中间件
+// 对携带 Idempotency-Key 请求头的 POST/PUT/PATCH 请求，按用户与幂等键记录请求指纹(方法、路径、查询参数与请求体的哈希)，
+// 首次请求正常处理并保存响应(状态码、响应头、响应体)，之后的重复请求直接重放该响应；
+// 相同幂等键的请求仍在处理中时返回 409，幂等键已用于指纹不同的请求时返回 422。
+// 服务端错误(5xx)、并发冲突(409)与限流(429)的响应不保存，客户端可用相同幂等键重试；
+// 响应体超出保存上限时只保存状态码，重复请求返回 409 而不重新处理。处理期间持续延长处理中记录的有效期。
+// 需挂载在 Auth 之后，依赖 Auth 写入 context 的 openid 区分用户
+func Idempotency(repo repository.IdempotencyRepository, cfg config.IdempotencyConfig) gin.HandlerFunc {
+	ttl := time.Duration(cfg.TTL) * time.Second
+	lockTTL := time.Duration(cfg.LockTTL) * time.Second
+	if lockTTL <= 0 {
+		lockTTL = defaultIdempotencyLockTTL
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if ttl <= 0 || key == "" || !isIdempotentMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.ErrorWithMessage(c, errors.ParamError, "Idempotency-Key 不能超过 255 个字符")
+			c.Abort()
+			return
+		}
+
+		fingerprint, cleanup, err := fingerprintRequest(c.Request)
+		if err != nil {
+			response.ErrorWithMessage(c, errors.ParamError, "读取请求体失败")
+			c.Abort()
+			return
+		}
+		defer cleanup()
+
+		record := &entity.IdempotencyRecord{
+			Key:         idempotencyStoreKey(c.GetString("openid"), key),
+			Fingerprint: fingerprint,
+			Owner:       strconv.FormatInt(snowflake.Generate(), 10),
+			CreatedAt:   time.Now(),
+		}
+		existing, acquired, err := repo.Acquire(c.Request.Context(), record, lockTTL)
+		if err != nil {
+			response.Error(c, err)
+			c.Abort()
+			return
+		}
+		if !acquired {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				response.ErrorWithMessage(c, errors.IdempotencyKeyMismatch, "Idempotency-Key 已用于内容不同的请求")
+			case !existing.IsCompleted():
+				c.Header("Retry-After", "1")
+				response.ErrorWithMessage(c, errors.IdempotencyKeyInProgress, "相同 Idempotency-Key 的请求正在处理中")
+			case existing.Truncated:
+				response.ErrorWithMessage(c, errors.IdempotencyNoReplay, "相同 Idempotency-Key 的请求已处理，响应过大无法重放")
+			default:
+				replayResponse(c, existing)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, limit: cfg.MaxResponseSize}
+		c.Writer = recorder
+
+		// 未保存响应时释放记录(含处理过程中 panic)，相同幂等键的请求可以重新处理
+		keep := false
+		stopKeepAlive := keepIdempotencyKey(repo, record, lockTTL, c.Request.URL.Path)
+		defer func() {
+			stopKeepAlive()
+			if keep {
+				return
+			}
+			if err := repo.Release(context.Background(), record.Key, record.Owner); err != nil {
+				logger.Warn("Failed to release idempotency key", zap.String("path", c.Request.URL.Path), zap.Error(err))
+			}
+		}()
+
+		c.Next()
+		stopKeepAlive()
+
+		status := recorder.Status()
+		if !isCacheableStatus(status) {
+			return
+		}
+
+		record.StatusCode = status
+		if recorder.overflow {
+			// 请求已处理但响应无法保存，只记录状态码，重复请求返回 409 而不重新处理
+			record.Truncated = true
+		} else {
+			record.Header = replayableHeader(recorder.Header())
+			record.Body = recorder.body.Bytes()
+		}
+		// 请求已处理，保存失败时也不释放记录，避免重试重复执行；记录在 lockTTL 后失效
+		keep = true
+		saved, err := repo.Complete(context.Background(), record, ttl)
+		switch {
+		case err != nil:
+			logger.Warn("Failed to save idempotent response", zap.String("path", c.Request.URL.Path), zap.Error(err))
+		case !saved:
+			logger.Warn("Idempotency key taken over by another request before the response was saved", zap.String("path", c.Request.URL.Path))
+		}
+	}
+}
+
+// keepIdempotencyKey 每隔 lockTTL/3 延长处理中记录的有效期，处理时间超过 lockTTL 时相同幂等键的重试仍返回 409
+// 返回的 stop 可重复调用，停止后不再延长
+func keepIdempotencyKey(repo repository.IdempotencyRepository, record *entity.IdempotencyRecord, lockTTL time.Duration, path string) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				extended, err := repo.Extend(context.Background(), record.Key, record.Owner, lockTTL)
+				if err != nil {
+					logger.Warn("Failed to extend idempotency key", zap.String("path", path), zap.Error(err))
+					continue
+				}
+				if !extended {
+					logger.Warn("Idempotency key expired and was taken over by another request", zap.String("path", path))
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// isIdempotentMethod 是否为需要幂等处理的请求方法
+func isIdempotentMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// isCacheableStatus 响应是否可以保存重放，可重试的错误不保存
+func isCacheableStatus(status int) bool {
+	return status < http.StatusInternalServerError &&
+		status != http.StatusConflict &&
+		status != http.StatusTooManyRequests
+}
+
+// idempotencyStoreKey 由用户与幂等键派生存储键，不同用户的相同幂等键互不影响
+func idempotencyStoreKey(openID, key string) string {
+	sum := sha256.Sum256([]byte(openID + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprintRequest 计算请求指纹并重置请求体供后续处理器读取，返回的 cleanup 用于删除暂存的临时文件
+func fingerprintRequest(r *http.Request) (string, func(), error) {
+	cleanup := func() {}
+	h := sha256.New()
+	io.WriteString(h, r.Method+"\n"+r.URL.Path+"\n"+r.URL.RawQuery+"\n")
+	if r.Body == nil || r.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(h, &buf), io.LimitReader(r.Body, idempotencyMemoryBodySize+1))
+	if err != nil {
+		return "", cleanup, err
+	}
+	if n <= idempotencyMemoryBodySize {
+		r.Body = io.NopCloser(&buf)
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	// 大请求体(如文件上传)暂存到临时文件
+	file, err := os.CreateTemp("", "idempotency-*")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	if _, err := io.Copy(io.MultiWriter(h, file), r.Body); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	r.Body = io.NopCloser(file)
+	return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+}
+
+// replayResponse 重放保存的响应
+func replayResponse(c *gin.Context, record *entity.IdempotencyRecord) {
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(record.StatusCode)
+	_, _ = c.Writer.Write(record.Body)
+}
+
+// replayableHeader 复制需要重放的响应头，跨域与逐跳响应头由每次请求重新生成
+func replayableHeader(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		switch {
+		case strings.HasPrefix(name, "Access-Control-"),
+			name == "Content-Length", name == "Date", name == "Connection", name == "Transfer-Encoding":
+			continue
+		}
+		result[name] = append([]string(nil), values...)
+	}
+	return result
+}
+
+// responseRecorder 在写出响应的同时记录响应体，超过 limit 后停止记录
+type responseRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+// Write 写出并记录响应体
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写出并记录响应体
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// record 记录响应体
+func (w *responseRecorder) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.limit > 0 && int64(w.body.Len()+len(data)) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
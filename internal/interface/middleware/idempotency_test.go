@@ -0,0 +1,389 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/infrastructure/logger"
+	"github.com/wxlbd/polaris/pkg/errors"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeIdempotencyRepo 幂等请求记录仓储的内存实现
+type fakeIdempotencyRepo struct {
+	mu       sync.Mutex
+	records  map[string]*entity.IdempotencyRecord
+	acquires int
+}
+
+func newFakeIdempotencyRepo() *fakeIdempotencyRepo {
+	return &fakeIdempotencyRepo{records: make(map[string]*entity.IdempotencyRecord)}
+}
+
+func (r *fakeIdempotencyRepo) Acquire(ctx context.Context, record *entity.IdempotencyRecord, lockTTL time.Duration) (*entity.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.acquires++
+	if existing, ok := r.records[record.Key]; ok {
+		clone := *existing
+		return &clone, false, nil
+	}
+	clone := *record
+	r.records[record.Key] = &clone
+	return nil, true, nil
+}
+
+func (r *fakeIdempotencyRepo) Extend(ctx context.Context, key, owner string, lockTTL time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.records[key]
+	return ok && existing.Owner == owner && !existing.IsCompleted(), nil
+}
+
+func (r *fakeIdempotencyRepo) Complete(ctx context.Context, record *entity.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.records[record.Key]
+	if !ok || existing.Owner != record.Owner {
+		return false, nil
+	}
+	clone := *record
+	r.records[record.Key] = &clone
+	return true, nil
+}
+
+func (r *fakeIdempotencyRepo) Release(ctx context.Context, key, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.records[key]; ok && existing.Owner == owner && !existing.IsCompleted() {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *fakeIdempotencyRepo) size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+func (r *fakeIdempotencyRepo) acquireCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.acquires
+}
+
+var testIdempotencyConfig = config.IdempotencyConfig{TTL: 3600, LockTTL: 60, MaxResponseSize: 1 << 10}
+
+// newIdempotencyEngine 挂载幂等中间件，openid 取自 X-Openid 请求头以模拟 Auth
+func newIdempotencyEngine(repo *fakeIdempotencyRepo, cfg config.IdempotencyConfig, handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(func(c *gin.Context) {
+		c.Set("openid", c.GetHeader("X-Openid"))
+		c.Next()
+	})
+	r.Use(Idempotency(repo, cfg))
+	r.Any("/orders", handler)
+	r.Any("/orders/:id", handler)
+	return r
+}
+
+func doRequest(r http.Handler, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-Openid", "user-1")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) errors.ErrorCode {
+	t.Helper()
+	var resp struct {
+		Code errors.ErrorCode `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return resp.Code
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	var calls atomic.Int32
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		n := calls.Add(1)
+		c.Header("Location", "/orders/1")
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.JSON(http.StatusCreated, gin.H{"id": 1, "call": n})
+	})
+
+	first := doRequest(r, http.MethodPost, "/orders", "key-1", `{"amount":100}`)
+	second := doRequest(r, http.MethodPost, "/orders", "key-1", `{"amount":100}`)
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("Idempotent-Replayed = %q/%q, want only on the replay",
+			first.Header().Get(IdempotentReplayedHeader), second.Header().Get(IdempotentReplayedHeader))
+	}
+	if second.Header().Get("Location") != "/orders/1" {
+		t.Errorf("replayed Location = %q", second.Header().Get("Location"))
+	}
+	// 跨域响应头不保存
+	if got := second.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("replayed Access-Control-Allow-Origin = %q, want empty", got)
+	}
+
+	// 不同用户的相同幂等键互不影响
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"amount":100}`))
+	req.Header.Set("X-Openid", "user-2")
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if calls.Load() != 2 {
+		t.Errorf("handler calls = %d, want 2 after another user's request", calls.Load())
+	}
+}
+
+func TestIdempotencySkipped(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	var calls atomic.Int32
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		calls.Add(1)
+		c.Status(http.StatusNoContent)
+	})
+
+	doRequest(r, http.MethodPost, "/orders", "", `{}`)
+	doRequest(r, http.MethodPost, "/orders", "", `{}`)
+	doRequest(r, http.MethodDelete, "/orders/1", "key-1", "")
+	doRequest(r, http.MethodDelete, "/orders/1", "key-1", "")
+
+	if calls.Load() != 4 || repo.acquireCount() != 0 {
+		t.Errorf("handler calls = %d, acquires = %d, want 4 and 0", calls.Load(), repo.acquireCount())
+	}
+
+	w := doRequest(r, http.MethodPost, "/orders", strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("over-long key status = %d, want 400", w.Code)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	}()
+	<-started
+
+	w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	if w.Code != http.StatusConflict || errorCode(t, w) != errors.IdempotencyKeyInProgress {
+		t.Errorf("duplicate in flight = %d %s, want 409 in progress", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("duplicate in flight has no Retry-After")
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusOK {
+		t.Fatalf("first request status = %d", first.Code)
+	}
+	w = doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	if w.Code != http.StatusOK || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("after completion = %d replayed %q, want replay", w.Code, w.Header().Get(IdempotentReplayedHeader))
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyFingerprintMismatch(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	var calls atomic.Int32
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	doRequest(r, http.MethodPost, "/orders", "key-1", `{"amount":100}`)
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodPost, "/orders", `{"amount":200}`},
+		{http.MethodPost, "/orders?coupon=1", `{"amount":100}`},
+		{http.MethodPut, "/orders", `{"amount":100}`},
+		{http.MethodPost, "/orders/1", `{"amount":100}`},
+	} {
+		w := doRequest(r, tt.method, tt.path, "key-1", tt.body)
+		if w.Code != http.StatusUnprocessableEntity || errorCode(t, w) != errors.IdempotencyKeyMismatch {
+			t.Errorf("%s %s %s = %d %s, want 422", tt.method, tt.path, tt.body, w.Code, w.Body.String())
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyRetryableResponsesNotSaved(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusConflict, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			repo := newFakeIdempotencyRepo()
+			var calls atomic.Int32
+			r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+				if calls.Add(1) == 1 {
+					c.JSON(status, gin.H{"error": "retry"})
+					return
+				}
+				c.JSON(http.StatusCreated, gin.H{"ok": true})
+			})
+
+			if w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`); w.Code != status {
+				t.Fatalf("first status = %d, want %d", w.Code, status)
+			}
+			if repo.size() != 0 {
+				t.Fatalf("records = %d after a retryable response, want released", repo.size())
+			}
+			if w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+				t.Fatalf("retry = %d replayed %q, want processed again", w.Code, w.Header().Get(IdempotentReplayedHeader))
+			}
+			if w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "true" {
+				t.Errorf("third = %d, want replay of the successful response", w.Code)
+			}
+			if calls.Load() != 2 {
+				t.Errorf("handler calls = %d, want 2", calls.Load())
+			}
+		})
+	}
+}
+
+func TestIdempotencyClientErrorSaved(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	var calls atomic.Int32
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+	})
+
+	doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	if w.Code != http.StatusBadRequest || w.Header().Get(IdempotentReplayedHeader) != "true" || calls.Load() != 1 {
+		t.Errorf("replay = %d replayed %q calls %d, want the saved 400", w.Code, w.Header().Get(IdempotentReplayedHeader), calls.Load())
+	}
+}
+
+func TestIdempotencyPanicReleasesKey(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	var calls atomic.Int32
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	if w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("panic status = %d, want 500", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`); w.Code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("retry after panic = %d calls %d, want processed again", w.Code, calls.Load())
+	}
+}
+
+func TestIdempotencyTruncatedResponse(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	var calls atomic.Int32
+	large := strings.Repeat("x", int(testIdempotencyConfig.MaxResponseSize)+1)
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		calls.Add(1)
+		c.String(http.StatusOK, large)
+	})
+
+	first := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	if first.Code != http.StatusOK || first.Body.String() != large {
+		t.Fatalf("first response = %d with %d bytes, want the full body", first.Code, first.Body.Len())
+	}
+
+	// 请求已处理但响应未保存，重复请求既不重放也不重新处理
+	w := doRequest(r, http.MethodPost, "/orders", "key-1", `{}`)
+	if w.Code != http.StatusConflict || errorCode(t, w) != errors.IdempotencyNoReplay {
+		t.Errorf("duplicate = %d %s, want 409 no replay", w.Code, w.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyLargeBodySpilledToTempFile(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	repo := newFakeIdempotencyRepo()
+	body := bytes.Repeat([]byte("0123456789abcdef"), idempotencyMemoryBodySize/16+1024)
+	var received []byte
+	var spilled []os.DirEntry
+	r := newIdempotencyEngine(repo, testIdempotencyConfig, func(c *gin.Context) {
+		spilled, _ = os.ReadDir(tmp)
+		received, _ = io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"size": len(received)})
+	})
+
+	w := doRequest(r, http.MethodPatch, "/orders/1", "key-1", string(body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if !bytes.Equal(received, body) {
+		t.Errorf("handler received %d bytes, want the original %d", len(received), len(body))
+	}
+	if len(spilled) != 1 || !strings.HasPrefix(spilled[0].Name(), "idempotency-") {
+		t.Errorf("temp files during handling = %v, want one spilled body", spilled)
+	}
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Errorf("temp files after handling = %v, want removed", left)
+	}
+
+	// 大请求体同样参与指纹计算
+	changed := bytes.Clone(body)
+	changed[len(changed)-1] = '!'
+	if w := doRequest(r, http.MethodPatch, "/orders/1", "key-1", string(changed)); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("changed large body status = %d, want 422", w.Code)
+	}
+	if w := doRequest(r, http.MethodPatch, "/orders/1", "key-1", string(body)); w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("same large body status = %d, want replay", w.Code)
+	}
+}
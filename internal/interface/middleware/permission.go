@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/domain/entity"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// PermissionResolver 角色权限解析器
+type PermissionResolver interface {
+	// ResolvePermissions 解析角色集合拥有的全部权限
+	ResolvePermissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// RequirePermission 权限校验中间件
+// 需挂载在 Auth 之后，依赖 Auth 写入 context 的权限集合
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !entity.HasPermission(c.GetStringSlice("permissions"), permission) {
+			response.Error(c, errors.ErrPermissionDenied)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/wxlbd/polaris/internal/domain/repository"
+	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/pkg/errors"
+	"github.com/wxlbd/polaris/pkg/response"
+)
+
+// RateLimit 每用户请求频率限制中间件
+// 按用户在固定时间窗口内计数，超出 cfg.Limit 时返回 429 并通过 Retry-After 告知窗口剩余秒数；
+// name 区分不同路由的计数，Limit 不大于 0 时不限制。需挂载在 Auth 之后，依赖 Auth 写入 context 的 openid 区分用户
+func RateLimit(repo repository.RateLimitRepository, name string, cfg config.RateLimitConfig) gin.HandlerFunc {
+	window := time.Duration(cfg.Window) * time.Second
+
+	return func(c *gin.Context) {
+		if cfg.Limit <= 0 || window <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, err := repo.Allow(c.Request.Context(), name+":"+c.GetString("openid"), cfg.Limit, window)
+		if err != nil {
+			response.Error(c, err)
+			c.Abort()
+			return
+		}
+		if !allowed {
+			remaining := window - time.Duration(time.Now().UnixNano()%window.Nanoseconds())
+			c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+			response.ErrorWithMessage(c, errors.TooManyRequests, "请求过于频繁，请稍后重试")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TusVersion 支持的 tus 断点续传协议版本
+const TusVersion = "1.0.0"
+
+// TusResumable tus 协议版本协商中间件
+// 除 OPTIONS 外的请求必须携带 Tus-Resumable 请求头且版本受支持，否则返回 412
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", TusVersion)
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Tus-Version", TusVersion)
+			c.Next()
+			return
+		}
+
+		if c.GetHeader("Tus-Resumable") != TusVersion {
+			c.Header("Tus-Version", TusVersion)
+			c.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+
+		c.Next()
+	}
+}
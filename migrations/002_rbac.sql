@@ -0,0 +1,60 @@
+-- 角色权限 (RBAC)
+-- 包含角色表、角色权限表和用户角色关联表
+
+-- 角色表
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255),
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_code ON roles(code);
+
+COMMENT ON TABLE roles IS '角色表';
+COMMENT ON COLUMN roles.code IS '角色编码';
+
+-- 角色权限表
+CREATE TABLE IF NOT EXISTS role_permissions (
+    id BIGSERIAL PRIMARY KEY,
+    role_id BIGINT NOT NULL,
+    permission VARCHAR(64) NOT NULL,
+    created_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_permissions_role_perm ON role_permissions(role_id, permission);
+
+COMMENT ON TABLE role_permissions IS '角色权限表';
+COMMENT ON COLUMN role_permissions.permission IS '权限标识(资源:操作)，* 表示全部权限';
+
+-- 用户角色关联表
+CREATE TABLE IF NOT EXISTS user_roles (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    role_id BIGINT NOT NULL,
+    created_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_roles_user_role ON user_roles(user_id, role_id);
+
+COMMENT ON TABLE user_roles IS '用户角色关联表，未分配角色的用户视为普通用户(user)';
+
+-- 内置角色
+INSERT INTO roles (code, name, description) VALUES
+    ('admin', '管理员', '拥有全部权限'),
+    ('user', '普通用户', '默认角色')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, '*' FROM roles WHERE code = 'admin'
+ON CONFLICT (role_id, permission) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'content:create' FROM roles WHERE code = 'user'
+ON CONFLICT (role_id, permission) DO NOTHING;
+
+-- 授予管理员示例:
+-- INSERT INTO user_roles (user_id, role_id)
+-- SELECT u.id, r.id FROM users u, roles r WHERE u.openid = '<openid>' AND r.code = 'admin';
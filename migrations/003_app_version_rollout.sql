@@ -0,0 +1,21 @@
+-- 应用版本灰度发布
+-- 为版本增加发布渠道、目标平台与灰度放量字段
+
+ALTER TABLE app_versions ADD COLUMN IF NOT EXISTS channel VARCHAR(20) NOT NULL DEFAULT 'stable';
+ALTER TABLE app_versions ADD COLUMN IF NOT EXISTS platform VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE app_versions ADD COLUMN IF NOT EXISTS rollout_percentage INT NOT NULL DEFAULT 0;
+ALTER TABLE app_versions ADD COLUMN IF NOT EXISTS rollout_status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+-- 存量版本中已启用的视为全量发布，未启用的回填为草稿，避免被推送给客户端
+UPDATE app_versions SET rollout_status = 'draft' WHERE rollout_status = 'active' AND rollout_percentage = 0 AND is_active = FALSE;
+UPDATE app_versions SET rollout_percentage = 100 WHERE rollout_status = 'active' AND rollout_percentage = 0 AND is_active = TRUE;
+
+-- 创建时 is_active 由应用显式写入
+ALTER TABLE app_versions ALTER COLUMN is_active SET DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_app_versions_channel_platform ON app_versions(channel, platform);
+
+COMMENT ON COLUMN app_versions.channel IS '发布渠道: stable/beta/internal';
+COMMENT ON COLUMN app_versions.platform IS '目标平台，空表示全平台';
+COMMENT ON COLUMN app_versions.rollout_percentage IS '灰度放量比例(0-100)';
+COMMENT ON COLUMN app_versions.rollout_status IS '灰度发布状态: draft/active/paused/rolled_back';
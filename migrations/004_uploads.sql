@@ -0,0 +1,26 @@
+-- 上传文件记录
+-- 文件按内容 SHA-256 存储并去重，每次上传保留一条归属记录
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id BIGINT PRIMARY KEY,
+    openid VARCHAR(64) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    related_id VARCHAR(64),
+    storage_key VARCHAR(255) NOT NULL,
+    filename VARCHAR(255),
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_openid_type ON uploads(openid, type);
+CREATE INDEX IF NOT EXISTS idx_uploads_storage_key ON uploads(storage_key);
+CREATE INDEX IF NOT EXISTS idx_uploads_sha256 ON uploads(sha256);
+
+COMMENT ON TABLE uploads IS '上传文件记录表';
+COMMENT ON COLUMN uploads.id IS '雪花ID';
+COMMENT ON COLUMN uploads.openid IS '上传者OpenID';
+COMMENT ON COLUMN uploads.storage_key IS '存储对象键，相同内容的上传共用同一对象';
+COMMENT ON COLUMN uploads.sha256 IS '文件内容 SHA-256';
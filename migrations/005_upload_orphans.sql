@@ -0,0 +1,8 @@
+-- 上传文件引用跟踪
+-- 头像更换后旧文件标记为孤儿，由后台任务延迟清理
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS orphaned_at BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_uploads_orphaned_at ON uploads(orphaned_at);
+
+COMMENT ON COLUMN uploads.orphaned_at IS '不再被引用的时间(毫秒时间戳)，0 表示仍在使用';
@@ -0,0 +1,34 @@
+-- 通知发件箱
+-- 通知先写入本表，再由后台任务以 FOR UPDATE SKIP LOCKED 分批领取投递，失败按指数退避重试
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT DEFAULT 0,
+    channel VARCHAR(16) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    template_id VARCHAR(128),
+    title VARCHAR(255),
+    content TEXT,
+    payload TEXT,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    sent_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_status_next ON notifications(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient ON notifications(recipient);
+
+COMMENT ON TABLE notifications IS '通知发件箱表';
+COMMENT ON COLUMN notifications.id IS '雪花ID';
+COMMENT ON COLUMN notifications.channel IS '通知渠道: sms/email/push/wechat';
+COMMENT ON COLUMN notifications.recipient IS '接收方: 手机号/邮箱/OpenID/用户ID';
+COMMENT ON COLUMN notifications.payload IS '额外数据(JSON)，微信渠道为订阅消息模板数据';
+COMMENT ON COLUMN notifications.status IS '投递状态: pending/sending/sent/failed';
+COMMENT ON COLUMN notifications.attempts IS '已尝试投递次数';
+COMMENT ON COLUMN notifications.next_attempt_at IS '下次可投递时间(毫秒时间戳)，sending 状态下为领取租约到期时间';
+COMMENT ON COLUMN notifications.last_error IS '最近一次投递错误';
@@ -0,0 +1,27 @@
+-- 用户通知偏好
+-- 免打扰时段按用户时区计算；渠道设置按通知类型与渠道退订，未设置的组合默认接收
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id BIGINT PRIMARY KEY,
+    timezone VARCHAR(64),
+    quiet_hours_start VARCHAR(5),
+    quiet_hours_end VARCHAR(5),
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS notification_channel_settings (
+    user_id BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    channel VARCHAR(16) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type, channel)
+);
+
+COMMENT ON TABLE notification_preferences IS '用户通知偏好表';
+COMMENT ON COLUMN notification_preferences.timezone IS 'IANA 时区，为空时使用系统默认时区';
+COMMENT ON COLUMN notification_preferences.quiet_hours_start IS '免打扰开始时间 HH:MM，为空表示不启用';
+COMMENT ON COLUMN notification_preferences.quiet_hours_end IS '免打扰结束时间 HH:MM，早于开始时间表示跨越午夜';
+COMMENT ON TABLE notification_channel_settings IS '用户通知渠道设置表';
+COMMENT ON COLUMN notification_channel_settings.type IS '通知类型';
+COMMENT ON COLUMN notification_channel_settings.channel IS '通知渠道: sms/email/push/wechat';
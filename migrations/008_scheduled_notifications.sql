@@ -0,0 +1,34 @@
+-- 定时通知
+-- 到期后由后台任务以 FOR UPDATE SKIP LOCKED 领取，在同一事务内推进到下次发送时间后再写入通知发件箱，多实例下至多发送一次
+
+CREATE TABLE IF NOT EXISTS scheduled_notifications (
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    channel VARCHAR(16) NOT NULL,
+    locale VARCHAR(16),
+    variables TEXT,
+    title VARCHAR(255),
+    content TEXT,
+    recurrence VARCHAR(64),
+    timezone VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    next_run_at BIGINT NOT NULL DEFAULT 0,
+    last_run_at BIGINT NOT NULL DEFAULT 0,
+    run_count INT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_notifications_status_next ON scheduled_notifications(status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_notifications_user_id ON scheduled_notifications(user_id);
+
+COMMENT ON TABLE scheduled_notifications IS '定时通知表';
+COMMENT ON COLUMN scheduled_notifications.id IS '雪花ID';
+COMMENT ON COLUMN scheduled_notifications.type IS '通知类型，如 feeding_reminder';
+COMMENT ON COLUMN scheduled_notifications.channel IS '通知渠道: push/wechat';
+COMMENT ON COLUMN scheduled_notifications.variables IS '模板变量(JSON)';
+COMMENT ON COLUMN scheduled_notifications.recurrence IS '重复规则(5 段 cron 表达式)，为空表示单次发送';
+COMMENT ON COLUMN scheduled_notifications.timezone IS '计算重复规则使用的 IANA 时区';
+COMMENT ON COLUMN scheduled_notifications.status IS '状态: active/completed/cancelled';
+COMMENT ON COLUMN scheduled_notifications.next_run_at IS '下次发送时间(毫秒时间戳)';
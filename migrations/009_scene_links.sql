@@ -0,0 +1,25 @@
+-- 小程序码场景短链
+-- 小程序码 scene 最多 32 个字符，业务数据以短码登记在本表，scene 中只放 c=<短码>
+
+CREATE TABLE IF NOT EXISTS scene_links (
+    code VARCHAR(16) PRIMARY KEY,
+    creator_id BIGINT NOT NULL,
+    page VARCHAR(128),
+    payload TEXT NOT NULL,
+    max_uses INT NOT NULL DEFAULT 0,
+    use_count INT NOT NULL DEFAULT 0,
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_scene_links_creator_id ON scene_links(creator_id);
+CREATE INDEX IF NOT EXISTS idx_scene_links_expires_at ON scene_links(expires_at);
+
+COMMENT ON TABLE scene_links IS '小程序码场景短链表';
+COMMENT ON COLUMN scene_links.code IS '短码';
+COMMENT ON COLUMN scene_links.creator_id IS '创建者用户ID';
+COMMENT ON COLUMN scene_links.payload IS '业务数据(JSON)';
+COMMENT ON COLUMN scene_links.max_uses IS '最大使用次数，0 表示不限制';
+COMMENT ON COLUMN scene_links.use_count IS '已使用次数';
+COMMENT ON COLUMN scene_links.expires_at IS '过期时间(毫秒时间戳)，0 表示永不过期';
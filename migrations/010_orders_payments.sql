@@ -0,0 +1,73 @@
+-- 订单、支付单与支付事件
+-- 支付单状态按状态机变更: pending → paid → partially_refunded → refunded，pending → cancelled/failed
+-- 订单与支付单更新时按 version 做乐观锁；支付事件只追加不修改
+
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGINT PRIMARY KEY,
+    order_no VARCHAR(32) NOT NULL,
+    user_id BIGINT NOT NULL,
+    subject VARCHAR(127) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    paid_at BIGINT NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_order_no ON orders(order_no);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
+
+COMMENT ON TABLE orders IS '订单表';
+COMMENT ON COLUMN orders.amount IS '应付金额(分)';
+COMMENT ON COLUMN orders.status IS '订单状态: pending/paid/refunded';
+COMMENT ON COLUMN orders.version IS '乐观锁版本号';
+
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGINT PRIMARY KEY,
+    payment_no VARCHAR(32) NOT NULL,
+    order_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    method VARCHAR(16) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    transaction_id VARCHAR(64),
+    expire_at BIGINT NOT NULL DEFAULT 0,
+    paid_at BIGINT NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_payment_no ON payments(payment_no);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_user_id ON payments(user_id);
+CREATE INDEX IF NOT EXISTS idx_payments_status_expire ON payments(status, expire_at);
+
+COMMENT ON TABLE payments IS '支付单表';
+COMMENT ON COLUMN payments.payment_no IS '商户支付单号，即支付渠道的商户订单号';
+COMMENT ON COLUMN payments.method IS '支付方式: wechat/alipay/balance';
+COMMENT ON COLUMN payments.amount IS '支付金额(分)';
+COMMENT ON COLUMN payments.status IS '支付状态: pending/paid/partially_refunded/refunded/cancelled/failed';
+COMMENT ON COLUMN payments.transaction_id IS '支付渠道交易号';
+COMMENT ON COLUMN payments.expire_at IS '支付过期时间(毫秒时间戳)，过期仍待支付时由后台任务取消';
+COMMENT ON COLUMN payments.version IS '乐观锁版本号';
+
+CREATE TABLE IF NOT EXISTS payment_events (
+    id BIGINT PRIMARY KEY,
+    payment_id BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20),
+    source VARCHAR(16) NOT NULL,
+    detail TEXT,
+    created_at BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_events_payment_id ON payment_events(payment_id);
+
+COMMENT ON TABLE payment_events IS '支付事件表(只追加)';
+COMMENT ON COLUMN payment_events.type IS '事件类型: created/status_changed';
+COMMENT ON COLUMN payment_events.source IS '事件来源: api/notify/query/job';
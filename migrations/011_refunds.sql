@@ -0,0 +1,40 @@
+-- 退款单与支付单累计退款金额
+-- 同一支付单可多次部分退款: 登记退款时占用 refunding_amount，成功后转入 refunded_amount，失败时释放，
+-- refunded_amount + refunding_amount 不超过支付金额；同一支付单内按幂等键去重
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunding_amount BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN payments.refunded_amount IS '累计已退款金额(分)';
+COMMENT ON COLUMN payments.refunding_amount IS '退款处理中占用的金额(分)';
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id BIGINT PRIMARY KEY,
+    refund_no VARCHAR(32) NOT NULL,
+    payment_id BIGINT NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    reason VARCHAR(80),
+    status VARCHAR(16) NOT NULL,
+    channel_refund_id VARCHAR(64),
+    fail_reason VARCHAR(255),
+    attempts INT NOT NULL DEFAULT 0,
+    next_query_at BIGINT NOT NULL DEFAULT 0,
+    succeeded_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_refund_no ON refunds(refund_no);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_payment_key ON refunds(payment_id, idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_refunds_status_next ON refunds(status, next_query_at);
+
+COMMENT ON TABLE refunds IS '退款单表';
+COMMENT ON COLUMN refunds.refund_no IS '商户退款单号，即支付渠道的商户退款单号';
+COMMENT ON COLUMN refunds.idempotency_key IS '退款请求幂等键，同一支付单内唯一';
+COMMENT ON COLUMN refunds.amount IS '退款金额(分)';
+COMMENT ON COLUMN refunds.status IS '退款状态: pending/processing/succeeded/failed';
+COMMENT ON COLUMN refunds.next_query_at IS '下次对账时间(毫秒时间戳)，未完成的退款由后台任务查询支付渠道';
+
+COMMENT ON COLUMN payment_events.type IS '事件类型: created/status_changed/refund_requested/refund_failed';
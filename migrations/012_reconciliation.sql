@@ -0,0 +1,43 @@
+-- 支付对账差异
+-- 按账单日期下载支付渠道对账单，与本地支付单、退款单逐条核对，不一致的记录写入差异表供人工复核；
+-- 同一账单日期重新对账时替换待处理(open)的差异，已处理(resolved)的差异保留
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id BIGINT PRIMARY KEY,
+    bill_date VARCHAR(10) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    record_type VARCHAR(10) NOT NULL,
+    payment_no VARCHAR(32) NOT NULL,
+    refund_no VARCHAR(32) NOT NULL DEFAULT '',
+    transaction_id VARCHAR(64),
+    local_amount BIGINT NOT NULL DEFAULT 0,
+    remote_amount BIGINT NOT NULL DEFAULT 0,
+    detail VARCHAR(255),
+    status VARCHAR(10) NOT NULL,
+    note VARCHAR(255),
+    resolved_by VARCHAR(64),
+    resolved_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT DEFAULT 0,
+    updated_at BIGINT DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_key
+    ON reconciliation_discrepancies(bill_date, type, record_type, payment_no, refund_no);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_status ON reconciliation_discrepancies(status);
+
+-- 对账时按支付成功时间、退款成功时间查询本地记录
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments(paid_at);
+CREATE INDEX IF NOT EXISTS idx_refunds_succeeded_at ON refunds(succeeded_at);
+
+COMMENT ON TABLE reconciliation_discrepancies IS '支付对账差异表';
+COMMENT ON COLUMN reconciliation_discrepancies.bill_date IS '账单日期 YYYY-MM-DD(北京时间)';
+COMMENT ON COLUMN reconciliation_discrepancies.type IS '差异类型: missing_locally/missing_remotely/amount_mismatch/paid_after_close(关闭后收到支付成功通知)';
+COMMENT ON COLUMN reconciliation_discrepancies.record_type IS '记录类型: trade/refund';
+COMMENT ON COLUMN reconciliation_discrepancies.refund_no IS '商户退款单号，仅退款记录';
+COMMENT ON COLUMN reconciliation_discrepancies.transaction_id IS '渠道交易号或渠道退款单号';
+COMMENT ON COLUMN reconciliation_discrepancies.local_amount IS '本地金额(分)，本地不存在时为 0';
+COMMENT ON COLUMN reconciliation_discrepancies.remote_amount IS '渠道金额(分)，渠道不存在时为 0';
+COMMENT ON COLUMN reconciliation_discrepancies.status IS '处理状态: open/resolved';
+COMMENT ON COLUMN reconciliation_discrepancies.resolved_by IS '处理人 OpenID';
+
+COMMENT ON COLUMN payment_events.type IS '事件类型: created/status_changed/refund_requested/refund_failed/paid_after_close';
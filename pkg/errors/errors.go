@@ -10,6 +10,7 @@ var (
 	WithStack = errors.WithStack
 	Wrapf     = errors.Wrapf
 	Is        = errors.Is
+	As        = errors.As
 	Errorf    = errors.Errorf
 )
 
@@ -26,6 +27,7 @@ const (
 	NotFound         ErrorCode = 1003
 	Conflict         ErrorCode = 1004
 	PermissionDenied ErrorCode = 1005
+	TooManyRequests  ErrorCode = 1006
 
 	// 服务器错误 2000-2999
 	InternalError ErrorCode = 2001
@@ -40,6 +42,25 @@ const (
 	FamilyNotFound    ErrorCode = 3005
 	InvalidInvitation ErrorCode = 3006
 	RecordNotFound    ErrorCode = 3007
+	TokenRevoked      ErrorCode = 3008
+
+	// 微信接口错误 3100-3199
+	WechatAPIError           ErrorCode = 3100 // 微信接口调用失败(含 access_token 失效、系统繁忙等可重试错误)
+	WechatInvalidOpenID      ErrorCode = 3101 // openid 无效
+	WechatInvalidTemplate    ErrorCode = 3102 // 订阅消息模板无效
+	WechatSubscribeQuota     ErrorCode = 3103 // 用户未订阅或订阅消息次数已用完
+	WechatRateLimited        ErrorCode = 3104 // 调用频率超过限制
+	WechatInvalidMessageData ErrorCode = 3105 // 订阅消息模板参数不合法
+
+	// 支付错误 3200-3299
+	PaymentGatewayError     ErrorCode = 3200 // 支付渠道接口调用失败
+	PaymentInvalidSignature ErrorCode = 3201 // 支付渠道应答或回调签名验证失败
+	PaymentNotConfigured    ErrorCode = 3202 // 支付渠道未配置
+
+	// 幂等请求错误 3300-3399
+	IdempotencyKeyInProgress ErrorCode = 3300 // 相同幂等键的请求正在处理中
+	IdempotencyKeyMismatch   ErrorCode = 3301 // 幂等键已用于内容不同的请求
+	IdempotencyNoReplay      ErrorCode = 3302 // 相同幂等键的请求已处理，但响应过大未保存，无法重放
 )
 
 // AppError 应用错误
@@ -57,6 +78,11 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
 }
 
+// Unwrap 返回被包装的原始错误，以便 errors.Is/As 判断
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
 // New 创建新错误
 func New(code ErrorCode, message string) *AppError {
 	return &AppError{
@@ -90,4 +116,19 @@ var (
 	ErrFamilyNotFound    = New(FamilyNotFound, "家庭不存在")
 	ErrInvalidInvitation = New(InvalidInvitation, "邀请码无效或已过期")
 	ErrRecordNotFound    = New(RecordNotFound, "记录不存在")
+	ErrTokenRevoked      = New(TokenRevoked, "令牌已失效")
+
+	ErrWechatSubscribeQuota = New(WechatSubscribeQuota, "用户未订阅该消息或订阅次数已用完")
 )
+
+// CodeOf 返回错误链中第一个 AppError 的错误码，不含 AppError 时返回 InternalError
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return Success
+	}
+	var appErr *AppError
+	if As(err, &appErr) {
+		return appErr.Code
+	}
+	return InternalError
+}
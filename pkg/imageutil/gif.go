@@ -0,0 +1,82 @@
+package imageutil
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// GIF 数据块标识
+const (
+	gifExtension       = 0x21
+	gifImageDescriptor = 0x2C
+	gifTrailer         = 0x3B
+)
+
+// checkGIFFrames 在解码前遍历 GIF 数据块，统计帧数与全部帧的像素总数
+// 只读取块头与图像描述符，不解压图像数据；超出 maxFrames 或 maxPixels(为 0 表示不限制)时立即返回，
+// 避免 gif.DecodeAll 为大量帧或超大帧分配内存。数据截断等格式错误留给解码器报告
+func checkGIFFrames(data []byte, maxFrames int, maxPixels int64) error {
+	// 文件头(6 字节)与逻辑屏幕描述符(7 字节)，之后是可选的全局颜色表
+	if len(data) < 13 {
+		return nil
+	}
+	pos := 13 + colorTableSize(data[10])
+
+	frames := 0
+	var pixels int64
+	for pos < len(data) {
+		switch data[pos] {
+		case gifExtension:
+			// 标识、扩展类型，之后为数据子块
+			pos = skipSubBlocks(data, pos+2)
+
+		case gifImageDescriptor:
+			// 标识、左、上、宽、高、标志位，之后为可选的局部颜色表、LZW 最小码长与数据子块
+			if pos+10 > len(data) {
+				return nil
+			}
+			width := int64(binary.LittleEndian.Uint16(data[pos+5:]))
+			height := int64(binary.LittleEndian.Uint16(data[pos+7:]))
+			pos = skipSubBlocks(data, pos+10+colorTableSize(data[pos+9])+1)
+
+			frames++
+			pixels += width * height
+			if maxFrames > 0 && frames > maxFrames {
+				return errors.Wrapf(ErrFramesExceeded, "more than %d frames", maxFrames)
+			}
+			if maxPixels > 0 && pixels > maxPixels {
+				return errors.Wrapf(ErrFramesExceeded, "frames exceed %d pixels in total", maxPixels)
+			}
+
+		case gifTrailer:
+			return nil
+
+		default:
+			// 无法识别的块
+			return nil
+		}
+	}
+	return nil
+}
+
+// colorTableSize 根据标志位计算颜色表字节数，未设置颜色表标志时为 0
+func colorTableSize(flags byte) int {
+	if flags&0x80 == 0 {
+		return 0
+	}
+	return 3 << ((flags & 0x07) + 1)
+}
+
+// skipSubBlocks 跳过以长度为 0 的子块结尾的数据子块序列，返回其后的位置
+func skipSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
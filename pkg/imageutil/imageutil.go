@@ -0,0 +1,146 @@
+package imageutil
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// 支持的图片类型
+const (
+	MIMEJPEG = "image/jpeg"
+	MIMEPNG  = "image/png"
+	MIMEGIF  = "image/gif"
+)
+
+// JPEG 重新编码质量
+const jpegQuality = 90
+
+var (
+	// ErrUnsupportedFormat 不支持的图片格式
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrMalformedImage 图片数据损坏或格式不正确
+	ErrMalformedImage = errors.New("malformed image")
+	// ErrDimensionsExceeded 图片尺寸超出限制
+	ErrDimensionsExceeded = errors.New("image dimensions exceed limit")
+	// ErrFramesExceeded GIF 帧数或全部帧的像素总数超出限制
+	ErrFramesExceeded = errors.New("image frames exceed limit")
+)
+
+// Options 图片处理选项
+type Options struct {
+	MaxWidth  int   // 最大宽度(像素)，0 表示不限制
+	MaxHeight int   // 最大高度(像素)，0 表示不限制
+	MaxFrames int   // GIF 最大帧数，0 表示不限制
+	MaxPixels int64 // GIF 全部帧的像素总数上限，0 表示不限制
+}
+
+// Image 处理后的图片
+type Image struct {
+	Data        []byte      // 重新编码后的图片数据，不含任何元数据
+	Decoded     image.Image // 解码后的图片(已按 EXIF 方向摆正)，GIF 为第一帧
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// DetectContentType 根据文件头嗅探 MIME 类型，不依赖文件名
+func DetectContentType(data []byte) string {
+	if len(data) > 512 {
+		data = data[:512]
+	}
+	return http.DetectContentType(data)
+}
+
+// ExtensionOf 返回图片类型对应的扩展名
+func ExtensionOf(contentType string) string {
+	switch contentType {
+	case MIMEJPEG:
+		return ".jpg"
+	case MIMEPNG:
+		return ".png"
+	case MIMEGIF:
+		return ".gif"
+	default:
+		return ""
+	}
+}
+
+// Sanitize 校验并清洗图片
+// 先读取图片头校验尺寸(GIF 还校验帧数与全部帧的像素总数)，避免解码超大图片耗尽内存；再完整解码以确认数据完好，
+// 按 EXIF 方向摆正后重新编码，从而丢弃 EXIF(含 GPS 定位)、ICC 注释等全部元数据
+func Sanitize(data []byte, opts Options) (*Image, error) {
+	contentType := DetectContentType(data)
+	if ExtensionOf(contentType) == "" {
+		return nil, errors.Wrapf(ErrUnsupportedFormat, "detected %s", contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedImage, err.Error())
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, opts); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	out := &Image{ContentType: contentType, Width: cfg.Width, Height: cfg.Height}
+
+	switch contentType {
+	case MIMEJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(ErrMalformedImage, err.Error())
+		}
+		img = applyOrientation(img, jpegOrientation(data))
+		out.Decoded = img
+		out.Width, out.Height = img.Bounds().Dx(), img.Bounds().Dy()
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("encode jpeg: %w", err)
+		}
+
+	case MIMEPNG:
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(ErrMalformedImage, err.Error())
+		}
+		out.Decoded = img
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("encode png: %w", err)
+		}
+
+	case MIMEGIF:
+		// 保留全部帧，丢弃注释与应用扩展
+		if err := checkGIFFrames(data, opts.MaxFrames, opts.MaxPixels); err != nil {
+			return nil, err
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(ErrMalformedImage, err.Error())
+		}
+		out.Decoded = g.Image[0]
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			return nil, fmt.Errorf("encode gif: %w", err)
+		}
+	}
+
+	out.Data = buf.Bytes()
+	return out, nil
+}
+
+// checkDimensions 校验尺寸限制
+func checkDimensions(width, height int, opts Options) error {
+	if width <= 0 || height <= 0 {
+		return errors.Wrap(ErrMalformedImage, "invalid dimensions")
+	}
+	if (opts.MaxWidth > 0 && width > opts.MaxWidth) || (opts.MaxHeight > 0 && height > opts.MaxHeight) {
+		return errors.Wrapf(ErrDimensionsExceeded, "%dx%d exceeds %dx%d", width, height, opts.MaxWidth, opts.MaxHeight)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package imageutil
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+// 缩略图编码格式
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp" // 无损 WebP，纯 Go 编码
+)
+
+// MIMEWebP WebP 图片类型
+const MIMEWebP = "image/webp"
+
+// Resize 等比缩放图片，使长边不超过 maxEdge
+// 只缩小不放大；使用 Catmull-Rom 插值以保证缩略图清晰度
+func Resize(img image.Image, maxEdge int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxEdge <= 0 || (w <= maxEdge && h <= maxEdge) {
+		return img
+	}
+
+	dstW, dstH := maxEdge, maxEdge
+	if w >= h {
+		dstH = max(1, h*maxEdge/w)
+	} else {
+		dstW = max(1, w*maxEdge/h)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Encode 按指定格式编码图片
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	case FormatWebP:
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported encode format %q", format)
+	}
+}
+
+// FormatExtension 返回编码格式对应的扩展名
+func FormatExtension(format string) string {
+	switch format {
+	case FormatJPEG:
+		return ".jpg"
+	case FormatWebP:
+		return ".webp"
+	default:
+		return ""
+	}
+}
+
+// FormatContentType 返回编码格式对应的 MIME 类型
+func FormatContentType(format string) string {
+	switch format {
+	case FormatJPEG:
+		return MIMEJPEG
+	case FormatWebP:
+		return MIMEWebP
+	default:
+		return ""
+	}
+}
@@ -67,7 +67,7 @@ func getHTTPStatus(code errors.ErrorCode) int {
 		return http.StatusOK
 	case errors.ParamError:
 		return http.StatusBadRequest
-	case errors.Unauthorized, errors.InvalidToken, errors.TokenExpired:
+	case errors.Unauthorized, errors.InvalidToken, errors.TokenExpired, errors.TokenRevoked:
 		return http.StatusUnauthorized
 	case errors.NotFound, errors.UserNotFound, errors.BabyNotFound,
 		errors.FamilyNotFound, errors.RecordNotFound:
@@ -76,6 +76,24 @@ func getHTTPStatus(code errors.ErrorCode) int {
 		return http.StatusConflict
 	case errors.PermissionDenied:
 		return http.StatusForbidden
+	case errors.WechatSubscribeQuota:
+		return http.StatusForbidden
+	case errors.TooManyRequests, errors.WechatRateLimited:
+		return http.StatusTooManyRequests
+	case errors.WechatAPIError:
+		return http.StatusBadGateway
+	case errors.InvalidInvitation:
+		return http.StatusGone
+	case errors.PaymentGatewayError:
+		return http.StatusBadGateway
+	case errors.PaymentInvalidSignature:
+		return http.StatusUnauthorized
+	case errors.PaymentNotConfigured:
+		return http.StatusServiceUnavailable
+	case errors.IdempotencyKeyInProgress, errors.IdempotencyNoReplay:
+		return http.StatusConflict
+	case errors.IdempotencyKeyMismatch:
+		return http.StatusUnprocessableEntity
 	default:
 		return http.StatusInternalServerError
 	}
@@ -4,15 +4,20 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"math/big"
+	"sort"
 	"strings"
 )
 
 // GenerateShortCode 生成6位短码（字母数字组合）
 // 使用 Base36 编码（0-9, A-Z），避免混淆字符（如0和O，1和I）
 func GenerateShortCode() (string, error) {
+	return GenerateCode(6)
+}
+
+// GenerateCode 生成指定长度的随机码，字符集与 GenerateShortCode 相同，每个字符 5 位熵
+func GenerateCode(codeLength int) (string, error) {
 	// 字符集：去除容易混淆的字符 0,O,1,I,l
 	const charset = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
-	const codeLength = 6
 
 	result := make([]byte, codeLength)
 	charsetLength := big.NewInt(int64(len(charset)))
@@ -40,7 +45,7 @@ func GenerateToken(length int) (string, error) {
 }
 
 // ParseScene 解析小程序码 scene 参数
-// 格式: "key1=val1&key2=val2"
+// 格式: "key1=val1&key2=val2"，缺少 "=" 或键为空的片段忽略，重复的键以最后一个为准
 // 返回: map[string]string
 func ParseScene(scene string) map[string]string {
 	result := make(map[string]string)
@@ -50,26 +55,43 @@ func ParseScene(scene string) map[string]string {
 
 	pairs := strings.Split(scene, "&")
 	for _, pair := range pairs {
-		parts := strings.SplitN(pair, "=", 2)
-		if len(parts) == 2 {
-			result[parts[0]] = parts[1]
+		key, value, ok := strings.Cut(pair, "=")
+		if ok && key != "" {
+			result[key] = value
 		}
 	}
 
 	return result
 }
 
+// NormalizeScene 规范化小程序码 scene 参数
+// scene 为 "key1=val1&key2=val2" 格式时按键排序，参数顺序不同的相同 scene 得到相同结果；
+// 其他格式(如 "12345")或含空键、重复键时原样返回，避免丢失内容
+func NormalizeScene(scene string) string {
+	params := ParseScene(scene)
+	if len(params) == 0 || len(params) != strings.Count(scene, "&")+1 {
+		return scene
+	}
+	return BuildScene(params)
+}
+
 // BuildScene 构建小程序码 scene 参数
-// 格式: "key1=val1&key2=val2"
+// 格式: "key1=val1&key2=val2"，按键排序，相同参数总是得到相同的 scene
 // 注意: 总长度不能超过32个字符
 func BuildScene(params map[string]string) string {
 	if len(params) == 0 {
 		return ""
 	}
 
-	var parts []string
-	for key, value := range params {
-		parts = append(parts, key+"="+value)
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+params[key])
 	}
 
 	return strings.Join(parts, "&")
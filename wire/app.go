@@ -2,22 +2,32 @@ package wire
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wxlbd/polaris/internal/application/service"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
+	"github.com/wxlbd/polaris/internal/interface/job"
 )
 
 // App 应用程序
 type App struct {
-	Config *config.Config
-	Router *gin.Engine
+	Config    *config.Config
+	Router    *gin.Engine
+	Scheduler *job.Scheduler
+
+	Reconciliation *service.ReconciliationService // 支付对账，供 reconcile 子命令使用
 }
 
 // NewApp 创建应用实例
 func NewApp(
 	cfg *config.Config,
 	router *gin.Engine,
+	scheduler *job.Scheduler,
+	reconciliation *service.ReconciliationService,
 ) *App {
 	return &App{
-		Config: cfg,
-		Router: router,
+		Config:    cfg,
+		Router:    router,
+		Scheduler: scheduler,
+
+		Reconciliation: reconciliation,
 	}
 }
@@ -6,12 +6,18 @@ package wire
 import (
 	"github.com/google/wire"
 	"github.com/wxlbd/polaris/internal/application/service"
+	domainservice "github.com/wxlbd/polaris/internal/domain/service"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
 	"github.com/wxlbd/polaris/internal/infrastructure/logger"
+	"github.com/wxlbd/polaris/internal/infrastructure/notification"
+	"github.com/wxlbd/polaris/internal/infrastructure/payment"
 	"github.com/wxlbd/polaris/internal/infrastructure/persistence"
+	"github.com/wxlbd/polaris/internal/infrastructure/storage"
+	"github.com/wxlbd/polaris/internal/infrastructure/token"
 	"github.com/wxlbd/polaris/internal/infrastructure/wechat"
 	"github.com/wxlbd/polaris/internal/interface/http/handler"
 	"github.com/wxlbd/polaris/internal/interface/http/router"
+	"github.com/wxlbd/polaris/internal/interface/job"
 )
 
 // InitApp 初始化应用(Wire自动生成)
@@ -20,25 +26,85 @@ func InitApp(cfg *config.Config) (*App, error) {
 		// 基础设施层
 		logger.NewLogger, // 日志系统
 		persistence.NewDatabase,
-		persistence.NewRedis, // Redis 客户端
-		wechat.NewClient,     // 微信 SDK 客户端
+		persistence.NewRedis,        // Redis 客户端
+		wechat.NewClient,            // 微信 SDK 客户端
+		payment.NewWechatPayGateway, // 微信支付 API v3 网关
+		token.NewKeyManager,         // JWT 密钥管理
+		storage.NewFileStorage,      // 文件存储(本地/S3)
 
 		// 仓储层
 		persistence.NewUserRepository,
-		persistence.NewAppVersionRepository, // 应用版本仓储
+		persistence.NewAppVersionRepository,             // 应用版本仓储
+		persistence.NewTokenRepository,                  // 令牌仓储(Redis)
+		persistence.NewRoleRepository,                   // 角色仓储
+		persistence.NewUploadRepository,                 // 上传文件记录仓储
+		persistence.NewUploadSessionRepository,          // 断点续传会话仓储(Redis)
+		persistence.NewNotificationRepository,           // 通知发件箱仓储
+		persistence.NewNotificationPreferenceRepository, // 通知偏好仓储
+		persistence.NewScheduledNotificationRepository,  // 定时通知仓储
+		persistence.NewSubscribeQuotaRepository,         // 微信订阅消息额度仓储(Redis)
+		persistence.NewSceneLinkRepository,              // 小程序码场景短链仓储
+		persistence.NewOrderRepository,                  // 订单仓储
+		persistence.NewPaymentRepository,                // 支付单仓储
+		persistence.NewRefundRepository,                 // 退款单仓储
+		persistence.NewIdempotencyRepository,            // 写请求幂等记录仓储(Redis)
+		persistence.NewRateLimitRepository,              // 请求频率限制仓储(Redis)
+		persistence.NewReconciliationRepository,         // 支付对账差异仓储
+		persistence.NewLockRepository,                   // 分布式锁仓储(Redis)
+
+		// 通知投递
+		notification.NewDeliverers,       // 各渠道投递器(邮件/微信)
+		notification.NewOutboxSender,     // 发件箱通知发送器
+		notification.NewDispatcher,       // 发件箱投递器
+		notification.NewRedisRateLimiter, // 通知频率限制(Redis)
+		notification.NewPolicy,           // 通知发送策略(免打扰、频率限制)
+		notification.NewTemplateRegistry, // 通知模板注册表
+		wire.Bind(new(domainservice.NotificationSender), new(*notification.OutboxSender)),
+		wire.Bind(new(domainservice.NotificationRateLimiter), new(*notification.RedisRateLimiter)),
+		wire.Bind(new(domainservice.NotificationRenderer), new(*notification.TemplateRegistry)),
+		wire.Bind(new(notification.SubscribeMessageSender), new(*service.WechatService)),
+
+		// 支付
+		wire.Bind(new(domainservice.PaymentGateway), new(*payment.WechatPayGateway)),
+
+		// 领域服务层
+		domainservice.NewNotificationDomainService,
+		domainservice.NewPaymentDomainService,
+		domainservice.NewReconciliationDomainService,
 
 		// 应用服务层
 		service.NewAuthService,
-		service.NewUploadService,     // 文件上传服务
-		service.NewAppVersionService, // 应用版本服务
+		service.NewUploadService,                // 文件上传服务
+		service.NewResumableUploadService,       // 断点续传上传服务
+		service.NewDirectUploadService,          // 预签名直传服务
+		service.NewAppVersionService,            // 应用版本服务
+		service.NewPermissionService,            // 权限服务
+		service.NewWechatService,                // 微信服务
+		service.NewNotificationService,          // 通知服务
+		service.NewScheduledNotificationService, // 定时通知服务
+		service.NewSceneLinkService,             // 小程序码场景短链服务
+		service.NewPaymentService,               // 支付服务
+		service.NewReconciliationService,        // 支付对账服务
 
 		// HTTP处理器
 		handler.NewAuthHandler,
-		handler.NewUploadHandler, // 文件上传处理器
+		handler.NewUploadHandler,                // 文件上传处理器
+		handler.NewResumableUploadHandler,       // 断点续传处理器
+		handler.NewDirectUploadHandler,          // 预签名直传处理器
+		handler.NewAppVersionHandler,            // 应用版本管理处理器
+		handler.NewNotificationHandler,          // 通知投递记录处理器
+		handler.NewScheduledNotificationHandler, // 定时通知处理器
+		handler.NewWechatHandler,                // 微信订阅消息与小程序码处理器
+		handler.NewSceneLinkHandler,             // 小程序码场景短链处理器
+		handler.NewPaymentHandler,               // 支付回调处理器
+		handler.NewReconciliationHandler,        // 支付对账处理器
 
 		// 路由
 		router.NewRouter,
 
+		// 后台任务
+		job.NewScheduler,
+
 		// 应用
 		NewApp,
 	)
@@ -8,12 +8,18 @@ package wire
 
 import (
 	"github.com/wxlbd/polaris/internal/application/service"
+	service2 "github.com/wxlbd/polaris/internal/domain/service"
 	"github.com/wxlbd/polaris/internal/infrastructure/config"
 	"github.com/wxlbd/polaris/internal/infrastructure/logger"
+	"github.com/wxlbd/polaris/internal/infrastructure/notification"
+	"github.com/wxlbd/polaris/internal/infrastructure/payment"
 	"github.com/wxlbd/polaris/internal/infrastructure/persistence"
+	"github.com/wxlbd/polaris/internal/infrastructure/storage"
+	"github.com/wxlbd/polaris/internal/infrastructure/token"
 	"github.com/wxlbd/polaris/internal/infrastructure/wechat"
 	"github.com/wxlbd/polaris/internal/interface/http/handler"
 	"github.com/wxlbd/polaris/internal/interface/http/router"
+	"github.com/wxlbd/polaris/internal/interface/job"
 )
 
 // Injectors from wire.go:
@@ -25,22 +31,87 @@ func InitApp(cfg *config.Config) (*App, error) {
 		return nil, err
 	}
 	userRepository := persistence.NewUserRepository(db)
+	roleRepository := persistence.NewRoleRepository(db)
 	client, err := persistence.NewRedis(cfg)
 	if err != nil {
 		return nil, err
 	}
+	tokenRepository := persistence.NewTokenRepository(client)
+	keyManager, err := token.NewKeyManager(cfg)
+	if err != nil {
+		return nil, err
+	}
 	wechatClient := wechat.NewClient(cfg, client)
-	authService := service.NewAuthService(userRepository, cfg, wechatClient)
+	fileStorage, err := storage.NewFileStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	uploadRepository := persistence.NewUploadRepository(db)
+	lockRepository := persistence.NewLockRepository(client)
+	uploadService := service.NewUploadService(cfg, fileStorage, uploadRepository, userRepository, lockRepository)
+	authService := service.NewAuthService(userRepository, roleRepository, tokenRepository, keyManager, cfg, wechatClient, uploadService)
 	appVersionRepository := persistence.NewAppVersionRepository(db)
 	appVersionService := service.NewAppVersionService(appVersionRepository)
 	authHandler := handler.NewAuthHandler(authService, appVersionService)
-	uploadService := service.NewUploadService(cfg)
 	uploadHandler := handler.NewUploadHandler(uploadService)
+	uploadSessionRepository := persistence.NewUploadSessionRepository(client)
 	zapLogger, err := logger.NewLogger(cfg)
 	if err != nil {
 		return nil, err
 	}
-	engine := router.NewRouter(cfg, authHandler, uploadHandler, zapLogger)
-	app := NewApp(cfg, engine)
+	resumableUploadService := service.NewResumableUploadService(cfg, uploadService, uploadSessionRepository, fileStorage, zapLogger)
+	resumableUploadHandler := handler.NewResumableUploadHandler(resumableUploadService)
+	directUploadService := service.NewDirectUploadService(cfg, uploadService, resumableUploadService, uploadSessionRepository, fileStorage)
+	directUploadHandler := handler.NewDirectUploadHandler(directUploadService)
+	appVersionHandler := handler.NewAppVersionHandler(appVersionService)
+	notificationRepository := persistence.NewNotificationRepository(db)
+	subscribeQuotaRepository := persistence.NewSubscribeQuotaRepository(client)
+	wechatService := service.NewWechatService(wechatClient, fileStorage, subscribeQuotaRepository, cfg, zapLogger)
+	deliverers := notification.NewDeliverers(cfg, wechatService, zapLogger)
+	outboxSender := notification.NewOutboxSender(notificationRepository, deliverers)
+	notificationPolicy, err := notification.NewPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	templateRegistry, err := notification.NewTemplateRegistry(cfg, notificationPolicy)
+	if err != nil {
+		return nil, err
+	}
+	notificationPreferenceRepository := persistence.NewNotificationPreferenceRepository(db)
+	redisRateLimiter := notification.NewRedisRateLimiter(client)
+	notificationDomainService := service2.NewNotificationDomainService(outboxSender, templateRegistry, notificationPreferenceRepository, redisRateLimiter, notificationPolicy)
+	notificationService := service.NewNotificationService(notificationDomainService, notificationRepository, notificationPreferenceRepository, userRepository)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	scheduledNotificationRepository := persistence.NewScheduledNotificationRepository(db)
+	scheduledNotificationService := service.NewScheduledNotificationService(cfg, notificationDomainService, scheduledNotificationRepository, notificationPreferenceRepository, userRepository, zapLogger)
+	scheduledNotificationHandler := handler.NewScheduledNotificationHandler(scheduledNotificationService)
+	wechatHandler := handler.NewWechatHandler(wechatService)
+	sceneLinkRepository := persistence.NewSceneLinkRepository(db)
+	sceneLinkService := service.NewSceneLinkService(cfg, sceneLinkRepository, userRepository, wechatService)
+	sceneLinkHandler := handler.NewSceneLinkHandler(sceneLinkService)
+	wechatPayGateway, err := payment.NewWechatPayGateway(cfg, zapLogger)
+	if err != nil {
+		return nil, err
+	}
+	orderRepository := persistence.NewOrderRepository(db)
+	paymentRepository := persistence.NewPaymentRepository(db)
+	refundRepository := persistence.NewRefundRepository(db)
+	reconciliationRepository := persistence.NewReconciliationRepository(db)
+	paymentDomainService := service2.NewPaymentDomainService(wechatPayGateway, orderRepository, paymentRepository, refundRepository, reconciliationRepository)
+	paymentService := service.NewPaymentService(cfg, paymentDomainService, wechatPayGateway, zapLogger)
+	paymentHandler := handler.NewPaymentHandler(paymentService)
+	reconciliationDomainService := service2.NewReconciliationDomainService(wechatPayGateway, paymentRepository, refundRepository, reconciliationRepository)
+	reconciliationService, err := service.NewReconciliationService(cfg, reconciliationDomainService, reconciliationRepository, lockRepository, zapLogger)
+	if err != nil {
+		return nil, err
+	}
+	reconciliationHandler := handler.NewReconciliationHandler(reconciliationService)
+	idempotencyRepository := persistence.NewIdempotencyRepository(client)
+	rateLimitRepository := persistence.NewRateLimitRepository(client)
+	permissionService := service.NewPermissionService(roleRepository)
+	engine := router.NewRouter(cfg, authHandler, uploadHandler, resumableUploadHandler, directUploadHandler, appVersionHandler, notificationHandler, scheduledNotificationHandler, wechatHandler, sceneLinkHandler, paymentHandler, reconciliationHandler, tokenRepository, idempotencyRepository, rateLimitRepository, keyManager, permissionService, zapLogger)
+	dispatcher := notification.NewDispatcher(cfg, notificationRepository, deliverers, zapLogger)
+	scheduler := job.NewScheduler(cfg, uploadService, resumableUploadService, dispatcher, scheduledNotificationService, sceneLinkService, paymentService, reconciliationService, zapLogger)
+	app := NewApp(cfg, engine, scheduler, reconciliationService)
 	return app, nil
 }